@@ -56,4 +56,37 @@ type AddonSpec struct {
 	// version of the software we are packaging.  But we always want to reinstall when we
 	// switch kubernetes versions.
 	Id string `json:"id,omitempty"`
+
+	// Prune deletes the objects applied by earlier versions of the addon which are missing from the manifest,
+	// once the manifest has been applied.  Defaults to true.
+	Prune *bool `json:"prune,omitempty"`
+
+	// Health defines the workloads which must become ready once the manifest has been applied.  If they are not
+	// ready within the timeout, the previously installed version of the addon is applied again.
+	Health *AddonHealthSpec `json:"health,omitempty"`
+
+	// Dependencies are the names of the addons which must be applied before this addon.  Before this addon is
+	// updated, the workloads in the health spec of each dependency must be ready.
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// AddonHealthSpec defines the workloads checked after an addon is applied
+type AddonHealthSpec struct {
+	// Deployments must have all their replicas updated and available
+	Deployments []AddonObjectReference `json:"deployments,omitempty"`
+
+	// DaemonSets must have their pods updated and available on every node on which they are scheduled
+	DaemonSets []AddonObjectReference `json:"daemonSets,omitempty"`
+
+	// Timeout is how long to wait for the workloads to become ready; defaults to 5 minutes
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// AddonObjectReference refers to an object applied by an addon
+type AddonObjectReference struct {
+	// Namespace is the namespace of the object, defaulting to the namespace of the addon
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the object
+	Name string `json:"name,omitempty"`
 }
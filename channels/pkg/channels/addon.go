@@ -19,13 +19,28 @@ package channels
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/kops/channels/pkg/api"
+	"k8s.io/kops/util/pkg/vfs"
 )
 
+const (
+	// LabelAddonName is set on each object applied for an addon, to the name of the addon
+	LabelAddonName = "addon.kops.k8s.io/name"
+	// LabelAddonVersion is set on each object applied for an addon, to the version of the addon
+	LabelAddonVersion = "addon.kops.k8s.io/version"
+)
+
+// invalidLabelValueCharacters matches the characters which are not allowed in a label value
+var invalidLabelValueCharacters = regexp.MustCompile("[^-A-Za-z0-9_.]")
+
 // Addon is a wrapper around a single version of an addon
 type Addon struct {
 	Name            string
@@ -39,6 +54,12 @@ type AddonUpdate struct {
 	Name            string
 	ExistingVersion *ChannelVersion
 	NewVersion      *ChannelVersion
+	// Results are the outcome of applying each object of the manifest
+	Results []*ApplyResult
+	// RolledBackTo is the version applied again after the update failed its health checks
+	RolledBackTo *ChannelVersion
+	// RollbackResults are the outcome of applying each object of the manifest of the version rolled back to
+	RollbackResults []*ApplyResult
 }
 
 // AddonMenu is a collection of addons, with helpers for computing the latest versions
@@ -99,6 +120,16 @@ func (a *Addon) GetRequiredUpdates(k8sClient kubernetes.Interface) (*AddonUpdate
 		return nil, nil
 	}
 
+	// An update which failed its health checks is not retried until a newer version is available
+	failed, err := channel.GetFailedUpdate(k8sClient)
+	if err != nil {
+		return nil, err
+	}
+	if failed != nil && failed.Version != nil && !newVersion.replaces(failed.Version) {
+		glog.Infof("Not updating addon %q to %s, which failed its health checks: %s", a.Name, newVersion, failed.Message)
+		return nil, nil
+	}
+
 	return &AddonUpdate{
 		Name:            a.Name,
 		ExistingVersion: existingVersion,
@@ -106,7 +137,13 @@ func (a *Addon) GetRequiredUpdates(k8sClient kubernetes.Interface) (*AddonUpdate
 	}, nil
 }
 
-func (a *Addon) EnsureUpdated(k8sClient kubernetes.Interface) (*AddonUpdate, error) {
+// EnsureUpdated applies the manifest of the addon if it replaces the installed version, prunes the objects
+// of the addon missing from the manifest (unless disabled), and records the new version and its manifest.
+// If the addon has a health spec, the installed version is only recorded once its workloads are ready; otherwise the
+// previously installed manifest is applied again, the failed update is recorded, and an error is returned.
+// If the manifest fails to apply, the update is returned along with the error, with the result for each object.
+// In a dry run, the installed version is not changed and the health spec is not checked.
+func (a *Addon) EnsureUpdated(k8sClient kubernetes.Interface, applier *Applier) (*AddonUpdate, error) {
 	required, err := a.GetRequiredUpdates(k8sClient)
 	if err != nil {
 		return nil, err
@@ -129,12 +166,51 @@ func (a *Addon) EnsureUpdated(k8sClient kubernetes.Interface) (*AddonUpdate, err
 	}
 	glog.Infof("Applying update from %q", manifestURL)
 
-	err = Apply(manifestURL.String())
+	// We read the manifest through vfs because it is likely e.g. an s3 URL
+	data, err := vfs.Context.ReadFile(manifestURL.String())
 	if err != nil {
-		return nil, fmt.Errorf("error applying update from %q: %v", manifest, err)
+		return nil, fmt.Errorf("error reading manifest %q: %v", manifest, err)
 	}
 
 	channel := a.buildChannel()
+	checkHealth := a.Spec.Health != nil && !applier.DryRun
+
+	// The previous manifest is only needed to roll back an update which fails its health checks
+	var previous *InstalledManifest
+	if checkHealth {
+		previous, err = channel.GetInstalledManifest(k8sClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	required.Results, err = applier.ApplyManifest(data, a.labels(a.Spec.Version))
+	if err != nil {
+		return required, fmt.Errorf("error applying update from %q: %v", manifest, err)
+	}
+
+	if checkHealth {
+		if err := a.WaitHealthy(k8sClient); err != nil {
+			return required, a.rollback(k8sClient, applier, required, previous, err)
+		}
+	}
+
+	pruned, err := a.prune(applier, required.Results)
+	required.Results = append(required.Results, pruned...)
+	if err != nil {
+		return required, err
+	}
+
+	if applier.DryRun {
+		return required, nil
+	}
+
+	// The manifest is recorded whatever the health spec, as a later version can add one and need to roll back to it
+	installed := &InstalledManifest{Version: a.ChannelVersion(), Manifest: data}
+	if err := channel.SetInstalledManifest(k8sClient, installed); err != nil {
+		glog.Warningf("unable to record manifest of addon %q; a failed update will not be rolled back: %v", a.Name, err)
+	}
+
 	err = channel.SetInstalledVersion(k8sClient, a.ChannelVersion())
 	if err != nil {
 		return nil, fmt.Errorf("error applying annotation to record addon installation: %v", err)
@@ -142,3 +218,69 @@ func (a *Addon) EnsureUpdated(k8sClient kubernetes.Interface) (*AddonUpdate, err
 
 	return required, nil
 }
+
+// rollback records an update which failed its health checks, and applies the previously installed manifest again.
+// It returns the error to report for the update.
+func (a *Addon) rollback(k8sClient kubernetes.Interface, applier *Applier, update *AddonUpdate, previous *InstalledManifest, healthErr error) error {
+	channel := a.buildChannel()
+
+	failed := &FailedUpdate{
+		Version:   a.ChannelVersion(),
+		Message:   healthErr.Error(),
+		Timestamp: metav1.Now(),
+	}
+	if err := channel.SetFailedUpdate(k8sClient, failed); err != nil {
+		glog.Warningf("unable to record failed update of addon %q: %v", a.Name, err)
+	}
+
+	if previous == nil {
+		return fmt.Errorf("%v; no previous version of the addon is recorded, so it was not rolled back", healthErr)
+	}
+
+	glog.Warningf("Rolling back addon %q to %s", a.Name, previous.Version)
+	update.RolledBackTo = previous.Version
+
+	results, err := applier.ApplyManifest(previous.Manifest, a.labels(previous.Version.Version))
+	update.RollbackResults = results
+	if err != nil {
+		return fmt.Errorf("%v; error rolling back to %s: %v", healthErr, previous.Version, err)
+	}
+
+	pruned, err := a.prune(applier, results)
+	update.RollbackResults = append(update.RollbackResults, pruned...)
+	if err != nil {
+		return fmt.Errorf("%v; error rolling back to %s: %v", healthErr, previous.Version, err)
+	}
+
+	return fmt.Errorf("%v; rolled back to %s", healthErr, previous.Version)
+}
+
+// labels returns the labels set on each object applied for the given version of the addon
+func (a *Addon) labels(version *string) map[string]string {
+	return map[string]string{
+		LabelAddonName:    labelValue(a.Name),
+		LabelAddonVersion: labelValue(stringValue(version)),
+	}
+}
+
+// prune deletes the objects of the addon which are not in the applied results, unless pruning is disabled
+func (a *Addon) prune(applier *Applier, applied []*ApplyResult) ([]*ApplyResult, error) {
+	if a.Spec.Prune != nil && !*a.Spec.Prune {
+		return nil, nil
+	}
+
+	pruned, err := applier.Prune(map[string]string{LabelAddonName: labelValue(a.Name)}, applied)
+	if err != nil {
+		return pruned, fmt.Errorf("error pruning objects of %q: %v", a.Name, err)
+	}
+	return pruned, nil
+}
+
+// labelValue converts s to a valid label value, replacing the characters which are not allowed
+func labelValue(s string) string {
+	v := invalidLabelValueCharacters.ReplaceAllString(s, "_")
+	if len(v) > validation.LabelValueMaxLength {
+		v = v[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(v, "-_.")
+}
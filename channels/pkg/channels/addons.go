@@ -34,6 +34,12 @@ type Addons struct {
 	APIObject       *api.Addons
 }
 
+// WellKnownChannelLocation returns the location of a well-known channel, given by a name with no slashes:
+// the addon of that name in the kops repository
+func WellKnownChannelLocation(name string) string {
+	return "https://raw.githubusercontent.com/kubernetes/kops/master/addons/" + name + "/addon.yaml"
+}
+
 func LoadAddons(name string, location *url.URL) (*Addons, error) {
 	glog.V(2).Infof("Loading addons channel from %q", location)
 	data, err := vfs.Context.ReadFile(location.String())
@@ -17,6 +17,7 @@ limitations under the License.
 package channels
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/blang/semver"
@@ -150,6 +151,22 @@ func Test_Replacement(t *testing.T) {
 	}
 }
 
+func Test_LabelValue(t *testing.T) {
+	grid := map[string]string{
+		"kube-dns.addons.k8s.io": "kube-dns.addons.k8s.io",
+		"1.14.10":                "1.14.10",
+		"1.0.0+build.5":          "1.0.0_build.5",
+		"":                       "",
+		"-leading/trailing/":     "leading_trailing",
+		strings.Repeat("a", 70):  strings.Repeat("a", 63),
+	}
+	for input, expected := range grid {
+		if actual := labelValue(input); actual != expected {
+			t.Errorf("unexpected label value for %q: %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
 func s(v string) *string {
 	return &v
 }
@@ -17,60 +17,452 @@ limitations under the License.
 package channels
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
-	"path"
+	"io"
 	"strings"
 
 	"github.com/golang/glog"
-	"k8s.io/kops/util/pkg/vfs"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
 )
 
-// Apply calls kubectl apply to apply the manifest.
-// We will likely in future change this to create things directly (or more likely embed this logic into kubectl itself)
-func Apply(manifest string) error {
-	// We copy the manifest to a temp file because it is likely e.g. an s3 URL, which kubectl can't read
-	data, err := vfs.Context.ReadFile(manifest)
+const (
+	// ApplyActionCreated is the action for an object which did not exist
+	ApplyActionCreated = "created"
+	// ApplyActionConfigured is the action for an existing object which was patched
+	ApplyActionConfigured = "configured"
+	// ApplyActionUnchanged is the action for an existing object which already matched the manifest
+	ApplyActionUnchanged = "unchanged"
+	// ApplyActionPruned is the action for an object which was deleted because it is no longer in the manifest
+	ApplyActionPruned = "pruned"
+)
+
+// ApplyResult is the outcome of applying one object of a manifest
+type ApplyResult struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Action is what was done to the object, or would have been done in a dry run
+	Action string
+	// Error is set if the object could not be applied
+	Error error
+}
+
+// Applier applies manifests in-process, the same way as kubectl apply: the configuration applied is recorded
+// in the last-applied-configuration annotation of each object, and an existing object is patched with a
+// three-way merge of the previous configuration, the manifest and the live object.
+type Applier struct {
+	// Dynamic is the client used to read and write the objects
+	Dynamic dynamic.Interface
+	// Discovery is used to map the kind of each object to its API resource
+	Discovery discovery.DiscoveryInterface
+	// DryRun computes the result for each object without changing the cluster
+	DryRun bool
+
+	mapper         meta.RESTMapper
+	groupResources []*restmapper.APIGroupResources
+	// dryRunKinds are the kinds of the CustomResourceDefinitions which would have been created by a dry run
+	dryRunKinds map[schema.GroupKind]bool
+}
+
+// NewApplier builds an Applier using the given clients
+func NewApplier(dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) *Applier {
+	return &Applier{
+		Dynamic:   dynamicClient,
+		Discovery: discoveryClient,
+	}
+}
+
+// ApplyManifest applies the objects of a yaml or json manifest, which may hold several documents or lists
+func (a *Applier) ApplyManifest(data []byte, extraLabels map[string]string) ([]*ApplyResult, error) {
+	objects, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*ApplyResult
+	failed := 0
+	for _, obj := range objects {
+		if len(extraLabels) != 0 {
+			objLabels := obj.GetLabels()
+			if objLabels == nil {
+				objLabels = make(map[string]string)
+			}
+			for k, v := range extraLabels {
+				objLabels[k] = v
+			}
+			obj.SetLabels(objLabels)
+		}
+
+		result := a.applyObject(obj)
+		if result.Error != nil {
+			glog.Warningf("error applying %s %q: %v", result.Kind, result.Name, result.Error)
+			failed++
+		} else {
+			glog.V(2).Infof("%s %q %s", result.Kind, result.Name, result.Action)
+		}
+		results = append(results, result)
+	}
+
+	if failed != 0 {
+		return results, fmt.Errorf("%d of %d objects could not be applied", failed, len(results))
+	}
+	return results, nil
+}
+
+// parseManifest splits a manifest into its objects, expanding lists
+func parseManifest(data []byte) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("error parsing manifest: %v", err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			// An empty document
+			continue
+		}
+
+		obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, raw)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing object in manifest: %v", err)
+		}
+
+		switch obj := obj.(type) {
+		case *unstructured.Unstructured:
+			objects = append(objects, obj)
+		case *unstructured.UnstructuredList:
+			for i := range obj.Items {
+				objects = append(objects, &obj.Items[i])
+			}
+		default:
+			return nil, fmt.Errorf("unexpected object type %T in manifest", obj)
+		}
+	}
+
+	return objects, nil
+}
+
+func (a *Applier) applyObject(obj *unstructured.Unstructured) *ApplyResult {
+	gvk := obj.GroupVersionKind()
+	result := &ApplyResult{
+		Kind:      gvk.Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+
+	if obj.GetName() == "" {
+		result.Error = fmt.Errorf("object has no name")
+		return result
+	}
+
+	mapping, err := a.restMapping(gvk)
+	if err != nil {
+		if a.DryRun && meta.IsNoMatchError(err) && a.dryRunKinds[gvk.GroupKind()] {
+			// The CustomResourceDefinition is created earlier in the manifest, so the object cannot exist yet
+			result.Action = ApplyActionCreated
+			return result
+		}
+		result.Error = err
+		return result
+	}
+
+	var client dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
+			result.Namespace = metav1.NamespaceDefault
+		}
+		client = a.Dynamic.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	} else {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "namespace")
+		result.Namespace = ""
+		client = a.Dynamic.Resource(mapping.Resource)
+	}
+
+	modified, err := setLastAppliedConfiguration(obj)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	current, err := client.Get(obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			result.Error = fmt.Errorf("error getting object: %v", err)
+			return result
+		}
+
+		if a.DryRun {
+			a.recordDryRunKind(obj)
+		} else if _, err := client.Create(obj); err != nil {
+			result.Error = fmt.Errorf("error creating object: %v", err)
+			return result
+		}
+		result.Action = ApplyActionCreated
+		return result
+	}
+
+	patchType, patch, err := createApplyPatch(gvk, current, modified)
 	if err != nil {
-		return fmt.Errorf("error reading manifest: %v", err)
+		result.Error = fmt.Errorf("error computing patch: %v", err)
+		return result
 	}
 
-	tmpDir, err := ioutil.TempDir("", "channel")
+	if string(patch) == "{}" {
+		result.Action = ApplyActionUnchanged
+		return result
+	}
+
+	glog.V(4).Infof("patching %s %q: %s", gvk.Kind, obj.GetName(), string(patch))
+	if !a.DryRun {
+		if _, err := client.Patch(obj.GetName(), patchType, patch); err != nil {
+			result.Error = fmt.Errorf("error patching object: %v", err)
+			return result
+		}
+	}
+	result.Action = ApplyActionConfigured
+	return result
+}
+
+// restMapping maps a kind to its API resource, rediscovering the resources of the cluster if the kind is not found,
+// as it may be defined by a CustomResourceDefinition created earlier in the manifest
+func (a *Applier) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	if a.mapper != nil {
+		mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil || !meta.IsNoMatchError(err) {
+			return mapping, err
+		}
+	}
+
+	if err := a.discover(); err != nil {
+		return nil, err
+	}
+	return a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// discover lists the API resources of the cluster, and builds the mapper from them
+func (a *Applier) discover() error {
+	groupResources, err := restmapper.GetAPIGroupResources(a.Discovery)
 	if err != nil {
-		return fmt.Errorf("error creating temp dir: %v", err)
+		return fmt.Errorf("error discovering API resources: %v", err)
+	}
+	a.groupResources = groupResources
+	a.mapper = restmapper.NewDiscoveryRESTMapper(groupResources)
+	return nil
+}
+
+// pruneKinds are the kinds of objects which Prune deletes, like the default whitelist of kubectl apply --prune.
+// Other kinds, notably CustomResourceDefinitions and custom resources, are never pruned.
+var pruneKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "ConfigMap"}:                                   true,
+	{Group: "", Kind: "Endpoints"}:                                   true,
+	{Group: "", Kind: "Namespace"}:                                   true,
+	{Group: "", Kind: "PersistentVolume"}:                            true,
+	{Group: "", Kind: "PersistentVolumeClaim"}:                       true,
+	{Group: "", Kind: "Pod"}:                                         true,
+	{Group: "", Kind: "ReplicationController"}:                       true,
+	{Group: "", Kind: "Secret"}:                                      true,
+	{Group: "", Kind: "Service"}:                                     true,
+	{Group: "", Kind: "ServiceAccount"}:                              true,
+	{Group: "apps", Kind: "DaemonSet"}:                               true,
+	{Group: "apps", Kind: "Deployment"}:                              true,
+	{Group: "apps", Kind: "ReplicaSet"}:                              true,
+	{Group: "apps", Kind: "StatefulSet"}:                             true,
+	{Group: "batch", Kind: "CronJob"}:                                true,
+	{Group: "batch", Kind: "Job"}:                                    true,
+	{Group: "extensions", Kind: "DaemonSet"}:                         true,
+	{Group: "extensions", Kind: "Deployment"}:                        true,
+	{Group: "extensions", Kind: "Ingress"}:                           true,
+	{Group: "extensions", Kind: "ReplicaSet"}:                        true,
+	{Group: "policy", Kind: "PodDisruptionBudget"}:                   true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: true,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:               true,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        true,
+}
+
+// Prune deletes the objects matching the labels which are not among the objects applied, such as the objects
+// of an earlier version of an addon which are missing from the manifest of the new version.
+// Like kubectl apply --prune, only objects of the kinds in pruneKinds which were created by an apply, and so have
+// the last-applied-configuration annotation, are deleted; objects with an owner are left to the garbage collector.
+// Resources which cannot be listed are skipped; an error is returned if any object could not be deleted.
+func (a *Applier) Prune(selector map[string]string, applied []*ApplyResult) ([]*ApplyResult, error) {
+	keep := make(map[string]bool)
+	for _, r := range applied {
+		keep[r.Kind+"/"+r.Namespace+"/"+r.Name] = true
+	}
+
+	// We rediscover the resources, to include those of the CustomResourceDefinitions just applied
+	if err := a.discover(); err != nil {
+		return nil, err
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()}
+	propagationPolicy := metav1.DeletePropagationBackground
+	deleteOptions := &metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}
+
+	var results []*ApplyResult
+	failed := 0
+	// The same object can be listed under several groups, e.g. deployments in extensions and apps
+	seen := make(map[types.UID]bool)
+	for _, group := range a.groupResources {
+		version := group.Group.PreferredVersion.Version
+		for _, resource := range group.VersionedResources[version] {
+			if strings.Contains(resource.Name, "/") || !hasVerb(resource, "list") || !hasVerb(resource, "delete") {
+				continue
+			}
+			if !pruneKinds[schema.GroupKind{Group: group.Group.Name, Kind: resource.Kind}] {
+				continue
+			}
+
+			gvr := schema.GroupVersionResource{Group: group.Group.Name, Version: version, Resource: resource.Name}
+			list, err := a.Dynamic.Resource(gvr).List(listOptions)
+			if err != nil {
+				glog.Warningf("error listing %s to prune: %v", gvr, err)
+				continue
+			}
+
+			for i := range list.Items {
+				obj := &list.Items[i]
+				if uid := obj.GetUID(); uid != "" {
+					if seen[uid] {
+						continue
+					}
+					seen[uid] = true
+				}
+				if keep[resource.Kind+"/"+obj.GetNamespace()+"/"+obj.GetName()] {
+					continue
+				}
+				if _, found := obj.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; !found {
+					glog.V(2).Infof("not pruning %s %q, which was not created by an apply", resource.Kind, obj.GetName())
+					continue
+				}
+				if len(obj.GetOwnerReferences()) != 0 {
+					glog.V(2).Infof("not pruning %s %q, which has an owner", resource.Kind, obj.GetName())
+					continue
+				}
+
+				result := &ApplyResult{
+					Kind:      resource.Kind,
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetName(),
+					Action:    ApplyActionPruned,
+				}
+				if !a.DryRun {
+					err := a.Dynamic.Resource(gvr).Namespace(obj.GetNamespace()).Delete(obj.GetName(), deleteOptions)
+					if err != nil && !apierrors.IsNotFound(err) {
+						result.Error = fmt.Errorf("error deleting object: %v", err)
+						glog.Warningf("error pruning %s %q: %v", result.Kind, result.Name, result.Error)
+						failed++
+					}
+				}
+				results = append(results, result)
+			}
+		}
 	}
 
-	defer func() {
-		if err := os.RemoveAll(tmpDir); err != nil {
-			glog.Warningf("error deleting temp dir %q: %v", tmpDir, err)
+	if failed != 0 {
+		return results, fmt.Errorf("%d of %d objects could not be pruned", failed, len(results))
+	}
+	return results, nil
+}
+
+func hasVerb(resource metav1.APIResource, verb string) bool {
+	for _, v := range resource.Verbs {
+		if v == verb {
+			return true
 		}
-	}()
+	}
+	return false
+}
 
-	localManifestFile := path.Join(tmpDir, "manifest.yaml")
-	if err := ioutil.WriteFile(localManifestFile, data, 0600); err != nil {
-		return fmt.Errorf("error writing temp file: %v", err)
+// recordDryRunKind records the kind defined by a CustomResourceDefinition which a dry run would have created
+func (a *Applier) recordDryRunKind(obj *unstructured.Unstructured) {
+	if obj.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}) {
+		return
 	}
 
-	_, err = execKubectl("apply", "-f", localManifestFile)
-	return err
+	group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+	if a.dryRunKinds == nil {
+		a.dryRunKinds = make(map[schema.GroupKind]bool)
+	}
+	a.dryRunKinds[schema.GroupKind{Group: group, Kind: kind}] = true
 }
 
-func execKubectl(args ...string) (string, error) {
-	kubectlPath := "kubectl" // Assume in PATH
-	cmd := exec.Command(kubectlPath, args...)
-	env := os.Environ()
-	cmd.Env = env
+// setLastAppliedConfiguration records the configuration of the object in its last-applied-configuration annotation,
+// returning the object encoded as json
+func setLastAppliedConfiguration(obj *unstructured.Unstructured) ([]byte, error) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
 
-	human := strings.Join(cmd.Args, " ")
-	glog.V(2).Infof("Running command: %s", human)
-	output, err := cmd.CombinedOutput()
+	// The configuration recorded does not include the annotation itself
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	obj.SetAnnotations(annotations)
+	original, err := obj.MarshalJSON()
 	if err != nil {
-		glog.Infof("error running %s", human)
-		glog.Info(string(output))
-		return string(output), fmt.Errorf("error running kubectl")
+		return nil, fmt.Errorf("error encoding object: %v", err)
 	}
 
-	return string(output), err
+	annotations[corev1.LastAppliedConfigAnnotation] = string(original)
+	obj.SetAnnotations(annotations)
+	modified, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding object: %v", err)
+	}
+	return modified, nil
+}
+
+// createApplyPatch computes the three-way merge of the last applied configuration, the modified configuration and
+// the live object.  Like kubectl, it uses a strategic merge patch for the built-in kinds, and a json merge patch
+// for the others, such as custom resources.
+func createApplyPatch(gvk schema.GroupVersionKind, current *unstructured.Unstructured, modified []byte) (types.PatchType, []byte, error) {
+	original := []byte(current.GetAnnotations()[corev1.LastAppliedConfigAnnotation])
+
+	currentJSON, err := current.MarshalJSON()
+	if err != nil {
+		return "", nil, fmt.Errorf("error encoding live object: %v", err)
+	}
+
+	versioned, err := scheme.Scheme.New(gvk)
+	if err != nil {
+		if !runtime.IsNotRegisteredError(err) {
+			return "", nil, err
+		}
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, currentJSON)
+		return types.MergePatchType, patch, err
+	}
+
+	lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+	if err != nil {
+		return "", nil, err
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, currentJSON, lookupPatchMeta, true)
+	return types.StrategicMergePatchType, patch, err
 }
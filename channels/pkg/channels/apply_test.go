@@ -0,0 +1,448 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package channels
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// fakeDynamic is an in-memory dynamic client, supporting the operations used by the Applier
+type fakeDynamic struct {
+	objects map[string]*unstructured.Unstructured
+	actions []string
+}
+
+type fakeDynamicResource struct {
+	client    *fakeDynamic
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+var _ dynamic.Interface = &fakeDynamic{}
+
+func (f *fakeDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &fakeDynamicResource{client: f, resource: resource}
+}
+
+func (r *fakeDynamicResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &fakeDynamicResource{client: r.client, resource: r.resource, namespace: namespace}
+}
+
+func (r *fakeDynamicResource) key(name string) string {
+	return r.resource.Resource + "/" + r.namespace + "/" + name
+}
+
+func (r *fakeDynamicResource) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	obj := r.client.objects[r.key(name)]
+	if obj == nil {
+		return nil, apierrors.NewNotFound(r.resource.GroupResource(), name)
+	}
+	return obj.DeepCopy(), nil
+}
+
+func (r *fakeDynamicResource) Create(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	r.client.actions = append(r.client.actions, "create "+r.key(obj.GetName()))
+	r.client.objects[r.key(obj.GetName())] = obj.DeepCopy()
+	return obj, nil
+}
+
+func (r *fakeDynamicResource) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*unstructured.Unstructured, error) {
+	r.client.actions = append(r.client.actions, "patch "+r.key(name))
+
+	current := r.client.objects[r.key(name)]
+	currentJSON, err := current.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch pt {
+	case types.StrategicMergePatchType:
+		versioned, err := scheme.Scheme.New(current.GroupVersionKind())
+		if err != nil {
+			return nil, err
+		}
+		patched, err = strategicpatch.StrategicMergePatch(currentJSON, data, versioned)
+		if err != nil {
+			return nil, err
+		}
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(currentJSON, data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected patch type %q", pt)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(patched); err != nil {
+		return nil, err
+	}
+	r.client.objects[r.key(name)] = obj
+	return obj, nil
+}
+
+func (r *fakeDynamicResource) Update(obj *unstructured.Unstructured, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *fakeDynamicResource) UpdateStatus(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (r *fakeDynamicResource) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	r.client.actions = append(r.client.actions, "delete "+r.key(name))
+	if r.client.objects[r.key(name)] == nil {
+		return apierrors.NewNotFound(r.resource.GroupResource(), name)
+	}
+	delete(r.client.objects, r.key(name))
+	return nil
+}
+
+func (r *fakeDynamicResource) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (r *fakeDynamicResource) List(opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	for key, obj := range r.client.objects {
+		if !strings.HasPrefix(key, r.resource.Resource+"/") {
+			continue
+		}
+		if r.namespace != "" && obj.GetNamespace() != r.namespace {
+			continue
+		}
+		if selector.Matches(labels.Set(obj.GetLabels())) {
+			list.Items = append(list.Items, *obj.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+func (r *fakeDynamicResource) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func newTestApplier() (*Applier, *fakeDynamic) {
+	dynamicClient := &fakeDynamic{objects: make(map[string]*unstructured.Unstructured)}
+	verbs := metav1.Verbs{"create", "delete", "get", "list", "patch"}
+	discoveryClient := &fakediscovery.FakeDiscovery{
+		Fake: &clienttesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{
+						{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs},
+						{Name: "namespaces", Kind: "Namespace", Verbs: verbs},
+						{Name: "namespaces/status", Kind: "Namespace", Verbs: metav1.Verbs{"get"}},
+					},
+				},
+				{
+					GroupVersion: "example.com/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: verbs},
+					},
+				},
+			},
+		},
+	}
+	return NewApplier(dynamicClient, discoveryClient), dynamicClient
+}
+
+func applyTestManifest(t *testing.T, applier *Applier, manifest string) []string {
+	results, err := applier.ApplyManifest([]byte(manifest), nil)
+	if err != nil {
+		t.Fatalf("error applying manifest: %v", err)
+	}
+	return resultActions(results)
+}
+
+func resultActions(results []*ApplyResult) []string {
+	var actions []string
+	for _, result := range results {
+		actions = append(actions, fmt.Sprintf("%s %s/%s %s", result.Kind, result.Namespace, result.Name, result.Action))
+	}
+	return actions
+}
+
+const testApplyManifest = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: example
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  color: blue
+  size: large
+---
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: example.com/v1
+  kind: Widget
+  metadata:
+    name: gadget
+    namespace: example
+  spec:
+    replicas: 1
+    color: blue
+`
+
+const testApplyManifestChanged = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: example
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  color: red
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: gadget
+  namespace: example
+spec:
+  replicas: 2
+`
+
+func TestApply(t *testing.T) {
+	applier, dynamicClient := newTestApplier()
+
+	actions := applyTestManifest(t, applier, testApplyManifest)
+	expected := []string{
+		"Namespace /example created",
+		"ConfigMap default/settings created",
+		"Widget example/gadget created",
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of first apply %v", actions)
+	}
+
+	actions = applyTestManifest(t, applier, testApplyManifest)
+	expected = []string{
+		"Namespace /example unchanged",
+		"ConfigMap default/settings unchanged",
+		"Widget example/gadget unchanged",
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of second apply %v", actions)
+	}
+
+	// Fields set on the live objects outside of the manifest are kept
+	configMap := dynamicClient.objects["configmaps/default/settings"]
+	unstructured.SetNestedField(configMap.Object, "round", "data", "shape")
+	widget := dynamicClient.objects["widgets/example/gadget"]
+	unstructured.SetNestedField(widget.Object, "metal", "spec", "material")
+
+	actions = applyTestManifest(t, applier, testApplyManifestChanged)
+	expected = []string{
+		"Namespace /example unchanged",
+		"ConfigMap default/settings configured",
+		"Widget example/gadget configured",
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of changed apply %v", actions)
+	}
+
+	data, _, _ := unstructured.NestedStringMap(dynamicClient.objects["configmaps/default/settings"].Object, "data")
+	if !reflect.DeepEqual(data, map[string]string{"color": "red", "shape": "round"}) {
+		t.Errorf("unexpected configmap data after three-way merge %v", data)
+	}
+	spec, _, _ := unstructured.NestedMap(dynamicClient.objects["widgets/example/gadget"].Object, "spec")
+	if !reflect.DeepEqual(spec, map[string]interface{}{"replicas": int64(2), "material": "metal"}) {
+		t.Errorf("unexpected widget spec after three-way merge %v", spec)
+	}
+}
+
+func TestApplyDryRun(t *testing.T) {
+	applier, dynamicClient := newTestApplier()
+	applyTestManifest(t, applier, testApplyManifest)
+	dynamicClient.actions = nil
+
+	applier.DryRun = true
+	actions := applyTestManifest(t, applier, testApplyManifestChanged+`
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: extra
+  namespace: example
+`)
+	expected := []string{
+		"Namespace /example unchanged",
+		"ConfigMap default/settings configured",
+		"Widget example/gadget configured",
+		"ConfigMap example/extra created",
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of dry run %v", actions)
+	}
+	if len(dynamicClient.actions) != 0 {
+		t.Errorf("expected a dry run not to change the cluster, got %v", dynamicClient.actions)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	applier, dynamicClient := newTestApplier()
+
+	results, err := applier.ApplyManifest([]byte(`
+apiVersion: example.com/v1
+kind: Gizmo
+metadata:
+  name: unknown
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`), nil)
+	if err == nil {
+		t.Fatalf("expected an error applying an unknown kind")
+	}
+	if len(results) != 2 || results[0].Error == nil || results[1].Action != ApplyActionCreated {
+		t.Errorf("expected only the unknown kind to fail, got %+v %+v", results[0], results[1])
+	}
+	if dynamicClient.objects["configmaps/default/settings"] == nil {
+		t.Errorf("expected the objects after the failure to be applied")
+	}
+
+	if _, err := applier.ApplyManifest([]byte("kind: ["), nil); err == nil {
+		t.Errorf("expected an error parsing an invalid manifest")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	applier, dynamicClient := newTestApplier()
+
+	addonLabels := map[string]string{LabelAddonName: "example", LabelAddonVersion: "1.0.0"}
+	if _, err := applier.ApplyManifest([]byte(testApplyManifest), addonLabels); err != nil {
+		t.Fatalf("error applying manifest: %v", err)
+	}
+	// An object which is not part of the addon
+	if _, err := applier.ApplyManifest([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n"), nil); err != nil {
+		t.Fatalf("error applying manifest: %v", err)
+	}
+	// Objects of the addon which were not created by an apply, or which have an owner, are not pruned
+	unapplied := &unstructured.Unstructured{}
+	unapplied.SetAPIVersion("v1")
+	unapplied.SetKind("ConfigMap")
+	unapplied.SetNamespace("default")
+	unapplied.SetName("unapplied")
+	unapplied.SetLabels(map[string]string{LabelAddonName: "example"})
+	dynamicClient.objects["configmaps/default/unapplied"] = unapplied
+	owned := unapplied.DeepCopy()
+	owned.SetName("owned")
+	owned.SetAnnotations(map[string]string{corev1.LastAppliedConfigAnnotation: "{}"})
+	owned.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Widget", Name: "gadget"}})
+	dynamicClient.objects["configmaps/default/owned"] = owned
+
+	// The new version of the addon drops the namespace, and the widget moves to the default namespace
+	addonLabels[LabelAddonVersion] = "2.0.0"
+	applied, err := applier.ApplyManifest([]byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: gadget
+`), addonLabels)
+	if err != nil {
+		t.Fatalf("error applying manifest: %v", err)
+	}
+
+	selector := map[string]string{LabelAddonName: "example"}
+	applier.DryRun = true
+	pruned, err := applier.Prune(selector, applied)
+	if err != nil {
+		t.Fatalf("error pruning: %v", err)
+	}
+	actions := resultActions(pruned)
+	sort.Strings(actions)
+	// Custom resources are not pruned, like the widget left in the example namespace
+	expected := []string{
+		"Namespace /example pruned",
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of dry run prune %v", actions)
+	}
+	if dynamicClient.objects["namespaces//example"] == nil {
+		t.Errorf("expected a dry run not to delete objects")
+	}
+
+	applier.DryRun = false
+	pruned, err = applier.Prune(selector, applied)
+	if err != nil {
+		t.Fatalf("error pruning: %v", err)
+	}
+	actions = resultActions(pruned)
+	sort.Strings(actions)
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of prune %v", actions)
+	}
+
+	var remaining []string
+	for key := range dynamicClient.objects {
+		remaining = append(remaining, key)
+	}
+	sort.Strings(remaining)
+	if !reflect.DeepEqual(remaining, []string{"configmaps/default/other", "configmaps/default/owned", "configmaps/default/settings", "configmaps/default/unapplied", "widgets/default/gadget", "widgets/example/gadget"}) {
+		t.Errorf("unexpected objects after prune %v", remaining)
+	}
+
+	settings := dynamicClient.objects["configmaps/default/settings"]
+	if settings.GetLabels()[LabelAddonVersion] != "2.0.0" {
+		t.Errorf("expected the objects applied to be labelled with the new version, got %v", settings.GetLabels())
+	}
+}
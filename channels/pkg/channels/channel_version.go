@@ -24,6 +24,7 @@ import (
 	"github.com/blang/semver"
 	"github.com/golang/glog"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
@@ -31,6 +32,12 @@ import (
 
 const AnnotationPrefix = "addons.k8s.io/"
 
+// FailedAnnotationPrefix is the prefix of the namespace annotations recording the updates which failed their health checks
+const FailedAnnotationPrefix = "failed.addons.k8s.io/"
+
+// manifestConfigMapPrefix is the prefix of the name of the ConfigMap recording the installed manifest of an addon
+const manifestConfigMapPrefix = "addon-manifest."
+
 type Channel struct {
 	Namespace string
 	Name      string
@@ -42,6 +49,19 @@ type ChannelVersion struct {
 	Id      string  `json:"id,omitempty"`
 }
 
+// FailedUpdate records an update of an addon which failed its health checks and was rolled back
+type FailedUpdate struct {
+	Version   *ChannelVersion `json:"version,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	Timestamp metav1.Time     `json:"timestamp,omitempty"`
+}
+
+// InstalledManifest is the manifest of the installed version of an addon, kept so that a failed update can be rolled back
+type InstalledManifest struct {
+	Version  *ChannelVersion
+	Manifest []byte
+}
+
 func stringValue(s *string) string {
 	if s == nil {
 		return ""
@@ -85,6 +105,26 @@ func FindAddons(ns *v1.Namespace) map[string]*ChannelVersion {
 	return addons
 }
 
+// FindFailedUpdates returns the failed updates recorded on the namespace, by addon name
+func FindFailedUpdates(ns *v1.Namespace) map[string]*FailedUpdate {
+	failed := make(map[string]*FailedUpdate)
+	for k, v := range ns.Annotations {
+		if !strings.HasPrefix(k, FailedAnnotationPrefix) || v == "" {
+			continue
+		}
+
+		f := &FailedUpdate{}
+		if err := json.Unmarshal([]byte(v), f); err != nil {
+			glog.Warningf("failed to parse annotation %q=%q", k, v)
+			continue
+		}
+
+		name := strings.TrimPrefix(k, FailedAnnotationPrefix)
+		failed[name] = f
+	}
+	return failed
+}
+
 func (c *ChannelVersion) Encode() (string, error) {
 	data, err := json.Marshal(c)
 	if err != nil {
@@ -97,6 +137,16 @@ func (c *Channel) AnnotationName() string {
 	return AnnotationPrefix + c.Name
 }
 
+// FailedAnnotationName is the namespace annotation recording the failed update of the addon
+func (c *Channel) FailedAnnotationName() string {
+	return FailedAnnotationPrefix + c.Name
+}
+
+// ManifestConfigMapName is the name of the ConfigMap in the namespace recording the installed manifest of the addon
+func (c *Channel) ManifestConfigMapName() string {
+	return manifestConfigMapPrefix + c.Name
+}
+
 func (c *ChannelVersion) replaces(existing *ChannelVersion) bool {
 	if existing.Version != nil {
 		if c.Version == nil {
@@ -148,26 +198,65 @@ func (c *Channel) GetInstalledVersion(k8sClient kubernetes.Interface) (*ChannelV
 	return ParseChannelVersion(annotationValue)
 }
 
+// GetFailedUpdate returns the update of the addon which last failed its health checks, or nil
+func (c *Channel) GetFailedUpdate(k8sClient kubernetes.Interface) (*FailedUpdate, error) {
+	ns, err := k8sClient.CoreV1().Namespaces().Get(c.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error querying namespace %q: %v", c.Namespace, err)
+	}
+
+	annotationValue := ns.Annotations[c.FailedAnnotationName()]
+	if annotationValue == "" {
+		return nil, nil
+	}
+
+	failed := &FailedUpdate{}
+	if err := json.Unmarshal([]byte(annotationValue), failed); err != nil {
+		return nil, fmt.Errorf("error parsing annotation %q: %v", c.FailedAnnotationName(), err)
+	}
+	return failed, nil
+}
+
 type annotationPatch struct {
 	Metadata annotationPatchMetadata `json:"metadata,omitempty"`
 }
 type annotationPatchMetadata struct {
-	Annotations map[string]string `json:"annotations,omitempty"`
+	// Annotations holds the values to set; a nil value removes the annotation
+	Annotations map[string]*string `json:"annotations,omitempty"`
 }
 
+// SetInstalledVersion records the installed version of the addon, clearing any failed update
 func (c *Channel) SetInstalledVersion(k8sClient kubernetes.Interface, version *ChannelVersion) error {
-	// Primarily to check it exists
-	_, err := k8sClient.CoreV1().Namespaces().Get(c.Namespace, metav1.GetOptions{})
+	value, err := version.Encode()
 	if err != nil {
-		return fmt.Errorf("error querying namespace %q: %v", c.Namespace, err)
+		return err
 	}
 
-	value, err := version.Encode()
+	return c.patchAnnotations(k8sClient, map[string]*string{
+		c.AnnotationName():       &value,
+		c.FailedAnnotationName(): nil,
+	})
+}
+
+// SetFailedUpdate records an update of the addon which failed its health checks, leaving the installed version unchanged
+func (c *Channel) SetFailedUpdate(k8sClient kubernetes.Interface, failed *FailedUpdate) error {
+	data, err := json.Marshal(failed)
 	if err != nil {
-		return err
+		return fmt.Errorf("error encoding failed update: %v", err)
 	}
+	value := string(data)
+
+	return c.patchAnnotations(k8sClient, map[string]*string{c.FailedAnnotationName(): &value})
+}
 
-	annotationPatch := &annotationPatch{Metadata: annotationPatchMetadata{Annotations: map[string]string{c.AnnotationName(): value}}}
+func (c *Channel) patchAnnotations(k8sClient kubernetes.Interface, annotations map[string]*string) error {
+	// Primarily to check it exists
+	_, err := k8sClient.CoreV1().Namespaces().Get(c.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error querying namespace %q: %v", c.Namespace, err)
+	}
+
+	annotationPatch := &annotationPatch{Metadata: annotationPatchMetadata{Annotations: annotations}}
 	annotationPatchJson, err := json.Marshal(annotationPatch)
 	if err != nil {
 		return fmt.Errorf("error building annotation patch: %v", err)
@@ -181,3 +270,59 @@ func (c *Channel) SetInstalledVersion(k8sClient kubernetes.Interface, version *C
 	}
 	return nil
 }
+
+// GetInstalledManifest returns the recorded manifest of the installed version of the addon, or nil
+func (c *Channel) GetInstalledManifest(k8sClient kubernetes.Interface) (*InstalledManifest, error) {
+	configMap, err := k8sClient.CoreV1().ConfigMaps(c.Namespace).Get(c.ManifestConfigMapName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading ConfigMap %s/%s: %v", c.Namespace, c.ManifestConfigMapName(), err)
+	}
+
+	version, err := ParseChannelVersion(configMap.Data["version"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstalledManifest{
+		Version:  version,
+		Manifest: []byte(configMap.Data["manifest"]),
+	}, nil
+}
+
+// SetInstalledManifest records the manifest of the installed version of the addon
+func (c *Channel) SetInstalledManifest(k8sClient kubernetes.Interface, installed *InstalledManifest) error {
+	value, err := installed.Version.Encode()
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"version":  value,
+		"manifest": string(installed.Manifest),
+	}
+
+	configMaps := k8sClient.CoreV1().ConfigMaps(c.Namespace)
+	configMap, err := configMaps.Get(c.ManifestConfigMapName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error reading ConfigMap %s/%s: %v", c.Namespace, c.ManifestConfigMapName(), err)
+		}
+
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: c.Namespace, Name: c.ManifestConfigMapName()},
+			Data:       data,
+		}
+		if _, err := configMaps.Create(configMap); err != nil {
+			return fmt.Errorf("error creating ConfigMap %s/%s: %v", c.Namespace, c.ManifestConfigMapName(), err)
+		}
+		return nil
+	}
+
+	configMap.Data = data
+	if _, err := configMaps.Update(configMap); err != nil {
+		return fmt.Errorf("error updating ConfigMap %s/%s: %v", c.Namespace, c.ManifestConfigMapName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package channels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Sorted returns the addons of the menu ordered so that each addon comes after its dependencies.
+// Addons which do not depend on each other are ordered by name.  An error is returned if an addon depends on an
+// addon which is not in the menu, or if the dependencies form a cycle.
+func (m *AddonMenu) Sorted() ([]*Addon, error) {
+	var names []string
+	for name := range m.Addons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sorted []*Addon
+	visited := make(map[string]bool)
+	// path is the chain of dependencies being visited, to detect and report cycles
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		for i, n := range path {
+			if n == name {
+				cycle := append(append([]string{}, path[i:]...), name)
+				return fmt.Errorf("addons have a dependency cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		addon := m.Addons[name]
+		path = append(path, name)
+
+		dependencies := append([]string{}, addon.Spec.Dependencies...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if m.Addons[dependency] == nil {
+				return fmt.Errorf("addon %q depends on %q, which is not in the channel", name, dependency)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		visited[name] = true
+		sorted = append(sorted, addon)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// WaitForDependencies waits for the workloads in the health spec of each dependency of the addon to be ready
+func (m *AddonMenu) WaitForDependencies(k8sClient kubernetes.Interface, addon *Addon) error {
+	for _, name := range addon.Spec.Dependencies {
+		dependency := m.Addons[name]
+		if dependency == nil {
+			return fmt.Errorf("addon %q depends on %q, which is not in the channel", addon.Name, name)
+		}
+
+		glog.V(2).Infof("Checking health of %q, which %q depends on", name, addon.Name)
+		if err := dependency.WaitHealthy(k8sClient); err != nil {
+			return fmt.Errorf("dependency %q of addon %q is not healthy: %v", name, addon.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package channels
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/channels/pkg/api"
+)
+
+func buildTestMenu(dependencies map[string][]string) *AddonMenu {
+	menu := NewAddonMenu()
+	for name, deps := range dependencies {
+		menu.Addons[name] = &Addon{
+			Name: name,
+			Spec: &api.AddonSpec{Name: s(name), Dependencies: deps},
+		}
+	}
+	return menu
+}
+
+func Test_Sorted(t *testing.T) {
+	grid := []struct {
+		Dependencies map[string][]string
+		Expected     []string
+		Error        string
+	}{
+		{
+			Dependencies: map[string][]string{
+				"dns-controller":   {"networking.weave"},
+				"kube-dns":         {"networking.weave"},
+				"networking.weave": nil,
+				"cert-manager":     nil,
+				"issuers":          {"cert-manager", "kube-dns"},
+			},
+			Expected: []string{"cert-manager", "networking.weave", "dns-controller", "kube-dns", "issuers"},
+		},
+		{
+			Dependencies: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"a"},
+				"d": nil,
+			},
+			Error: "addons have a dependency cycle: a -> b -> c -> a",
+		},
+		{
+			Dependencies: map[string][]string{
+				"a": {"a"},
+			},
+			Error: "addons have a dependency cycle: a -> a",
+		},
+		{
+			Dependencies: map[string][]string{
+				"kube-dns": {"networking.calico"},
+			},
+			Error: "addon \"kube-dns\" depends on \"networking.calico\", which is not in the channel",
+		},
+	}
+
+	for _, g := range grid {
+		sorted, err := buildTestMenu(g.Dependencies).Sorted()
+		if g.Error != "" {
+			if err == nil || err.Error() != g.Error {
+				t.Errorf("expected error %q for %v, got %v", g.Error, g.Dependencies, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error sorting %v: %v", g.Dependencies, err)
+			continue
+		}
+
+		var names []string
+		for _, addon := range sorted {
+			names = append(names, addon.Name)
+		}
+		if !reflect.DeepEqual(names, g.Expected) {
+			t.Errorf("unexpected order %v, expected %v", names, g.Expected)
+		}
+	}
+}
+
+func Test_WaitForDependencies(t *testing.T) {
+	defer func(interval time.Duration) { healthCheckInterval = interval }(healthCheckInterval)
+	healthCheckInterval = time.Millisecond
+
+	menu := buildTestMenu(map[string][]string{
+		"networking": nil,
+		"kube-dns":   {"networking"},
+	})
+	menu.Addons["networking"].Spec.Health = &api.AddonHealthSpec{
+		Deployments: []api.AddonObjectReference{{Name: "networking"}},
+		Timeout:     &metav1.Duration{Duration: 10 * time.Millisecond},
+	}
+
+	k8sClient := fake.NewSimpleClientset(testDeployment("kube-system", "networking", 1))
+	err := menu.WaitForDependencies(k8sClient, menu.Addons["kube-dns"])
+	if err == nil || !strings.Contains(err.Error(), "dependency \"networking\" of addon \"kube-dns\" is not healthy") {
+		t.Errorf("expected an error for an unhealthy dependency, got %v", err)
+	}
+
+	k8sClient = fake.NewSimpleClientset(testDeployment("kube-system", "networking", 2))
+	if err := menu.WaitForDependencies(k8sClient, menu.Addons["kube-dns"]); err != nil {
+		t.Errorf("unexpected error for a healthy dependency: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kops/channels/pkg/api"
+)
+
+// defaultHealthTimeout is how long to wait for the workloads of an addon to become ready, if the addon does not say
+const defaultHealthTimeout = 5 * time.Minute
+
+// healthCheckInterval is the interval between checks of the workloads of an addon
+var healthCheckInterval = 5 * time.Second
+
+// WaitHealthy waits for the workloads named in the health spec of the addon to become ready,
+// returning an error if they are not ready within the timeout.  It returns immediately if the addon has no health spec.
+func (a *Addon) WaitHealthy(k8sClient kubernetes.Interface) error {
+	health := a.Spec.Health
+	if health == nil {
+		return nil
+	}
+
+	timeout := defaultHealthTimeout
+	if health.Timeout != nil {
+		timeout = health.Timeout.Duration
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		notReady, err := a.checkHealth(k8sClient)
+		if err == nil && len(notReady) == 0 {
+			glog.Infof("Addon %q is healthy", a.Name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("error checking health of addon %q: %v", a.Name, err)
+			}
+			return fmt.Errorf("addon %q was not healthy after %v: %s", a.Name, timeout, strings.Join(notReady, "; "))
+		}
+
+		if err != nil {
+			glog.Warningf("error checking health of addon %q: %v", a.Name, err)
+		} else {
+			glog.Infof("Waiting for addon %q: %s", a.Name, strings.Join(notReady, "; "))
+		}
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+// checkHealth returns a message for each workload of the addon which is not ready
+func (a *Addon) checkHealth(k8sClient kubernetes.Interface) ([]string, error) {
+	var notReady []string
+
+	for _, ref := range a.Spec.Health.Deployments {
+		namespace := a.healthNamespace(ref)
+		deployment, err := k8sClient.ExtensionsV1beta1().Deployments(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				notReady = append(notReady, fmt.Sprintf("deployment %s/%s not found", namespace, ref.Name))
+				continue
+			}
+			return nil, fmt.Errorf("error getting deployment %s/%s: %v", namespace, ref.Name, err)
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		status := deployment.Status
+		if status.ObservedGeneration < deployment.Generation || status.UpdatedReplicas < replicas || status.AvailableReplicas < replicas {
+			notReady = append(notReady, fmt.Sprintf("deployment %s/%s has %d of %d replicas updated and %d available",
+				namespace, ref.Name, status.UpdatedReplicas, replicas, status.AvailableReplicas))
+		}
+	}
+
+	for _, ref := range a.Spec.Health.DaemonSets {
+		namespace := a.healthNamespace(ref)
+		daemonSet, err := k8sClient.ExtensionsV1beta1().DaemonSets(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				notReady = append(notReady, fmt.Sprintf("daemonset %s/%s not found", namespace, ref.Name))
+				continue
+			}
+			return nil, fmt.Errorf("error getting daemonset %s/%s: %v", namespace, ref.Name, err)
+		}
+
+		status := daemonSet.Status
+		if status.ObservedGeneration < daemonSet.Generation || status.UpdatedNumberScheduled < status.DesiredNumberScheduled || status.NumberAvailable < status.DesiredNumberScheduled {
+			notReady = append(notReady, fmt.Sprintf("daemonset %s/%s has %d of %d pods updated and %d available",
+				namespace, ref.Name, status.UpdatedNumberScheduled, status.DesiredNumberScheduled, status.NumberAvailable))
+		}
+	}
+
+	return notReady, nil
+}
+
+// healthNamespace returns the namespace of a workload checked for the addon, defaulting to the namespace of the addon
+func (a *Addon) healthNamespace(ref api.AddonObjectReference) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return a.buildChannel().Namespace
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package channels
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/channels/pkg/api"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func testDeployment(namespace, name string, available int32) *extensionsv1beta1.Deployment {
+	replicas := int32(2)
+	return &extensionsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       extensionsv1beta1.DeploymentSpec{Replicas: &replicas},
+		Status:     extensionsv1beta1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: available},
+	}
+}
+
+func Test_CheckHealth(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(
+		testDeployment("kube-system", "ready", 2),
+		testDeployment("monitoring", "unavailable", 1),
+		&extensionsv1beta1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "agent"},
+			Status:     extensionsv1beta1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3},
+		},
+	)
+
+	addon := &Addon{
+		Name: "example",
+		Spec: &api.AddonSpec{
+			Health: &api.AddonHealthSpec{
+				Deployments: []api.AddonObjectReference{
+					{Name: "ready"},
+					{Namespace: "monitoring", Name: "unavailable"},
+					{Name: "missing"},
+				},
+				DaemonSets: []api.AddonObjectReference{
+					{Name: "agent"},
+				},
+			},
+		},
+	}
+
+	notReady, err := addon.checkHealth(k8sClient)
+	if err != nil {
+		t.Fatalf("error checking health: %v", err)
+	}
+	expected := []string{
+		"deployment monitoring/unavailable has 2 of 2 replicas updated and 1 available",
+		"deployment kube-system/missing not found",
+		"daemonset kube-system/agent has 2 of 3 pods updated and 3 available",
+	}
+	if !reflect.DeepEqual(notReady, expected) {
+		t.Errorf("unexpected health %v", notReady)
+	}
+}
+
+func writeTestManifest(t *testing.T, location string, manifest string) {
+	p, err := vfs.Context.BuildVfsPath(location)
+	if err != nil {
+		t.Fatalf("error building vfs path: %v", err)
+	}
+	if err := p.WriteFile(bytes.NewReader([]byte(manifest)), nil); err != nil {
+		t.Fatalf("error writing manifest: %v", err)
+	}
+}
+
+func buildHealthCheckedAddon(version string, manifest string) *Addon {
+	return &Addon{
+		Name:        "example",
+		ChannelName: "test",
+		Spec: &api.AddonSpec{
+			Name:     s("example"),
+			Version:  s(version),
+			Manifest: s(manifest),
+			Health: &api.AddonHealthSpec{
+				Deployments: []api.AddonObjectReference{{Name: "example"}},
+				Timeout:     &metav1.Duration{Duration: 10 * time.Millisecond},
+			},
+		},
+	}
+}
+
+func Test_EnsureUpdatedRollback(t *testing.T) {
+	defer func(interval time.Duration) { healthCheckInterval = interval }(healthCheckInterval)
+	healthCheckInterval = time.Millisecond
+
+	vfs.Context.ResetMemfsContext(true)
+	writeTestManifest(t, "memfs://channel/v1.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  color: blue
+`)
+	writeTestManifest(t, "memfs://channel/v2.yaml", `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  color: red
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: extra
+`)
+
+	k8sClient := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		testDeployment("kube-system", "example", 2),
+	)
+	applier, dynamicClient := newTestApplier()
+
+	// The first version has no health spec, but its manifest is still recorded for a rollback
+	first := buildHealthCheckedAddon("1.0.0", "memfs://channel/v1.yaml")
+	first.Spec.Health = nil
+	if _, err := first.EnsureUpdated(k8sClient, applier); err != nil {
+		t.Fatalf("error installing addon: %v", err)
+	}
+
+	// The new version never becomes available
+	if _, err := k8sClient.ExtensionsV1beta1().Deployments("kube-system").Update(testDeployment("kube-system", "example", 0)); err != nil {
+		t.Fatalf("error updating deployment: %v", err)
+	}
+
+	addon := buildHealthCheckedAddon("2.0.0", "memfs://channel/v2.yaml")
+	update, err := addon.EnsureUpdated(k8sClient, applier)
+	if err == nil {
+		t.Fatalf("expected an error for an unhealthy update")
+	}
+	if update == nil || update.RolledBackTo == nil || stringValue(update.RolledBackTo.Version) != "1.0.0" {
+		t.Fatalf("expected the update to be rolled back to 1.0.0, got %+v", update)
+	}
+	expected := []string{
+		"ConfigMap default/settings configured",
+		"ConfigMap default/extra pruned",
+	}
+	if actions := resultActions(update.RollbackResults); !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected results of rollback %v", actions)
+	}
+
+	data, _, _ := unstructured.NestedStringMap(dynamicClient.objects["configmaps/default/settings"].Object, "data")
+	if data["color"] != "blue" {
+		t.Errorf("expected the previous manifest to be applied again, got %v", data)
+	}
+
+	channel := addon.buildChannel()
+	installed, err := channel.GetInstalledVersion(k8sClient)
+	if err != nil {
+		t.Fatalf("error getting installed version: %v", err)
+	}
+	if stringValue(installed.Version) != "1.0.0" {
+		t.Errorf("expected the installed version to be unchanged, got %s", installed)
+	}
+	failed, err := channel.GetFailedUpdate(k8sClient)
+	if err != nil {
+		t.Fatalf("error getting failed update: %v", err)
+	}
+	if failed == nil || stringValue(failed.Version.Version) != "2.0.0" || failed.Message == "" {
+		t.Errorf("expected the failed update to be recorded, got %+v", failed)
+	}
+
+	// The failed version is not retried, but a newer version is
+	required, err := addon.GetRequiredUpdates(k8sClient)
+	if err != nil {
+		t.Fatalf("error getting required updates: %v", err)
+	}
+	if required != nil {
+		t.Errorf("expected the failed version not to be retried, got %+v", required)
+	}
+	required, err = buildHealthCheckedAddon("3.0.0", "memfs://channel/v2.yaml").GetRequiredUpdates(k8sClient)
+	if err != nil {
+		t.Fatalf("error getting required updates: %v", err)
+	}
+	if required == nil {
+		t.Errorf("expected a newer version to be applied")
+	}
+}
@@ -30,8 +30,9 @@ import (
 )
 
 type ApplyChannelOptions struct {
-	Yes   bool
-	Files []string
+	Yes    bool
+	DryRun bool
+	Files  []string
 }
 
 func NewCmdApplyChannel(f Factory, out io.Writer) *cobra.Command {
@@ -46,6 +47,7 @@ func NewCmdApplyChannel(f Factory, out io.Writer) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&options.Yes, "yes", false, "Apply update")
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "Show the changes each update would make to the objects of its manifest, without applying them")
 	cmd.Flags().StringSliceVarP(&options.Files, "filename", "f", []string{}, "Apply from a local file")
 
 	return cmd
@@ -88,7 +90,7 @@ func RunApplyChannel(f Factory, out io.Writer, options *ApplyChannelOptions, arg
 			if strings.Contains(name, "/") {
 				return fmt.Errorf("Channel format not recognized (did you mean to use `-f` to specify a local file?): %q", name)
 			}
-			expanded := "https://raw.githubusercontent.com/kubernetes/kops/master/addons/" + name + "/addon.yaml"
+			expanded := channels.WellKnownChannelLocation(name)
 			location, err = url.Parse(expanded)
 			if err != nil {
 				return fmt.Errorf("unable to parse expanded argument %q as url", expanded)
@@ -134,9 +136,15 @@ func RunApplyChannel(f Factory, out io.Writer, options *ApplyChannelOptions, arg
 		menu.MergeAddons(current)
 	}
 
+	// Addons are applied after their dependencies
+	sorted, err := menu.Sorted()
+	if err != nil {
+		return err
+	}
+
 	var updates []*channels.AddonUpdate
 	var needUpdates []*channels.Addon
-	for _, addon := range menu.Addons {
+	for _, addon := range sorted {
 		// TODO: Cache lookups to prevent repeated lookups?
 		update, err := addon.GetRequiredUpdates(k8sClient)
 		if err != nil {
@@ -184,18 +192,44 @@ func RunApplyChannel(f Factory, out io.Writer, options *ApplyChannelOptions, arg
 		}
 	}
 
-	if !options.Yes {
+	if !options.Yes && !options.DryRun {
 		fmt.Printf("\nMust specify --yes to update\n")
 		return nil
 	}
 
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return err
+	}
+	applier := channels.NewApplier(dynamicClient, k8sClient.Discovery())
+	applier.DryRun = options.DryRun
+
 	for _, needUpdate := range needUpdates {
-		update, err := needUpdate.EnsureUpdated(k8sClient)
+		// In a dry run, the dependencies have not been changed
+		if !options.DryRun {
+			if err := menu.WaitForDependencies(k8sClient, needUpdate); err != nil {
+				return err
+			}
+		}
+
+		update, err := needUpdate.EnsureUpdated(k8sClient, applier)
+		if update != nil && len(update.Results) != 0 {
+			fmt.Printf("\n")
+			if err := renderApplyResults(update.Name, update.Results, out); err != nil {
+				return err
+			}
+		}
+		if update != nil && update.RolledBackTo != nil {
+			fmt.Printf("\nRolled back %q to %s\n", update.Name, update.RolledBackTo)
+			if err := renderApplyResults(update.Name, update.RollbackResults, out); err != nil {
+				return err
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("error updating %q: %v", needUpdate.Name, err)
 		}
 		// Could have been a concurrent request
-		if update != nil {
+		if update != nil && !options.DryRun {
 			if update.NewVersion.Version != nil {
 				fmt.Printf("Updated %q to %s\n", update.Name, *update.NewVersion.Version)
 			} else {
@@ -208,3 +242,31 @@ func RunApplyChannel(f Factory, out io.Writer, options *ApplyChannelOptions, arg
 
 	return nil
 }
+
+// renderApplyResults prints the outcome of applying each object of the manifest of an addon
+func renderApplyResults(name string, results []*channels.ApplyResult, out io.Writer) error {
+	t := &tables.Table{}
+	t.AddColumn("ADDON", func(r *channels.ApplyResult) string {
+		return name
+	})
+	t.AddColumn("KIND", func(r *channels.ApplyResult) string {
+		return r.Kind
+	})
+	t.AddColumn("NAMESPACE", func(r *channels.ApplyResult) string {
+		if r.Namespace == "" {
+			return "-"
+		}
+		return r.Namespace
+	})
+	t.AddColumn("NAME", func(r *channels.ApplyResult) string {
+		return r.Name
+	})
+	t.AddColumn("RESULT", func(r *channels.ApplyResult) string {
+		if r.Error != nil {
+			return "error: " + r.Error.Error()
+		}
+		return r.Action
+	})
+
+	return t.Render(results, out, "ADDON", "KIND", "NAMESPACE", "NAME", "RESULT")
+}
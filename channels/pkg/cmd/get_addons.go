@@ -67,6 +67,8 @@ type addonInfo struct {
 	Name      string
 	Version   *channels.ChannelVersion
 	Namespace *v1.Namespace
+	// Failed is the last update of the addon which failed its health checks, if any
+	Failed *channels.FailedUpdate
 }
 
 func RunGetAddons(f Factory, out io.Writer, options *GetAddonsOptions) error {
@@ -85,11 +87,25 @@ func RunGetAddons(f Factory, out io.Writer, options *GetAddonsOptions) error {
 	for i := range namespaces.Items {
 		ns := &namespaces.Items[i]
 		addons := channels.FindAddons(ns)
+		failed := channels.FindFailedUpdates(ns)
 		for name, version := range addons {
 			i := &addonInfo{
 				Name:      name,
 				Version:   version,
 				Namespace: ns,
+				Failed:    failed[name],
+			}
+			info = append(info, i)
+		}
+		// The first installation of an addon may have failed
+		for name, f := range failed {
+			if addons[name] != nil {
+				continue
+			}
+			i := &addonInfo{
+				Name:      name,
+				Namespace: ns,
+				Failed:    f,
 			}
 			info = append(info, i)
 		}
@@ -127,7 +143,18 @@ func RunGetAddons(f Factory, out io.Writer, options *GetAddonsOptions) error {
 			return "?"
 		})
 
-		columns := []string{"NAMESPACE", "NAME", "VERSION", "CHANNEL"}
+		t.AddColumn("FAILED", func(r *addonInfo) string {
+			if r.Failed == nil || r.Failed.Version == nil {
+				return "-"
+			}
+			version := "?"
+			if r.Failed.Version.Version != nil {
+				version = *r.Failed.Version.Version
+			}
+			return fmt.Sprintf("%s: %s", version, r.Failed.Message)
+		})
+
+		columns := []string{"NAMESPACE", "NAME", "VERSION", "CHANNEL", "FAILED"}
 		err := t.Render(info, os.Stdout, columns...)
 		if err != nil {
 			return err
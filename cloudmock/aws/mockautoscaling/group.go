@@ -62,6 +62,8 @@ func (m *MockAutoscaling) CreateAutoScalingGroup(input *autoscaling.CreateAutoSc
 		HealthCheckType:         input.HealthCheckType,
 		Instances:               []*autoscaling.Instance{},
 		LaunchConfigurationName: input.LaunchConfigurationName,
+		LaunchTemplate:          input.LaunchTemplate,
+		MixedInstancesPolicy:    input.MixedInstancesPolicy,
 		LoadBalancerNames:       input.LoadBalancerNames,
 		MaxSize:                 input.MaxSize,
 		MinSize:                 input.MinSize,
@@ -169,6 +171,38 @@ func (m *MockAutoscaling) TerminateInstanceInAutoScalingGroup(input *autoscaling
 	return nil, fmt.Errorf("Instance not found")
 }
 
+func (m *MockAutoscaling) DetachInstances(input *autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.V(2).Infof("DetachInstances %v", input)
+
+	g := m.Groups[aws.StringValue(input.AutoScalingGroupName)]
+	if g == nil {
+		return nil, fmt.Errorf("AutoScaling Group not found")
+	}
+
+	for _, instanceID := range input.InstanceIds {
+		found := false
+		for i := range g.Instances {
+			if aws.StringValue(g.Instances[i].InstanceId) == aws.StringValue(instanceID) {
+				g.Instances = append(g.Instances[:i], g.Instances[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Instance %q not found in AutoScaling Group", aws.StringValue(instanceID))
+		}
+	}
+
+	if aws.BoolValue(input.ShouldDecrementDesiredCapacity) && g.DesiredCapacity != nil {
+		g.DesiredCapacity = aws.Int64(aws.Int64Value(g.DesiredCapacity) - int64(len(input.InstanceIds)))
+	}
+
+	return &autoscaling.DetachInstancesOutput{}, nil
+}
+
 func (m *MockAutoscaling) DescribeAutoScalingGroupsWithContext(aws.Context, *autoscaling.DescribeAutoScalingGroupsInput, ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
 	glog.Fatalf("Not implemented")
 	return nil, nil
@@ -302,9 +302,6 @@ func (m *MockAutoscaling) DescribeTerminationPolicyTypesRequest(*autoscaling.Des
 	panic("Not implemented")
 }
 
-func (m *MockAutoscaling) DetachInstances(*autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error) {
-	panic("Not implemented")
-}
 func (m *MockAutoscaling) DetachInstancesWithContext(aws.Context, *autoscaling.DetachInstancesInput, ...request.Option) (*autoscaling.DetachInstancesOutput, error) {
 	panic("Not implemented")
 }
@@ -519,3 +516,27 @@ func (m *MockAutoscaling) WaitUntilGroupNotExists(*autoscaling.DescribeAutoScali
 func (m *MockAutoscaling) WaitUntilGroupNotExistsWithContext(aws.Context, *autoscaling.DescribeAutoScalingGroupsInput, ...request.WaiterOption) error {
 	panic("Not implemented")
 }
+
+func (m *MockAutoscaling) BatchDeleteScheduledAction(*autoscaling.BatchDeleteScheduledActionInput) (*autoscaling.BatchDeleteScheduledActionOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockAutoscaling) BatchDeleteScheduledActionWithContext(aws.Context, *autoscaling.BatchDeleteScheduledActionInput, ...request.Option) (*autoscaling.BatchDeleteScheduledActionOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockAutoscaling) BatchDeleteScheduledActionRequest(*autoscaling.BatchDeleteScheduledActionInput) (*request.Request, *autoscaling.BatchDeleteScheduledActionOutput) {
+	panic("Not implemented")
+}
+
+func (m *MockAutoscaling) BatchPutScheduledUpdateGroupAction(*autoscaling.BatchPutScheduledUpdateGroupActionInput) (*autoscaling.BatchPutScheduledUpdateGroupActionOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockAutoscaling) BatchPutScheduledUpdateGroupActionWithContext(aws.Context, *autoscaling.BatchPutScheduledUpdateGroupActionInput, ...request.Option) (*autoscaling.BatchPutScheduledUpdateGroupActionOutput, error) {
+	panic("Not implemented")
+}
+
+func (m *MockAutoscaling) BatchPutScheduledUpdateGroupActionRequest(*autoscaling.BatchPutScheduledUpdateGroupActionInput) (*request.Request, *autoscaling.BatchPutScheduledUpdateGroupActionOutput) {
+	panic("Not implemented")
+}
@@ -28,6 +28,11 @@ func (m *MockEC2) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeI
 	return &ec2.DescribeInstancesOutput{}, nil
 }
 
+func (m *MockEC2) TerminateInstances(*ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	glog.Warningf("MockEc2::TerminateInstances is stub-implemented")
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
 func (m *MockEC2) DescribeInstancesWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.Option) (*ec2.DescribeInstancesOutput, error) {
 	panic("Not implemented")
 }
@@ -0,0 +1,271 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockec2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/glog"
+)
+
+type launchTemplateInfo struct {
+	main     ec2.LaunchTemplate
+	versions []*ec2.LaunchTemplateVersion
+}
+
+// toResponseLaunchTemplateData converts the request data to the response data; the structures share field names
+func toResponseLaunchTemplateData(data *ec2.RequestLaunchTemplateData) (*ec2.ResponseLaunchTemplateData, error) {
+	if data == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing launch template data: %v", err)
+	}
+	response := &ec2.ResponseLaunchTemplateData{}
+	if err := json.Unmarshal(b, response); err != nil {
+		return nil, fmt.Errorf("error deserializing launch template data: %v", err)
+	}
+	return response, nil
+}
+
+func (m *MockEC2) addLaunchTemplateVersion(lt *launchTemplateInfo, description *string, data *ec2.RequestLaunchTemplateData) (*ec2.LaunchTemplateVersion, error) {
+	responseData, err := toResponseLaunchTemplateData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	versionNumber := aws.Int64Value(lt.main.LatestVersionNumber) + 1
+	createTime := time.Now().UTC()
+	version := &ec2.LaunchTemplateVersion{
+		CreateTime:         &createTime,
+		DefaultVersion:     aws.Bool(versionNumber == aws.Int64Value(lt.main.DefaultVersionNumber)),
+		LaunchTemplateData: responseData,
+		LaunchTemplateId:   lt.main.LaunchTemplateId,
+		LaunchTemplateName: lt.main.LaunchTemplateName,
+		VersionDescription: description,
+		VersionNumber:      aws.Int64(versionNumber),
+	}
+	lt.versions = append(lt.versions, version)
+	lt.main.LatestVersionNumber = aws.Int64(versionNumber)
+	return version, nil
+}
+
+func (m *MockEC2) findLaunchTemplate(id *string, name *string) *launchTemplateInfo {
+	if id != nil {
+		return m.LaunchTemplates[aws.StringValue(id)]
+	}
+	for _, lt := range m.LaunchTemplates {
+		if aws.StringValue(lt.main.LaunchTemplateName) == aws.StringValue(name) {
+			return lt
+		}
+	}
+	return nil
+}
+
+func (m *MockEC2) CreateLaunchTemplate(request *ec2.CreateLaunchTemplateInput) (*ec2.CreateLaunchTemplateOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("CreateLaunchTemplate: %v", request)
+
+	if m.findLaunchTemplate(nil, request.LaunchTemplateName) != nil {
+		return nil, fmt.Errorf("LaunchTemplate %q already exists", aws.StringValue(request.LaunchTemplateName))
+	}
+
+	id := m.allocateId("lt")
+	createTime := time.Now().UTC()
+	lt := &launchTemplateInfo{
+		main: ec2.LaunchTemplate{
+			CreateTime:           &createTime,
+			DefaultVersionNumber: aws.Int64(1),
+			LatestVersionNumber:  aws.Int64(0),
+			LaunchTemplateId:     aws.String(id),
+			LaunchTemplateName:   request.LaunchTemplateName,
+		},
+	}
+	if _, err := m.addLaunchTemplateVersion(lt, request.VersionDescription, request.LaunchTemplateData); err != nil {
+		return nil, err
+	}
+
+	if m.LaunchTemplates == nil {
+		m.LaunchTemplates = make(map[string]*launchTemplateInfo)
+	}
+	m.LaunchTemplates[id] = lt
+
+	copy := lt.main
+	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: &copy}, nil
+}
+
+func (m *MockEC2) CreateLaunchTemplateVersion(request *ec2.CreateLaunchTemplateVersionInput) (*ec2.CreateLaunchTemplateVersionOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("CreateLaunchTemplateVersion: %v", request)
+
+	lt := m.findLaunchTemplate(request.LaunchTemplateId, request.LaunchTemplateName)
+	if lt == nil {
+		return nil, fmt.Errorf("LaunchTemplate not found")
+	}
+
+	version, err := m.addLaunchTemplateVersion(lt, request.VersionDescription, request.LaunchTemplateData)
+	if err != nil {
+		return nil, err
+	}
+
+	copy := *version
+	return &ec2.CreateLaunchTemplateVersionOutput{LaunchTemplateVersion: &copy}, nil
+}
+
+func (m *MockEC2) DescribeLaunchTemplates(request *ec2.DescribeLaunchTemplatesInput) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("DescribeLaunchTemplates: %v", request)
+
+	if len(request.LaunchTemplateIds) != 0 || len(request.LaunchTemplateNames) != 0 {
+		glog.Fatalf("DescribeLaunchTemplates by id or name not implemented")
+	}
+
+	var launchTemplates []*ec2.LaunchTemplate
+
+	for _, lt := range m.LaunchTemplates {
+		allFiltersMatch := true
+		for _, filter := range request.Filters {
+			match := false
+			switch *filter.Name {
+			case "launch-template-name":
+				for _, v := range filter.Values {
+					if aws.StringValue(lt.main.LaunchTemplateName) == aws.StringValue(v) {
+						match = true
+					}
+				}
+			default:
+				return nil, fmt.Errorf("unknown filter name: %q", *filter.Name)
+			}
+
+			if !match {
+				allFiltersMatch = false
+				break
+			}
+		}
+
+		if !allFiltersMatch {
+			continue
+		}
+
+		copy := lt.main
+		launchTemplates = append(launchTemplates, &copy)
+	}
+
+	response := &ec2.DescribeLaunchTemplatesOutput{
+		LaunchTemplates: launchTemplates,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) DescribeLaunchTemplateVersions(request *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("DescribeLaunchTemplateVersions: %v", request)
+
+	if len(request.Filters) != 0 {
+		glog.Fatalf("DescribeLaunchTemplateVersions filters not implemented")
+	}
+
+	lt := m.findLaunchTemplate(request.LaunchTemplateId, request.LaunchTemplateName)
+	if lt == nil {
+		return nil, fmt.Errorf("LaunchTemplate not found")
+	}
+
+	wanted := make(map[string]bool)
+	for _, v := range request.Versions {
+		switch s := aws.StringValue(v); s {
+		case "$Latest":
+			wanted[strconv.FormatInt(aws.Int64Value(lt.main.LatestVersionNumber), 10)] = true
+		case "$Default":
+			wanted[strconv.FormatInt(aws.Int64Value(lt.main.DefaultVersionNumber), 10)] = true
+		default:
+			wanted[s] = true
+		}
+	}
+
+	var versions []*ec2.LaunchTemplateVersion
+	for _, version := range lt.versions {
+		if len(wanted) != 0 && !wanted[strconv.FormatInt(aws.Int64Value(version.VersionNumber), 10)] {
+			continue
+		}
+		copy := *version
+		copy.DefaultVersion = aws.Bool(aws.Int64Value(version.VersionNumber) == aws.Int64Value(lt.main.DefaultVersionNumber))
+		versions = append(versions, &copy)
+	}
+
+	response := &ec2.DescribeLaunchTemplateVersionsOutput{
+		LaunchTemplateVersions: versions,
+	}
+
+	return response, nil
+}
+
+func (m *MockEC2) ModifyLaunchTemplate(request *ec2.ModifyLaunchTemplateInput) (*ec2.ModifyLaunchTemplateOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("ModifyLaunchTemplate: %v", request)
+
+	lt := m.findLaunchTemplate(request.LaunchTemplateId, request.LaunchTemplateName)
+	if lt == nil {
+		return nil, fmt.Errorf("LaunchTemplate not found")
+	}
+
+	if request.DefaultVersion != nil {
+		v, err := strconv.ParseInt(aws.StringValue(request.DefaultVersion), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DefaultVersion %q", aws.StringValue(request.DefaultVersion))
+		}
+		if v < 1 || v > aws.Int64Value(lt.main.LatestVersionNumber) {
+			return nil, fmt.Errorf("LaunchTemplate version %d not found", v)
+		}
+		lt.main.DefaultVersionNumber = aws.Int64(v)
+	}
+
+	copy := lt.main
+	return &ec2.ModifyLaunchTemplateOutput{LaunchTemplate: &copy}, nil
+}
+
+func (m *MockEC2) DeleteLaunchTemplate(request *ec2.DeleteLaunchTemplateInput) (*ec2.DeleteLaunchTemplateOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	glog.Infof("DeleteLaunchTemplate: %v", request)
+
+	lt := m.findLaunchTemplate(request.LaunchTemplateId, request.LaunchTemplateName)
+	if lt == nil {
+		return nil, fmt.Errorf("LaunchTemplate not found")
+	}
+	delete(m.LaunchTemplates, aws.StringValue(lt.main.LaunchTemplateId))
+
+	copy := lt.main
+	return &ec2.DeleteLaunchTemplateOutput{LaunchTemplate: &copy}, nil
+}
@@ -229,6 +229,12 @@ func RunCreateInstanceGroup(f *util.Factory, cmd *cobra.Command, args []string,
 		ig = group
 	}
 
+	unlock, err := lockStateStore(cluster, "create instancegroup")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	_, err = clientset.InstanceGroupsFor(cluster).Create(ig)
 	if err != nil {
 		return fmt.Errorf("error storing InstanceGroup: %v", err)
@@ -104,6 +104,12 @@ func RunCreateSecretDockerConfig(f *util.Factory, out io.Writer, options *Create
 		return err
 	}
 
+	unlock, err := lockStateStore(cluster, "create secret dockerconfig")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clientset, err := f.Clientset()
 	if err != nil {
 		return err
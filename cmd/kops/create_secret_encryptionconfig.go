@@ -105,6 +105,12 @@ func RunCreateSecretEncryptionConfig(f *util.Factory, out io.Writer, options *Cr
 		return err
 	}
 
+	unlock, err := lockStateStore(cluster, "create secret encryptionconfig")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clientset, err := f.Clientset()
 	if err != nil {
 		return err
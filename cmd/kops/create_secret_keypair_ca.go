@@ -99,6 +99,12 @@ func RunCreateSecretCaCert(f *util.Factory, out io.Writer, options *CreateSecret
 		return fmt.Errorf("error getting cluster: %q: %v", options.ClusterName, err)
 	}
 
+	unlock, err := lockStateStore(cluster, "create secret keypair ca")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clientSet, err := f.Clientset()
 	if err != nil {
 		return fmt.Errorf("error getting clientset: %v", err)
@@ -98,6 +98,12 @@ func RunCreateSecretPublicKey(f *util.Factory, out io.Writer, options *CreateSec
 		return err
 	}
 
+	unlock, err := lockStateStore(cluster, "create secret sshpublickey")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clientset, err := f.Clientset()
 	if err != nil {
 		return err
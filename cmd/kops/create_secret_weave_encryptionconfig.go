@@ -104,6 +104,12 @@ func RunCreateSecretWeaveEncryptionConfig(f *util.Factory, options *CreateSecret
 		return err
 	}
 
+	unlock, err := lockStateStore(cluster, "create secret weavepassword")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	clientset, err := f.Clientset()
 	if err != nil {
 		return err
@@ -102,6 +102,7 @@ func RunDeleteCluster(f *util.Factory, out io.Writer, options *DeleteClusterOpti
 	var cloud fi.Cloud
 	var cluster *api.Cluster
 	var err error
+	stateDeleted := false
 
 	if options.External {
 		region := options.Region
@@ -114,6 +115,18 @@ func RunDeleteCluster(f *util.Factory, out io.Writer, options *DeleteClusterOpti
 		if err != nil {
 			return fmt.Errorf("error initializing AWS client: %v", err)
 		}
+	} else if options.Yes {
+		var unlock func()
+		cluster, unlock, err = lockCluster(f, clusterName, "delete cluster")
+		if err != nil {
+			return err
+		}
+		// The lock is removed along with the rest of the state store, once the cluster is unregistered
+		defer func() {
+			if !stateDeleted {
+				unlock()
+			}
+		}()
 	} else {
 		cluster, err = GetCluster(f, clusterName)
 		if err != nil {
@@ -200,6 +213,7 @@ func RunDeleteCluster(f *util.Factory, out io.Writer, options *DeleteClusterOpti
 		if err != nil {
 			return fmt.Errorf("error removing cluster from state store: %v", err)
 		}
+		stateDeleted = true
 	}
 
 	b := kubeconfig.NewKubeconfigBuilder()
@@ -155,6 +155,12 @@ func RunDeleteInstanceGroup(f *util.Factory, out io.Writer, options *DeleteInsta
 		return nil
 	}
 
+	unlock, err := lockStateStore(cluster, "delete instancegroup")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	d := &instancegroups.DeleteInstanceGroup{}
 	d.Cluster = cluster
 	d.Cloud = cloud
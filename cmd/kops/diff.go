@@ -0,0 +1,292 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kops/cmd/kops/util"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/diff"
+	"k8s.io/kops/pkg/kopscodecs"
+	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/genericclioptions/resource"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	diffLong = templates.LongDesc(i18n.T(`
+	Show the differences between versions of a cluster configuration.
+
+	With a filename, the cluster and instance groups in the file are compared with those in the
+	state store, showing the changes kops replace -f would make.
+
+	The differences are shown as a text diff of the YAML of each object, or with --output json
+	as a list of the changed fields of each object.`))
+
+	diffExample = templates.Examples(i18n.T(`
+	# Show the changes replacing the cluster with a YAML file would make
+	kops diff -f my-cluster.yaml
+
+	# Show the changed fields as JSON
+	kops diff -f my-cluster.yaml -o json
+
+	# Show the changes to the cluster since revision 3
+	kops diff cluster --name k8s-cluster.example.com --revision 3
+
+	# Show the changes this version of kops would make to the completed cluster spec
+	kops diff cluster --name k8s-cluster.example.com --completed
+	`))
+
+	diffShort = i18n.T(`Show differences in cluster configuration.`)
+)
+
+// OutputText is a text diff of the YAML of each object
+const OutputText = "text"
+
+type DiffOptions struct {
+	// output is the format of the differences: text or json
+	output string
+
+	// FilenameOptions is a list of files containing resources, as for kops replace
+	resource.FilenameOptions
+}
+
+func NewCmdDiff(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &DiffOptions{
+		output: OutputText,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   diffShort,
+		Long:    diffLong,
+		Example: diffExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if cmdutil.IsFilenameSliceEmpty(options.Filenames) {
+				cmd.Help()
+				return
+			}
+
+			cmdutil.CheckErr(RunDiff(f, out, options))
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&options.output, "output", "o", options.output, "output format.  One of: text, json")
+	cmd.Flags().StringSliceVarP(&options.Filenames, "filename", "f", options.Filenames, "A list of one or more files separated by a comma.")
+
+	// create subcommands
+	cmd.AddCommand(NewCmdDiffCluster(f, out, options))
+
+	return cmd
+}
+
+// RunDiff compares the resources in the files with those in the state store
+func RunDiff(f *util.Factory, out io.Writer, options *DiffOptions) error {
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	codec := kopscodecs.Codecs.UniversalDecoder(kopsapi.SchemeGroupVersion)
+
+	var diffs []*objectDiff
+	for _, f := range options.Filenames {
+		var contents []byte
+		if f == "-" {
+			contents, err = ConsumeStdin()
+			if err != nil {
+				return err
+			}
+		} else {
+			contents, err = vfs.Context.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("error reading file %q: %v", f, err)
+			}
+		}
+		sections := bytes.Split(contents, []byte("\n---\n"))
+
+		for _, section := range sections {
+			o, gvk, err := codec.Decode(section, nil, nil)
+			if err != nil {
+				return fmt.Errorf("error parsing file %q: %v", f, err)
+			}
+
+			d := &objectDiff{To: o}
+			switch v := o.(type) {
+			case *kopsapi.Cluster:
+				d.Kind = "Cluster"
+				d.Name = v.ObjectMeta.Name
+				cluster, err := clientset.GetCluster(v.ObjectMeta.Name)
+				if err != nil {
+					if !errors.IsNotFound(err) {
+						return fmt.Errorf("error fetching cluster %q: %v", v.ObjectMeta.Name, err)
+					}
+				} else {
+					d.From = cluster
+				}
+
+			case *kopsapi.InstanceGroup:
+				d.Kind = "InstanceGroup"
+				d.Name = v.ObjectMeta.Name
+				clusterName := v.ObjectMeta.Labels[kopsapi.LabelClusterName]
+				if clusterName == "" {
+					return fmt.Errorf("must specify %q label with cluster name to compare instanceGroup", kopsapi.LabelClusterName)
+				}
+				cluster, err := clientset.GetCluster(clusterName)
+				if err != nil {
+					if errors.IsNotFound(err) {
+						return fmt.Errorf("cluster %q not found", clusterName)
+					}
+					return fmt.Errorf("error fetching cluster %q: %v", clusterName, err)
+				}
+				ig, err := clientset.InstanceGroupsFor(cluster).Get(v.ObjectMeta.Name, metav1.GetOptions{})
+				if err != nil {
+					if !errors.IsNotFound(err) {
+						return fmt.Errorf("error fetching instanceGroup %q: %v", v.ObjectMeta.Name, err)
+					}
+				} else {
+					d.From = ig
+				}
+
+			default:
+				return fmt.Errorf("Unhandled kind %q in %q", gvk, f)
+			}
+			diffs = append(diffs, d)
+		}
+	}
+
+	changed, err := writeDiffs(out, options.output, diffs)
+	if err != nil {
+		return err
+	}
+	if !changed && options.output == OutputText {
+		fmt.Fprintf(out, "No changes\n")
+	}
+	return nil
+}
+
+// objectDiff compares two versions of an object; From or To is nil if the object does not exist in that version
+type objectDiff struct {
+	Kind string
+	Name string
+	From runtime.Object
+	To   runtime.Object
+}
+
+// objectChanges are the changed fields of an object, as output with --output json
+type objectChanges struct {
+	Kind    string             `json:"kind"`
+	Name    string             `json:"name"`
+	Changes []diff.FieldChange `json:"changes"`
+}
+
+// writeDiffs writes the differences between the versions of the objects, returning false if there are none
+func writeDiffs(out io.Writer, output string, diffs []*objectDiff) (bool, error) {
+	switch output {
+	case OutputText:
+		changed := false
+		for _, d := range diffs {
+			from, err := diffSerialize(d.From, kopscodecs.ToVersionedYaml)
+			if err != nil {
+				return false, err
+			}
+			to, err := diffSerialize(d.To, kopscodecs.ToVersionedYaml)
+			if err != nil {
+				return false, err
+			}
+			if bytes.Equal(from, to) {
+				continue
+			}
+
+			switch {
+			case d.From == nil:
+				fmt.Fprintf(out, "%s/%s (added):\n", d.Kind, d.Name)
+			case d.To == nil:
+				fmt.Fprintf(out, "%s/%s (deleted):\n", d.Kind, d.Name)
+			default:
+				fmt.Fprintf(out, "%s/%s:\n", d.Kind, d.Name)
+			}
+			fmt.Fprintf(out, "%s\n", diff.FormatDiff(string(from), string(to)))
+			changed = true
+		}
+		return changed, nil
+
+	case OutputJSON:
+		all := []*objectChanges{}
+		for _, d := range diffs {
+			from, err := diffSerialize(d.From, kopscodecs.ToVersionedJSON)
+			if err != nil {
+				return false, err
+			}
+			to, err := diffSerialize(d.To, kopscodecs.ToVersionedJSON)
+			if err != nil {
+				return false, err
+			}
+			changes, err := diff.StructuredDiff(from, to)
+			if err != nil {
+				return false, err
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			all = append(all, &objectChanges{Kind: d.Kind, Name: d.Name, Changes: changes})
+		}
+
+		j, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", j); err != nil {
+			return false, fmt.Errorf("error writing to output: %v", err)
+		}
+		return len(all) != 0, nil
+
+	default:
+		return false, fmt.Errorf("Unknown output format: %q", output)
+	}
+}
+
+// diffSerialize serializes an object for comparison, without its ResourceVersion, which changes on every write
+func diffSerialize(o runtime.Object, serialize func(runtime.Object) ([]byte, error)) ([]byte, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	o = o.DeepCopyObject()
+	objectMeta, err := meta.Accessor(o)
+	if err != nil {
+		return nil, err
+	}
+	objectMeta.SetResourceVersion("")
+
+	data, err := serialize(o)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing object: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	kopsbase "k8s.io/kops"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/pkg/client/simple"
+	"k8s.io/kops/pkg/client/simple/vfsclientset"
+	"k8s.io/kops/upup/pkg/fi/cloudup"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	diffClusterLong = templates.LongDesc(i18n.T(`
+	Show the differences in the configuration of a cluster.
+
+	With --revision, a recorded revision of the cluster and instance group configuration is
+	compared with the current configuration in the state store.  A revision is recorded each
+	time kops changes the configuration; they are listed by kops get cluster --revisions.
+
+	With --completed, the completed cluster spec written by the last kops update cluster is
+	compared with the spec this version of kops would build, showing the changes upgrading
+	kops would make.`))
+
+	diffClusterExample = templates.Examples(i18n.T(`
+	# Show the changes to the cluster since revision 3
+	kops diff cluster --name k8s-cluster.example.com --revision 3
+
+	# Show the changes this version of kops would make to the completed cluster spec
+	kops diff cluster --name k8s-cluster.example.com --completed
+	`))
+
+	diffClusterShort = i18n.T(`Show differences in the configuration of a cluster.`)
+)
+
+type DiffClusterOptions struct {
+	*DiffOptions
+
+	ClusterName string
+
+	// Revision is the recorded revision to compare with the current configuration
+	Revision int
+
+	// Completed compares the completed cluster spec in the state store with the spec built by this version of kops
+	Completed bool
+}
+
+func NewCmdDiffCluster(f *util.Factory, out io.Writer, diffOptions *DiffOptions) *cobra.Command {
+	options := &DiffClusterOptions{
+		DiffOptions: diffOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "cluster",
+		Short:   diffClusterShort,
+		Long:    diffClusterLong,
+		Example: diffClusterExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := rootCommand.ProcessArgs(args)
+			if err != nil {
+				exitWithError(err)
+			}
+			options.ClusterName = rootCommand.ClusterName()
+
+			err = RunDiffCluster(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&options.Revision, "revision", options.Revision, "Revision to compare with the current configuration")
+	cmd.Flags().BoolVar(&options.Completed, "completed", options.Completed, "Compare the completed cluster spec with the spec built by this version of kops")
+
+	return cmd
+}
+
+func RunDiffCluster(f *util.Factory, out io.Writer, options *DiffClusterOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+	if (options.Revision > 0) == options.Completed {
+		return fmt.Errorf("one of --revision or --completed is required")
+	}
+
+	cluster, err := GetCluster(f, options.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	var diffs []*objectDiff
+	if options.Completed {
+		diffs, err = completedSpecDiffs(clientset, cluster)
+		if err != nil {
+			return err
+		}
+	} else {
+		store, err := revisionStore(clientset)
+		if err != nil {
+			return err
+		}
+		revision, err := store.GetRevision(cluster.ObjectMeta.Name, options.Revision)
+		if err != nil {
+			return err
+		}
+		current, err := store.GetCurrentState(cluster.ObjectMeta.Name)
+		if err != nil {
+			return err
+		}
+		diffs, err = revisionDiffs(revision, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	changed, err := writeDiffs(out, options.output, diffs)
+	if err != nil {
+		return err
+	}
+	if !changed && options.output == OutputText {
+		if options.Completed {
+			fmt.Fprintf(out, "No changes to the completed cluster spec with kops %s\n", kopsbase.Version)
+		} else {
+			fmt.Fprintf(out, "No changes since revision %d\n", options.Revision)
+		}
+	}
+	return nil
+}
+
+// completedSpecDiffs compares the completed cluster spec written by the last kops update cluster
+// with the spec this version of kops builds from the current configuration
+func completedSpecDiffs(clientset simple.Clientset, cluster *api.Cluster) ([]*objectDiff, error) {
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &api.Cluster{}
+	err = registry.ReadConfigDeprecated(configBase.Join(registry.PathClusterCompleted), stored)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cluster %q has no completed spec; it is written by kops update cluster", cluster.ObjectMeta.Name)
+		}
+		return nil, fmt.Errorf("error reading completed cluster spec for %q: %v", cluster.ObjectMeta.Name, err)
+	}
+
+	assetBuilder := assets.NewAssetBuilder(cluster, "")
+	fullCluster, err := cloudup.PopulateClusterSpec(clientset, cluster, assetBuilder)
+	if err != nil {
+		return nil, fmt.Errorf("error building completed cluster spec for %q: %v", cluster.ObjectMeta.Name, err)
+	}
+
+	return []*objectDiff{
+		{
+			Kind: "Cluster",
+			Name: cluster.ObjectMeta.Name,
+			From: stored,
+			To:   fullCluster,
+		},
+	}, nil
+}
+
+// revisionDiffs compares the cluster and instance groups of two revisions
+func revisionDiffs(from, to *vfsclientset.Revision) ([]*objectDiff, error) {
+	fromCluster, fromInstanceGroups, err := from.Decode()
+	if err != nil {
+		return nil, err
+	}
+	toCluster, toInstanceGroups, err := to.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []*objectDiff{
+		{
+			Kind: "Cluster",
+			Name: toCluster.ObjectMeta.Name,
+			From: fromCluster,
+			To:   toCluster,
+		},
+	}
+
+	instanceGroups := make(map[string]*objectDiff)
+	for _, ig := range fromInstanceGroups {
+		instanceGroups[ig.ObjectMeta.Name] = &objectDiff{Kind: "InstanceGroup", Name: ig.ObjectMeta.Name, From: ig}
+	}
+	for _, ig := range toInstanceGroups {
+		d := instanceGroups[ig.ObjectMeta.Name]
+		if d == nil {
+			d = &objectDiff{Kind: "InstanceGroup", Name: ig.ObjectMeta.Name}
+			instanceGroups[ig.ObjectMeta.Name] = d
+		}
+		d.To = ig
+	}
+
+	var names []string
+	for name := range instanceGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		diffs = append(diffs, instanceGroups[name])
+	}
+
+	return diffs, nil
+}
+
+// revisionStore returns the clientset of a VFS state store, which records the revisions of cluster configurations
+func revisionStore(clientset simple.Clientset) (*vfsclientset.VFSClientset, error) {
+	store, ok := clientset.(*vfsclientset.VFSClientset)
+	if !ok {
+		return nil, fmt.Errorf("revisions of the cluster configuration are only recorded in VFS state stores")
+	}
+	return store, nil
+}
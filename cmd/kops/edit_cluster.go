@@ -85,10 +85,18 @@ func RunEditCluster(f *util.Factory, cmd *cobra.Command, args []string, out io.W
 		return err
 	}
 
-	oldCluster, err := rootCommand.Cluster()
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	oldCluster, err := GetCluster(f, clusterName)
 	if err != nil {
 		return err
 	}
+	// We don't hold the state store lock while the editor is open; we take it to write the changes,
+	// and check then that the cluster was not changed in the meantime
+	readCluster := oldCluster.DeepCopy()
 
 	err = oldCluster.FillDefaults()
 	if err != nil {
@@ -234,6 +242,21 @@ func RunEditCluster(f *util.Factory, cmd *cobra.Command, args []string, out io.W
 			return preservedFile(err, file, out)
 		}
 
+		unlock, err := lockStateStore(readCluster, "edit cluster")
+		if err != nil {
+			return preservedFile(err, file, out)
+		}
+		defer unlock()
+
+		currentCluster, err := GetCluster(f, clusterName)
+		if err != nil {
+			return preservedFile(err, file, out)
+		}
+		if err := checkUnchanged("cluster", readCluster, currentCluster); err != nil {
+			return preservedFile(err, file, out)
+		}
+		newCluster.ObjectMeta.ResourceVersion = readCluster.ObjectMeta.ResourceVersion
+
 		// Retrieve the current status of the cluster.  This will eventually be part of the cluster object.
 		statusDiscovery := &commands.CloudDiscoveryStatusStore{}
 		status, err := statusDiscovery.FindClusterStatus(oldCluster)
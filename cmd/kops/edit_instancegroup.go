@@ -89,7 +89,12 @@ func RunEditInstanceGroup(f *util.Factory, cmd *cobra.Command, args []string, ou
 
 	groupName := args[0]
 
-	cluster, err := rootCommand.Cluster()
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	cluster, err := GetCluster(f, clusterName)
 	if err != nil {
 		return err
 	}
@@ -180,6 +185,23 @@ func RunEditInstanceGroup(f *util.Factory, cmd *cobra.Command, args []string, ou
 		return err
 	}
 
+	// We don't hold the state store lock while the editor is open; we take it to write the changes,
+	// and check then that the instance group was not changed in the meantime
+	unlock, err := lockStateStore(cluster, "edit instancegroup")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	currentGroup, err := clientset.InstanceGroupsFor(cluster).Get(groupName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error reading InstanceGroup %q: %v", groupName, err)
+	}
+	if err := checkUnchanged("InstanceGroup", oldGroup, currentGroup); err != nil {
+		return err
+	}
+	fullGroup.ObjectMeta.ResourceVersion = oldGroup.ObjectMeta.ResourceVersion
+
 	// Note we perform as much validation as we can, before writing a bad config
 	_, err = clientset.InstanceGroupsFor(cluster).Update(fullGroup)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kops/cmd/kops/util"
@@ -49,6 +50,9 @@ var (
 	# Save a cluster and its instancegroups' desired configuration to YAML file
 	kops get k8s-cluster.example.com -o yaml > cluster-desired-config.yaml
 
+	# Get the instances in a cluster, and whether they need a rolling update
+	kops get instances --name k8s-cluster.example.com
+
 	# Get a secret
 	kops get secrets kube -oplaintext
 
@@ -104,7 +108,9 @@ func NewCmdGet(f *util.Factory, out io.Writer) *cobra.Command {
 
 	// create subcommands
 	cmd.AddCommand(NewCmdGetCluster(f, out, options))
+	cmd.AddCommand(NewCmdGetEtcdBackups(f, out, options))
 	cmd.AddCommand(NewCmdGetInstanceGroups(f, out, options))
+	cmd.AddCommand(NewCmdGetInstances(f, out, options))
 	cmd.AddCommand(NewCmdGetSecrets(f, out, options))
 
 	return cmd
@@ -192,6 +198,13 @@ func writeYAMLSep(out io.Writer) error {
 type marshalFunc func(obj runtime.Object) ([]byte, error)
 
 func marshalToWriter(obj runtime.Object, marshal marshalFunc, w io.Writer) error {
+	// The ResourceVersion is the version of the file in the state store, not part of the object
+	if objectMeta, err := meta.Accessor(obj); err == nil && objectMeta.GetResourceVersion() != "" {
+		obj = obj.DeepCopyObject()
+		objectMeta, _ = meta.Accessor(obj)
+		objectMeta.SetResourceVersion("")
+	}
+
 	b, err := marshal(obj)
 	if err != nil {
 		return err
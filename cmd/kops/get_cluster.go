@@ -17,11 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,6 +34,8 @@ import (
 	"k8s.io/kops/cmd/kops/util"
 	api "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/client/simple"
+	"k8s.io/kops/pkg/client/simple/vfsclientset"
 	"k8s.io/kops/util/pkg/tables"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
 	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
@@ -51,6 +57,9 @@ var (
 
 	# Save a cluster desired configuration to YAML file
 	kops get cluster k8s-cluster.example.com -o yaml > cluster-desired-config.yaml
+
+	# List the recorded revisions of a cluster configuration
+	kops get cluster k8s-cluster.example.com --revisions
 	`))
 
 	getClusterShort = i18n.T(`Get one or many clusters.`)
@@ -79,6 +88,9 @@ type GetClusterOptions struct {
 
 	// ClusterNames is a list of cluster names to show; if not specified all clusters will be shown
 	ClusterNames []string
+
+	// Revisions determines if we should list the recorded revisions of the cluster configuration
+	Revisions bool
 }
 
 func NewCmdGetCluster(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
@@ -113,6 +125,7 @@ func NewCmdGetCluster(f *util.Factory, out io.Writer, getOptions *GetOptions) *c
 	}
 
 	cmd.Flags().BoolVar(&options.FullSpec, "full", options.FullSpec, "Show fully populated configuration")
+	cmd.Flags().BoolVar(&options.Revisions, "revisions", options.Revisions, "List the recorded revisions of the cluster configuration")
 
 	return cmd
 }
@@ -153,6 +166,13 @@ func RunGetClusters(context Factory, out io.Writer, options *GetClusterOptions)
 		return fmt.Errorf("no clusters found")
 	}
 
+	if options.Revisions {
+		if len(clusters) != 1 {
+			return fmt.Errorf("--revisions requires a single cluster")
+		}
+		return clusterRevisionsOutput(client, clusters[0], out, options.output)
+	}
+
 	if options.FullSpec {
 		var err error
 		clusters, err = fullClusterSpecs(clusters)
@@ -227,6 +247,65 @@ func clusterOutputTable(clusters []*api.Cluster, out io.Writer) error {
 	return t.Render(clusters, out, "NAME", "CLOUD", "ZONES")
 }
 
+// clusterRevisionsOutput lists the recorded revisions of the cluster configuration
+func clusterRevisionsOutput(client simple.Clientset, cluster *api.Cluster, out io.Writer, output string) error {
+	store, err := revisionStore(client)
+	if err != nil {
+		return err
+	}
+	revisions, err := store.ListRevisions(cluster.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputTable:
+		if len(revisions) == 0 {
+			return fmt.Errorf("no revisions found for cluster %q", cluster.ObjectMeta.Name)
+		}
+		t := &tables.Table{}
+		t.AddColumn("REVISION", func(r *vfsclientset.Revision) string {
+			return strconv.Itoa(r.Number)
+		})
+		t.AddColumn("TIMESTAMP", func(r *vfsclientset.Revision) string {
+			return r.Timestamp.Format(time.RFC3339)
+		})
+		t.AddColumn("AUTHOR", func(r *vfsclientset.Revision) string {
+			return stringOrDash(r.Author)
+		})
+		t.AddColumn("KOPS-VERSION", func(r *vfsclientset.Revision) string {
+			return stringOrDash(r.KopsVersion)
+		})
+		t.AddColumn("CHANGE", func(r *vfsclientset.Revision) string {
+			return stringOrDash(r.Change)
+		})
+		return t.Render(revisions, out, "REVISION", "TIMESTAMP", "AUTHOR", "KOPS-VERSION", "CHANGE")
+
+	case OutputYaml:
+		y, err := yaml.Marshal(revisions)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+		if _, err := out.Write(y); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	case OutputJSON:
+		j, err := json.MarshalIndent(revisions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := out.Write(j); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown output format: %q", output)
+	}
+}
+
 // fullOutputJson outputs the marshalled JSON of a list of clusters and instance groups.  It will handle
 // nils for clusters and instanceGroups slices.
 func fullOutputJSON(out io.Writer, args ...runtime.Object) error {
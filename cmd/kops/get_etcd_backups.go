@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	getEtcdBackupsLong = templates.LongDesc(i18n.T(`
+	Display the backups of the etcd clusters of a cluster.
+
+	The backups are those written by etcd-manager into the backup store of each etcd cluster.
+	Any of them can be restored with kops restore etcd.`))
+
+	getEtcdBackupsExample = templates.Examples(i18n.T(`
+	# Get the backups of all etcd clusters
+	kops get etcd-backups --name k8s-cluster.example.com
+
+	# Get the backups of the main etcd cluster
+	kops get etcd-backups --name k8s-cluster.example.com main
+	`))
+
+	getEtcdBackupsShort = i18n.T(`Get the etcd backups of a cluster.`)
+)
+
+type GetEtcdBackupsOptions struct {
+	*GetOptions
+}
+
+func NewCmdGetEtcdBackups(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetEtcdBackupsOptions{
+		GetOptions: getOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "etcd-backups",
+		Aliases: []string{"etcd-backup"},
+		Short:   getEtcdBackupsShort,
+		Long:    getEtcdBackupsLong,
+		Example: getEtcdBackupsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetEtcdBackups(f, out, &options, args)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func RunGetEtcdBackups(f *util.Factory, out io.Writer, options *GetEtcdBackupsOptions, args []string) error {
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	cluster, err := GetCluster(f, clusterName)
+	if err != nil {
+		return err
+	}
+
+	etcdClusters, err := findEtcdClusters(cluster, args)
+	if err != nil {
+		return err
+	}
+
+	var backups []*etcdbackup.Backup
+	for _, etcdCluster := range etcdClusters {
+		store, err := etcdbackup.NewBackupStore(etcdCluster)
+		if err != nil {
+			return err
+		}
+		list, err := store.ListBackups()
+		if err != nil {
+			return err
+		}
+		backups = append(backups, list...)
+	}
+
+	switch options.output {
+	case OutputTable:
+		if len(backups) == 0 {
+			return fmt.Errorf("No etcd backups found")
+		}
+		t := &tables.Table{}
+		t.AddColumn("ETCD-CLUSTER", func(b *etcdbackup.Backup) string {
+			return b.EtcdCluster
+		})
+		t.AddColumn("NAME", func(b *etcdbackup.Backup) string {
+			return b.Name
+		})
+		t.AddColumn("ETCD-VERSION", func(b *etcdbackup.Backup) string {
+			return stringOrDash(b.EtcdVersion)
+		})
+		return t.Render(backups, out, "ETCD-CLUSTER", "NAME", "ETCD-VERSION")
+
+	case OutputYaml:
+		y, err := yaml.Marshal(backups)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+		if _, err := out.Write(y); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	case OutputJSON:
+		j, err := json.MarshalIndent(backups, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := out.Write(j); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
+
+// findEtcdClusters returns the etcd clusters managed by etcd-manager, from the full cluster spec.
+// If names are specified only those etcd clusters are returned.
+func findEtcdClusters(cluster *api.Cluster, names []string) ([]*api.EtcdClusterSpec, error) {
+	fullSpecs, err := fullClusterSpecs([]*api.Cluster{cluster})
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []*api.EtcdClusterSpec
+	for _, etcdCluster := range fullSpecs[0].Spec.EtcdClusters {
+		if etcdCluster.Provider == api.EtcdProviderTypeManager {
+			managed = append(managed, etcdCluster)
+		}
+	}
+
+	if len(managed) == 0 {
+		return nil, fmt.Errorf("cluster %q does not use etcd-manager, so has no etcd backups", cluster.ObjectMeta.Name)
+	}
+
+	if len(names) == 0 {
+		return managed, nil
+	}
+
+	var etcdClusters []*api.EtcdClusterSpec
+	for _, name := range names {
+		var found *api.EtcdClusterSpec
+		for _, etcdCluster := range managed {
+			if etcdCluster.Name == name {
+				found = etcdCluster
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("etcd cluster %q not found, or not managed by etcd-manager", name)
+		}
+		etcdClusters = append(etcdClusters, found)
+	}
+	return etcdClusters, nil
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/upup/pkg/fi/cloudup"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/genericclioptions"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	getInstancesLong = templates.LongDesc(i18n.T(`
+	Display the cloud instances backing the instancegroups of a cluster.
+
+	For each instance the cloud ID, instancegroup, role and whether it needs a rolling update
+	are shown, together with the kubernetes node name, internal IP and readiness when the
+	kubernetes API can be reached.`))
+
+	getInstancesExample = templates.Examples(i18n.T(`
+	# Get all instances in a cluster
+	kops get instances --name k8s-cluster.example.com
+
+	# Get the instances of a single instancegroup
+	kops get instances --name k8s-cluster.example.com nodes
+
+	# Get the instances without querying the kubernetes API
+	kops get instances --name k8s-cluster.example.com --cloudonly -o yaml
+	`))
+
+	getInstancesShort = i18n.T(`Get the instances in a cluster.`)
+)
+
+const (
+	// InstanceStatusUpToDate is the status of an instance matching the current instancegroup configuration
+	InstanceStatusUpToDate = "UpToDate"
+	// InstanceStatusNeedsUpdate is the status of an instance that will be replaced by a rolling update
+	InstanceStatusNeedsUpdate = "NeedsUpdate"
+	// InstanceStatusDetached is the status of an instance detached from its group, pending deletion
+	InstanceStatusDetached = "Detached"
+)
+
+type GetInstancesOptions struct {
+	*GetOptions
+
+	// CloudOnly skips the kubernetes API, so node name, internal IP and readiness are not reported
+	CloudOnly bool
+}
+
+// instanceInfo is the per-instance view printed by kops get instances
+type instanceInfo struct {
+	ID            string `json:"id"`
+	InstanceGroup string `json:"instanceGroup"`
+	Role          string `json:"role"`
+	Status        string `json:"status"`
+	NodeName      string `json:"nodeName,omitempty"`
+	InternalIP    string `json:"internalIP,omitempty"`
+	Ready         string `json:"ready,omitempty"`
+}
+
+func NewCmdGetInstances(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetInstancesOptions{
+		GetOptions: getOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "instances",
+		Aliases: []string{"instance"},
+		Short:   getInstancesShort,
+		Long:    getInstancesLong,
+		Example: getInstancesExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetInstances(f, out, &options, args)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.CloudOnly, "cloudonly", options.CloudOnly, "Only query the cloud provider, not the kubernetes API")
+
+	return cmd
+}
+
+func RunGetInstances(f *util.Factory, out io.Writer, options *GetInstancesOptions, args []string) error {
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(clusterName)
+	if err != nil {
+		return fmt.Errorf("error fetching cluster %q: %v", clusterName, err)
+	}
+
+	if cluster == nil {
+		return fmt.Errorf("cluster %q was not found", clusterName)
+	}
+
+	list, err := clientset.InstanceGroupsFor(cluster).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	instanceGroups, err := filterInstanceGroupsByName(args, list.Items)
+	if err != nil {
+		return err
+	}
+
+	if len(instanceGroups) == 0 {
+		return fmt.Errorf("No InstanceGroup objects found")
+	}
+
+	var nodes []v1.Node
+	if !options.CloudOnly {
+		nodes, err = listClusterNodes(cluster)
+		if err != nil {
+			// The cloud view is still useful, e.g. when the cluster is not healthy
+			fmt.Fprintf(os.Stderr, "Unable to reach the kubernetes API, node information will be missing: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Use --cloudonly to skip the kubernetes API\n\n")
+		}
+	}
+
+	cloud, err := cloudup.BuildCloud(cluster)
+	if err != nil {
+		return err
+	}
+
+	// Don't warn about cloud groups we didn't ask for
+	warnUnmatched := len(args) == 0
+	groups, err := cloud.GetCloudGroups(cluster, instanceGroups, warnUnmatched, nodes)
+	if err != nil {
+		return err
+	}
+
+	instances := buildInstanceInfos(groups)
+
+	switch options.output {
+	case OutputTable:
+		return instancesOutputTable(instances, out)
+
+	case OutputYaml:
+		y, err := yaml.Marshal(instances)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+		if _, err := out.Write(y); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	case OutputJSON:
+		j, err := json.MarshalIndent(instances, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := out.Write(j); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
+
+// listClusterNodes lists the kubernetes nodes, using the kubecfg context named after the cluster
+func listClusterNodes(cluster *api.Cluster) ([]v1.Node, error) {
+	contextName := cluster.ObjectMeta.Name
+	clientGetter := genericclioptions.NewConfigFlags()
+	clientGetter.Context = &contextName
+
+	config, err := clientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load kubecfg settings for %q: %v", contextName, err)
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build kube client for %q: %v", contextName, err)
+	}
+
+	nodeList, err := k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes in cluster: %v", err)
+	}
+
+	return nodeList.Items, nil
+}
+
+// buildInstanceInfos flattens the members of the cloud groups, sorted by instancegroup and then instance id
+func buildInstanceInfos(groups map[string]*cloudinstances.CloudInstanceGroup) []*instanceInfo {
+	var instances []*instanceInfo
+	for _, group := range groups {
+		for _, member := range group.Ready {
+			instances = append(instances, buildInstanceInfo(member, InstanceStatusUpToDate))
+		}
+		for _, member := range group.NeedUpdate {
+			status := InstanceStatusNeedsUpdate
+			if member.Detached {
+				status = InstanceStatusDetached
+			}
+			instances = append(instances, buildInstanceInfo(member, status))
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].InstanceGroup != instances[j].InstanceGroup {
+			return instances[i].InstanceGroup < instances[j].InstanceGroup
+		}
+		return instances[i].ID < instances[j].ID
+	})
+
+	return instances
+}
+
+func buildInstanceInfo(member *cloudinstances.CloudInstanceGroupMember, status string) *instanceInfo {
+	info := &instanceInfo{
+		ID:     member.ID,
+		Status: status,
+	}
+
+	if ig := member.CloudInstanceGroup.InstanceGroup; ig != nil {
+		info.InstanceGroup = ig.ObjectMeta.Name
+		info.Role = string(ig.Spec.Role)
+	} else {
+		info.InstanceGroup = member.CloudInstanceGroup.HumanName
+	}
+
+	if node := member.Node; node != nil {
+		info.NodeName = node.Name
+		for _, address := range node.Status.Addresses {
+			if address.Type == v1.NodeInternalIP {
+				info.InternalIP = address.Address
+				break
+			}
+		}
+		info.Ready = string(v1.ConditionUnknown)
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady {
+				info.Ready = string(condition.Status)
+				break
+			}
+		}
+	}
+
+	return info
+}
+
+func instancesOutputTable(instances []*instanceInfo, out io.Writer) error {
+	t := &tables.Table{}
+	t.AddColumn("ID", func(i *instanceInfo) string {
+		return i.ID
+	})
+	t.AddColumn("INSTANCEGROUP", func(i *instanceInfo) string {
+		return i.InstanceGroup
+	})
+	t.AddColumn("ROLE", func(i *instanceInfo) string {
+		return i.Role
+	})
+	t.AddColumn("STATUS", func(i *instanceInfo) string {
+		return i.Status
+	})
+	t.AddColumn("NODE", func(i *instanceInfo) string {
+		return stringOrDash(i.NodeName)
+	})
+	t.AddColumn("INTERNAL-IP", func(i *instanceInfo) string {
+		return stringOrDash(i.InternalIP)
+	})
+	t.AddColumn("READY", func(i *instanceInfo) string {
+		return stringOrDash(i.Ready)
+	})
+	return t.Render(instances, out, "ID", "INSTANCEGROUP", "ROLE", "STATUS", "NODE", "INTERNAL-IP", "READY")
+}
+
+func stringOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
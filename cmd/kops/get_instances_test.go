@@ -0,0 +1,108 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+)
+
+func TestBuildInstanceInfos(t *testing.T) {
+	nodes := &cloudinstances.CloudInstanceGroup{
+		InstanceGroup: &api.InstanceGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nodes"},
+			Spec:       api.InstanceGroupSpec{Role: api.InstanceGroupRoleNode},
+		},
+	}
+	master := &cloudinstances.CloudInstanceGroup{
+		InstanceGroup: &api.InstanceGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "master-us-test-1a"},
+			Spec:       api.InstanceGroupSpec{Role: api.InstanceGroupRoleMaster},
+		},
+	}
+
+	readyNode := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "ip-172-20-1-1.ec2.internal"},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: "54.1.1.1"},
+				{Type: v1.NodeInternalIP, Address: "172.20.1.1"},
+			},
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			},
+		},
+	}
+	notReadyNode := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "ip-172-20-1-2.ec2.internal"},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionFalse},
+			},
+		},
+	}
+
+	nodes.Ready = []*cloudinstances.CloudInstanceGroupMember{
+		{ID: "i-2", CloudInstanceGroup: nodes, Node: readyNode},
+	}
+	nodes.NeedUpdate = []*cloudinstances.CloudInstanceGroupMember{
+		{ID: "i-3", CloudInstanceGroup: nodes, Node: notReadyNode},
+		{ID: "i-1", CloudInstanceGroup: nodes, Detached: true},
+	}
+	master.NeedUpdate = []*cloudinstances.CloudInstanceGroupMember{
+		{ID: "i-9", CloudInstanceGroup: master},
+	}
+
+	actual := buildInstanceInfos(map[string]*cloudinstances.CloudInstanceGroup{
+		"nodes":             nodes,
+		"master-us-test-1a": master,
+	})
+
+	expected := []*instanceInfo{
+		{ID: "i-9", InstanceGroup: "master-us-test-1a", Role: "Master", Status: InstanceStatusNeedsUpdate},
+		{ID: "i-1", InstanceGroup: "nodes", Role: "Node", Status: InstanceStatusDetached},
+		{ID: "i-2", InstanceGroup: "nodes", Role: "Node", Status: InstanceStatusUpToDate, NodeName: "ip-172-20-1-1.ec2.internal", InternalIP: "172.20.1.1", Ready: "True"},
+		{ID: "i-3", InstanceGroup: "nodes", Role: "Node", Status: InstanceStatusNeedsUpdate, NodeName: "ip-172-20-1-2.ec2.internal", Ready: "False"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		for i := range actual {
+			t.Logf("actual[%d]: %+v", i, *actual[i])
+		}
+		t.Fatalf("unexpected instances")
+	}
+
+	var out bytes.Buffer
+	if err := instancesOutputTable(actual, &out); err != nil {
+		t.Fatalf("error rendering table: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header and 4 rows, got:\n%s", out.String())
+	}
+	// The table sorts rows by its leading columns, so the instance ids are in order
+	if fields := strings.Fields(lines[1]); !reflect.DeepEqual(fields, []string{"i-1", "nodes", "Node", "Detached", "-", "-", "-"}) {
+		t.Fatalf("unexpected row for instance without a node: %v", fields)
+	}
+}
@@ -57,6 +57,11 @@ func TestMinimal(t *testing.T) {
 	runTestAWS(t, "minimal.example.com", "minimal", "v1alpha2", false, 1, true, nil)
 }
 
+// TestMinimalTerraform012 runs the test on a minimum configuration, with the output written for terraform 0.12
+func TestMinimalTerraform012(t *testing.T) {
+	runTestAWS(t, "minimal-tf12.example.com", "minimal-tf12", "v1alpha2", false, 1, true, nil)
+}
+
 // TestRestrictAccess runs the test on a simple SG configuration, similar to kops create cluster minimal.example.com --ssh-access=$(IPS) --admin-access=$(IPS) --master-count=3
 func TestRestrictAccess(t *testing.T) {
 	runTestAWS(t, "restrictaccess.example.com", "restrict_access", "v1alpha2", false, 1, true, nil)
@@ -149,7 +149,12 @@ func RunReplace(f *util.Factory, cmd *cobra.Command, out io.Writer, c *replaceOp
 							return fmt.Errorf("error creating cluster: %v", err)
 						}
 					} else {
+						unlock, err := lockStateStore(cluster, "replace cluster")
+						if err != nil {
+							return err
+						}
 						_, err = clientset.UpdateCluster(v, status)
+						unlock()
 						if err != nil {
 							return fmt.Errorf("error replacing cluster: %v", err)
 						}
@@ -189,7 +194,12 @@ func RunReplace(f *util.Factory, cmd *cobra.Command, out io.Writer, c *replaceOp
 						return fmt.Errorf("error creating instanceGroup: %v", err)
 					}
 				default:
+					unlock, err := lockStateStore(cluster, "replace instancegroup")
+					if err != nil {
+						return err
+					}
 					_, err = clientset.InstanceGroupsFor(cluster).Update(v)
+					unlock()
 					if err != nil {
 						return fmt.Errorf("error replacing instanceGroup: %v", err)
 					}
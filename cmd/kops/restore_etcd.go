@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	restoreEtcdLong = templates.LongDesc(i18n.T(`
+	Restore an etcd cluster from one of its backups.
+
+	The restore command is written into the backup store of the etcd cluster, where
+	etcd-manager picks it up: etcd-manager stops etcd, restores the backup on every member
+	and starts etcd again.  The data written since the backup was taken is lost.
+
+	The backups of each etcd cluster can be listed with kops get etcd-backups.`))
+
+	restoreEtcdExample = templates.Examples(i18n.T(`
+	# List the backups of the main etcd cluster
+	kops get etcd-backups --name k8s-cluster.example.com main
+
+	# Restore the main etcd cluster from a backup
+	kops restore etcd --name k8s-cluster.example.com --backup 2018-08-03T09:54:22Z-000001 --yes
+
+	# Restore the events etcd cluster from a backup
+	kops restore etcd --name k8s-cluster.example.com --etcd-cluster events --backup 2018-08-03T09:54:22Z-000001 --yes
+	`))
+
+	restoreEtcdShort = i18n.T(`Restore an etcd cluster from a backup.`)
+)
+
+type RestoreEtcdOptions struct {
+	ClusterName string
+	EtcdCluster string
+	Backup      string
+	Yes         bool
+}
+
+func NewCmdRestoreEtcd(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RestoreEtcdOptions{
+		EtcdCluster: "main",
+	}
+
+	cmd := &cobra.Command{
+		Use:     "etcd",
+		Short:   restoreEtcdShort,
+		Long:    restoreEtcdLong,
+		Example: restoreEtcdExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunRestoreEtcd(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&options.EtcdCluster, "etcd-cluster", options.EtcdCluster, "Name of the etcd cluster to restore")
+	cmd.Flags().StringVar(&options.Backup, "backup", options.Backup, "Name of the backup to restore, as listed by kops get etcd-backups")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Write the restore command")
+
+	return cmd
+}
+
+func RunRestoreEtcd(f *util.Factory, out io.Writer, options *RestoreEtcdOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+	if options.Backup == "" {
+		return fmt.Errorf("--backup is required")
+	}
+
+	cluster, err := GetCluster(f, options.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	etcdClusters, err := findEtcdClusters(cluster, []string{options.EtcdCluster})
+	if err != nil {
+		return err
+	}
+
+	store, err := etcdbackup.NewBackupStore(etcdClusters[0])
+	if err != nil {
+		return err
+	}
+
+	backups, err := store.ListBackups()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, b := range backups {
+		if b.Name == options.Backup {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("backup %q not found for etcd cluster %q; the backups can be listed with kops get etcd-backups", options.Backup, options.EtcdCluster)
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "Will restore etcd cluster %q from backup %q in %s\n", options.EtcdCluster, options.Backup, store.Path())
+		fmt.Fprintf(out, "The data written to etcd since the backup was taken will be lost\n")
+		fmt.Fprintf(out, "\nMust specify --yes to restore\n")
+		return nil
+	}
+
+	unlock, err := lockStateStore(cluster, "restore etcd")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	p, err := store.AddRestoreCommand(options.Backup)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Wrote the command to restore etcd cluster %q from backup %q to %s\n", options.EtcdCluster, options.Backup, p)
+	fmt.Fprintf(out, "etcd-manager will restore the backup the next time it reads its backup store; this usually takes a few minutes\n")
+
+	return nil
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/commands"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	rollbackClusterLong = templates.LongDesc(i18n.T(`
+	Roll back the cluster and instance group configuration in the state store to a recorded revision.
+
+	The differences are shown first; the configuration is only changed with --yes.  Instance groups
+	which were created after the revision are deleted, and those deleted since are created again.
+	Rolling back records a new revision, so it can itself be rolled back.
+
+	Like kops edit cluster, this only changes the configuration: run kops update cluster to apply it.`))
+
+	rollbackClusterExample = templates.Examples(i18n.T(`
+	# List the revisions of the cluster
+	kops get cluster --name k8s-cluster.example.com --revisions
+
+	# Show the changes which rolling back to revision 3 would make
+	kops rollback cluster --name k8s-cluster.example.com --to 3
+
+	# Roll back to revision 3, and apply the result
+	kops rollback cluster --name k8s-cluster.example.com --to 3 --yes
+	kops update cluster --name k8s-cluster.example.com --yes
+	`))
+
+	rollbackClusterShort = i18n.T(`Roll back the configuration of a cluster to a revision.`)
+)
+
+type RollbackClusterOptions struct {
+	ClusterName string
+	To          int
+	Yes         bool
+}
+
+func NewCmdRollbackCluster(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RollbackClusterOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "cluster",
+		Short:   rollbackClusterShort,
+		Long:    rollbackClusterLong,
+		Example: rollbackClusterExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := rootCommand.ProcessArgs(args)
+			if err != nil {
+				exitWithError(err)
+			}
+			options.ClusterName = rootCommand.ClusterName()
+
+			err = RunRollbackCluster(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&options.To, "to", options.To, "Revision to roll back to")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Roll back the configuration")
+
+	return cmd
+}
+
+func RunRollbackCluster(f *util.Factory, out io.Writer, options *RollbackClusterOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+	if options.To <= 0 {
+		return fmt.Errorf("--to is required")
+	}
+
+	var cluster *api.Cluster
+	if options.Yes {
+		c, unlock, err := lockCluster(f, options.ClusterName, "rollback cluster")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		cluster = c
+	} else {
+		c, err := GetCluster(f, options.ClusterName)
+		if err != nil {
+			return err
+		}
+		cluster = c
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	store, err := revisionStore(clientset)
+	if err != nil {
+		return err
+	}
+	revision, err := store.GetRevision(cluster.ObjectMeta.Name, options.To)
+	if err != nil {
+		return err
+	}
+	current, err := store.GetCurrentState(cluster.ObjectMeta.Name)
+	if err != nil {
+		return err
+	}
+
+	diffs, err := revisionDiffs(current, revision)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Changes to roll back cluster %q to revision %d:\n\n", options.ClusterName, options.To)
+	changed, err := writeDiffs(out, OutputText, diffs)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Fprintf(out, "No changes; the configuration matches revision %d\n", options.To)
+		return nil
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to roll back\n")
+		return nil
+	}
+
+	desiredCluster, desiredInstanceGroups, err := revision.Decode()
+	if err != nil {
+		return err
+	}
+
+	// The rollback is recorded as a single revision once it is complete, rather than one for each write
+	writer := store.WithoutRevisions()
+	rolledBack := cluster
+
+	if revision.Cluster != current.Cluster {
+		// Retrieve the current status of the cluster.  This will eventually be part of the cluster object.
+		statusDiscovery := &commands.CloudDiscoveryStatusStore{}
+		status, err := statusDiscovery.FindClusterStatus(cluster)
+		if err != nil {
+			return err
+		}
+
+		if desiredCluster.ObjectMeta.Name == "" {
+			desiredCluster.ObjectMeta.Name = cluster.ObjectMeta.Name
+		}
+		desiredCluster.ObjectMeta.ResourceVersion = cluster.ObjectMeta.ResourceVersion
+		rolledBack, err = writer.UpdateCluster(desiredCluster, status)
+		if err != nil {
+			return fmt.Errorf("error rolling back cluster: %v", err)
+		}
+	}
+
+	igClient := writer.InstanceGroupsFor(cluster)
+	list, err := igClient.List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*api.InstanceGroup)
+	for i := range list.Items {
+		ig := &list.Items[i]
+		existing[ig.ObjectMeta.Name] = ig
+	}
+
+	for _, ig := range desiredInstanceGroups {
+		name := ig.ObjectMeta.Name
+		old := existing[name]
+		delete(existing, name)
+
+		if old == nil {
+			if _, err := igClient.Create(ig); err != nil {
+				return fmt.Errorf("error creating instance group %q: %v", name, err)
+			}
+			continue
+		}
+		if revision.InstanceGroups[name] == current.InstanceGroups[name] {
+			continue
+		}
+		ig.ObjectMeta.ResourceVersion = old.ObjectMeta.ResourceVersion
+		if _, err := igClient.Update(ig); err != nil {
+			return fmt.Errorf("error rolling back instance group %q: %v", name, err)
+		}
+	}
+
+	for name := range existing {
+		if err := igClient.Delete(name, nil); err != nil {
+			return fmt.Errorf("error deleting instance group %q: %v", name, err)
+		}
+	}
+
+	if err := store.RecordRevision(rolledBack, fmt.Sprintf("rollback to revision %d", options.To)); err != nil {
+		glog.Warningf("error recording revision of cluster %q after rollback: %v", options.ClusterName, err)
+	}
+
+	fmt.Fprintf(out, "\nRolled back the configuration of cluster %q to revision %d.\n", options.ClusterName, options.To)
+	fmt.Fprintf(out, "Run kops update cluster to apply the changes to the cluster.\n")
+	return nil
+}
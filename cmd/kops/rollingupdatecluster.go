@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/kops/cmd/kops/util"
@@ -36,6 +38,7 @@ import (
 	"k8s.io/kops/pkg/featureflag"
 	"k8s.io/kops/pkg/instancegroups"
 	"k8s.io/kops/pkg/pretty"
+	"k8s.io/kops/pkg/validation"
 	"k8s.io/kops/upup/pkg/fi/cloudup"
 	"k8s.io/kops/util/pkg/tables"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
@@ -58,6 +61,13 @@ var (
 	to wait for 3 minutes after a master is rolled, and another 3 minutes for the cluster to stabilize and pass
 	validation.
 
+	With the canary flag, rolling-update first updates only some of the instances of each instance group.  It then
+	validates the cluster and runs the canary health checks for the canary-soak period, and only updates the rest of
+	the instances if they pass.
+
+	Rolling update records its progress in the state store.  If it is interrupted, it can be resumed with the
+	resume flag, which only updates the instances the interrupted rolling update had not yet replaced.
+
 	Note: terraform users will need to run all of the following commands from the same directory
 	` + pretty.Bash("kops update cluster --target=terraform") + ` then ` + pretty.Bash("terraform plan") + ` then
 	` + pretty.Bash("terraform apply") + ` prior to running ` + pretty.Bash("kops rolling-update cluster") + `.`))
@@ -94,6 +104,21 @@ var (
 		  --fail-on-validate-error="false" \
 		  --node-interval 8m \
 		  --instance-group nodes
+
+		# Roll one instance of each instance group of the k8s-cluster.example.com kops cluster,
+		# and roll the rest if the cluster validates and the pods in the
+		# ingress namespace are ready for 10 minutes.
+		kops rolling-update cluster k8s-cluster.example.com --yes \
+		  --canary 1 \
+		  --canary-soak 10m \
+		  --canary-pods-ready-namespace ingress \
+		  --canary-http-probe https://www.example.com/healthz
+
+		# Resume an interrupted rolling-update of the k8s-cluster.example.com kops cluster,
+		# first uncordoning the nodes it cordoned but did not replace.
+		kops rolling-update cluster k8s-cluster.example.com --yes \
+		  --resume \
+		  --uncordon
 		`))
 
 	rollingupdateShort = i18n.T(`Rolling update a cluster.`)
@@ -142,6 +167,27 @@ type RollingUpdateOptions struct {
 	// InstanceGroupRoles is the list of roles we should rolling-update
 	// if not specified, all instance groups will be updated
 	InstanceGroupRoles []string
+
+	// Drain overrides the drain settings of the cluster and instance groups
+	Drain api.DrainSpec
+
+	// Resume continues the interrupted rolling update recorded in the state store
+	Resume bool
+
+	// Uncordon uncordons the nodes which the interrupted rolling update cordoned but did not replace, before resuming it
+	Uncordon bool
+
+	// Canary is the number or percentage of the instances of each instance group to update first
+	Canary string
+
+	// CanarySoak is how long to check the health of the cluster for, after updating the canary instances
+	CanarySoak time.Duration
+
+	// CanaryPodsReadyNamespaces are namespaces in which all pods must be ready after updating the canary instances
+	CanaryPodsReadyNamespaces []string
+
+	// CanaryHTTPProbes are URLs which must return a successful status after updating the canary instances
+	CanaryHTTPProbes []string
 }
 
 func (o *RollingUpdateOptions) InitDefaults() {
@@ -159,6 +205,8 @@ func (o *RollingUpdateOptions) InitDefaults() {
 	o.PostDrainDelay = 90 * time.Second
 	o.ValidationTimeout = 5 * time.Minute
 
+	o.CanarySoak = 5 * time.Minute
+
 }
 
 func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
@@ -183,12 +231,27 @@ func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVarP(&options.Interactive, "interactive", "i", options.Interactive, "Prompt to continue after each instance is updated")
 	cmd.Flags().StringSliceVar(&options.InstanceGroups, "instance-group", options.InstanceGroups, "List of instance groups to update (defaults to all if not specified)")
 	cmd.Flags().StringSliceVar(&options.InstanceGroupRoles, "instance-group-roles", options.InstanceGroupRoles, "If specified, only instance groups of the specified role will be updated (e.g. Master,Node,Bastion)")
+	cmd.Flags().BoolVar(&options.Resume, "resume", options.Resume, "Resume an interrupted rolling update, only updating the instances it had not yet replaced")
+	cmd.Flags().BoolVar(&options.Uncordon, "uncordon", options.Uncordon, "With --resume, uncordon the nodes which the interrupted rolling update cordoned but did not replace")
+	cmd.Flags().StringVar(&options.Canary, "canary", options.Canary, "Number or percentage of the instances of each instance group to update first, updating the rest only if the cluster stays healthy")
+	cmd.Flags().DurationVar(&options.CanarySoak, "canary-soak", options.CanarySoak, "Time to check the health of the cluster for, after updating the canary instances")
+	cmd.Flags().StringSliceVar(&options.CanaryPodsReadyNamespaces, "canary-pods-ready-namespace", options.CanaryPodsReadyNamespaces, "Namespaces in which all pods must be ready after updating the canary instances")
+	cmd.Flags().StringSliceVar(&options.CanaryHTTPProbes, "canary-http-probe", options.CanaryHTTPProbes, "URLs which must return a successful status after updating the canary instances")
 
 	if featureflag.DrainAndValidateRollingUpdate.Enabled() {
 		cmd.Flags().BoolVar(&options.FailOnDrainError, "fail-on-drain-error", true, "The rolling-update will fail if draining a node fails.")
 		cmd.Flags().BoolVar(&options.FailOnValidate, "fail-on-validate-error", true, "The rolling-update will fail if the cluster fails to validate.")
 	}
 
+	var drainTimeout time.Duration
+	var drainGracePeriod int64
+	var deleteLocalData bool
+	cmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 0, "Maximum time to wait for the pods of a node to be evicted, overriding the cluster and instance group settings (defaults to 15m, 0 waits indefinitely)")
+	cmd.Flags().Int64Var(&drainGracePeriod, "drain-grace-period", 0, "Termination grace period in seconds for evicted pods, overriding the grace period of each pod")
+	cmd.Flags().BoolVar(&deleteLocalData, "delete-local-data", true, "Evict pods with emptyDir volumes, whose data is lost; if false the rolling-update stops at a node running such pods")
+	cmd.Flags().StringVar(&options.Drain.SkipPodSelector, "drain-skip-pod-selector", "", "Label selector for pods which are not evicted when draining a node")
+	cmd.Flags().StringVar(&options.Drain.BlockingPodSelector, "drain-blocking-pod-selector", "", "Label selector for pods which must not be evicted; the rolling-update stops at a node running them")
+
 	cmd.Run = func(cmd *cobra.Command, args []string) {
 		err := rootCommand.ProcessArgs(args)
 		if err != nil {
@@ -196,6 +259,17 @@ func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 			return
 		}
 
+		// Only the drain settings set on the command line override the cluster and instance group settings
+		if cmd.Flags().Changed("drain-timeout") {
+			options.Drain.Timeout = &metav1.Duration{Duration: drainTimeout}
+		}
+		if cmd.Flags().Changed("drain-grace-period") {
+			options.Drain.GracePeriodSeconds = &drainGracePeriod
+		}
+		if cmd.Flags().Changed("delete-local-data") {
+			options.Drain.DeleteLocalData = &deleteLocalData
+		}
+
 		clusterName := rootCommand.ClusterName()
 		if clusterName == "" {
 			exitWithError(fmt.Errorf("--name is required"))
@@ -216,6 +290,23 @@ func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 }
 
 func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpdateOptions) error {
+	if options.Uncordon && !options.Resume {
+		return fmt.Errorf("--uncordon can only be used with --resume")
+	}
+	if options.Uncordon && options.CloudOnly {
+		return fmt.Errorf("--uncordon cannot be used with --cloudonly")
+	}
+
+	var canary *intstr.IntOrString
+	if options.Canary != "" {
+		v := intstr.Parse(options.Canary)
+		if n, err := intstr.GetValueFromIntOrPercent(&v, 100, true); err != nil || n <= 0 {
+			return fmt.Errorf("invalid --canary %q: must be a positive number or percentage of instances", options.Canary)
+		}
+		canary = &v
+	} else if len(options.CanaryPodsReadyNamespaces) != 0 || len(options.CanaryHTTPProbes) != 0 {
+		return fmt.Errorf("the canary health checks can only be used with --canary")
+	}
 
 	clientset, err := f.Clientset()
 	if err != nil {
@@ -227,6 +318,16 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		return err
 	}
 
+	// The lock is taken before the progress of an interrupted rolling update is read, so two rolling updates
+	// don't both resume it, or overwrite each other's progress
+	if options.Yes {
+		unlock, err := lockStateStore(cluster, "rolling-update cluster")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
 	contextName := cluster.ObjectMeta.Name
 	clientGetter := genericclioptions.NewConfigFlags()
 	clientGetter.Context = &contextName
@@ -320,6 +421,26 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		return err
 	}
 
+	var progress *instancegroups.Progress
+	if options.Resume {
+		progress, err = instancegroups.ReadProgress(cluster)
+		if err != nil {
+			return err
+		}
+		if progress == nil {
+			return fmt.Errorf("no interrupted rolling update of cluster %q to resume", options.ClusterName)
+		}
+		options.Force = options.Force || progress.Force()
+	} else {
+		interrupted, err := instancegroups.ReadProgress(cluster)
+		if err != nil {
+			return err
+		}
+		if interrupted != nil {
+			fmt.Fprintf(out, "The rolling update started at %s was interrupted; use --resume to continue it instead of starting over.\n\n", interrupted.StartedAt().Format(time.RFC3339))
+		}
+	}
+
 	{
 		t := &tables.Table{}
 		t.AddColumn("NAME", func(r *cloudinstances.CloudInstanceGroup) string {
@@ -376,7 +497,15 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		}
 	}
 
-	if !needUpdate && !options.Force {
+	if progress != nil {
+		fmt.Fprintf(out, "\nResuming the rolling update started at %s.\n", progress.StartedAt().Format(time.RFC3339))
+		if cordoned := progress.CordonedNodes(); len(cordoned) != 0 {
+			fmt.Fprintf(out, "Nodes cordoned but not replaced by the interrupted rolling update: %s\n", strings.Join(cordoned, ", "))
+			if !options.Uncordon {
+				fmt.Fprintf(out, "Use --uncordon to uncordon them first.\n")
+			}
+		}
+	} else if !needUpdate && !options.Force {
 		fmt.Printf("\nNo rolling-update required.\n")
 		return nil
 	}
@@ -386,6 +515,19 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		return nil
 	}
 
+	if progress != nil {
+		if options.Uncordon {
+			if err := progress.Uncordon(k8sClient); err != nil {
+				return err
+			}
+		}
+	} else {
+		progress, err = instancegroups.NewProgress(cluster, options.Force)
+		if err != nil {
+			return err
+		}
+	}
+
 	if featureflag.DrainAndValidateRollingUpdate.Enabled() {
 		glog.V(2).Infof("Rolling update with drain and validate enabled.")
 	}
@@ -397,13 +539,102 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		Force:             options.Force,
 		Cloud:             cloud,
 		K8sClient:         k8sClient,
-		ClientGetter:      clientGetter,
 		FailOnDrainError:  options.FailOnDrainError,
 		FailOnValidate:    options.FailOnValidate,
 		CloudOnly:         options.CloudOnly,
 		ClusterName:       options.ClusterName,
 		PostDrainDelay:    options.PostDrainDelay,
 		ValidationTimeout: options.ValidationTimeout,
+		Drain:             &options.Drain,
+		Progress:          progress,
+		Canary:            canary,
+		CanarySoak:        options.CanarySoak,
+		CanaryChecks: instancegroups.CanaryHealthChecks{
+			PodsReadyNamespaces: options.CanaryPodsReadyNamespaces,
+			HTTPProbes:          options.CanaryHTTPProbes,
+		},
 	}
-	return d.RollingUpdate(groups, cluster, list)
+	err = d.RollingUpdate(groups, cluster, list)
+
+	if report := d.CanaryReport(); report != nil {
+		if renderErr := renderCanaryReport(report, out); renderErr != nil {
+			glog.Warningf("error writing canary report: %v", renderErr)
+		}
+	}
+
+	if blocked := d.BlockedEvictions(); len(blocked) != 0 {
+		fmt.Fprintf(out, "\nPod evictions refused because of PodDisruptionBudgets:\n\n")
+		if renderErr := renderBlockedEvictions(blocked, out); renderErr != nil {
+			glog.Warningf("error writing blocked evictions: %v", renderErr)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(out, "\nThe rolling update can be resumed with: kops rolling-update cluster --name %s --resume --yes\n", options.ClusterName)
+		return err
+	}
+
+	return progress.Finish()
+}
+
+// renderCanaryReport writes the canary instances which were updated, and the health checks they failed
+func renderCanaryReport(report *instancegroups.CanaryReport, out io.Writer) error {
+	var groupNames []string
+	for groupName := range report.Instances {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+
+	fmt.Fprintf(out, "\nCanary instances:\n\n")
+	t := &tables.Table{}
+	t.AddColumn("INSTANCEGROUP", func(groupName string) string {
+		return groupName
+	})
+	t.AddColumn("INSTANCES", func(groupName string) string {
+		return strings.Join(report.Instances[groupName], ",")
+	})
+	if err := t.Render(groupNames, out, "INSTANCEGROUP", "INSTANCES"); err != nil {
+		return err
+	}
+
+	if report.Passed {
+		fmt.Fprintf(out, "\nThe cluster passed the health checks for %v after updating the canary instances.\n", report.Soaked.Round(time.Second))
+	} else if len(report.Failures) != 0 {
+		fmt.Fprintf(out, "\nThe cluster failed the health checks after updating the canary instances:\n\n")
+		t := &tables.Table{}
+		t.AddColumn("KIND", func(v *validation.ValidationError) string {
+			return v.Kind
+		})
+		t.AddColumn("NAME", func(v *validation.ValidationError) string {
+			return v.Name
+		})
+		t.AddColumn("MESSAGE", func(v *validation.ValidationError) string {
+			return v.Message
+		})
+		if err := t.Render(report.Failures, out, "KIND", "NAME", "MESSAGE"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderBlockedEvictions writes the report of the pods whose eviction was refused because of a PodDisruptionBudget
+func renderBlockedEvictions(blocked []instancegroups.BlockedEviction, out io.Writer) error {
+	t := &tables.Table{}
+	t.AddColumn("NODE", func(b instancegroups.BlockedEviction) string {
+		return b.Node
+	})
+	t.AddColumn("POD", func(b instancegroups.BlockedEviction) string {
+		return b.Namespace + "/" + b.Name
+	})
+	t.AddColumn("BUDGETS", func(b instancegroups.BlockedEviction) string {
+		return strings.Join(b.Budgets, ",")
+	})
+	t.AddColumn("WAITED", func(b instancegroups.BlockedEviction) string {
+		return b.Waited.Round(time.Second).String()
+	})
+	t.AddColumn("EVICTED", func(b instancegroups.BlockedEviction) string {
+		return strconv.FormatBool(b.Evicted)
+	})
+	return t.Render(blocked, out, "NODE", "POD", "BUDGETS", "WAITED", "EVICTED")
 }
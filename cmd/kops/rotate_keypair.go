@@ -0,0 +1,263 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/client/simple"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/pkg/keyrotation"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	rotateKeypairLong = templates.LongDesc(i18n.T(`
+	Rotate a keypair of the cluster, in three stages.  Each invocation runs the next stage,
+	which is inferred from the keyset in the state store.
+
+	1. Trust: a new key is added to the keyset.  It is trusted alongside the current key,
+	   but certificates are still issued with the current key.
+	2. Promote: the new key becomes the primary key.  When rotating a CA, the certificates
+	   signed by the old CA are reissued by the new CA, keeping their private keys.
+	3. Cleanup: the old key, and the certificates it issued, are removed.
+
+	Every node must be rolled after each stage, before running the next one.  The cleanup
+	stage is refused while any instance still needs updating, unless --force is given.
+
+	The keypairs which can be rotated are the kubernetes CA (ca), which also issues the etcd
+	certificates, the apiserver aggregator CA (apiserver-aggregator-ca), and the master keypair
+	(master), whose key signs service account tokens.`))
+
+	rotateKeypairExample = templates.Examples(i18n.T(`
+	# Show the next stage of the rotation of the kubernetes CA
+	kops rotate keypair ca --name k8s-cluster.example.com
+
+	# Run the next stage of the rotation of the kubernetes CA
+	kops rotate keypair ca --name k8s-cluster.example.com --yes
+	`))
+
+	rotateKeypairShort = i18n.T(`Rotate a keypair.`)
+)
+
+type RotateKeypairOptions struct {
+	ClusterName string
+	Keypair     string
+	Yes         bool
+	Force       bool
+}
+
+func NewCmdRotateKeypair(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RotateKeypairOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "keypair KEYPAIR",
+		Short:   rotateKeypairShort,
+		Long:    rotateKeypairLong,
+		Example: rotateKeypairExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				exitWithError(fmt.Errorf("Specify the name of the keypair to rotate, one of %s", strings.Join(keyrotation.RotatableKeypairs, ", ")))
+			}
+			options.Keypair = args[0]
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunRotateKeypair(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Run the next stage of the rotation")
+	cmd.Flags().BoolVar(&options.Force, "force", options.Force, "Remove the old keys even if some instances have not been rolled")
+
+	return cmd
+}
+
+func RunRotateKeypair(f *util.Factory, out io.Writer, options *RotateKeypairOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	var cluster *kops.Cluster
+	var err error
+	if options.Yes {
+		var unlock func()
+		cluster, unlock, err = lockCluster(f, options.ClusterName, "rotate keypair")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	} else {
+		cluster, err = GetCluster(f, options.ClusterName)
+		if err != nil {
+			return err
+		}
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	rotation, err := keyrotation.NewRotation(keyStore, options.Keypair)
+	if err != nil {
+		return err
+	}
+
+	status, err := rotation.FindStatus()
+	if err != nil {
+		return err
+	}
+
+	name := options.Keypair
+	clusterName := cluster.ObjectMeta.Name
+
+	// Removing the old keys cuts off any instance which was not rolled after the new key was promoted
+	var notRolled error
+	if status.Next == keyrotation.StageCleanup {
+		groups, err := findCloudGroups(clientset, cluster)
+		if err != nil {
+			return err
+		}
+		notRolled = checkRolled(groups)
+	}
+
+	if !options.Yes {
+		switch status.Next {
+		case keyrotation.StageTrust:
+			fmt.Fprintf(out, "Will add a new key to keypair %q, trusted alongside the current key %s\n", name, status.PrimaryId)
+		case keyrotation.StagePromote:
+			fmt.Fprintf(out, "Will make key %s the primary key of keypair %q, replacing %s\n", status.NewId, name, status.PrimaryId)
+		case keyrotation.StageCleanup:
+			fmt.Fprintf(out, "Will remove the old keys %s from keypair %q\n", strings.Join(status.OldIds, ", "), name)
+			fmt.Fprintf(out, "Every node must have been rolled since key %s was promoted\n", status.PrimaryId)
+			if notRolled != nil {
+				fmt.Fprintf(out, "\n%v\n", notRolled)
+			}
+		}
+		fmt.Fprintf(out, "\nMust specify --yes to run the %s stage\n", status.Next)
+		return nil
+	}
+
+	nextCommand := fmt.Sprintf("kops rotate keypair %s --name %s --yes", name, clusterName)
+
+	switch status.Next {
+	case keyrotation.StageTrust:
+		id, err := rotation.Trust(status)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Added key %s to keypair %q; it is trusted, but not yet used for issuing\n", id, name)
+
+	case keyrotation.StagePromote:
+		reissued, err := rotation.Promote(status)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Key %s is now the primary key of keypair %q\n", status.NewId, name)
+		if len(reissued) != 0 {
+			fmt.Fprintf(out, "Reissued keypairs: %s\n", strings.Join(reissued, ", "))
+		}
+
+	case keyrotation.StageCleanup:
+		if notRolled != nil {
+			if !options.Force {
+				return fmt.Errorf("%v; use --force to remove the old keys anyway", notRolled)
+			}
+			glog.Warningf("Removing the old keys despite instances which have not been rolled: %v", notRolled)
+		}
+		removed, err := rotation.Cleanup(status)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed keys %s from keypair %q\n", strings.Join(removed, ", "), name)
+		nextCommand = ""
+
+	default:
+		return fmt.Errorf("unknown rotation stage %q", status.Next)
+	}
+
+	fmt.Fprintf(out, "\nNext steps:\n")
+	fmt.Fprintf(out, " * apply the keystore to the cluster: kops update cluster --name %s --yes\n", clusterName)
+	fmt.Fprintf(out, " * roll every node: kops rolling-update cluster --name %s --force --yes\n", clusterName)
+	if status.Next != keyrotation.StagePromote && name == fi.CertificateId_CA {
+		fmt.Fprintf(out, " * export a kubecfg with the trusted CAs: kops export kubecfg --name %s\n", clusterName)
+	}
+	if status.Next == keyrotation.StagePromote && name == "master" {
+		fmt.Fprintf(out, " * delete the service account token secrets, so they are reissued with the new key\n")
+	}
+	if nextCommand != "" {
+		fmt.Fprintf(out, " * then run the next stage: %s\n", nextCommand)
+	}
+
+	return nil
+}
+
+// findCloudGroups lists the cloud instance groups of the cluster
+func findCloudGroups(clientset simple.Clientset, cluster *kops.Cluster) (map[string]*cloudinstances.CloudInstanceGroup, error) {
+	list, err := clientset.InstanceGroupsFor(cluster).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var instanceGroups []*kops.InstanceGroup
+	for i := range list.Items {
+		instanceGroups = append(instanceGroups, &list.Items[i])
+	}
+
+	cloud, err := cloudup.BuildCloud(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloud.GetCloudGroups(cluster, instanceGroups, false, nil)
+}
+
+// checkRolled returns an error naming the instance groups with instances which still need updating,
+// and so have not been rolled since the new key was promoted
+func checkRolled(groups map[string]*cloudinstances.CloudInstanceGroup) error {
+	var pending []string
+	for _, group := range groups {
+		if len(group.NeedUpdate) != 0 {
+			pending = append(pending, fmt.Sprintf("%s (%d)", group.InstanceGroup.ObjectMeta.Name, len(group.NeedUpdate)))
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Strings(pending)
+
+	return fmt.Errorf("instances still need updating in instance groups %s; roll every node before removing the old keys", strings.Join(pending, ", "))
+}
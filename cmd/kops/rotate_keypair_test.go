@@ -0,0 +1,61 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+)
+
+func TestCheckRolled(t *testing.T) {
+	group := func(name string, ready int, needUpdate int) *cloudinstances.CloudInstanceGroup {
+		g := &cloudinstances.CloudInstanceGroup{
+			InstanceGroup: &api.InstanceGroup{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		}
+		for i := 0; i < ready; i++ {
+			g.Ready = append(g.Ready, &cloudinstances.CloudInstanceGroupMember{CloudInstanceGroup: g})
+		}
+		for i := 0; i < needUpdate; i++ {
+			g.NeedUpdate = append(g.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{CloudInstanceGroup: g})
+		}
+		return g
+	}
+
+	rolled := map[string]*cloudinstances.CloudInstanceGroup{
+		"master-us-test-1a": group("master-us-test-1a", 1, 0),
+		"nodes":             group("nodes", 2, 0),
+	}
+	if err := checkRolled(rolled); err != nil {
+		t.Errorf("unexpected error when every instance has been rolled: %v", err)
+	}
+
+	notRolled := map[string]*cloudinstances.CloudInstanceGroup{
+		"master-us-test-1a": group("master-us-test-1a", 1, 0),
+		"nodes":             group("nodes", 1, 2),
+	}
+	err := checkRolled(notRolled)
+	if err == nil {
+		t.Fatalf("expected cleanup to be refused when instances need updating")
+	}
+	if !strings.Contains(err.Error(), "nodes (2)") || strings.Contains(err.Error(), "master-us-test-1a") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
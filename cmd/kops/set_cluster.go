@@ -70,7 +70,16 @@ func NewCmdSetCluster(f *util.Factory, out io.Writer) *cobra.Command {
 				options.ClusterName = rootCommand.ClusterName()
 			}
 
-			if err := commands.RunSetCluster(f, cmd, out, options); err != nil {
+			_, unlock, err := lockCluster(f, options.ClusterName, "set cluster")
+			if err != nil {
+				exitWithError(err)
+				return
+			}
+
+			// exitWithError exits, so we release the lock first
+			err = commands.RunSetCluster(f, cmd, out, options)
+			unlock()
+			if err != nil {
 				exitWithError(err)
 			}
 		},
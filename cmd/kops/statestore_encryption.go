@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/client/simple"
+	"k8s.io/kops/pkg/envelope"
+	"k8s.io/kops/pkg/model/iam"
+)
+
+// checkStateEncryption checks that the instances of the cluster can decrypt the secrets and private keys which kops
+// encrypts with the key provider set by KOPS_STATE_ENCRYPTION.  The instances read them from the state store,
+// and can only decrypt them with a KMS key which their instance role is allowed to use.
+func checkStateEncryption(clientset simple.Clientset, cluster *api.Cluster) error {
+	provider, err := envelope.ProviderFromEnvironment()
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+
+	kmsProvider, ok := provider.(*envelope.AWSKMSProvider)
+	if !ok {
+		return fmt.Errorf("the instances of cluster %q read their secrets from the state store, and cannot decrypt files encrypted with the %s key provider; set %s to awskms:<key id>", cluster.ObjectMeta.Name, provider.Name(), envelope.EnvStateEncryption)
+	}
+	if api.CloudProviderID(cluster.Spec.CloudProvider) != api.CloudProviderAWS {
+		return fmt.Errorf("the instances of cluster %q cannot decrypt files encrypted with the %s key provider, which needs an AWS instance role", cluster.ObjectMeta.Name, provider.Name())
+	}
+
+	keyARN, err := kmsProvider.KeyARN()
+	if err != nil {
+		return err
+	}
+
+	list, err := clientset.InstanceGroupsFor(cluster).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Roles whose instance groups use an existing instance profile may be granted access outside of kops
+	roles := make(map[api.InstanceGroupRole]bool)
+	for i := range list.Items {
+		ig := &list.Items[i]
+		if ig.Spec.Role == api.InstanceGroupRoleBastion {
+			continue
+		}
+		if ig.Spec.IAM != nil && ig.Spec.IAM.Profile != nil {
+			glog.Warningf("cannot check that instance group %q, which uses instance profile %q, is allowed kms:Decrypt on KMS key %s", ig.ObjectMeta.Name, *ig.Spec.IAM.Profile, keyARN)
+			continue
+		}
+		roles[ig.Spec.Role] = true
+	}
+
+	for role := range roles {
+		allowed, err := iam.RoleAllowsKMSDecrypt(cluster, role, keyARN)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("the %s instance role of cluster %q is not allowed kms:Decrypt on KMS key %s, so its instances cannot decrypt their secrets; grant it with additionalPolicies", role, cluster.ObjectMeta.Name, keyARN)
+		}
+	}
+	return nil
+}
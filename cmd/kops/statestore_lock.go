@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/kopscodecs"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const (
+	// stateStoreLockTTL is how long a lock is held without being refreshed, before another command may break it.
+	// A lock left behind by an interrupted command expires after this.
+	stateStoreLockTTL = 15 * time.Minute
+	// stateStoreLockRefreshInterval is how often a held lock is refreshed
+	stateStoreLockRefreshInterval = stateStoreLockTTL / 3
+)
+
+// lockStateStore takes the state store lock of the cluster for the operation.
+// The lock is refreshed while it is held, so that long running commands keep it.
+// The returned function releases the lock.
+func lockStateStore(cluster *api.Cluster, operation string) (func(), error) {
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := vfs.AcquireLock(configBase.Join(registry.PathLock), lockHolder(), operation, stateStoreLockTTL)
+	if err != nil {
+		if vfs.IsLockHeld(err) {
+			return nil, fmt.Errorf("%v\nIf no other kops command is running against cluster %q, the lock can be removed with: kops unlock --name %s --yes", err, cluster.ObjectMeta.Name, cluster.ObjectMeta.Name)
+		}
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		refreshLock(lock, stop)
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		if err := lock.Release(); err != nil {
+			glog.Warningf("error releasing state store lock: %v", err)
+		}
+	}, nil
+}
+
+// refreshLock refreshes the lock until stop is closed.
+// If the lock was broken, or can't be refreshed before it expires, kops exits:
+// another command could take the lock, and the two commands would overwrite each other's changes.
+func refreshLock(lock *vfs.Lock, stop <-chan struct{}) {
+	ticker := time.NewTicker(stateStoreLockRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := lock.Refresh(stateStoreLockTTL)
+		if err == nil {
+			continue
+		}
+		if vfs.IsLockLost(err) || !time.Now().Add(stateStoreLockRefreshInterval).Before(lock.Info().Expires) {
+			exitWithError(fmt.Errorf("lost the state store lock, stopping so as not to overwrite the changes of another command: %v", err))
+		}
+		glog.Warningf("error refreshing state store lock, will retry: %v", err)
+	}
+}
+
+// lockCluster reads the cluster and takes its state store lock for the operation.
+// The cluster is read again once the lock is held, so the changes of the previous holder are not lost.
+// The returned function releases the lock.
+func lockCluster(factory Factory, clusterName string, operation string) (*api.Cluster, func(), error) {
+	cluster, err := GetCluster(factory, clusterName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock, err := lockStateStore(cluster, operation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cluster, err = GetCluster(factory, clusterName)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+
+	return cluster, unlock, nil
+}
+
+// checkUnchanged checks that an object, read before the lock was taken, is the same as the current object read under the lock.
+// It compares the ResourceVersions where the state store has them, and the contents otherwise.
+func checkUnchanged(kind string, before runtime.Object, current runtime.Object) error {
+	beforeMeta, err := meta.Accessor(before)
+	if err != nil {
+		return err
+	}
+	currentMeta, err := meta.Accessor(current)
+	if err != nil {
+		return err
+	}
+
+	if beforeMeta.GetResourceVersion() != "" && currentMeta.GetResourceVersion() != "" {
+		if beforeMeta.GetResourceVersion() == currentMeta.GetResourceVersion() {
+			return nil
+		}
+	} else {
+		beforeData, err := diffSerialize(before, kopscodecs.ToVersionedYaml)
+		if err != nil {
+			return err
+		}
+		currentData, err := diffSerialize(current, kopscodecs.ToVersionedYaml)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(beforeData, currentData) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s %q was changed by another command while it was being edited", kind, beforeMeta.GetName())
+}
+
+// lockHolder describes the user running kops, as user@host
+func lockHolder() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s@%s (pid %d)", username, hostname, os.Getpid())
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	api "k8s.io/kops/pkg/apis/kops"
+)
+
+func TestCheckUnchanged(t *testing.T) {
+	group := func(resourceVersion string, machineType string) *api.InstanceGroup {
+		return &api.InstanceGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nodes", ResourceVersion: resourceVersion},
+			Spec:       api.InstanceGroupSpec{MachineType: machineType},
+		}
+	}
+
+	grid := []struct {
+		Before  *api.InstanceGroup
+		Current *api.InstanceGroup
+		Changed bool
+	}{
+		{
+			Before:  group("1", "t2.medium"),
+			Current: group("1", "t2.medium"),
+		},
+		{
+			Before:  group("1", "t2.medium"),
+			Current: group("2", "t2.medium"),
+			Changed: true,
+		},
+		{
+			// Without versions, the contents are compared
+			Before:  group("", "t2.medium"),
+			Current: group("", "t2.medium"),
+		},
+		{
+			Before:  group("", "t2.medium"),
+			Current: group("", "t2.large"),
+			Changed: true,
+		},
+	}
+
+	for i, g := range grid {
+		err := checkUnchanged("InstanceGroup", g.Before, g.Current)
+		if g.Changed && err == nil {
+			t.Errorf("test case %d: expected a change to be detected", i)
+		}
+		if !g.Changed && err != nil {
+			t.Errorf("test case %d: unexpected error: %v", i, err)
+		}
+	}
+}
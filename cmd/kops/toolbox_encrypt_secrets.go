@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/envelope"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	toolboxEncryptSecretsLong = templates.LongDesc(i18n.T(`
+	Encrypt the secrets and private keys of a cluster which are still stored as plaintext,
+	with the key provider set by KOPS_STATE_ENCRYPTION.
+
+	The instances of the cluster read their secrets from the state store, so only the awskms
+	key provider can be used, and the instance roles must be allowed kms:Decrypt on the key.
+	Files which are already encrypted are left as they are.`))
+
+	toolboxEncryptSecretsExample = templates.Examples(i18n.T(`
+	# List the secrets and private keys which are not encrypted
+	KOPS_STATE_ENCRYPTION=awskms:arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab \
+	  kops toolbox encrypt-secrets --name k8s-cluster.example.com
+
+	# Encrypt them
+	KOPS_STATE_ENCRYPTION=awskms:arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab \
+	  kops toolbox encrypt-secrets --name k8s-cluster.example.com --yes
+	`))
+
+	toolboxEncryptSecretsShort = i18n.T(`Encrypt the secrets and private keys in the state store.`)
+)
+
+type ToolboxEncryptSecretsOptions struct {
+	ClusterName string
+	Yes         bool
+}
+
+func NewCmdToolboxEncryptSecrets(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &ToolboxEncryptSecretsOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "encrypt-secrets",
+		Short:   toolboxEncryptSecretsShort,
+		Long:    toolboxEncryptSecretsLong,
+		Example: toolboxEncryptSecretsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunToolboxEncryptSecrets(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Encrypt the secrets and private keys")
+
+	return cmd
+}
+
+func RunToolboxEncryptSecrets(f *util.Factory, out io.Writer, options *ToolboxEncryptSecretsOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	provider, err := envelope.ProviderFromEnvironment()
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return fmt.Errorf("%s must be set to the key provider to encrypt with", envelope.EnvStateEncryption)
+	}
+
+	cluster, err := GetCluster(f, options.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	if options.Yes {
+		unlock, err := lockStateStore(cluster, "encrypt secrets")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	if err := checkStateEncryption(clientset, cluster); err != nil {
+		return err
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+	secretStore, err := clientset.SecretStore(cluster)
+	if err != nil {
+		return err
+	}
+	keyStorePath, ok := keyStore.(fi.HasVFSPath)
+	if !ok {
+		return fmt.Errorf("the keystore of cluster %q is not in the state store, and cannot be encrypted", options.ClusterName)
+	}
+	secretStorePath, ok := secretStore.(fi.HasVFSPath)
+	if !ok {
+		return fmt.Errorf("the secret store of cluster %q is not in the state store, and cannot be encrypted", options.ClusterName)
+	}
+
+	plaintext, err := fi.PlaintextSecretFiles(keyStorePath.VFSPath(), secretStorePath.VFSPath())
+	if err != nil {
+		return err
+	}
+	if len(plaintext) == 0 {
+		fmt.Fprintf(out, "The secrets and private keys of cluster %q are already encrypted\n", options.ClusterName)
+		return nil
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "Will encrypt with the %s key provider:\n", provider.Name())
+		for _, p := range plaintext {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+		fmt.Fprintf(out, "\nMust specify --yes to encrypt\n")
+		return nil
+	}
+
+	for _, p := range plaintext {
+		encrypted, err := fi.EncryptSecretFile(p, cluster, provider)
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			fmt.Fprintf(out, "Encrypted %s\n", p)
+		}
+	}
+
+	fmt.Fprintf(out, "\nApply the encrypted files to the copies the instances read: kops update cluster --name %s --yes\n", options.ClusterName)
+	return nil
+}
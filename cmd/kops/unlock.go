@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+)
+
+var (
+	unlockLong = templates.LongDesc(i18n.T(`
+	Remove the state store lock of a cluster.
+
+	Commands which change the state store, such as kops update cluster and kops edit cluster,
+	hold a lock in the state store while they run, so that two of them cannot overwrite each
+	other's changes.  A command which is interrupted may leave the lock behind; it expires
+	after 15 minutes, or can be removed with this command.
+
+	Only remove the lock if no other kops command is running against the cluster.`))
+
+	unlockExample = templates.Examples(i18n.T(`
+	# Show who holds the state store lock
+	kops unlock --name k8s-cluster.example.com
+
+	# Remove the state store lock
+	kops unlock --name k8s-cluster.example.com --yes
+	`))
+
+	unlockShort = i18n.T(`Remove the state store lock of a cluster.`)
+)
+
+type UnlockOptions struct {
+	ClusterName string
+	Yes         bool
+}
+
+func NewCmdUnlock(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &UnlockOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "unlock",
+		Short:   unlockShort,
+		Long:    unlockLong,
+		Example: unlockExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunUnlock(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Remove the lock")
+
+	return cmd
+}
+
+func RunUnlock(f *util.Factory, out io.Writer, options *UnlockOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	cluster, err := GetCluster(f, options.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return err
+	}
+	lockPath := configBase.Join(registry.PathLock)
+
+	if !options.Yes {
+		info, err := vfs.ReadLock(lockPath)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			fmt.Fprintf(out, "The state store of cluster %q is not locked\n", options.ClusterName)
+			return nil
+		}
+		fmt.Fprintf(out, "The state store of cluster %q is locked: %s\n", options.ClusterName, info)
+		fmt.Fprintf(out, "\nMust specify --yes to remove the lock\n")
+		return nil
+	}
+
+	info, err := vfs.BreakLock(lockPath)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		fmt.Fprintf(out, "The state store of cluster %q was not locked\n", options.ClusterName)
+		return nil
+	}
+	fmt.Fprintf(out, "Removed the state store lock of cluster %q, which was %s\n", options.ClusterName, info)
+	return nil
+}
@@ -18,12 +18,14 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,6 +53,9 @@ var (
 	updateClusterExample = templates.Examples(i18n.T(`
 	# After cluster has been edited or upgraded, configure it with:
 	kops update cluster k8s-cluster.example.com --yes --state=s3://kops-state-1234 --yes
+
+	# Print the changes which would be made as a JSON document, e.g. for review in CI
+	kops update cluster k8s-cluster.example.com --state=s3://kops-state-1234 --output json
 	`))
 
 	updateClusterShort = i18n.T("Update a cluster.")
@@ -67,6 +72,9 @@ type UpdateClusterOptions struct {
 
 	Phase string
 
+	// Output is the format of the dry run plan: json or yaml.  If not set, a human-readable report is printed.
+	Output string
+
 	// LifecycleOverrides is a slice of taskName=lifecycle name values.  This slice is used
 	// to populate the LifecycleOverrides struct member in ApplyClusterCmd struct.
 	LifecycleOverrides []string
@@ -112,6 +120,7 @@ func NewCmdUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.Flags().StringVar(&options.OutDir, "out", options.OutDir, "Path to write any local output")
 	cmd.Flags().BoolVar(&options.CreateKubecfg, "create-kube-config", options.CreateKubecfg, "Will control automatically creating the kube config file on your local filesystem")
 	cmd.Flags().StringVar(&options.Phase, "phase", options.Phase, "Subset of tasks to run: "+strings.Join(cloudup.Phases.List(), ", "))
+	cmd.Flags().StringVarP(&options.Output, "output", "o", options.Output, "Output format of the dry run plan. One of: json|yaml")
 	cmd.Flags().StringSliceVar(&options.LifecycleOverrides, "lifecycle-overrides", options.LifecycleOverrides, "comma separated list of phase overrides, example: SecurityGroups=Ignore,InternetGateway=ExistsAndWarnIfChanges")
 
 	return cmd
@@ -143,6 +152,15 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		targetName = cloudup.TargetDryRun
 	}
 
+	switch c.Output {
+	case "", OutputJSON, OutputYaml:
+	default:
+		return results, fmt.Errorf("unknown output format %q, available formats: %s, %s", c.Output, OutputJSON, OutputYaml)
+	}
+	if c.Output != "" && !isDryrun {
+		return results, fmt.Errorf("--output can only be used in dry run mode (without --yes)")
+	}
+
 	if c.OutDir == "" {
 		if c.Target == cloudup.TargetTerraform {
 			c.OutDir = "out/terraform"
@@ -153,9 +171,20 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		}
 	}
 
-	cluster, err := GetCluster(f, clusterName)
-	if err != nil {
-		return results, err
+	var cluster *kops.Cluster
+	var err error
+	if isDryrun {
+		cluster, err = GetCluster(f, clusterName)
+		if err != nil {
+			return results, err
+		}
+	} else {
+		var unlock func()
+		cluster, unlock, err = lockCluster(f, clusterName, "update cluster")
+		if err != nil {
+			return results, err
+		}
+		defer unlock()
 	}
 
 	clientset, err := f.Clientset()
@@ -163,6 +192,10 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		return results, err
 	}
 
+	if err := checkStateEncryption(clientset, cluster); err != nil {
+		return results, err
+	}
+
 	keyStore, err := clientset.KeyStore(cluster)
 	if err != nil {
 		return results, err
@@ -252,6 +285,10 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		TargetName:         targetName,
 		LifecycleOverrides: lifecycleOverrideMap,
 	}
+	if c.Output != "" {
+		// The plan is the only output
+		applyCmd.DryRunOutput = ioutil.Discard
+	}
 
 	if err := applyCmd.Run(); err != nil {
 		return results, err
@@ -262,6 +299,13 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 
 	if isDryrun {
 		target := applyCmd.Target.(*fi.DryRunTarget)
+		if c.Output != "" {
+			plan, err := target.BuildPlan(applyCmd.TaskMap)
+			if err != nil {
+				return results, err
+			}
+			return results, writePlan(plan, c.Output, out)
+		}
 		if target.HasChanges() {
 			fmt.Fprintf(out, "Must specify --yes to apply changes\n")
 		} else {
@@ -373,6 +417,31 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 	return results, nil
 }
 
+// writePlan writes the dry run plan in the given format
+func writePlan(plan *fi.Plan, format string, out io.Writer) error {
+	var b []byte
+	var err error
+	switch format {
+	case OutputJSON:
+		b, err = json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		b = append(b, '\n')
+	case OutputYaml:
+		b, err = yaml.Marshal(plan)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if _, err := out.Write(b); err != nil {
+		return fmt.Errorf("error writing to output: %v", err)
+	}
+	return nil
+}
+
 func parseLifecycle(lifecycle string) (fi.Lifecycle, error) {
 	if v, ok := fi.LifecycleNameMap[lifecycle]; ok {
 		return v, nil
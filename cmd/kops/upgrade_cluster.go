@@ -77,9 +77,24 @@ func (c *UpgradeClusterCmd) Run(args []string) error {
 		return err
 	}
 
-	cluster, err := rootCommand.Cluster()
-	if err != nil {
-		return err
+	var cluster *api.Cluster
+	if c.Yes {
+		clusterName := rootCommand.ClusterName()
+		if clusterName == "" {
+			return fmt.Errorf("--name is required")
+		}
+
+		var unlock func()
+		cluster, unlock, err = lockCluster(rootCommand.factory, clusterName, "upgrade cluster")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	} else {
+		cluster, err = rootCommand.Cluster()
+		if err != nil {
+			return err
+		}
 	}
 
 	clientset, err := rootCommand.Clientset()
@@ -33,6 +33,8 @@ var (
 	2. All k8s nodes are running and have "Ready" status.
 	3. Component status returns healthy for all components.
 	4. All pods in the kube-system namespace are running and healthy.
+	5. The extra checks in spec.validation of the cluster, and in the validation file
+	   in the state store, pass.
 	`))
 
 	validateExample = templates.Examples(i18n.T(`
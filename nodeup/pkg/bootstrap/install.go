@@ -140,6 +140,15 @@ func (i *Installation) buildSystemdJob() *nodetasks.Service {
 		buffer.WriteString("\" ")
 	}
 
+	// Pass in the SAS token for a state store in Azure Blob Storage
+	for _, name := range []string{"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_SAS_TOKEN", "AZURE_STORAGE_ENDPOINT"} {
+		if os.Getenv(name) != "" {
+			buffer.WriteString("\"" + name + "=")
+			buffer.WriteString(os.Getenv(name))
+			buffer.WriteString("\" ")
+		}
+	}
+
 	if os.Getenv("DIGITALOCEAN_ACCESS_TOKEN") != "" {
 		buffer.WriteString("\"DIGITALOCEAN_ACCESS_TOKEN=")
 		buffer.WriteString(os.Getenv("DIGITALOCEAN_ACCESS_TOKEN"))
@@ -0,0 +1,188 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/flagbuilder"
+	"k8s.io/kops/pkg/systemd"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+const (
+	// containerdConfigPath is the path of the containerd configuration file
+	containerdConfigPath = "/etc/containerd/config.toml"
+	// containerdSysconfigPath holds the flags for the containerd daemon
+	containerdSysconfigPath = "/etc/sysconfig/containerd"
+)
+
+// ContainerdBuilder installs containerd and runc, for use by the kubelet over CRI
+type ContainerdBuilder struct {
+	*NodeupModelContext
+}
+
+var _ fi.ModelBuilder = &ContainerdBuilder{}
+
+// Build is responsible for installing and configuring containerd
+func (b *ContainerdBuilder) Build(c *fi.ModelBuilderContext) error {
+	if !b.UseContainerd() {
+		glog.V(4).Infof("containerRuntime is not containerd; skipping containerd install")
+		return nil
+	}
+
+	// We install the release binaries on every distro, rather than relying on distro packages,
+	// so that all nodes run the same version; on CoreOS & ContainerOS this shadows the bundled containerd
+	for _, name := range []string{"containerd", "containerd-shim", "ctr"} {
+		asset, err := b.Assets.Find(name, "bin/"+name)
+		if err != nil {
+			return fmt.Errorf("error trying to locate asset %q: %v", name, err)
+		}
+		if asset == nil {
+			return fmt.Errorf("unable to locate asset %q", name)
+		}
+
+		c.AddTask(&nodetasks.File{
+			Path:     filepath.Join(b.ContainerdBinDir(), name),
+			Contents: asset,
+			Type:     nodetasks.FileType_File,
+			Mode:     s("0755"),
+		})
+	}
+
+	runc, err := b.findRuncAsset()
+	if err != nil {
+		return err
+	}
+	c.AddTask(&nodetasks.File{
+		Path:     filepath.Join(b.ContainerdBinDir(), "runc"),
+		Contents: runc,
+		Type:     nodetasks.FileType_File,
+		Mode:     s("0755"),
+	})
+
+	c.AddTask(&nodetasks.File{
+		Path:     containerdConfigPath,
+		Contents: fi.NewStringResource(b.buildConfigFile()),
+		Type:     nodetasks.FileType_File,
+	})
+
+	if err := b.buildSysconfig(c); err != nil {
+		return err
+	}
+
+	c.AddTask(b.buildSystemdService())
+
+	return nil
+}
+
+// findRuncAsset locates the runc binary, which is published with the architecture as a suffix
+func (b *ContainerdBuilder) findRuncAsset() (fi.Resource, error) {
+	for _, name := range []string{"runc." + string(b.Architecture), "runc"} {
+		asset, err := b.Assets.Find(name, "")
+		if err != nil {
+			return nil, fmt.Errorf("error trying to locate asset %q: %v", name, err)
+		}
+		if asset != nil {
+			return asset, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to locate asset %q", "runc")
+}
+
+// buildConfigFile renders the containerd config.toml, pointing the CRI plugin at the kops CNI directories
+func (b *ContainerdBuilder) buildConfigFile() string {
+	if b.Cluster.Spec.Containerd != nil && b.Cluster.Spec.Containerd.ConfigOverride != nil {
+		return fi.StringValue(b.Cluster.Spec.Containerd.ConfigOverride)
+	}
+
+	lines := []string{
+		"[plugins.cri.cni]",
+		fmt.Sprintf("  bin_dir = %q", b.CNIBinDir()),
+		fmt.Sprintf("  conf_dir = %q", b.CNIConfDir()),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// buildSysconfig is responsible for extracting the containerd configuration and writing the sysconfig file
+func (b *ContainerdBuilder) buildSysconfig(c *fi.ModelBuilderContext) error {
+	var containerd kops.ContainerdConfig
+	if b.Cluster.Spec.Containerd != nil {
+		containerd = *b.Cluster.Spec.Containerd
+	}
+
+	flagsString, err := flagbuilder.BuildFlags(&containerd)
+	if err != nil {
+		return fmt.Errorf("error building containerd flags: %v", err)
+	}
+
+	c.AddTask(&nodetasks.File{
+		Path:     containerdSysconfigPath,
+		Contents: fi.NewStringResource("CONTAINERD_OPTS=" + flagsString + "\n"),
+		Type:     nodetasks.FileType_File,
+	})
+
+	return nil
+}
+
+func (b *ContainerdBuilder) buildSystemdService() *nodetasks.Service {
+	manifest := &systemd.Manifest{}
+	manifest.Set("Unit", "Description", "containerd container runtime")
+	manifest.Set("Unit", "Documentation", "https://containerd.io")
+	manifest.Set("Unit", "After", "network.target")
+
+	manifest.Set("Service", "EnvironmentFile", containerdSysconfigPath)
+	manifest.Set("Service", "EnvironmentFile", "/etc/environment")
+	// containerd finds containerd-shim and runc on the PATH
+	manifest.Set("Service", "Environment", "PATH="+b.ContainerdBinDir()+":/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin")
+	manifest.Set("Service", "ExecStartPre", "-/sbin/modprobe overlay")
+	manifest.Set("Service", "ExecStart", filepath.Join(b.ContainerdBinDir(), "containerd")+" -c "+containerdConfigPath+" $CONTAINERD_OPTS")
+
+	// kill only the containerd process, not all processes in the cgroup
+	manifest.Set("Service", "KillMode", "process")
+	// set delegate yes so that systemd does not reset the cgroups of containers
+	manifest.Set("Service", "Delegate", "yes")
+	manifest.Set("Service", "OOMScoreAdjust", "-999")
+
+	manifest.Set("Service", "LimitNOFILE", "1048576")
+	manifest.Set("Service", "LimitNPROC", "infinity")
+	manifest.Set("Service", "LimitCORE", "infinity")
+	manifest.Set("Service", "TasksMax", "infinity")
+
+	manifest.Set("Service", "Restart", "always")
+	manifest.Set("Service", "RestartSec", "2s")
+	manifest.Set("Service", "StartLimitInterval", "0")
+
+	manifest.Set("Install", "WantedBy", "multi-user.target")
+
+	manifestString := manifest.Render()
+	glog.V(8).Infof("Built service manifest %q\n%s", "containerd", manifestString)
+
+	service := &nodetasks.Service{
+		Name:       "containerd.service",
+		Definition: s(manifestString),
+	}
+
+	service.InitDefaults()
+
+	return service
+}
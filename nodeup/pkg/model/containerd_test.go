@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"testing"
+
+	"k8s.io/kops/nodeup/pkg/distros"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/flagbuilder"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestContainerdBuilder_SkippedForDocker(t *testing.T) {
+	builder := ContainerdBuilder{
+		NodeupModelContext: &NodeupModelContext{
+			Cluster: &kops.Cluster{},
+		},
+	}
+
+	context := &fi.ModelBuilderContext{
+		Tasks: make(map[string]fi.Task),
+	}
+	if err := builder.Build(context); err != nil {
+		t.Fatalf("error from ContainerdBuilder Build: %v", err)
+	}
+	if len(context.Tasks) != 0 {
+		t.Fatalf("expected no tasks when containerRuntime is docker, got %v", context.Tasks)
+	}
+}
+
+func TestContainerdBuilder_BuildFlags(t *testing.T) {
+	grid := []struct {
+		config   kops.ContainerdConfig
+		expected string
+	}{
+		{
+			kops.ContainerdConfig{},
+			"",
+		},
+		{
+			// Version and ConfigOverride are not flags
+			kops.ContainerdConfig{
+				Version:        fi.String("1.2.4"),
+				ConfigOverride: fi.String("[plugins]"),
+			},
+			"",
+		},
+		{
+			kops.ContainerdConfig{
+				LogLevel: fi.String("debug"),
+				Root:     fi.String("/mnt/containerd"),
+			},
+			"--log-level=debug --root=/mnt/containerd",
+		},
+	}
+
+	for _, g := range grid {
+		actual, err := flagbuilder.BuildFlags(&g.config)
+		if err != nil {
+			t.Errorf("error building flags for %v: %v", g.config, err)
+			continue
+		}
+		if actual != g.expected {
+			t.Errorf("flags did not match.  actual=%q expected=%q", actual, g.expected)
+		}
+	}
+}
+
+func TestContainerdBuilder_ConfigFile(t *testing.T) {
+	grid := []struct {
+		distribution distros.Distribution
+		config       *kops.ContainerdConfig
+		expected     string
+	}{
+		{
+			distros.DistributionXenial,
+			nil,
+			"[plugins.cri.cni]\n  bin_dir = \"/opt/cni/bin/\"\n  conf_dir = \"/etc/cni/net.d/\"\n",
+		},
+		{
+			distros.DistributionContainerOS,
+			&kops.ContainerdConfig{},
+			"[plugins.cri.cni]\n  bin_dir = \"/home/kubernetes/bin/\"\n  conf_dir = \"/etc/cni/net.d/\"\n",
+		},
+		{
+			distros.DistributionXenial,
+			&kops.ContainerdConfig{ConfigOverride: fi.String("[plugins.cri]\n")},
+			"[plugins.cri]\n",
+		},
+	}
+
+	for _, g := range grid {
+		builder := ContainerdBuilder{
+			NodeupModelContext: &NodeupModelContext{
+				Cluster: &kops.Cluster{
+					Spec: kops.ClusterSpec{
+						ContainerRuntime: kops.ContainerRuntimeContainerd,
+						Containerd:       g.config,
+					},
+				},
+				Distribution: g.distribution,
+			},
+		}
+
+		actual := builder.buildConfigFile()
+		if actual != g.expected {
+			t.Errorf("config did not match for %s.  actual=%q expected=%q", g.distribution, actual, g.expected)
+		}
+	}
+}
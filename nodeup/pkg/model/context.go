@@ -151,7 +151,7 @@ func (c *NodeupModelContext) CNIConfDir() string {
 
 // BuildPKIKubeconfig generates a kubeconfig
 func (c *NodeupModelContext) BuildPKIKubeconfig(name string) (string, error) {
-	ca, err := c.FindCert(fi.CertificateId_CA)
+	ca, err := c.FindCertificatePool(fi.CertificateId_CA)
 	if err != nil {
 		return "", err
 	}
@@ -343,6 +343,31 @@ func (c *NodeupModelContext) KubectlPath() string {
 	return kubeletCommand
 }
 
+// UseContainerd checks if the kubelet runs containers with containerd rather than docker
+func (c *NodeupModelContext) UseContainerd() bool {
+	return c.Cluster.Spec.ContainerRuntime == kops.ContainerRuntimeContainerd
+}
+
+// ContainerdBinDir returns the distro based path for the containerd and runc binaries
+func (c *NodeupModelContext) ContainerdBinDir() string {
+	switch c.Distribution {
+	case distros.DistributionCoreOS:
+		return "/opt/bin"
+	case distros.DistributionContainerOS:
+		return "/home/kubernetes/bin"
+	default:
+		return "/usr/local/bin"
+	}
+}
+
+// ContainerdAddress returns the path of the containerd GRPC socket
+func (c *NodeupModelContext) ContainerdAddress() string {
+	if c.Cluster.Spec.Containerd != nil && fi.StringValue(c.Cluster.Spec.Containerd.Address) != "" {
+		return fi.StringValue(c.Cluster.Spec.Containerd.Address)
+	}
+	return "/run/containerd/containerd.sock"
+}
+
 // BuildCertificatePairTask creates the tasks to pull down the certificate and private key
 func (c *NodeupModelContext) BuildCertificatePairTask(ctx *fi.ModelBuilderContext, key, path, filename string) error {
 	certificateName := filepath.Join(path, filename+".pem")
@@ -381,6 +406,32 @@ func (c *NodeupModelContext) BuildCertificateTask(ctx *fi.ModelBuilderContext, n
 	return nil
 }
 
+// BuildCertificatePoolTask is responsible for writing all the trusted certificates of a keyset, i.e. a CA bundle
+func (c *NodeupModelContext) BuildCertificatePoolTask(ctx *fi.ModelBuilderContext, name, filename string) error {
+	pool, err := c.KeyStore.FindCertificatePool(name)
+	if err != nil {
+		return err
+	}
+
+	if pool == nil || pool.Primary == nil {
+		return fmt.Errorf("certificate %q not found", name)
+	}
+
+	serialized, err := pool.AsString()
+	if err != nil {
+		return err
+	}
+
+	ctx.AddTask(&nodetasks.File{
+		Path:     filepath.Join(c.PathSrvKubernetes(), filename),
+		Contents: fi.NewStringResource(serialized),
+		Type:     nodetasks.FileType_File,
+		Mode:     s("0600"),
+	})
+
+	return nil
+}
+
 // BuildPrivateKeyTask is responsible for build a certificate request task
 func (c *NodeupModelContext) BuildPrivateKeyTask(ctx *fi.ModelBuilderContext, name, filename string) error {
 	cert, err := c.KeyStore.FindPrivateKey(name)
@@ -478,6 +529,23 @@ func (c *NodeupModelContext) FindCert(name string) ([]byte, error) {
 	return cert.AsBytes()
 }
 
+// FindCertificatePool is a helper method to retrieving all the trusted certificates of a keyset from the store
+func (c *NodeupModelContext) FindCertificatePool(name string) ([]byte, error) {
+	pool, err := c.KeyStore.FindCertificatePool(name)
+	if err != nil {
+		return []byte{}, fmt.Errorf("error fetching certificate: %v from keystore: %v", name, err)
+	}
+	if pool == nil || pool.Primary == nil {
+		return []byte{}, fmt.Errorf("unable to found certificate: %s", name)
+	}
+
+	serialized, err := pool.AsString()
+	if err != nil {
+		return []byte{}, err
+	}
+	return []byte(serialized), nil
+}
+
 // FindPrivateKey is a helper method to retrieving a private key from the store
 func (c *NodeupModelContext) FindPrivateKey(name string) ([]byte, error) {
 	key, err := c.KeyStore.FindPrivateKey(name)
@@ -570,6 +570,10 @@ func (d *dockerVersion) matches(arch Architecture, dockerVersion string, distro
 
 // Build is responsible for configuring the docker daemon
 func (b *DockerBuilder) Build(c *fi.ModelBuilderContext) error {
+	if b.UseContainerd() {
+		glog.Infof("containerRuntime is containerd; won't install Docker")
+		return nil
+	}
 
 	// @check: neither coreos or containeros need provision docker.service, just the docker daemon options
 	switch b.Distribution {
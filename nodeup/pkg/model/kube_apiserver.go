@@ -56,6 +56,10 @@ func (b *KubeAPIServerBuilder) Build(c *fi.ModelBuilderContext) error {
 		return err
 	}
 
+	if err := b.writeServiceAccountKeys(c); err != nil {
+		return err
+	}
+
 	if b.Cluster.Spec.EncryptionConfig != nil {
 		if *b.Cluster.Spec.EncryptionConfig && b.IsKubernetesGTE("1.7") {
 			b.Cluster.Spec.KubeAPIServer.ExperimentalEncryptionProviderConfig = fi.String(filepath.Join(b.PathSrvKubernetes(), "encryptionconfig.yaml"))
@@ -113,6 +117,27 @@ func (b *KubeAPIServerBuilder) Build(c *fi.ModelBuilderContext) error {
 	return nil
 }
 
+// writeServiceAccountKeys trusts every key of the master keypair for service account tokens while it is being rotated;
+// otherwise the apiserver defaults to the public key of server.key
+func (b *KubeAPIServerBuilder) writeServiceAccountKeys(c *fi.ModelBuilderContext) error {
+	name := "master"
+	pool, err := b.KeyStore.FindCertificatePool(name)
+	if err != nil {
+		return fmt.Errorf("error fetching %q certificates from keystore: %v", name, err)
+	}
+	if pool == nil || len(pool.Secondary) == 0 {
+		return nil
+	}
+
+	filename := "service-account-keys.pem"
+	if err := b.BuildCertificatePoolTask(c, name, filename); err != nil {
+		return err
+	}
+	b.Cluster.Spec.KubeAPIServer.ServiceAccountKeyFile = []string{filepath.Join(b.PathSrvKubernetes(), filename)}
+
+	return nil
+}
+
 func (b *KubeAPIServerBuilder) writeAuthenticationConfig(c *fi.ModelBuilderContext) error {
 	if b.Cluster.Spec.Authentication == nil || b.Cluster.Spec.Authentication.IsEmpty() {
 		return nil
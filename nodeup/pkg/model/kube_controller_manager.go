@@ -53,6 +53,10 @@ func (b *KubeControllerManagerBuilder) Build(c *fi.ModelBuilderContext) error {
 		if err := b.BuildPrivateKeyTask(c, fi.CertificateId_CA, "ca.key"); err != nil {
 			return err
 		}
+		// ca.crt can hold more than one CA during a rotation, but the signer expects exactly the one matching ca.key
+		if err := b.BuildCertificateTask(c, fi.CertificateId_CA, "ca-signer.crt"); err != nil {
+			return err
+		}
 	}
 
 	{
@@ -130,7 +134,7 @@ func (b *KubeControllerManagerBuilder) buildPod() (*v1.Pod, error) {
 	// Configure CA certificate to be used to sign keys, if we are using CSRs
 	if b.useCertificateSigner() {
 		flags = append(flags, []string{
-			"--cluster-signing-cert-file=" + filepath.Join(b.PathSrvKubernetes(), "ca.crt"),
+			"--cluster-signing-cert-file=" + filepath.Join(b.PathSrvKubernetes(), "ca-signer.crt"),
 			"--cluster-signing-key-file=" + filepath.Join(b.PathSrvKubernetes(), "ca.key")}...)
 	}
 
@@ -200,6 +200,15 @@ func (b *KubeletBuilder) buildSystemdEnvironmentFile(kubeletConfig *kops.Kubelet
 		flags += " --experimental-mounter-path=" + path.Join(containerizedMounterHome, "mounter")
 	}
 
+	if b.UseContainerd() {
+		// The kubelet talks to containerd over CRI, rather than using the built-in docker shim
+		flags += " --container-runtime=remote"
+		flags += " --container-runtime-endpoint=unix://" + b.ContainerdAddress()
+		if kubeletConfig.RuntimeRequestTimeout == nil {
+			flags += " --runtime-request-timeout=15m"
+		}
+	}
+
 	sysconfig := "DAEMON_ARGS=\"" + flags + "\"\n"
 	// Makes kubelet read /root/.docker/config.json properly
 	sysconfig = sysconfig + "HOME=\"/root" + "\"\n"
@@ -220,7 +229,11 @@ func (b *KubeletBuilder) buildSystemdService() *nodetasks.Service {
 	manifest := &systemd.Manifest{}
 	manifest.Set("Unit", "Description", "Kubernetes Kubelet Server")
 	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kubernetes")
-	manifest.Set("Unit", "After", "docker.service")
+	if b.UseContainerd() {
+		manifest.Set("Unit", "After", "containerd.service")
+	} else {
+		manifest.Set("Unit", "After", "docker.service")
+	}
 
 	if b.Distribution == distros.DistributionCoreOS {
 		// We add /opt/kubernetes/bin for our utilities (socat, conntrack)
@@ -546,7 +559,7 @@ func (b *KubeletBuilder) buildMasterKubeletKubeconfig() (*nodetasks.File, error)
 		return nil, fmt.Errorf("error signing certificate for master kubelet: %v", err)
 	}
 
-	caBytes, err := caCert.AsBytes()
+	caBytes, err := b.FindCertificatePool(fi.CertificateId_CA)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get certificate authority data: %s", err)
 	}
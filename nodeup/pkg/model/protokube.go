@@ -34,6 +34,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
 
 	"github.com/blang/semver"
+	"github.com/docker/distribution/reference"
 	"github.com/golang/glog"
 )
 
@@ -107,6 +108,44 @@ func (t *ProtokubeBuilder) buildSystemdService() (*nodetasks.Service, error) {
 		return nil, err
 	}
 
+	var runArgs []string
+	if t.UseContainerd() {
+		runArgs = t.protokubeContainerdRunArgs()
+	} else {
+		runArgs = t.protokubeDockerRunArgs()
+	}
+
+	protokubeCommand := strings.Join(runArgs, " ") + " " + protokubeFlagsArgs
+
+	manifest := &systemd.Manifest{}
+	manifest.Set("Unit", "Description", "Kubernetes Protokube Service")
+	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kops")
+	if t.UseContainerd() {
+		// Clean up a container left behind if protokube did not exit cleanly
+		manifest.Set("Service", "ExecStartPre", "-"+t.ctrCommand()+" containers delete protokube")
+	}
+	manifest.Set("Service", "ExecStartPre", t.ProtokubeImagePullCommand())
+	manifest.Set("Service", "ExecStart", protokubeCommand)
+	manifest.Set("Service", "Restart", "always")
+	manifest.Set("Service", "RestartSec", "2s")
+	manifest.Set("Service", "StartLimitInterval", "0")
+	manifest.Set("Install", "WantedBy", "multi-user.target")
+
+	manifestString := manifest.Render()
+	glog.V(8).Infof("Built service manifest %q\n%s", "protokube", manifestString)
+
+	service := &nodetasks.Service{
+		Name:       "protokube.service",
+		Definition: s(manifestString),
+	}
+
+	service.InitDefaults()
+
+	return service, nil
+}
+
+// protokubeDockerRunArgs returns the command to run protokube with docker
+func (t *ProtokubeBuilder) protokubeDockerRunArgs() []string {
 	dockerArgs := []string{
 		"/usr/bin/docker", "run",
 		"-v", "/:/rootfs/",
@@ -134,29 +173,53 @@ func (t *ProtokubeBuilder) buildSystemdService() (*nodetasks.Service, error) {
 		"/usr/bin/protokube",
 	}...)
 
-	protokubeCommand := strings.Join(dockerArgs, " ") + " " + protokubeFlagsArgs
-
-	manifest := &systemd.Manifest{}
-	manifest.Set("Unit", "Description", "Kubernetes Protokube Service")
-	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kops")
-	manifest.Set("Service", "ExecStartPre", t.ProtokubeImagePullCommand())
-	manifest.Set("Service", "ExecStart", protokubeCommand)
-	manifest.Set("Service", "Restart", "always")
-	manifest.Set("Service", "RestartSec", "2s")
-	manifest.Set("Service", "StartLimitInterval", "0")
-	manifest.Set("Install", "WantedBy", "multi-user.target")
+	return dockerArgs
+}
 
-	manifestString := manifest.Render()
-	glog.V(8).Infof("Built service manifest %q\n%s", "protokube", manifestString)
+// protokubeContainerdRunArgs returns the command to run protokube with ctr, mirroring protokubeDockerRunArgs
+func (t *ProtokubeBuilder) protokubeContainerdRunArgs() []string {
+	ctrArgs := []string{
+		t.ctrCommand(), "run", "--rm",
+		"--mount", "type=bind,src=/,dst=/rootfs/,options=rbind:rslave",
+		"--mount", "type=bind,src=/var/run/dbus,dst=/var/run/dbus,options=rbind",
+		"--mount", "type=bind,src=/run/systemd,dst=/run/systemd,options=rbind",
+	}
 
-	service := &nodetasks.Service{
-		Name:       "protokube.service",
-		Definition: s(manifestString),
+	if t.IsMaster {
+		ctrArgs = append(ctrArgs, []string{
+			"--mount", "type=bind,src=" + t.KubectlPath() + ",dst=/opt/kops/bin,options=rbind:ro",
+			"--env", "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/opt/kops/bin",
+		}...)
 	}
 
-	service.InitDefaults()
+	ctrArgs = append(ctrArgs, []string{
+		"--net-host",
+		"--with-ns", "pid:/proc/1/ns/pid", // Needed for mounting in a container (when using systemd mounting?)
+		"--privileged", // We execute in the host namespace
+		"--env", "KUBECONFIG=/rootfs/var/lib/kops/kubeconfig",
+		t.ProtokubeEnvironmentVariables(),
+		normalizeImageName(t.ProtokubeImageName()),
+		"protokube",
+		"/usr/bin/protokube",
+	}...)
 
-	return service, nil
+	return ctrArgs
+}
+
+// ctrCommand returns the ctr invocation for the namespace the kubelet uses
+func (t *ProtokubeBuilder) ctrCommand() string {
+	return filepath.Join(t.ContainerdBinDir(), "ctr") + " --namespace k8s.io"
+}
+
+// normalizeImageName expands an image name to the fully qualified form used by containerd,
+// e.g. protokube:1.10.0 becomes docker.io/library/protokube:1.10.0
+func normalizeImageName(name string) string {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		glog.Warningf("unable to normalize image name %q: %v", name, err)
+		return name
+	}
+	return named.String()
 }
 
 // ProtokubeImageName returns the docker image for protokube
@@ -187,6 +250,9 @@ func (t *ProtokubeBuilder) ProtokubeImagePullCommand() string {
 		return "/bin/true"
 	}
 
+	if t.UseContainerd() {
+		return t.ctrCommand() + " images pull " + normalizeImageName(t.NodeupConfig.ProtokubeImage.Source)
+	}
 	return "/usr/bin/docker pull " + t.NodeupConfig.ProtokubeImage.Source
 }
 
@@ -374,7 +440,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 	// Passin gossip dns connection limit
 	if os.Getenv("GOSSIP_DNS_CONN_LIMIT") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'GOSSIP_DNS_CONN_LIMIT=")
+		buffer.WriteString("--env 'GOSSIP_DNS_CONN_LIMIT=")
 		buffer.WriteString(os.Getenv("GOSSIP_DNS_CONN_LIMIT"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -383,7 +449,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 	// Pass in required credentials when using user-defined s3 endpoint
 	if os.Getenv("AWS_REGION") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'AWS_REGION=")
+		buffer.WriteString("--env 'AWS_REGION=")
 		buffer.WriteString(os.Getenv("AWS_REGION"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -391,28 +457,39 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 	if os.Getenv("S3_ENDPOINT") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e S3_ENDPOINT=")
+		buffer.WriteString("--env S3_ENDPOINT=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_ENDPOINT"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_REGION=")
+		buffer.WriteString(" --env S3_REGION=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_REGION"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_ACCESS_KEY_ID=")
+		buffer.WriteString(" --env S3_ACCESS_KEY_ID=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_ACCESS_KEY_ID"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_SECRET_ACCESS_KEY=")
+		buffer.WriteString(" --env S3_SECRET_ACCESS_KEY=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_SECRET_ACCESS_KEY"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
 	}
 
+	// Pass in the SAS token for a state store in Azure Blob Storage
+	for _, name := range []string{"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_SAS_TOKEN", "AZURE_STORAGE_ENDPOINT"} {
+		if os.Getenv(name) != "" {
+			buffer.WriteString(" --env '")
+			buffer.WriteString(name)
+			buffer.WriteString("=")
+			buffer.WriteString(os.Getenv(name))
+			buffer.WriteString("' ")
+		}
+	}
+
 	if kops.CloudProviderID(t.Cluster.Spec.CloudProvider) == kops.CloudProviderDO && os.Getenv("DIGITALOCEAN_ACCESS_TOKEN") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'DIGITALOCEAN_ACCESS_TOKEN=")
+		buffer.WriteString("--env 'DIGITALOCEAN_ACCESS_TOKEN=")
 		buffer.WriteString(os.Getenv("DIGITALOCEAN_ACCESS_TOKEN"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -425,7 +502,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 func (t *ProtokubeBuilder) writeProxyEnvVars(buffer *bytes.Buffer) {
 	for _, envVar := range getProxyEnvVars(t.Cluster.Spec.EgressProxy) {
-		buffer.WriteString(" -e ")
+		buffer.WriteString(" --env ")
 		buffer.WriteString(envVar.Name)
 		buffer.WriteString("=")
 		buffer.WriteString(envVar.Value)
@@ -44,8 +44,8 @@ func (b *SecretBuilder) Build(c *fi.ModelBuilderContext) error {
 		return fmt.Errorf("KeyStore not set")
 	}
 
-	// @step: retrieve the platform ca
-	if err := b.BuildCertificateTask(c, fi.CertificateId_CA, "ca.crt"); err != nil {
+	// @step: retrieve the platform ca, including any CA still trusted during a rotation
+	if err := b.BuildCertificatePoolTask(c, fi.CertificateId_CA, "ca.crt"); err != nil {
 		return err
 	}
 
@@ -98,7 +98,7 @@ func (b *SecretBuilder) Build(c *fi.ModelBuilderContext) error {
 	}
 
 	if b.IsKubernetesGTE("1.7") {
-		if err := b.BuildCertificateTask(c, "apiserver-aggregator-ca", "apiserver-aggregator-ca.cert"); err != nil {
+		if err := b.BuildCertificatePoolTask(c, "apiserver-aggregator-ca", "apiserver-aggregator-ca.cert"); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/values"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// azureBlobPublicAclStrategy is the AclStrategy for blobs that are written with public read access.
+// This strategy is used by custom file assets.
+type azureBlobPublicAclStrategy struct {
+}
+
+var _ acls.ACLStrategy = &azureBlobPublicAclStrategy{}
+
+// GetACL requires public read access for files written to the container of the assets FileRepository,
+// unless the files are inside the state store.
+func (s *azureBlobPublicAclStrategy) GetACL(p vfs.Path, cluster *kops.Cluster) (vfs.ACL, error) {
+	if cluster.Spec.Assets == nil || cluster.Spec.Assets.FileRepository == nil {
+		return nil, nil
+	}
+
+	azurePath, ok := p.(*vfs.AzureBlobPath)
+	if !ok {
+		return nil, nil
+	}
+
+	fileRepository := values.StringValue(cluster.Spec.Assets.FileRepository)
+
+	u, err := url.Parse(fileRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse: %q", fileRepository)
+	}
+
+	// We do NOT require the state store to be public
+	if cluster.Spec.ConfigStore != "" && strings.HasPrefix(azurePath.Path()+"/", strings.TrimSuffix(cluster.Spec.ConfigStore, "/")+"/") {
+		glog.V(8).Infof("path %q is inside of config store %q, not setting public-read acl", azurePath, cluster.Spec.ConfigStore)
+		return nil, nil
+	}
+
+	// The file repository is https://<account>.blob.core.windows.net/<container>/<path>
+	container := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)[0]
+	if container != azurePath.Container() {
+		glog.V(8).Infof("path %q is not inside the file registry %q, not setting public-read acl", azurePath, fileRepository)
+		return nil, nil
+	}
+
+	return &vfs.AzureBlobACL{
+		PublicRead: true,
+	}, nil
+}
+
+func Register() {
+	acls.RegisterPlugin("k8s.io/kops/acl/azure", &azureBlobPublicAclStrategy{})
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/values"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func Test_Strategy(t *testing.T) {
+	grid := []struct {
+		Path       string
+		PublicRead bool
+	}{
+		{
+			Path:       "assets/kubernetes-release/v1.9.3/bin/linux/amd64/kubelet",
+			PublicRead: true,
+		},
+		{
+			Path:       "state/example.com/config",
+			PublicRead: false,
+		},
+	}
+
+	cluster := &kops.Cluster{
+		Spec: kops.ClusterSpec{
+			ConfigStore: "azureblob://assets/state/example.com",
+			Assets: &kops.Assets{
+				FileRepository: values.String("https://kopsassets.blob.core.windows.net/assets/"),
+			},
+		},
+	}
+
+	s := &azureBlobPublicAclStrategy{}
+	for _, g := range grid {
+		p := vfs.NewAzureBlobPath(nil, "assets", g.Path)
+		acl, err := s.GetACL(p, cluster)
+		if err != nil {
+			t.Errorf("error getting ACL for %s: %v", p, err)
+			continue
+		}
+
+		if !g.PublicRead {
+			if acl != nil {
+				t.Errorf("unexpected ACL %v for %s in the state store", acl, p)
+			}
+			continue
+		}
+		azureACL, ok := acl.(*vfs.AzureBlobACL)
+		if !ok || !azureACL.PublicRead {
+			t.Errorf("expected public read ACL for %s, got %v", p, acl)
+		}
+	}
+
+	// Files in other containers are not public
+	acl, err := s.GetACL(vfs.NewAzureBlobPath(nil, "other", "file"), cluster)
+	if err != nil || acl != nil {
+		t.Errorf("unexpected ACL %v (error %v) for file in other container", acl, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +genclient
@@ -131,8 +132,11 @@ type ClusterSpec struct {
 	FileAssets []FileAssetSpec `json:"fileAssets,omitempty"`
 	// EtcdClusters stores the configuration for each cluster
 	EtcdClusters []*EtcdClusterSpec `json:"etcdClusters,omitempty"`
+	// ContainerRuntime is the container runtime used by the kubelet: "docker" (default) or "containerd"
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
 	// Component configurations
 	Docker                         *DockerConfig                 `json:"docker,omitempty"`
+	Containerd                     *ContainerdConfig             `json:"containerd,omitempty"`
 	KubeDNS                        *KubeDNSConfig                `json:"kubeDNS,omitempty"`
 	KubeAPIServer                  *KubeAPIServerConfig          `json:"kubeAPIServer,omitempty"`
 	KubeControllerManager          *KubeControllerManagerConfig  `json:"kubeControllerManager,omitempty"`
@@ -166,6 +170,12 @@ type ClusterSpec struct {
 	EncryptionConfig *bool `json:"encryptionConfig,omitempty"`
 	// Target allows for us to nest extra config for targets such as terraform
 	Target *TargetSpec `json:"target,omitempty"`
+	// RollingUpdate defines the default rolling-update settings for instance groups
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+	// Validation defines extra checks run when validating the cluster
+	Validation *ClusterValidationSpec `json:"validation,omitempty"`
+	// RevisionHistoryLimit is the number of revisions of the configuration kept in the state store (default 50)
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
 }
 
 // NodeAuthorizationSpec is used to node authorization
@@ -486,10 +496,91 @@ func (t *TargetSpec) IsEmpty() bool {
 type TerraformSpec struct {
 	// ProviderExtraConfig contains key/value pairs to add to the rendered terraform "provider" block
 	ProviderExtraConfig *map[string]string `json:"providerExtraConfig,omitempty"`
+	// Version is the version of terraform the output is written for: 0.11 (the default) writes HCL1, 0.12 writes the HCL2 syntax of terraform 0.12 and later
+	Version string `json:"version,omitempty"`
 }
 
 func (t *TerraformSpec) IsEmpty() bool {
-	return t.ProviderExtraConfig == nil
+	return t.ProviderExtraConfig == nil && t.Version == ""
+}
+
+const (
+	// TerraformVersion011 is the HCL1 output, for terraform 0.11 and earlier
+	TerraformVersion011 = "0.11"
+	// TerraformVersion012 is the HCL2 output, for terraform 0.12 and later
+	TerraformVersion012 = "0.12"
+)
+
+// SupportedTerraformVersions is the list of supported values for TerraformSpec.Version
+var SupportedTerraformVersions = []string{TerraformVersion011, TerraformVersion012}
+
+// RollingUpdate defines the rolling-update behavior of an instance group
+type RollingUpdate struct {
+	// MaxUnavailable is the maximum number of nodes that can be unavailable during the update.
+	// The value can be an absolute number (for example 5) or a percentage of desired
+	// nodes (for example 10%).
+	// The absolute number is calculated from a percentage by rounding down, with a minimum of 1.
+	// A value of 0 for both this and MaxSurge disables rolling updates.
+	// Defaults to 1 if MaxSurge is 0, otherwise defaults to 0.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// MaxSurge is the maximum number of extra nodes that can be created during the update.
+	// The value can be an absolute number (for example 5) or a percentage of
+	// desired nodes (for example 10%).
+	// The absolute number is calculated from a percentage by rounding up.
+	// Has no effect on instance groups with role "Master", and is only supported on AWS.
+	// Defaults to 0.
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// Drain defines how nodes are drained during the update.
+	Drain *DrainSpec `json:"drain,omitempty"`
+}
+
+// DrainSpec defines how nodes are drained before their instances are deleted by a rolling update
+type DrainSpec struct {
+	// Timeout is the maximum time to wait for the pods of a node to be evicted.
+	// Evictions refused because of a PodDisruptionBudget are retried until then.
+	// Defaults to 15 minutes; 0 waits indefinitely.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// GracePeriodSeconds overrides the termination grace period of evicted pods.
+	// Defaults to the grace period of each pod.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// DeleteLocalData allows evicting pods with emptyDir volumes, whose data is lost.
+	// If false, the rolling update stops at a node running such pods.  Defaults to true.
+	DeleteLocalData *bool `json:"deleteLocalData,omitempty"`
+	// SkipPodSelector is a label selector for pods which are not evicted; they are stopped along with their instance.
+	SkipPodSelector string `json:"skipPodSelector,omitempty"`
+	// BlockingPodSelector is a label selector for pods which must not be evicted.
+	// The rolling update stops at a node running such pods.
+	BlockingPodSelector string `json:"blockingPodSelector,omitempty"`
+}
+
+// ClusterValidationSpec defines extra checks run by kops validate cluster, and so before and during rolling updates
+type ClusterValidationSpec struct {
+	// Deployments must have all of their desired replicas updated and available
+	Deployments []ValidationObjectRef `json:"deployments,omitempty"`
+	// DaemonSets must have their pods updated and available on every node they are scheduled to
+	DaemonSets []ValidationObjectRef `json:"daemonSets,omitempty"`
+	// CustomResourceDefinitions are the names of CRDs which must be served, for example certificates.certmanager.k8s.io
+	CustomResourceDefinitions []string `json:"customResourceDefinitions,omitempty"`
+	// Nodes are minimum numbers of ready nodes with the given labels
+	Nodes []ValidationNodesSpec `json:"nodes,omitempty"`
+	// HTTPEndpoints are URLs which must return a successful (2xx) status
+	HTTPEndpoints []string `json:"httpEndpoints,omitempty"`
+}
+
+// ValidationObjectRef refers to a namespaced object checked by cluster validation
+type ValidationObjectRef struct {
+	// Namespace is the namespace of the object, defaulting to kube-system
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object
+	Name string `json:"name,omitempty"`
+}
+
+// ValidationNodesSpec requires a minimum number of ready nodes matching a label selector
+type ValidationNodesSpec struct {
+	// Selector is a label selector for the nodes, for example kops.k8s.io/instancegroup=nodes
+	Selector string `json:"selector,omitempty"`
+	// MinReady is the minimum number of ready nodes matching the selector
+	MinReady int32 `json:"minReady,omitempty"`
 }
 
 // FillDefaults populates default values.
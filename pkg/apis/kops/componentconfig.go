@@ -272,6 +272,8 @@ type KubeAPIServerConfig struct {
 	TLSPrivateKeyFile string `json:"tlsPrivateKeyFile,omitempty" flag:"tls-private-key-file"`
 	// TODO: Remove unused TokenAuthFile
 	TokenAuthFile string `json:"tokenAuthFile,omitempty" flag:"token-auth-file"`
+	// ServiceAccountKeyFile is the list of files holding the public keys used to verify service account tokens
+	ServiceAccountKeyFile []string `json:"serviceAccountKeyFile,omitempty" flag:"service-account-key-file,repeat"`
 	// AllowPrivileged indicates if we can run privileged containers
 	AllowPrivileged *bool `json:"allowPrivileged,omitempty" flag:"allow-privileged"`
 	// APIServerCount is the number of api servers
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+const (
+	// ContainerRuntimeDocker runs containers with docker; this is the default
+	ContainerRuntimeDocker = "docker"
+	// ContainerRuntimeContainerd runs containers with containerd, which the kubelet talks to over CRI
+	ContainerRuntimeContainerd = "containerd"
+)
+
+// SupportedContainerRuntimes is the list of supported values for ClusterSpec.ContainerRuntime
+var SupportedContainerRuntimes = []string{ContainerRuntimeDocker, ContainerRuntimeContainerd}
+
+// ContainerdConfig is the configuration for containerd
+type ContainerdConfig struct {
+	// Address is the path of the containerd GRPC socket (default "/run/containerd/containerd.sock")
+	Address *string `json:"address,omitempty" flag:"address"`
+	// ConfigOverride is the complete containerd config file, replacing the one generated by kops
+	ConfigOverride *string `json:"configOverride,omitempty"`
+	// LogLevel is the logging level ("debug", "info", "warn", "error", "fatal", "panic") (default "info")
+	LogLevel *string `json:"logLevel,omitempty" flag:"log-level"`
+	// Root is the directory for persistent containerd data (default "/var/lib/containerd")
+	Root *string `json:"root,omitempty" flag:"root"`
+	// RuncVersion is used to pick the runc release installed alongside containerd
+	RuncVersion *string `json:"runcVersion,omitempty"`
+	// State is the directory for containerd execution state (default "/run/containerd")
+	State *string `json:"state,omitempty" flag:"state"`
+	// Version is used to pick the containerd release to install
+	Version *string `json:"version,omitempty"`
+}
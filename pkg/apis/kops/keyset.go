@@ -69,4 +69,8 @@ type KeysetSpec struct {
 
 	// Keys is the set of keys that make up the keyset
 	Keys []KeysetItem `json:"keys,omitempty"`
+
+	// PrimaryId is the id of the key used to sign and issue; the other keys are only trusted.
+	// If not set, the key with the highest id is the primary.
+	PrimaryId string `json:"primaryId,omitempty"`
 }
@@ -30,6 +30,21 @@ const PathCluster = "config"
 // Path for completed cluster spec in the state store
 const PathClusterCompleted = "cluster.spec"
 
+// Path for the policy rules which are checked against the completed specs
+const PathPolicy = "policy"
+
+// Path for the advisory lock held by commands which change the state store
+const PathLock = "lock"
+
+// Path for the revisions of the cluster and instance group configuration
+const PathRevisions = "revisions"
+
+// Path for the progress of a rolling update, so that it can be resumed if interrupted
+const PathRollingUpdate = "rolling-update"
+
+// Path for the extra checks run by cluster validation, in addition to those in the cluster spec
+const PathValidation = "validation"
+
 func ConfigBase(c *api.Cluster) (vfs.Path, error) {
 	if c.Spec.ConfigBase == "" {
 		return nil, field.Required(field.NewPath("Spec", "ConfigBase"), "")
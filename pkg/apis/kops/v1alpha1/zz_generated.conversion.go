@@ -70,12 +70,18 @@ func RegisterConversions(scheme *runtime.Scheme) error {
 		Convert_kops_ClusterList_To_v1alpha1_ClusterList,
 		Convert_v1alpha1_ClusterSpec_To_kops_ClusterSpec,
 		Convert_kops_ClusterSpec_To_v1alpha1_ClusterSpec,
+		Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec,
+		Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec,
+		Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig,
+		Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig,
 		Convert_v1alpha1_DNSAccessSpec_To_kops_DNSAccessSpec,
 		Convert_kops_DNSAccessSpec_To_v1alpha1_DNSAccessSpec,
 		Convert_v1alpha1_DNSSpec_To_kops_DNSSpec,
 		Convert_kops_DNSSpec_To_v1alpha1_DNSSpec,
 		Convert_v1alpha1_DockerConfig_To_kops_DockerConfig,
 		Convert_kops_DockerConfig_To_v1alpha1_DockerConfig,
+		Convert_v1alpha1_DrainSpec_To_kops_DrainSpec,
+		Convert_kops_DrainSpec_To_v1alpha1_DrainSpec,
 		Convert_v1alpha1_EgressProxySpec_To_kops_EgressProxySpec,
 		Convert_kops_EgressProxySpec_To_v1alpha1_EgressProxySpec,
 		Convert_v1alpha1_EtcdBackupSpec_To_kops_EtcdBackupSpec,
@@ -136,6 +142,8 @@ func RegisterConversions(scheme *runtime.Scheme) error {
 		Convert_kops_LoadBalancer_To_v1alpha1_LoadBalancer,
 		Convert_v1alpha1_LoadBalancerAccessSpec_To_kops_LoadBalancerAccessSpec,
 		Convert_kops_LoadBalancerAccessSpec_To_v1alpha1_LoadBalancerAccessSpec,
+		Convert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec,
+		Convert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec,
 		Convert_v1alpha1_NetworkingSpec_To_kops_NetworkingSpec,
 		Convert_kops_NetworkingSpec_To_v1alpha1_NetworkingSpec,
 		Convert_v1alpha1_NodeAuthorizationSpec_To_kops_NodeAuthorizationSpec,
@@ -144,6 +152,8 @@ func RegisterConversions(scheme *runtime.Scheme) error {
 		Convert_kops_NodeAuthorizerSpec_To_v1alpha1_NodeAuthorizerSpec,
 		Convert_v1alpha1_RBACAuthorizationSpec_To_kops_RBACAuthorizationSpec,
 		Convert_kops_RBACAuthorizationSpec_To_v1alpha1_RBACAuthorizationSpec,
+		Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate,
+		Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate,
 		Convert_v1alpha1_RomanaNetworkingSpec_To_kops_RomanaNetworkingSpec,
 		Convert_kops_RomanaNetworkingSpec_To_v1alpha1_RomanaNetworkingSpec,
 		Convert_v1alpha1_SSHCredential_To_kops_SSHCredential,
@@ -158,6 +168,10 @@ func RegisterConversions(scheme *runtime.Scheme) error {
 		Convert_kops_TerraformSpec_To_v1alpha1_TerraformSpec,
 		Convert_v1alpha1_UserData_To_kops_UserData,
 		Convert_kops_UserData_To_v1alpha1_UserData,
+		Convert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec,
+		Convert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec,
+		Convert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef,
+		Convert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef,
 		Convert_v1alpha1_WeaveNetworkingSpec_To_kops_WeaveNetworkingSpec,
 		Convert_kops_WeaveNetworkingSpec_To_v1alpha1_WeaveNetworkingSpec,
 	)
@@ -891,6 +905,7 @@ func autoConvert_v1alpha1_ClusterSpec_To_kops_ClusterSpec(in *ClusterSpec, out *
 	} else {
 		out.EtcdClusters = nil
 	}
+	out.ContainerRuntime = in.ContainerRuntime
 	if in.Docker != nil {
 		in, out := &in.Docker, &out.Docker
 		*out = new(kops.DockerConfig)
@@ -900,6 +915,15 @@ func autoConvert_v1alpha1_ClusterSpec_To_kops_ClusterSpec(in *ClusterSpec, out *
 	} else {
 		out.Docker = nil
 	}
+	if in.Containerd != nil {
+		in, out := &in.Containerd, &out.Containerd
+		*out = new(kops.ContainerdConfig)
+		if err := Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Containerd = nil
+	}
 	if in.KubeDNS != nil {
 		in, out := &in.KubeDNS, &out.KubeDNS
 		*out = new(kops.KubeDNSConfig)
@@ -1075,6 +1099,25 @@ func autoConvert_v1alpha1_ClusterSpec_To_kops_ClusterSpec(in *ClusterSpec, out *
 	} else {
 		out.Target = nil
 	}
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(kops.RollingUpdate)
+		if err := Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(kops.ClusterValidationSpec)
+		if err := Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Validation = nil
+	}
+	out.RevisionHistoryLimit = in.RevisionHistoryLimit
 	return nil
 }
 
@@ -1157,6 +1200,7 @@ func autoConvert_kops_ClusterSpec_To_v1alpha1_ClusterSpec(in *kops.ClusterSpec,
 	} else {
 		out.EtcdClusters = nil
 	}
+	out.ContainerRuntime = in.ContainerRuntime
 	if in.Docker != nil {
 		in, out := &in.Docker, &out.Docker
 		*out = new(DockerConfig)
@@ -1166,6 +1210,15 @@ func autoConvert_kops_ClusterSpec_To_v1alpha1_ClusterSpec(in *kops.ClusterSpec,
 	} else {
 		out.Docker = nil
 	}
+	if in.Containerd != nil {
+		in, out := &in.Containerd, &out.Containerd
+		*out = new(ContainerdConfig)
+		if err := Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Containerd = nil
+	}
 	if in.KubeDNS != nil {
 		in, out := &in.KubeDNS, &out.KubeDNS
 		*out = new(KubeDNSConfig)
@@ -1341,9 +1394,148 @@ func autoConvert_kops_ClusterSpec_To_v1alpha1_ClusterSpec(in *kops.ClusterSpec,
 	} else {
 		out.Target = nil
 	}
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdate)
+		if err := Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ClusterValidationSpec)
+		if err := Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Validation = nil
+	}
+	out.RevisionHistoryLimit = in.RevisionHistoryLimit
+	return nil
+}
+
+func autoConvert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in *ClusterValidationSpec, out *kops.ClusterValidationSpec, s conversion.Scope) error {
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]kops.ValidationObjectRef, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Deployments = nil
+	}
+	if in.DaemonSets != nil {
+		in, out := &in.DaemonSets, &out.DaemonSets
+		*out = make([]kops.ValidationObjectRef, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DaemonSets = nil
+	}
+	out.CustomResourceDefinitions = in.CustomResourceDefinitions
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]kops.ValidationNodesSpec, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Nodes = nil
+	}
+	out.HTTPEndpoints = in.HTTPEndpoints
+	return nil
+}
+
+// Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec is an autogenerated conversion function.
+func Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in *ClusterValidationSpec, out *kops.ClusterValidationSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in, out, s)
+}
+
+func autoConvert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in *kops.ClusterValidationSpec, out *ClusterValidationSpec, s conversion.Scope) error {
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]ValidationObjectRef, len(*in))
+		for i := range *in {
+			if err := Convert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Deployments = nil
+	}
+	if in.DaemonSets != nil {
+		in, out := &in.DaemonSets, &out.DaemonSets
+		*out = make([]ValidationObjectRef, len(*in))
+		for i := range *in {
+			if err := Convert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DaemonSets = nil
+	}
+	out.CustomResourceDefinitions = in.CustomResourceDefinitions
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]ValidationNodesSpec, len(*in))
+		for i := range *in {
+			if err := Convert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Nodes = nil
+	}
+	out.HTTPEndpoints = in.HTTPEndpoints
+	return nil
+}
+
+// Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec is an autogenerated conversion function.
+func Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in *kops.ClusterValidationSpec, out *ClusterValidationSpec, s conversion.Scope) error {
+	return autoConvert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in *ContainerdConfig, out *kops.ContainerdConfig, s conversion.Scope) error {
+	out.Address = in.Address
+	out.ConfigOverride = in.ConfigOverride
+	out.LogLevel = in.LogLevel
+	out.Root = in.Root
+	out.RuncVersion = in.RuncVersion
+	out.State = in.State
+	out.Version = in.Version
+	return nil
+}
+
+// Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig is an autogenerated conversion function.
+func Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in *ContainerdConfig, out *kops.ContainerdConfig, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in, out, s)
+}
+
+func autoConvert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in *kops.ContainerdConfig, out *ContainerdConfig, s conversion.Scope) error {
+	out.Address = in.Address
+	out.ConfigOverride = in.ConfigOverride
+	out.LogLevel = in.LogLevel
+	out.Root = in.Root
+	out.RuncVersion = in.RuncVersion
+	out.State = in.State
+	out.Version = in.Version
 	return nil
 }
 
+// Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig is an autogenerated conversion function.
+func Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in *kops.ContainerdConfig, out *ContainerdConfig, s conversion.Scope) error {
+	return autoConvert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in, out, s)
+}
+
 func autoConvert_v1alpha1_DNSAccessSpec_To_kops_DNSAccessSpec(in *DNSAccessSpec, out *kops.DNSAccessSpec, s conversion.Scope) error {
 	return nil
 }
@@ -1440,6 +1632,34 @@ func Convert_kops_DockerConfig_To_v1alpha1_DockerConfig(in *kops.DockerConfig, o
 	return autoConvert_kops_DockerConfig_To_v1alpha1_DockerConfig(in, out, s)
 }
 
+func autoConvert_v1alpha1_DrainSpec_To_kops_DrainSpec(in *DrainSpec, out *kops.DrainSpec, s conversion.Scope) error {
+	out.Timeout = in.Timeout
+	out.GracePeriodSeconds = in.GracePeriodSeconds
+	out.DeleteLocalData = in.DeleteLocalData
+	out.SkipPodSelector = in.SkipPodSelector
+	out.BlockingPodSelector = in.BlockingPodSelector
+	return nil
+}
+
+// Convert_v1alpha1_DrainSpec_To_kops_DrainSpec is an autogenerated conversion function.
+func Convert_v1alpha1_DrainSpec_To_kops_DrainSpec(in *DrainSpec, out *kops.DrainSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_DrainSpec_To_kops_DrainSpec(in, out, s)
+}
+
+func autoConvert_kops_DrainSpec_To_v1alpha1_DrainSpec(in *kops.DrainSpec, out *DrainSpec, s conversion.Scope) error {
+	out.Timeout = in.Timeout
+	out.GracePeriodSeconds = in.GracePeriodSeconds
+	out.DeleteLocalData = in.DeleteLocalData
+	out.SkipPodSelector = in.SkipPodSelector
+	out.BlockingPodSelector = in.BlockingPodSelector
+	return nil
+}
+
+// Convert_kops_DrainSpec_To_v1alpha1_DrainSpec is an autogenerated conversion function.
+func Convert_kops_DrainSpec_To_v1alpha1_DrainSpec(in *kops.DrainSpec, out *DrainSpec, s conversion.Scope) error {
+	return autoConvert_kops_DrainSpec_To_v1alpha1_DrainSpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_EgressProxySpec_To_kops_EgressProxySpec(in *EgressProxySpec, out *kops.EgressProxySpec, s conversion.Scope) error {
 	if err := Convert_v1alpha1_HTTPProxy_To_kops_HTTPProxy(&in.HTTPProxy, &out.HTTPProxy, s); err != nil {
 		return err
@@ -2035,6 +2255,24 @@ func autoConvert_v1alpha1_InstanceGroupSpec_To_kops_InstanceGroupSpec(in *Instan
 		out.IAM = nil
 	}
 	out.SecurityGroupOverride = in.SecurityGroupOverride
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(kops.RollingUpdate)
+		if err := Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.MixedInstancesPolicy != nil {
+		in, out := &in.MixedInstancesPolicy, &out.MixedInstancesPolicy
+		*out = new(kops.MixedInstancesPolicySpec)
+		if err := Convert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.MixedInstancesPolicy = nil
+	}
 	return nil
 }
 
@@ -2122,6 +2360,24 @@ func autoConvert_kops_InstanceGroupSpec_To_v1alpha1_InstanceGroupSpec(in *kops.I
 		out.IAM = nil
 	}
 	out.SecurityGroupOverride = in.SecurityGroupOverride
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdate)
+		if err := Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.MixedInstancesPolicy != nil {
+		in, out := &in.MixedInstancesPolicy, &out.MixedInstancesPolicy
+		*out = new(MixedInstancesPolicySpec)
+		if err := Convert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.MixedInstancesPolicy = nil
+	}
 	return nil
 }
 
@@ -2187,6 +2443,7 @@ func autoConvert_v1alpha1_KubeAPIServerConfig_To_kops_KubeAPIServerConfig(in *Ku
 	out.TLSCertFile = in.TLSCertFile
 	out.TLSPrivateKeyFile = in.TLSPrivateKeyFile
 	out.TokenAuthFile = in.TokenAuthFile
+	out.ServiceAccountKeyFile = in.ServiceAccountKeyFile
 	out.AllowPrivileged = in.AllowPrivileged
 	out.APIServerCount = in.APIServerCount
 	out.RuntimeConfig = in.RuntimeConfig
@@ -2260,6 +2517,7 @@ func autoConvert_kops_KubeAPIServerConfig_To_v1alpha1_KubeAPIServerConfig(in *ko
 	out.TLSCertFile = in.TLSCertFile
 	out.TLSPrivateKeyFile = in.TLSPrivateKeyFile
 	out.TokenAuthFile = in.TokenAuthFile
+	out.ServiceAccountKeyFile = in.ServiceAccountKeyFile
 	out.AllowPrivileged = in.AllowPrivileged
 	out.APIServerCount = in.APIServerCount
 	out.RuntimeConfig = in.RuntimeConfig
@@ -2789,6 +3047,32 @@ func Convert_kops_LoadBalancerAccessSpec_To_v1alpha1_LoadBalancerAccessSpec(in *
 	return autoConvert_kops_LoadBalancerAccessSpec_To_v1alpha1_LoadBalancerAccessSpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec(in *MixedInstancesPolicySpec, out *kops.MixedInstancesPolicySpec, s conversion.Scope) error {
+	out.Instances = in.Instances
+	out.OnDemandBase = in.OnDemandBase
+	out.OnDemandAboveBase = in.OnDemandAboveBase
+	out.SpotAllocationStrategy = in.SpotAllocationStrategy
+	return nil
+}
+
+// Convert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec is an autogenerated conversion function.
+func Convert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec(in *MixedInstancesPolicySpec, out *kops.MixedInstancesPolicySpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_MixedInstancesPolicySpec_To_kops_MixedInstancesPolicySpec(in, out, s)
+}
+
+func autoConvert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec(in *kops.MixedInstancesPolicySpec, out *MixedInstancesPolicySpec, s conversion.Scope) error {
+	out.Instances = in.Instances
+	out.OnDemandBase = in.OnDemandBase
+	out.OnDemandAboveBase = in.OnDemandAboveBase
+	out.SpotAllocationStrategy = in.SpotAllocationStrategy
+	return nil
+}
+
+// Convert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec is an autogenerated conversion function.
+func Convert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec(in *kops.MixedInstancesPolicySpec, out *MixedInstancesPolicySpec, s conversion.Scope) error {
+	return autoConvert_kops_MixedInstancesPolicySpec_To_v1alpha1_MixedInstancesPolicySpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_NetworkingSpec_To_kops_NetworkingSpec(in *NetworkingSpec, out *kops.NetworkingSpec, s conversion.Scope) error {
 	if in.Classic != nil {
 		in, out := &in.Classic, &out.Classic
@@ -3127,6 +3411,46 @@ func Convert_kops_RBACAuthorizationSpec_To_v1alpha1_RBACAuthorizationSpec(in *ko
 	return autoConvert_kops_RBACAuthorizationSpec_To_v1alpha1_RBACAuthorizationSpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in *RollingUpdate, out *kops.RollingUpdate, s conversion.Scope) error {
+	out.MaxUnavailable = in.MaxUnavailable
+	out.MaxSurge = in.MaxSurge
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(kops.DrainSpec)
+		if err := Convert_v1alpha1_DrainSpec_To_kops_DrainSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Drain = nil
+	}
+	return nil
+}
+
+// Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate is an autogenerated conversion function.
+func Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in *RollingUpdate, out *kops.RollingUpdate, s conversion.Scope) error {
+	return autoConvert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in, out, s)
+}
+
+func autoConvert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in *kops.RollingUpdate, out *RollingUpdate, s conversion.Scope) error {
+	out.MaxUnavailable = in.MaxUnavailable
+	out.MaxSurge = in.MaxSurge
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(DrainSpec)
+		if err := Convert_kops_DrainSpec_To_v1alpha1_DrainSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Drain = nil
+	}
+	return nil
+}
+
+// Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate is an autogenerated conversion function.
+func Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in *kops.RollingUpdate, out *RollingUpdate, s conversion.Scope) error {
+	return autoConvert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in, out, s)
+}
+
 func autoConvert_v1alpha1_RomanaNetworkingSpec_To_kops_RomanaNetworkingSpec(in *RomanaNetworkingSpec, out *kops.RomanaNetworkingSpec, s conversion.Scope) error {
 	out.DaemonServiceIP = in.DaemonServiceIP
 	out.EtcdServiceIP = in.EtcdServiceIP
@@ -3275,6 +3599,7 @@ func Convert_kops_TargetSpec_To_v1alpha1_TargetSpec(in *kops.TargetSpec, out *Ta
 
 func autoConvert_v1alpha1_TerraformSpec_To_kops_TerraformSpec(in *TerraformSpec, out *kops.TerraformSpec, s conversion.Scope) error {
 	out.ProviderExtraConfig = in.ProviderExtraConfig
+	out.Version = in.Version
 	return nil
 }
 
@@ -3285,6 +3610,7 @@ func Convert_v1alpha1_TerraformSpec_To_kops_TerraformSpec(in *TerraformSpec, out
 
 func autoConvert_kops_TerraformSpec_To_v1alpha1_TerraformSpec(in *kops.TerraformSpec, out *TerraformSpec, s conversion.Scope) error {
 	out.ProviderExtraConfig = in.ProviderExtraConfig
+	out.Version = in.Version
 	return nil
 }
 
@@ -3317,6 +3643,50 @@ func Convert_kops_UserData_To_v1alpha1_UserData(in *kops.UserData, out *UserData
 	return autoConvert_kops_UserData_To_v1alpha1_UserData(in, out, s)
 }
 
+func autoConvert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec(in *ValidationNodesSpec, out *kops.ValidationNodesSpec, s conversion.Scope) error {
+	out.Selector = in.Selector
+	out.MinReady = in.MinReady
+	return nil
+}
+
+// Convert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec is an autogenerated conversion function.
+func Convert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec(in *ValidationNodesSpec, out *kops.ValidationNodesSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ValidationNodesSpec_To_kops_ValidationNodesSpec(in, out, s)
+}
+
+func autoConvert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec(in *kops.ValidationNodesSpec, out *ValidationNodesSpec, s conversion.Scope) error {
+	out.Selector = in.Selector
+	out.MinReady = in.MinReady
+	return nil
+}
+
+// Convert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec is an autogenerated conversion function.
+func Convert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec(in *kops.ValidationNodesSpec, out *ValidationNodesSpec, s conversion.Scope) error {
+	return autoConvert_kops_ValidationNodesSpec_To_v1alpha1_ValidationNodesSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef(in *ValidationObjectRef, out *kops.ValidationObjectRef, s conversion.Scope) error {
+	out.Namespace = in.Namespace
+	out.Name = in.Name
+	return nil
+}
+
+// Convert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef is an autogenerated conversion function.
+func Convert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef(in *ValidationObjectRef, out *kops.ValidationObjectRef, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ValidationObjectRef_To_kops_ValidationObjectRef(in, out, s)
+}
+
+func autoConvert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef(in *kops.ValidationObjectRef, out *ValidationObjectRef, s conversion.Scope) error {
+	out.Namespace = in.Namespace
+	out.Name = in.Name
+	return nil
+}
+
+// Convert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef is an autogenerated conversion function.
+func Convert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef(in *kops.ValidationObjectRef, out *ValidationObjectRef, s conversion.Scope) error {
+	return autoConvert_kops_ValidationObjectRef_To_v1alpha1_ValidationObjectRef(in, out, s)
+}
+
 func autoConvert_v1alpha1_WeaveNetworkingSpec_To_kops_WeaveNetworkingSpec(in *WeaveNetworkingSpec, out *kops.WeaveNetworkingSpec, s conversion.Scope) error {
 	out.MTU = in.MTU
 	out.ConnLimit = in.ConnLimit
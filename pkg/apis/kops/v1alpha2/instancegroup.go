@@ -113,6 +113,22 @@ type InstanceGroupSpec struct {
 	IAM *IAMProfileSpec `json:"iam,omitempty"`
 	// SecurityGroupOverride overrides the default security group created by Kops for this IG (AWS only).
 	SecurityGroupOverride *string `json:"securityGroupOverride,omitempty"`
+	// RollingUpdate defines the rolling-update behavior, overriding the cluster-wide defaults
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+	// MixedInstancesPolicy defines a mix of instance types and on-demand / spot capacity for the group (AWS only)
+	MixedInstancesPolicy *MixedInstancesPolicySpec `json:"mixedInstancesPolicy,omitempty"`
+}
+
+// MixedInstancesPolicySpec defines the instance types and purchase options of a group with mixed instances
+type MixedInstancesPolicySpec struct {
+	// Instances is a list of instance types which the group can launch; machineType is used if this is empty
+	Instances []string `json:"instances,omitempty"`
+	// OnDemandBase is the minimum number of on-demand instances in the group, before any spot instances are launched
+	OnDemandBase *int64 `json:"onDemandBase,omitempty"`
+	// OnDemandAboveBase is the percentage of on-demand instances (0-100) in the capacity above OnDemandBase
+	OnDemandAboveBase *int64 `json:"onDemandAboveBase,omitempty"`
+	// SpotAllocationStrategy is how spot instances are allocated across instance types and zones (e.g. lowest-price)
+	SpotAllocationStrategy *string `json:"spotAllocationStrategy,omitempty"`
 }
 
 // UserData defines a user-data section
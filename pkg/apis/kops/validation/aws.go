@@ -48,6 +48,12 @@ func awsValidateInstanceGroup(ig *kops.InstanceGroup) field.ErrorList {
 
 	allErrs = append(allErrs, awsValidateAMIforNVMe(field.NewPath(ig.GetName(), "spec", "machineType"), ig)...)
 
+	if ig.Spec.MixedInstancesPolicy != nil {
+		for i, instance := range ig.Spec.MixedInstancesPolicy.Instances {
+			allErrs = append(allErrs, awsValidateMachineType(field.NewPath(ig.GetName(), "spec", "mixedInstancesPolicy", "instances").Index(i), instance)...)
+		}
+	}
+
 	return allErrs
 }
 
@@ -19,7 +19,9 @@ package validation
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/apis/kops/util"
@@ -91,9 +93,52 @@ func ValidateInstanceGroup(g *kops.InstanceGroup) error {
 		return err
 	}
 
+	if g.Spec.RollingUpdate != nil {
+		if errs := validateRollingUpdate(g.Spec.RollingUpdate, field.NewPath("rollingUpdate")); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+	}
+
+	if g.Spec.MixedInstancesPolicy != nil {
+		if errs := validateMixedInstancesPolicy(g.Spec.MixedInstancesPolicy, field.NewPath("spec", "mixedInstancesPolicy")); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+	}
+
 	return nil
 }
 
+// validateMixedInstancesPolicy checks the instance types and purchase options of a mixed instances policy
+func validateMixedInstancesPolicy(spec *kops.MixedInstancesPolicySpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	instances := sets.NewString()
+	for i, instance := range spec.Instances {
+		if strings.TrimSpace(instance) == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("instances").Index(i), instance, "instance type cannot be empty"))
+			continue
+		}
+		if instances.Has(instance) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("instances").Index(i), instance))
+		}
+		instances.Insert(instance)
+	}
+
+	if spec.OnDemandBase != nil && *spec.OnDemandBase < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("onDemandBase"), *spec.OnDemandBase, "onDemandBase cannot be negative"))
+	}
+
+	if spec.OnDemandAboveBase != nil && (*spec.OnDemandAboveBase < 0 || *spec.OnDemandAboveBase > 100) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("onDemandAboveBase"), *spec.OnDemandAboveBase, "onDemandAboveBase must be a percentage between 0 and 100"))
+	}
+
+	if spec.SpotAllocationStrategy != nil && !sets.NewString(kops.SpotAllocationStrategies...).Has(*spec.SpotAllocationStrategy) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("spotAllocationStrategy"), *spec.SpotAllocationStrategy, kops.SpotAllocationStrategies))
+	}
+
+	return allErrs
+}
+
 // CrossValidateInstanceGroup performs validation of the instance group, including that it is consistent with the Cluster
 // It calls ValidateInstanceGroup, so all that validation is included.
 func CrossValidateInstanceGroup(g *kops.InstanceGroup, cluster *kops.Cluster, strict bool) error {
@@ -128,6 +173,14 @@ func CrossValidateInstanceGroup(g *kops.InstanceGroup, cluster *kops.Cluster, st
 	allErrs := field.ErrorList{}
 	fieldPath := field.NewPath("InstanceGroup")
 
+	if g.Spec.MixedInstancesPolicy != nil && kops.CloudProviderID(cluster.Spec.CloudProvider) != kops.CloudProviderAWS {
+		allErrs = append(allErrs, field.Forbidden(fieldPath.Child("Spec").Child("MixedInstancesPolicy"), "mixedInstancesPolicy is only supported on AWS"))
+	}
+
+	if g.Spec.RollingUpdate != nil {
+		allErrs = append(allErrs, validateRollingUpdateSurge(g.Spec.RollingUpdate, cluster.Spec.CloudProvider, fieldPath.Child("Spec").Child("RollingUpdate"))...)
+	}
+
 	if k8sVersion.Major == 1 && k8sVersion.Minor <= 5 {
 		if len(g.Spec.Taints) > 0 {
 			if !(g.IsMaster() && g.Spec.Taints[0] == kops.TaintNoScheduleMaster15 && len(g.Spec.Taints) == 1) {
@@ -127,3 +127,54 @@ func TestValidateInstanceProfile(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateMixedInstancesPolicy(t *testing.T) {
+	grid := []struct {
+		Input          *kops.MixedInstancesPolicySpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				Instances:              []string{"m4.large", "m5.large"},
+				OnDemandBase:           fi.Int64(1),
+				OnDemandAboveBase:      fi.Int64(50),
+				SpotAllocationStrategy: s("lowest-price"),
+			},
+		},
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				Instances: []string{"m4.large", ""},
+			},
+			ExpectedErrors: []string{"Invalid value::MixedInstancesPolicy.instances[1]"},
+		},
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				Instances: []string{"m4.large", "m4.large"},
+			},
+			ExpectedErrors: []string{"Duplicate value::MixedInstancesPolicy.instances[1]"},
+		},
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				OnDemandBase: fi.Int64(-1),
+			},
+			ExpectedErrors: []string{"Invalid value::MixedInstancesPolicy.onDemandBase"},
+		},
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				OnDemandAboveBase: fi.Int64(101),
+			},
+			ExpectedErrors: []string{"Invalid value::MixedInstancesPolicy.onDemandAboveBase"},
+		},
+		{
+			Input: &kops.MixedInstancesPolicySpec{
+				SpotAllocationStrategy: s("cheapest"),
+			},
+			ExpectedErrors: []string{"Unsupported value::MixedInstancesPolicy.spotAllocationStrategy"},
+		},
+	}
+
+	for _, g := range grid {
+		errs := validateMixedInstancesPolicy(g.Input, field.NewPath("MixedInstancesPolicy"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
@@ -19,9 +19,12 @@ package validation
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -110,6 +113,25 @@ func validateClusterSpec(spec *kops.ClusterSpec, fieldPath *field.Path) field.Er
 		}
 	}
 
+	if spec.RollingUpdate != nil {
+		allErrs = append(allErrs, validateRollingUpdate(spec.RollingUpdate, fieldPath.Child("rollingUpdate"))...)
+		allErrs = append(allErrs, validateRollingUpdateSurge(spec.RollingUpdate, spec.CloudProvider, fieldPath.Child("rollingUpdate"))...)
+	}
+
+	if spec.Validation != nil {
+		allErrs = append(allErrs, ValidateClusterValidationSpec(spec.Validation, fieldPath.Child("validation"))...)
+	}
+
+	allErrs = append(allErrs, validateRevisionHistoryLimit(spec.RevisionHistoryLimit, fieldPath.Child("revisionHistoryLimit"))...)
+
+	if spec.ContainerRuntime != "" {
+		allErrs = append(allErrs, validateContainerRuntime(spec, fieldPath)...)
+	}
+
+	if spec.Target != nil && spec.Target.Terraform != nil && spec.Target.Terraform.Version != "" {
+		allErrs = append(allErrs, IsValidValue(fieldPath.Child("target", "terraform", "version"), &spec.Target.Terraform.Version, kops.SupportedTerraformVersions)...)
+	}
+
 	return allErrs
 }
 
@@ -341,3 +363,152 @@ func validateEtcdClusterSpec(spec *kops.EtcdClusterSpec, fieldPath *field.Path)
 
 	return errs
 }
+
+func validateRollingUpdate(rollingUpdate *kops.RollingUpdate, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if rollingUpdate.MaxUnavailable != nil {
+		unavailable, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxUnavailable, 1, false)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("MaxUnavailable"), rollingUpdate.MaxUnavailable.String(), fmt.Sprintf("Unable to parse: %v", err)))
+		} else if unavailable < 0 {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("MaxUnavailable"), rollingUpdate.MaxUnavailable.String(), "Cannot be negative"))
+		}
+	}
+
+	if rollingUpdate.MaxSurge != nil {
+		surge, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, 1000, true)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("MaxSurge"), rollingUpdate.MaxSurge.String(), fmt.Sprintf("Unable to parse: %v", err)))
+		} else if surge < 0 {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("MaxSurge"), rollingUpdate.MaxSurge.String(), "Cannot be negative"))
+		}
+	}
+
+	if rollingUpdate.Drain != nil {
+		allErrs = append(allErrs, validateDrain(rollingUpdate.Drain, fldpath.Child("drain"))...)
+	}
+
+	return allErrs
+}
+
+// validateRollingUpdateSurge checks that maxSurge is only set on clouds which can detach instances from their group
+func validateRollingUpdateSurge(rollingUpdate *kops.RollingUpdate, cloudProvider string, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if rollingUpdate.MaxSurge != nil && kops.CloudProviderID(cloudProvider) != kops.CloudProviderAWS {
+		surge, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, 1000, true)
+		if err == nil && surge > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldpath.Child("MaxSurge"), "maxSurge is only supported on AWS"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateDrain(drain *kops.DrainSpec, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if drain.Timeout != nil && drain.Timeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldpath.Child("timeout"), drain.Timeout.Duration.String(), "Cannot be negative"))
+	}
+
+	if drain.GracePeriodSeconds != nil && *drain.GracePeriodSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldpath.Child("gracePeriodSeconds"), *drain.GracePeriodSeconds, "Cannot be negative"))
+	}
+
+	if drain.SkipPodSelector != "" {
+		if _, err := labels.Parse(drain.SkipPodSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("skipPodSelector"), drain.SkipPodSelector, fmt.Sprintf("Unable to parse: %v", err)))
+		}
+	}
+
+	if drain.BlockingPodSelector != "" {
+		if _, err := labels.Parse(drain.BlockingPodSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("blockingPodSelector"), drain.BlockingPodSelector, fmt.Sprintf("Unable to parse: %v", err)))
+		}
+	}
+
+	return allErrs
+}
+
+// ValidateClusterValidationSpec checks the extra cluster validation checks, from the cluster spec or the validation file in the state store
+func ValidateClusterValidationSpec(spec *kops.ClusterValidationSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, ref := range spec.Deployments {
+		allErrs = append(allErrs, validateValidationObjectRef(ref, fldPath.Child("deployments").Index(i))...)
+	}
+
+	for i, ref := range spec.DaemonSets {
+		allErrs = append(allErrs, validateValidationObjectRef(ref, fldPath.Child("daemonSets").Index(i))...)
+	}
+
+	for i, name := range spec.CustomResourceDefinitions {
+		// CRDs are named <plural>.<group>
+		if !strings.Contains(name, ".") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("customResourceDefinitions").Index(i), name, "Must be of the form <plural>.<group>"))
+		}
+	}
+
+	for i, nodes := range spec.Nodes {
+		nodesPath := fldPath.Child("nodes").Index(i)
+		if _, err := labels.Parse(nodes.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(nodesPath.Child("selector"), nodes.Selector, fmt.Sprintf("Unable to parse: %v", err)))
+		}
+		if nodes.MinReady < 0 {
+			allErrs = append(allErrs, field.Invalid(nodesPath.Child("minReady"), nodes.MinReady, "Cannot be negative"))
+		}
+	}
+
+	for i, endpoint := range spec.HTTPEndpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("httpEndpoints").Index(i), endpoint, "Must be an http or https URL"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateValidationObjectRef(ref kops.ValidationObjectRef, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+
+	return allErrs
+}
+
+func validateContainerRuntime(spec *kops.ClusterSpec, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, IsValidValue(fieldPath.Child("containerRuntime"), &spec.ContainerRuntime, kops.SupportedContainerRuntimes)...)
+
+	if spec.ContainerRuntime == kops.ContainerRuntimeContainerd {
+		// Hooks and the node authorizer are run with the docker cli, which is not installed alongside containerd
+		for i, hook := range spec.Hooks {
+			if hook.ExecContainer != nil && !hook.Disabled {
+				allErrs = append(allErrs, field.Forbidden(fieldPath.Child("hooks").Index(i).Child("execContainer"), "execContainer hooks are not supported when containerRuntime is containerd"))
+			}
+		}
+		if spec.NodeAuthorization != nil {
+			allErrs = append(allErrs, field.Forbidden(fieldPath.Child("nodeAuthorization"), "nodeAuthorization is not supported when containerRuntime is containerd"))
+		}
+		// kubenet and classic networking are implemented by the kubelet's docker shim
+		if spec.Networking != nil && (spec.Networking.Kubenet != nil || spec.Networking.Classic != nil) {
+			allErrs = append(allErrs, field.Forbidden(fieldPath.Child("networking"), "containerd requires a CNI networking provider"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateRevisionHistoryLimit(limit *int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if limit != nil && *limit < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath, *limit, "Must be at least 1"))
+	}
+	return allErrs
+}
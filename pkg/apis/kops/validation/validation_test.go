@@ -18,11 +18,15 @@ package validation
 
 import (
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
 )
 
 func Test_Validate_DNS(t *testing.T) {
@@ -291,3 +295,259 @@ func Test_Validate_AdditionalPolicies(t *testing.T) {
 		testErrors(t, g.Input, errs, g.ExpectedErrors)
 	}
 }
+
+func Test_Validate_RollingUpdate(t *testing.T) {
+	intStr := func(v intstr.IntOrString) *intstr.IntOrString { return &v }
+
+	grid := []struct {
+		Input          kops.RollingUpdate
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.RollingUpdate{},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromInt(0)),
+				MaxSurge:       intStr(intstr.FromInt(0)),
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromString("25%")),
+				MaxSurge:       intStr(intstr.FromString("100%")),
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromString("nope")),
+			},
+			ExpectedErrors: []string{"Invalid value::spec.rollingUpdate.MaxUnavailable"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromInt(-1)),
+			},
+			ExpectedErrors: []string{"Invalid value::spec.rollingUpdate.MaxUnavailable"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("nope")),
+			},
+			ExpectedErrors: []string{"Invalid value::spec.rollingUpdate.MaxSurge"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("-10%")),
+			},
+			ExpectedErrors: []string{"Invalid value::spec.rollingUpdate.MaxSurge"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Drain: &kops.DrainSpec{
+					Timeout:             &metav1.Duration{Duration: 5 * time.Minute},
+					GracePeriodSeconds:  fi.Int64(30),
+					SkipPodSelector:     "app=batch",
+					BlockingPodSelector: "app in (database, queue)",
+				},
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Drain: &kops.DrainSpec{
+					Timeout:            &metav1.Duration{Duration: -time.Minute},
+					GracePeriodSeconds: fi.Int64(-1),
+				},
+			},
+			ExpectedErrors: []string{
+				"Invalid value::spec.rollingUpdate.drain.timeout",
+				"Invalid value::spec.rollingUpdate.drain.gracePeriodSeconds",
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Drain: &kops.DrainSpec{
+					SkipPodSelector:     "app in (web",
+					BlockingPodSelector: "!!",
+				},
+			},
+			ExpectedErrors: []string{
+				"Invalid value::spec.rollingUpdate.drain.skipPodSelector",
+				"Invalid value::spec.rollingUpdate.drain.blockingPodSelector",
+			},
+		},
+	}
+	for _, g := range grid {
+		errs := validateRollingUpdate(&g.Input, field.NewPath("spec").Child("rollingUpdate"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_RollingUpdateSurge(t *testing.T) {
+	intStr := func(v intstr.IntOrString) *intstr.IntOrString { return &v }
+
+	grid := []struct {
+		CloudProvider  string
+		Input          kops.RollingUpdate
+		ExpectedErrors []string
+	}{
+		{
+			CloudProvider: "aws",
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("25%")),
+			},
+		},
+		{
+			CloudProvider: "gce",
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromInt(0)),
+			},
+		},
+		{
+			CloudProvider: "gce",
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromInt(1)),
+			},
+			ExpectedErrors: []string{"Forbidden::spec.rollingUpdate.MaxSurge"},
+		},
+		{
+			CloudProvider: "openstack",
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("10%")),
+			},
+			ExpectedErrors: []string{"Forbidden::spec.rollingUpdate.MaxSurge"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateRollingUpdateSurge(&g.Input, g.CloudProvider, field.NewPath("spec").Child("rollingUpdate"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_ClusterValidation(t *testing.T) {
+	grid := []struct {
+		Input          kops.ClusterValidationSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.ClusterValidationSpec{},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				Deployments:               []kops.ValidationObjectRef{{Namespace: "ingress", Name: "nginx-ingress"}},
+				DaemonSets:                []kops.ValidationObjectRef{{Name: "fluentd"}},
+				CustomResourceDefinitions: []string{"certificates.certmanager.k8s.io"},
+				Nodes:                     []kops.ValidationNodesSpec{{Selector: "kops.k8s.io/instancegroup=nodes", MinReady: 3}},
+				HTTPEndpoints:             []string{"https://app.example.com/healthz"},
+			},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				Deployments: []kops.ValidationObjectRef{{Namespace: "ingress"}},
+				DaemonSets:  []kops.ValidationObjectRef{{}},
+			},
+			ExpectedErrors: []string{
+				"Required value::spec.validation.deployments[0].name",
+				"Required value::spec.validation.daemonSets[0].name",
+			},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				CustomResourceDefinitions: []string{"certificates"},
+				Nodes:                     []kops.ValidationNodesSpec{{Selector: "app in (web", MinReady: -1}},
+				HTTPEndpoints:             []string{"app.example.com/healthz"},
+			},
+			ExpectedErrors: []string{
+				"Invalid value::spec.validation.customResourceDefinitions[0]",
+				"Invalid value::spec.validation.nodes[0].selector",
+				"Invalid value::spec.validation.nodes[0].minReady",
+				"Invalid value::spec.validation.httpEndpoints[0]",
+			},
+		},
+	}
+	for _, g := range grid {
+		errs := ValidateClusterValidationSpec(&g.Input, field.NewPath("spec").Child("validation"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_ContainerRuntime(t *testing.T) {
+	grid := []struct {
+		Input          kops.ClusterSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "docker",
+			},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Hooks: []kops.HookSpec{
+					{Manifest: "Type=oneshot"},
+					{ExecContainer: &kops.ExecContainerAction{Image: "busybox"}, Disabled: true},
+				},
+			},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "rkt",
+			},
+			ExpectedErrors: []string{"Unsupported value::spec.containerRuntime"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Hooks: []kops.HookSpec{
+					{ExecContainer: &kops.ExecContainerAction{Image: "busybox"}},
+				},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.hooks[0].execContainer"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime:  "containerd",
+				NodeAuthorization: &kops.NodeAuthorizationSpec{},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.nodeAuthorization"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Networking: &kops.NetworkingSpec{
+					Kubenet: &kops.KubenetNetworkingSpec{},
+				},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.networking"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateContainerRuntime(&g.Input, field.NewPath("spec"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_RevisionHistoryLimit(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	grid := []struct {
+		Input          *int32
+		ExpectedErrors []string
+	}{
+		{
+			Input: nil,
+		},
+		{
+			Input: int32Ptr(10),
+		},
+		{
+			Input:          int32Ptr(0),
+			ExpectedErrors: []string{"Invalid value::spec.revisionHistoryLimit"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateRevisionHistoryLimit(g.Input, field.NewPath("spec").Child("revisionHistoryLimit"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
@@ -290,7 +290,7 @@ func (a *AssetBuilder) findHash(file *FileAsset) (*hashing.Hash, error) {
 		return nil, fmt.Errorf("file url is not defined")
 	}
 
-	for _, ext := range []string{".sha1"} {
+	for _, ext := range []string{".sha1", ".sha256", ".sha256sum"} {
 		hashURL := u.String() + ext
 		b, err := vfs.Context.ReadFile(hashURL)
 		if err != nil {
@@ -33,12 +33,16 @@ import (
 type VFSClientset struct {
 	basePath  vfs.Path
 	allowList bool
+	// skipRevisions stops writes recording revisions; see WithoutRevisions
+	skipRevisions bool
 }
 
 var _ simple.Clientset = &VFSClientset{}
 
 func (c *VFSClientset) clusters() *ClusterVFS {
-	return newClusterVFS(c.basePath)
+	r := newClusterVFS(c.basePath)
+	r.skipRevisions = c.skipRevisions
+	return r
 }
 
 // GetCluster implements the GetCluster method of simple.Clientset for a VFS-backed state store
@@ -120,6 +124,9 @@ func DeleteAllClusterState(basePath vfs.Path) error {
 		if relativePath == "config" || relativePath == "cluster.spec" {
 			continue
 		}
+		if relativePath == registry.PathPolicy || relativePath == registry.PathLock || relativePath == registry.PathRollingUpdate || relativePath == registry.PathValidation {
+			continue
+		}
 		if strings.HasPrefix(relativePath, "addons/") {
 			continue
 		}
@@ -135,6 +142,9 @@ func DeleteAllClusterState(basePath vfs.Path) error {
 		if strings.HasPrefix(relativePath, "manifests/") {
 			continue
 		}
+		if strings.HasPrefix(relativePath, "revisions/") {
+			continue
+		}
 		// TODO: offer an option _not_ to delete backups?
 		if strings.HasPrefix(relativePath, "backups/") {
 			continue
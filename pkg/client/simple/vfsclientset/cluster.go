@@ -38,6 +38,9 @@ import (
 
 type ClusterVFS struct {
 	commonVFS
+
+	// skipRevisions stops writes recording revisions
+	skipRevisions bool
 }
 
 func newClusterVFS(basePath vfs.Path) *ClusterVFS {
@@ -118,6 +121,10 @@ func (r *ClusterVFS) Create(c *api.Cluster) (*api.Cluster, error) {
 		return nil, fmt.Errorf("error writing Cluster %q: %v", c.ObjectMeta.Name, err)
 	}
 
+	if !r.skipRevisions {
+		recordRevision(r.basePath.Join(clusterName), c, "create Cluster")
+	}
+
 	return c, nil
 }
 
@@ -141,12 +148,16 @@ func (r *ClusterVFS) Update(c *api.Cluster, status *api.ClusterStatus) (*api.Clu
 	}
 
 	if err := r.writeConfig(c, r.basePath.Join(clusterName, registry.PathCluster), c, vfs.WriteOptionOnlyIfExists); err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) || errors.IsConflict(err) {
 			return nil, err
 		}
 		return nil, fmt.Errorf("error writing Cluster: %v", err)
 	}
 
+	if !r.skipRevisions {
+		recordRevision(r.basePath.Join(clusterName), c, "update Cluster")
+	}
+
 	return c, nil
 }
 
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -118,7 +119,7 @@ func (c *commonVFS) serialize(o runtime.Object) ([]byte, error) {
 }
 
 func (c *commonVFS) readConfig(configPath vfs.Path) (runtime.Object, error) {
-	data, err := configPath.ReadFile()
+	data, version, err := vfs.ReadFileWithVersion(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, err
@@ -130,22 +131,52 @@ func (c *commonVFS) readConfig(configPath vfs.Path) (runtime.Object, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error parsing %s: %v", configPath, err)
 	}
+
+	// The version of the file is the ResourceVersion, so that an update can check the object is unchanged
+	objectMeta, err := meta.Accessor(object)
+	if err != nil {
+		return nil, err
+	}
+	objectMeta.SetResourceVersion(version)
+
 	return object, nil
 }
 
 func (c *commonVFS) writeConfig(cluster *kops.Cluster, configPath vfs.Path, o runtime.Object, writeOptions ...vfs.WriteOption) error {
+	objectMeta, err := meta.Accessor(o)
+	if err != nil {
+		return err
+	}
+
+	// The ResourceVersion is the version of the file, so it is not stored in the file
+	resourceVersion := objectMeta.GetResourceVersion()
+	objectMeta.SetResourceVersion("")
 	data, err := c.serialize(o)
+	objectMeta.SetResourceVersion(resourceVersion)
 	if err != nil {
 		return fmt.Errorf("error marshalling object: %v", err)
 	}
 
 	create := false
+	ifMatch := false
 	for _, writeOption := range writeOptions {
 		switch writeOption {
 		case vfs.WriteOptionCreate:
 			create = true
 		case vfs.WriteOptionOnlyIfExists:
-			_, err = configPath.ReadFile()
+			if resourceVersion != "" {
+				// WriteFileIfMatch also fails if the file does not exist
+				ifMatch = true
+				continue
+			}
+			if _, ok := configPath.(vfs.VersionedPath); ok {
+				// Without a version from the caller, we write over the current version
+				_, resourceVersion, err = vfs.ReadFileWithVersion(configPath)
+				ifMatch = err == nil
+			} else {
+				glog.Warningf("%s does not support conditional writes; writing it unconditionally, which may overwrite a concurrent change", configPath)
+				_, err = configPath.ReadFile()
+			}
 			if err != nil {
 				if os.IsNotExist(err) {
 					return fmt.Errorf("cannot update configuration file %s: does not exist", configPath)
@@ -162,9 +193,21 @@ func (c *commonVFS) writeConfig(cluster *kops.Cluster, configPath vfs.Path, o ru
 		return err
 	}
 
+	// A plain write, such as a mirror, is not of the file the ResourceVersion refers to, so it keeps the ResourceVersion
+	newVersion := objectMeta.GetResourceVersion()
 	rs := bytes.NewReader(data)
 	if create {
-		err = configPath.CreateFile(rs, acl)
+		if _, ok := configPath.(vfs.VersionedPath); ok {
+			newVersion, err = vfs.WriteFileIfMatch(configPath, rs, acl, "")
+			if vfs.IsVersionConflict(err) {
+				err = os.ErrExist
+			}
+		} else {
+			newVersion = ""
+			err = configPath.CreateFile(rs, acl)
+		}
+	} else if ifMatch {
+		newVersion, err = vfs.WriteFileIfMatch(configPath, rs, acl, resourceVersion)
 	} else {
 		err = configPath.WriteFile(rs, acl)
 	}
@@ -173,8 +216,13 @@ func (c *commonVFS) writeConfig(cluster *kops.Cluster, configPath vfs.Path, o ru
 			glog.Warningf("failed to create file as already exists: %v", configPath)
 			return err
 		}
+		if vfs.IsVersionConflict(err) {
+			return errors.NewConflict(schema.GroupResource{Group: kops.GroupName, Resource: c.kind}, objectMeta.GetName(), err)
+		}
 		return fmt.Errorf("error writing configuration file %s: %v", configPath, err)
 	}
+
+	objectMeta.SetResourceVersion(newVersion)
 	return nil
 }
 
@@ -198,6 +246,9 @@ func (c *commonVFS) update(cluster *kops.Cluster, i runtime.Object) error {
 
 	err = c.writeConfig(cluster, c.basePath.Join(objectMeta.GetName()), i, vfs.WriteOptionOnlyIfExists)
 	if err != nil {
+		if errors.IsConflict(err) {
+			return err
+		}
 		return fmt.Errorf("error writing %s: %v", c.kind, err)
 	}
 
@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfsclientset
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestWriteConfigResourceVersion(t *testing.T) {
+	cluster := &kops.Cluster{}
+	cluster.ObjectMeta.Name = "example.com"
+	client := NewInstanceGroupMirror(cluster, vfs.NewMemFSPath(vfs.NewMemFSContext(), "state/example.com")).(*InstanceGroupVFS)
+
+	ig := &kops.InstanceGroup{}
+	ig.ObjectMeta.Name = "nodes"
+	ig.Spec.Role = kops.InstanceGroupRoleNode
+	if _, err := client.Create(ig); err != nil {
+		t.Fatalf("error creating instance group: %v", err)
+	}
+
+	stale, err := client.Get("nodes", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error reading instance group: %v", err)
+	}
+	if ig.ObjectMeta.ResourceVersion == "" || ig.ObjectMeta.ResourceVersion != stale.ObjectMeta.ResourceVersion {
+		t.Fatalf("expected created version %q to match stored version %q", ig.ObjectMeta.ResourceVersion, stale.ObjectMeta.ResourceVersion)
+	}
+
+	// The version after an update is the stored version, so the object can be updated again
+	for _, image := range []string{"image-a", "image-b"} {
+		ig.Spec.Image = image
+		if _, err := client.Update(ig); err != nil {
+			t.Fatalf("error updating instance group: %v", err)
+		}
+	}
+
+	stale.Spec.Image = "other"
+	if _, err := client.Update(stale); !errors.IsConflict(err) {
+		t.Fatalf("expected conflict updating a stale instance group, got %v", err)
+	}
+}
@@ -39,6 +39,10 @@ type InstanceGroupVFS struct {
 
 	clusterName string
 	cluster     *kops.Cluster
+
+	// clusterBase is where revisions are recorded; it is nil for mirrors, and for clientsets
+	// without revisions, which don't record revisions
+	clusterBase vfs.Path
 }
 
 type InstanceGroupMirror interface {
@@ -80,6 +84,9 @@ func newInstanceGroupVFS(c *VFSClientset, cluster *kops.Cluster) *InstanceGroupV
 		cluster:     cluster,
 		clusterName: clusterName,
 	}
+	if !c.skipRevisions {
+		r.clusterBase = c.basePath.Join(clusterName)
+	}
 	r.init(kind, c.basePath.Join(clusterName, "instancegroup"), StoreVersion)
 	defaultReadVersion := v1alpha1.SchemeGroupVersion.WithKind(kind)
 	r.defaultReadVersion = &defaultReadVersion
@@ -134,6 +141,7 @@ func (c *InstanceGroupVFS) Create(g *api.InstanceGroup) (*api.InstanceGroup, err
 	if err != nil {
 		return nil, err
 	}
+	c.recordRevision("create InstanceGroup " + g.ObjectMeta.Name)
 	return g, nil
 }
 
@@ -142,6 +150,7 @@ func (c *InstanceGroupVFS) Update(g *api.InstanceGroup) (*api.InstanceGroup, err
 	if err != nil {
 		return nil, err
 	}
+	c.recordRevision("update InstanceGroup " + g.ObjectMeta.Name)
 	return g, nil
 }
 
@@ -155,7 +164,18 @@ func (c *InstanceGroupVFS) WriteMirror(g *api.InstanceGroup) error {
 }
 
 func (c *InstanceGroupVFS) Delete(name string, options *metav1.DeleteOptions) error {
-	return c.delete(name, options)
+	err := c.delete(name, options)
+	if err != nil {
+		return err
+	}
+	c.recordRevision("delete InstanceGroup " + name)
+	return nil
+}
+
+func (c *InstanceGroupVFS) recordRevision(change string) {
+	if c.clusterBase != nil {
+		recordRevision(c.clusterBase, c.cluster, change)
+	}
 }
 
 func (r *InstanceGroupVFS) DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error {
@@ -0,0 +1,319 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfsclientset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	kopsbase "k8s.io/kops"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/apis/kops/v1alpha1"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// maxRevisionAttempts bounds the retries when another command records a revision with the same number
+const maxRevisionAttempts = 5
+
+// DefaultRevisionHistoryLimit is the number of revisions kept when the cluster does not set revisionHistoryLimit
+const DefaultRevisionHistoryLimit = 50
+
+// Revision is a copy of the cluster and instance group configuration, recorded after each change
+type Revision struct {
+	// Number identifies the revision; revisions are numbered from 1 in the order they were recorded
+	Number int `json:"number"`
+	// Timestamp is when the revision was recorded
+	Timestamp time.Time `json:"timestamp"`
+	// Author is the user who made the change, as user@host
+	Author string `json:"author,omitempty"`
+	// KopsVersion is the version of kops which made the change
+	KopsVersion string `json:"kopsVersion,omitempty"`
+	// Change describes the change, for example "update Cluster"
+	Change string `json:"change,omitempty"`
+
+	// Cluster is the cluster configuration, as stored in the state store
+	Cluster string `json:"cluster"`
+	// InstanceGroups is the configuration of each instance group, as stored in the state store
+	InstanceGroups map[string]string `json:"instanceGroups,omitempty"`
+}
+
+// ListRevisions returns the revisions recorded for the cluster, oldest first
+func (c *VFSClientset) ListRevisions(clusterName string) ([]*Revision, error) {
+	return listRevisions(c.basePath.Join(clusterName))
+}
+
+// GetRevision returns revision n of the cluster
+func (c *VFSClientset) GetRevision(clusterName string, n int) (*Revision, error) {
+	return readRevision(c.basePath.Join(clusterName), n)
+}
+
+// GetCurrentState returns the cluster and instance group configuration currently in the state store,
+// as a Revision which has not been recorded
+func (c *VFSClientset) GetCurrentState(clusterName string) (*Revision, error) {
+	return readCurrentState(c.basePath.Join(clusterName))
+}
+
+// WithoutRevisions returns a clientset for the same state store whose writes don't record revisions.
+// It is for commands which make several writes as one change, and record a single revision with RecordRevision.
+func (c *VFSClientset) WithoutRevisions() *VFSClientset {
+	r := *c
+	r.skipRevisions = true
+	return &r
+}
+
+// RecordRevision records the configuration of the cluster currently in the state store as a revision
+func (c *VFSClientset) RecordRevision(cluster *kops.Cluster, change string) error {
+	return writeRevision(c.basePath.Join(cluster.ObjectMeta.Name), cluster, change)
+}
+
+func readCurrentState(clusterBase vfs.Path) (*Revision, error) {
+	configPath := clusterBase.Join(registry.PathCluster)
+	data, err := configPath.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading %s: %v", configPath, err)
+	}
+
+	r := &Revision{
+		Cluster:        string(data),
+		InstanceGroups: make(map[string]string),
+	}
+
+	names, err := listChildNames(clusterBase.Join("instancegroup"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		p := clusterBase.Join("instancegroup", name)
+		data, err := p.ReadFile()
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Deleted since we listed it
+				continue
+			}
+			return nil, fmt.Errorf("error reading %s: %v", p, err)
+		}
+		r.InstanceGroups[name] = string(data)
+	}
+
+	return r, nil
+}
+
+func listRevisions(clusterBase vfs.Path) ([]*Revision, error) {
+	numbers, err := listRevisionNumbers(clusterBase)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*Revision
+	for _, n := range numbers {
+		r, err := readRevision(clusterBase, n)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func readRevision(clusterBase vfs.Path, n int) (*Revision, error) {
+	p := clusterBase.Join(registry.PathRevisions, strconv.Itoa(n))
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("revision %d not found", n)
+		}
+		return nil, fmt.Errorf("error reading %s: %v", p, err)
+	}
+
+	r := &Revision{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", p, err)
+	}
+	return r, nil
+}
+
+// Decode parses the cluster and instance groups of the revision
+func (r *Revision) Decode() (*kops.Cluster, []*kops.InstanceGroup, error) {
+	clusters := newClusterVFS(nil)
+	o, _, err := clusters.decoder.Decode([]byte(r.Cluster), clusters.defaultReadVersion, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing cluster of revision %d: %v", r.Number, err)
+	}
+	cluster, ok := o.(*kops.Cluster)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected object %T in cluster of revision %d", o, r.Number)
+	}
+
+	instanceGroups := &commonVFS{}
+	instanceGroups.init("InstanceGroup", nil, StoreVersion)
+	defaultReadVersion := v1alpha1.SchemeGroupVersion.WithKind("InstanceGroup")
+
+	var names []string
+	for name := range r.InstanceGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var igs []*kops.InstanceGroup
+	for _, name := range names {
+		o, _, err := instanceGroups.decoder.Decode([]byte(r.InstanceGroups[name]), &defaultReadVersion, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing instance group %q of revision %d: %v", name, r.Number, err)
+		}
+		ig, ok := o.(*kops.InstanceGroup)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected object %T in instance group %q of revision %d", o, name, r.Number)
+		}
+		if ig.ObjectMeta.Name == "" {
+			ig.ObjectMeta.Name = name
+		}
+		igs = append(igs, ig)
+	}
+
+	return cluster, igs, nil
+}
+
+// recordRevision records the configuration of the cluster after a change.
+// The change has already been made, so failures are only logged.
+func recordRevision(clusterBase vfs.Path, cluster *kops.Cluster, change string) {
+	if err := writeRevision(clusterBase, cluster, change); err != nil {
+		glog.Warningf("error recording revision of cluster %q after %s: %v", cluster.ObjectMeta.Name, change, err)
+	}
+}
+
+func writeRevision(clusterBase vfs.Path, cluster *kops.Cluster, change string) error {
+	r, err := readCurrentState(clusterBase)
+	if err != nil {
+		return err
+	}
+	r.Timestamp = time.Now().UTC()
+	r.Author = revisionAuthor()
+	r.KopsVersion = kopsbase.Version
+	r.Change = change
+
+	for attempt := 0; attempt < maxRevisionAttempts; attempt++ {
+		numbers, err := listRevisionNumbers(clusterBase)
+		if err != nil {
+			return err
+		}
+		r.Number = 1
+		if len(numbers) != 0 {
+			r.Number = numbers[len(numbers)-1] + 1
+		}
+
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error serializing revision: %v", err)
+		}
+
+		p := clusterBase.Join(registry.PathRevisions, strconv.Itoa(r.Number))
+		acl, err := acls.GetACL(p, cluster)
+		if err != nil {
+			return err
+		}
+
+		err = p.CreateFile(bytes.NewReader(data), acl)
+		if err == nil {
+			pruneRevisions(clusterBase, revisionHistoryLimit(cluster))
+			return nil
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("error writing %s: %v", p, err)
+		}
+		glog.V(2).Infof("revision %d was recorded concurrently, retrying", r.Number)
+	}
+
+	return fmt.Errorf("unable to allocate a revision number after %d attempts", maxRevisionAttempts)
+}
+
+// revisionHistoryLimit returns the number of revisions to keep for the cluster
+func revisionHistoryLimit(cluster *kops.Cluster) int {
+	if cluster != nil && cluster.Spec.RevisionHistoryLimit != nil {
+		return int(*cluster.Spec.RevisionHistoryLimit)
+	}
+	return DefaultRevisionHistoryLimit
+}
+
+// pruneRevisions removes the oldest revisions, keeping the newest limit revisions.
+// The revisions are only a history, so failures are only logged.
+func pruneRevisions(clusterBase vfs.Path, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	numbers, err := listRevisionNumbers(clusterBase)
+	if err != nil {
+		glog.Warningf("error listing revisions to prune: %v", err)
+		return
+	}
+	if len(numbers) <= limit {
+		return
+	}
+
+	for _, n := range numbers[:len(numbers)-limit] {
+		p := clusterBase.Join(registry.PathRevisions, strconv.Itoa(n))
+		glog.V(2).Infof("pruning revision %d", n)
+		if err := p.Remove(); err != nil && !os.IsNotExist(err) {
+			glog.Warningf("error pruning revision %s: %v", p, err)
+		}
+	}
+}
+
+// listRevisionNumbers returns the numbers of the recorded revisions, in ascending order
+func listRevisionNumbers(clusterBase vfs.Path) ([]int, error) {
+	names, err := listChildNames(clusterBase.Join(registry.PathRevisions))
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	for _, name := range names {
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			glog.V(2).Infof("ignoring unexpected file %q in revisions", name)
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+// revisionAuthor describes the user running kops, as user@host
+func revisionAuthor() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return username + "@" + hostname
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfsclientset
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	kopsbase "k8s.io/kops"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const testRevisionCluster = `apiVersion: kops/v1alpha2
+kind: Cluster
+metadata:
+  name: example.com
+spec:
+  kubernetesVersion: 1.10.0
+`
+
+const testRevisionInstanceGroup = `apiVersion: kops/v1alpha2
+kind: InstanceGroup
+metadata:
+  name: nodes
+spec:
+  role: Node
+  minSize: %d
+`
+
+func writeTestFile(t *testing.T, p vfs.Path, data string) {
+	if err := p.WriteFile(bytes.NewReader([]byte(data)), nil); err != nil {
+		t.Fatalf("error writing %s: %v", p, err)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	clusterBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "state/example.com")
+	cluster := &kops.Cluster{}
+	cluster.ObjectMeta.Name = "example.com"
+
+	writeTestFile(t, clusterBase.Join("config"), testRevisionCluster)
+	writeTestFile(t, clusterBase.Join("instancegroup", "nodes"), fmt.Sprintf(testRevisionInstanceGroup, 2))
+	if err := writeRevision(clusterBase, cluster, "create InstanceGroup nodes"); err != nil {
+		t.Fatalf("error recording revision: %v", err)
+	}
+
+	writeTestFile(t, clusterBase.Join("instancegroup", "nodes"), fmt.Sprintf(testRevisionInstanceGroup, 5))
+	if err := writeRevision(clusterBase, cluster, "update InstanceGroup nodes"); err != nil {
+		t.Fatalf("error recording revision: %v", err)
+	}
+
+	revisions, err := listRevisions(clusterBase)
+	if err != nil {
+		t.Fatalf("error listing revisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revisions))
+	}
+	for i, r := range revisions {
+		if r.Number != i+1 {
+			t.Errorf("expected revision %d, got %d", i+1, r.Number)
+		}
+		if r.KopsVersion != kopsbase.Version {
+			t.Errorf("unexpected kops version %q in revision %d", r.KopsVersion, r.Number)
+		}
+		if r.Author == "" || r.Timestamp.IsZero() {
+			t.Errorf("author and timestamp not recorded in revision %d: %v", r.Number, r)
+		}
+	}
+	if revisions[1].Change != "update InstanceGroup nodes" {
+		t.Errorf("unexpected change %q", revisions[1].Change)
+	}
+
+	r, err := readRevision(clusterBase, 1)
+	if err != nil {
+		t.Fatalf("error reading revision: %v", err)
+	}
+	c, igs, err := r.Decode()
+	if err != nil {
+		t.Fatalf("error decoding revision: %v", err)
+	}
+	if c.ObjectMeta.Name != "example.com" || c.Spec.KubernetesVersion != "1.10.0" {
+		t.Errorf("unexpected cluster in revision: %v", c)
+	}
+	if len(igs) != 1 || igs[0].ObjectMeta.Name != "nodes" {
+		t.Fatalf("unexpected instance groups in revision: %v", igs)
+	}
+	if igs[0].Spec.MinSize == nil || *igs[0].Spec.MinSize != 2 {
+		t.Errorf("expected minSize 2 in revision 1, got %v", igs[0].Spec.MinSize)
+	}
+
+	if _, err := readRevision(clusterBase, 3); err == nil {
+		t.Errorf("expected error reading a revision which does not exist")
+	}
+}
+
+func TestRevisionsPruned(t *testing.T) {
+	clusterBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "state/example.com")
+	limit := int32(3)
+	cluster := &kops.Cluster{}
+	cluster.ObjectMeta.Name = "example.com"
+	cluster.Spec.RevisionHistoryLimit = &limit
+
+	writeTestFile(t, clusterBase.Join("config"), testRevisionCluster)
+	for i := 1; i <= 5; i++ {
+		writeTestFile(t, clusterBase.Join("instancegroup", "nodes"), fmt.Sprintf(testRevisionInstanceGroup, i))
+		if err := writeRevision(clusterBase, cluster, "update InstanceGroup nodes"); err != nil {
+			t.Fatalf("error recording revision: %v", err)
+		}
+	}
+
+	numbers, err := listRevisionNumbers(clusterBase)
+	if err != nil {
+		t.Fatalf("error listing revisions: %v", err)
+	}
+	if fmt.Sprintf("%v", numbers) != "[3 4 5]" {
+		t.Errorf("expected revisions [3 4 5] to be kept, got %v", numbers)
+	}
+}
+
+func TestWithoutRevisions(t *testing.T) {
+	basePath := vfs.NewMemFSPath(vfs.NewMemFSContext(), "state")
+	clientset := &VFSClientset{basePath: basePath}
+	cluster := &kops.Cluster{}
+	cluster.ObjectMeta.Name = "example.com"
+
+	withoutRevisions := clientset.WithoutRevisions()
+	if !withoutRevisions.clusters().skipRevisions {
+		t.Errorf("expected cluster writes not to record revisions")
+	}
+	if withoutRevisions.InstanceGroupsFor(cluster).(*InstanceGroupVFS).clusterBase != nil {
+		t.Errorf("expected instance group writes not to record revisions")
+	}
+	if clientset.clusters().skipRevisions || clientset.InstanceGroupsFor(cluster).(*InstanceGroupVFS).clusterBase == nil {
+		t.Errorf("expected the original clientset to still record revisions")
+	}
+
+	// The change is recorded as a single revision
+	writeTestFile(t, basePath.Join("example.com", "config"), testRevisionCluster)
+	writeTestFile(t, basePath.Join("example.com", "instancegroup", "nodes"), fmt.Sprintf(testRevisionInstanceGroup, 2))
+	if err := clientset.RecordRevision(cluster, "rollback to revision 1"); err != nil {
+		t.Fatalf("error recording revision: %v", err)
+	}
+	revisions, err := clientset.ListRevisions("example.com")
+	if err != nil {
+		t.Fatalf("error listing revisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Change != "rollback to revision 1" {
+		t.Fatalf("expected a single rollback revision, got %v", revisions)
+	}
+}
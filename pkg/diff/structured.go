@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is a difference in the value of one field between two documents
+type FieldChange struct {
+	// Path is the field which changed, for example spec.subnets[0].zone
+	Path string `json:"path"`
+	// Old is the value of the field in the first document; it is omitted if the field was added
+	Old interface{} `json:"old,omitempty"`
+	// New is the value of the field in the second document; it is omitted if the field was removed
+	New interface{} `json:"new,omitempty"`
+}
+
+// StructuredDiff returns the fields which differ between two JSON documents.
+// Objects are compared field by field and lists item by item; other values are compared as a whole.
+func StructuredDiff(lJSON, rJSON []byte) ([]FieldChange, error) {
+	var l, r interface{}
+	if len(lJSON) != 0 {
+		if err := json.Unmarshal(lJSON, &l); err != nil {
+			return nil, fmt.Errorf("error parsing JSON: %v", err)
+		}
+	}
+	if len(rJSON) != 0 {
+		if err := json.Unmarshal(rJSON, &r); err != nil {
+			return nil, fmt.Errorf("error parsing JSON: %v", err)
+		}
+	}
+
+	var changes []FieldChange
+	diffValues("", l, r, &changes)
+	return changes, nil
+}
+
+func diffValues(path string, l, r interface{}, changes *[]FieldChange) {
+	switch lv := l.(type) {
+	case map[string]interface{}:
+		if rv, ok := r.(map[string]interface{}); ok {
+			var keys []string
+			for k := range lv {
+				keys = append(keys, k)
+			}
+			for k := range rv {
+				if _, found := lv[k]; !found {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				childPath := k
+				if path != "" {
+					childPath = path + "." + k
+				}
+				lChild, lFound := lv[k]
+				rChild, rFound := rv[k]
+				switch {
+				case !lFound:
+					*changes = append(*changes, FieldChange{Path: childPath, New: rChild})
+				case !rFound:
+					*changes = append(*changes, FieldChange{Path: childPath, Old: lChild})
+				default:
+					diffValues(childPath, lChild, rChild, changes)
+				}
+			}
+			return
+		}
+
+	case []interface{}:
+		if rv, ok := r.([]interface{}); ok {
+			n := len(lv)
+			if len(rv) > n {
+				n = len(rv)
+			}
+			for i := 0; i < n; i++ {
+				childPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(lv):
+					*changes = append(*changes, FieldChange{Path: childPath, New: rv[i]})
+				case i >= len(rv):
+					*changes = append(*changes, FieldChange{Path: childPath, Old: lv[i]})
+				default:
+					diffValues(childPath, lv[i], rv[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(l, r) {
+		*changes = append(*changes, FieldChange{Path: path, Old: l, New: r})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_StructuredDiff(t *testing.T) {
+	grid := []struct {
+		L        string
+		R        string
+		Expected string
+	}{
+		{
+			L:        `{"spec":{"minSize":1}}`,
+			R:        `{"spec":{"minSize":1}}`,
+			Expected: `null`,
+		},
+		{
+			L:        `{"spec":{"minSize":1,"maxSize":2}}`,
+			R:        `{"spec":{"minSize":1,"maxSize":3}}`,
+			Expected: `[{"path":"spec.maxSize","old":2,"new":3}]`,
+		},
+		{
+			L:        `{"spec":{"image":"a"}}`,
+			R:        `{"spec":{"machineType":"t2.medium"}}`,
+			Expected: `[{"path":"spec.image","old":"a"},{"path":"spec.machineType","new":"t2.medium"}]`,
+		},
+		{
+			L:        `{"subnets":[{"zone":"a"},{"zone":"b"}]}`,
+			R:        `{"subnets":[{"zone":"a"},{"zone":"c"},{"zone":"d"}]}`,
+			Expected: `[{"path":"subnets[1].zone","old":"b","new":"c"},{"path":"subnets[2]","new":{"zone":"d"}}]`,
+		},
+		{
+			L:        `{"spec":{"networking":{"kubenet":{}}}}`,
+			R:        `{"spec":{"networking":"calico"}}`,
+			Expected: `[{"path":"spec.networking","old":{"kubenet":{}},"new":"calico"}]`,
+		},
+		{
+			L:        ``,
+			R:        `{"spec":{}}`,
+			Expected: `[{"path":"","new":{"spec":{}}}]`,
+		},
+	}
+
+	for _, g := range grid {
+		changes, err := StructuredDiff([]byte(g.L), []byte(g.R))
+		if err != nil {
+			t.Errorf("unexpected error comparing %s and %s: %v", g.L, g.R, err)
+			continue
+		}
+		actual, err := json.Marshal(changes)
+		if err != nil {
+			t.Fatalf("error serializing changes: %v", err)
+		}
+		if string(actual) != g.Expected {
+			t.Errorf("unexpected diff of %s and %s.  expected=%s, actual=%s", g.L, g.R, g.Expected, actual)
+		}
+	}
+}
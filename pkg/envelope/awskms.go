@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// ProviderAWSKMS is the name of the AWS KMS key provider
+const ProviderAWSKMS = "awskms"
+
+// AWSKMSProvider wraps data keys with an AWS KMS key.
+// Nodes can unwrap the keys without configuration, if their instance role is allowed kms:Decrypt on the key.
+type AWSKMSProvider struct {
+	// keyID is the KMS key used to wrap keys: a key id, key ARN, alias name or alias ARN
+	keyID string
+
+	mutex   sync.Mutex
+	clients map[string]*kms.KMS
+}
+
+var _ KeyProvider = &AWSKMSProvider{}
+
+// NewAWSKMSProvider builds an AWSKMSProvider which wraps keys with the KMS key keyID.
+// The keyID can be empty if the provider is only used to unwrap keys.
+func NewAWSKMSProvider(keyID string) *AWSKMSProvider {
+	return &AWSKMSProvider{
+		keyID:   keyID,
+		clients: make(map[string]*kms.KMS),
+	}
+}
+
+// Name implements KeyProvider::Name
+func (p *AWSKMSProvider) Name() string {
+	return ProviderAWSKMS
+}
+
+// WrapKey implements KeyProvider::WrapKey.  The key id is the ARN of the KMS key, which records its region.
+func (p *AWSKMSProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	if p.keyID == "" {
+		return nil, "", fmt.Errorf("no KMS key configured")
+	}
+	client, err := p.client(p.keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	response, err := client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error encrypting with KMS key %q: %v", p.keyID, err)
+	}
+	return response.CiphertextBlob, aws.StringValue(response.KeyId), nil
+}
+
+// UnwrapKey implements KeyProvider::UnwrapKey
+func (p *AWSKMSProvider) UnwrapKey(wrapped []byte, keyID string) ([]byte, error) {
+	client, err := p.client(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with KMS key %q: %v", keyID, err)
+	}
+	return response.Plaintext, nil
+}
+
+// KeyARN returns the ARN of the KMS key which wraps keys, resolving a key id or alias with DescribeKey.
+// IAM policies grant kms:Decrypt on the key ARN.
+func (p *AWSKMSProvider) KeyARN() (string, error) {
+	if p.keyID == "" {
+		return "", fmt.Errorf("no KMS key configured")
+	}
+	client, err := p.client(p.keyID)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := client.DescribeKey(&kms.DescribeKeyInput{
+		KeyId: aws.String(p.keyID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing KMS key %q: %v", p.keyID, err)
+	}
+	if response.KeyMetadata == nil || aws.StringValue(response.KeyMetadata.Arn) == "" {
+		return "", fmt.Errorf("KMS key %q has no ARN", p.keyID)
+	}
+	return aws.StringValue(response.KeyMetadata.Arn), nil
+}
+
+// client returns a KMS client for the region of the key
+func (p *AWSKMSProvider) client(keyID string) (*kms.KMS, error) {
+	region := kmsKeyRegion(keyID)
+	if region == "" {
+		return nil, fmt.Errorf("cannot determine region of KMS key %q; use the key ARN or set AWS_REGION", keyID)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	client := p.clients[region]
+	if client == nil {
+		config := aws.NewConfig().WithRegion(region)
+		config = config.WithCredentialsChainVerboseErrors(true)
+
+		sess, err := session.NewSession(config)
+		if err != nil {
+			return nil, fmt.Errorf("error starting new AWS session: %v", err)
+		}
+		client = kms.New(sess, config)
+		p.clients[region] = client
+	}
+	return client, nil
+}
+
+// kmsKeyRegion returns the region of a KMS key: from its ARN, arn:aws:kms:<region>:<account>:key/<id>,
+// or else from the environment
+func kmsKeyRegion(keyID string) string {
+	if strings.HasPrefix(keyID, "arn:") {
+		tokens := strings.Split(keyID, ":")
+		if len(tokens) >= 4 && tokens[3] != "" {
+			return tokens[3]
+		}
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvStateEncryption enables encryption of secrets and private keys in the state store.
+	// It names the key provider: passphrase, keyfile:<path> or awskms:<key id>
+	EnvStateEncryption = "KOPS_STATE_ENCRYPTION"
+	// EnvStateEncryptionPassphrase is the passphrase for the passphrase key provider
+	EnvStateEncryptionPassphrase = "KOPS_STATE_ENCRYPTION_PASSPHRASE"
+)
+
+// environmentProviders caches the providers built by ProviderFromEnvironment, as building them can be slow
+var environmentProviders = struct {
+	mutex     sync.Mutex
+	providers map[string]KeyProvider
+}{
+	providers: make(map[string]KeyProvider),
+}
+
+// ProviderFromEnvironment returns the key provider configured by KOPS_STATE_ENCRYPTION,
+// or nil if encryption is not enabled
+func ProviderFromEnvironment() (KeyProvider, error) {
+	spec := strings.TrimSpace(os.Getenv(EnvStateEncryption))
+	if spec == "" {
+		return nil, nil
+	}
+
+	environmentProviders.mutex.Lock()
+	defer environmentProviders.mutex.Unlock()
+
+	key := spec + "\x00" + os.Getenv(EnvStateEncryptionPassphrase)
+	if provider := environmentProviders.providers[key]; provider != nil {
+		return provider, nil
+	}
+
+	provider, err := ParseProvider(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s=%q: %v", EnvStateEncryption, spec, err)
+	}
+	environmentProviders.providers[key] = provider
+	return provider, nil
+}
+
+// ParseProvider builds the key provider described by spec, which is one of
+// passphrase, keyfile:<path> or awskms:<key id>
+func ParseProvider(spec string) (KeyProvider, error) {
+	name := spec
+	arg := ""
+	if i := strings.Index(spec, ":"); i != -1 {
+		name = spec[:i]
+		arg = spec[i+1:]
+	}
+
+	switch name {
+	case ProviderPassphrase:
+		passphrase := os.Getenv(EnvStateEncryptionPassphrase)
+		if passphrase == "" {
+			return nil, fmt.Errorf("%s must be set for the passphrase key provider", EnvStateEncryptionPassphrase)
+		}
+		return NewPassphraseProvider(passphrase), nil
+
+	case ProviderKeyfile:
+		if arg == "" {
+			return nil, fmt.Errorf("the keyfile key provider must be specified as keyfile:<path>")
+		}
+		return NewKeyfileProvider(arg)
+
+	case ProviderAWSKMS:
+		if arg == "" {
+			return nil, fmt.Errorf("the awskms key provider must be specified as awskms:<key id>")
+		}
+		return NewAWSKMSProvider(arg), nil
+
+	default:
+		return nil, fmt.Errorf("unknown key provider %q", name)
+	}
+}
+
+// defaultAWSKMSProvider unwraps keys wrapped by any AWS KMS key
+var defaultAWSKMSProvider = NewAWSKMSProvider("")
+
+// defaultProvider returns a provider which can unwrap keys for the named provider without being configured,
+// or nil if there is none.  This lets nodes read files encrypted with a KMS key, using their instance role.
+func defaultProvider(name string) KeyProvider {
+	switch name {
+	case ProviderAWSKMS:
+		return defaultAWSKMSProvider
+	}
+	return nil
+}
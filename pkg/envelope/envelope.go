@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// magic is the first line of an encrypted file, so we can tell encrypted and plaintext files apart
+var magic = []byte("kops-envelope-v1\n")
+
+const (
+	// dataKeySize is the size of the AES-256 key which encrypts each file
+	dataKeySize = 32
+	// nonceSize is the size of the AES-GCM nonce
+	nonceSize = 12
+)
+
+// KeyProvider wraps and unwraps the data keys which encrypt the files.
+// The data key is different for every file; only the wrapped data key is stored, alongside the file.
+type KeyProvider interface {
+	// Name identifies the provider in the envelope, so we know which provider can unwrap the data key
+	Name() string
+	// WrapKey encrypts the data key; it returns the wrapped key, and the id of the key which wrapped it
+	WrapKey(dataKey []byte) ([]byte, string, error)
+	// UnwrapKey decrypts a data key which was wrapped by the key with id keyID
+	UnwrapKey(wrapped []byte, keyID string) ([]byte, error)
+}
+
+// envelope is the contents of an encrypted file, after the magic line
+type envelope struct {
+	// Provider is the name of the KeyProvider which wrapped the data key
+	Provider string `json:"provider"`
+	// KeyID identifies the key which wrapped the data key
+	KeyID string `json:"keyID,omitempty"`
+	// WrappedKey is the data key, wrapped by the provider
+	WrappedKey []byte `json:"wrappedKey"`
+	// Nonce is the AES-GCM nonce
+	Nonce []byte `json:"nonce"`
+	// Ciphertext is the contents of the file, encrypted with the data key
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// IsEncrypted returns true if data was written by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encrypt encrypts data with a new data key, which is wrapped by the provider
+func Encrypt(provider KeyProvider, data []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("error generating data key: %v", err)
+	}
+
+	wrapped, keyID, err := provider.WrapKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key with %s key provider: %v", provider.Name(), err)
+	}
+
+	nonce, ciphertext, err := seal(dataKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &envelope{
+		Provider:   provider.Name(),
+		KeyID:      keyID,
+		WrappedKey: wrapped,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing envelope: %v", err)
+	}
+
+	var out bytes.Buffer
+	out.Write(magic)
+	out.Write(b)
+	return out.Bytes(), nil
+}
+
+// Decrypt returns the plaintext of data, which was written by Encrypt.
+// Data which is not encrypted is returned unchanged, so that existing plaintext files can still be read.
+// The data key is unwrapped by provider if it has the same name as the provider which wrapped it,
+// otherwise by a provider which needs no configuration, such as awskms.
+func Decrypt(provider KeyProvider, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	e := &envelope{}
+	if err := json.Unmarshal(data[len(magic):], e); err != nil {
+		return nil, fmt.Errorf("error parsing envelope: %v", err)
+	}
+
+	if provider == nil || provider.Name() != e.Provider {
+		provider = defaultProvider(e.Provider)
+		if provider == nil {
+			return nil, fmt.Errorf("data was encrypted with the %s key provider; set %s to decrypt it", e.Provider, EnvStateEncryption)
+		}
+	}
+
+	dataKey, err := provider.UnwrapKey(e.WrappedKey, e.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key with %s key provider: %v", e.Provider, err)
+	}
+
+	return open(dataKey, e.Nonce, e.Ciphertext)
+}
+
+// seal encrypts plaintext with AES-GCM under key, returning the random nonce and the ciphertext
+func seal(key []byte, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// open decrypts ciphertext which was encrypted by seal
+func open(key []byte, nonce []byte, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != nonceSize {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %v", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error building cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error building cipher: %v", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// ProviderKeyfile is the name of the keyfile key provider
+const ProviderKeyfile = "keyfile"
+
+// KeyfileProvider wraps data keys with a key read from a local file.
+// The file holds a base64 encoded 32 byte key, and may have comment lines starting with #;
+// it can be created with: head -c 32 /dev/urandom | base64
+// Like the passphrase provider it is intended for testing, as everyone who reads the state store needs the file.
+type KeyfileProvider struct {
+	key []byte
+	// keyID is derived from the key, so that a file can't be decrypted with the wrong keyfile
+	keyID string
+}
+
+var _ KeyProvider = &KeyfileProvider{}
+
+// NewKeyfileProvider builds a KeyfileProvider from the keyfile at path
+func NewKeyfileProvider(path string) (*KeyfileProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keyfile %q: %v", path, err)
+	}
+	key, err := parseKeyfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing keyfile %q: %v", path, err)
+	}
+
+	hash := sha256.Sum256(key)
+	return &KeyfileProvider{
+		key:   key,
+		keyID: hex.EncodeToString(hash[:8]),
+	}, nil
+}
+
+func parseKeyfile(data []byte) ([]byte, error) {
+	var encoded string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if encoded != "" {
+			return nil, fmt.Errorf("expected a single key")
+		}
+		encoded = line
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("no key found")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64 encoded: %v", err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, was %d", dataKeySize, len(key))
+	}
+	return key, nil
+}
+
+// Name implements KeyProvider::Name
+func (p *KeyfileProvider) Name() string {
+	return ProviderKeyfile
+}
+
+// WrapKey implements KeyProvider::WrapKey; the wrapped key is the nonce and the encrypted data key
+func (p *KeyfileProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	nonce, ciphertext, err := seal(p.key, dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(nonce, ciphertext...), p.keyID, nil
+}
+
+// UnwrapKey implements KeyProvider::UnwrapKey
+func (p *KeyfileProvider) UnwrapKey(wrapped []byte, keyID string) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, fmt.Errorf("data key was wrapped with keyfile %q, but the configured keyfile is %q", keyID, p.keyID)
+	}
+	if len(wrapped) < nonceSize {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	return open(p.key, wrapped[:nonceSize], wrapped[nonceSize:])
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ProviderPassphrase is the name of the passphrase key provider
+const ProviderPassphrase = "passphrase"
+
+// scrypt parameters, as recommended for interactive logins
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptSaltLen = 16
+)
+
+// PassphraseProvider wraps data keys with a key derived from a passphrase.
+// It is intended for testing: anyone who needs to read the state store, including nodes, needs the passphrase.
+type PassphraseProvider struct {
+	passphrase string
+
+	mutex sync.Mutex
+	// salt is the salt used when wrapping keys; deriving a key is deliberately slow, so we reuse it
+	salt []byte
+	// keys caches the derived key for each salt
+	keys map[string][]byte
+}
+
+var _ KeyProvider = &PassphraseProvider{}
+
+// NewPassphraseProvider builds a PassphraseProvider for the passphrase
+func NewPassphraseProvider(passphrase string) *PassphraseProvider {
+	return &PassphraseProvider{
+		passphrase: passphrase,
+		keys:       make(map[string][]byte),
+	}
+}
+
+// Name implements KeyProvider::Name
+func (p *PassphraseProvider) Name() string {
+	return ProviderPassphrase
+}
+
+// WrapKey implements KeyProvider::WrapKey; the wrapped key is the salt, the nonce and the encrypted data key
+func (p *PassphraseProvider) WrapKey(dataKey []byte) ([]byte, string, error) {
+	p.mutex.Lock()
+	if p.salt == nil {
+		salt := make([]byte, scryptSaltLen)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			p.mutex.Unlock()
+			return nil, "", fmt.Errorf("error generating salt: %v", err)
+		}
+		p.salt = salt
+	}
+	salt := p.salt
+	p.mutex.Unlock()
+
+	key, err := p.deriveKey(salt)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce, ciphertext, err := seal(key, dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var wrapped []byte
+	wrapped = append(wrapped, salt...)
+	wrapped = append(wrapped, nonce...)
+	wrapped = append(wrapped, ciphertext...)
+	return wrapped, "", nil
+}
+
+// UnwrapKey implements KeyProvider::UnwrapKey
+func (p *PassphraseProvider) UnwrapKey(wrapped []byte, keyID string) ([]byte, error) {
+	if len(wrapped) < scryptSaltLen+nonceSize {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	salt := wrapped[:scryptSaltLen]
+	nonce := wrapped[scryptSaltLen : scryptSaltLen+nonceSize]
+	ciphertext := wrapped[scryptSaltLen+nonceSize:]
+
+	key, err := p.deriveKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(key, nonce, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase? %v", err)
+	}
+	return dataKey, nil
+}
+
+func (p *PassphraseProvider) deriveKey(salt []byte) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key := p.keys[string(salt)]; key != nil {
+		return key, nil
+	}
+	key, err := scrypt.Key([]byte(p.passphrase), salt, scryptN, scryptR, scryptP, dataKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key from passphrase: %v", err)
+	}
+	p.keys[string(salt)] = key
+	return key, nil
+}
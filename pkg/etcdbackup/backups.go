@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const (
+	// MetaFilename is the file etcd-manager writes into the directory of each backup
+	MetaFilename = "_etcd_backup.meta"
+	// CommandFilename is the file holding a command for etcd-manager, in its own directory under the control directory
+	CommandFilename = "_command.json"
+	// ControlDir is the directory of the backup store that etcd-manager polls for commands
+	ControlDir = "control"
+)
+
+// Backup is a backup of an etcd cluster, as written by etcd-manager
+type Backup struct {
+	// EtcdCluster is the name of the etcd cluster, e.g. main
+	EtcdCluster string `json:"etcdCluster"`
+	// Name is the name of the backup, which is its directory in the backup store
+	Name string `json:"name"`
+	// EtcdVersion is the version of etcd which wrote the backup
+	EtcdVersion string `json:"etcdVersion,omitempty"`
+	// Path is the location of the backup
+	Path string `json:"path"`
+}
+
+// backupInfo is the subset of the metadata etcd-manager writes alongside each backup
+type backupInfo struct {
+	EtcdVersion string `json:"etcdVersion,omitempty"`
+}
+
+// clusterSpec describes the etcd cluster which etcd-manager should restore a backup into
+type clusterSpec struct {
+	MemberCount int32  `json:"memberCount,omitempty"`
+	EtcdVersion string `json:"etcdVersion,omitempty"`
+}
+
+// command is a command read by etcd-manager from the control directory
+type command struct {
+	Timestamp     int64                 `json:"timestamp,omitempty"`
+	RestoreBackup *restoreBackupCommand `json:"restoreBackup,omitempty"`
+}
+
+type restoreBackupCommand struct {
+	ClusterSpec *clusterSpec `json:"clusterSpec,omitempty"`
+	Backup      string       `json:"backup,omitempty"`
+}
+
+// BackupStore is the backup store of a single etcd cluster
+type BackupStore struct {
+	etcdCluster *kops.EtcdClusterSpec
+	basedir     vfs.Path
+}
+
+// NewBackupStore returns the backup store of an etcd cluster managed by etcd-manager.
+// The cluster spec should be the full (completed) spec, where the provider and backup store are set.
+func NewBackupStore(etcdCluster *kops.EtcdClusterSpec) (*BackupStore, error) {
+	if etcdCluster.Provider != kops.EtcdProviderTypeManager {
+		return nil, fmt.Errorf("etcd cluster %q is not managed by etcd-manager", etcdCluster.Name)
+	}
+	if etcdCluster.Backups == nil || etcdCluster.Backups.BackupStore == "" {
+		return nil, fmt.Errorf("etcd cluster %q does not have a backup store", etcdCluster.Name)
+	}
+
+	basedir, err := vfs.Context.BuildVfsPath(etcdCluster.Backups.BackupStore)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing backup store %q for etcd cluster %q: %v", etcdCluster.Backups.BackupStore, etcdCluster.Name, err)
+	}
+
+	return &BackupStore{
+		etcdCluster: etcdCluster,
+		basedir:     basedir,
+	}, nil
+}
+
+// Path returns the location of the backup store
+func (s *BackupStore) Path() vfs.Path {
+	return s.basedir
+}
+
+// ListBackups returns the backups in the store, oldest first
+func (s *BackupStore) ListBackups() ([]*Backup, error) {
+	children, err := s.basedir.ReadDir()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing backups in %s: %v", s.basedir, err)
+	}
+
+	var backups []*Backup
+	for _, child := range children {
+		name := child.Base()
+		if name == ControlDir {
+			continue
+		}
+
+		backup := &Backup{
+			EtcdCluster: s.etcdCluster.Name,
+			Name:        name,
+			Path:        s.basedir.Join(name).Path(),
+		}
+
+		// Each backup is a directory holding a metadata file; anything else is not a (complete) backup
+		info, err := readBackupInfo(s.basedir.Join(name, MetaFilename))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			// Still list the backup, it may be restorable even if we can't parse the metadata
+			glog.Warningf("%v", err)
+		} else {
+			backup.EtcdVersion = info.EtcdVersion
+		}
+
+		backups = append(backups, backup)
+	}
+
+	// etcd-manager names backups by their timestamp, so this sorts them by age
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name < backups[j].Name
+	})
+
+	return backups, nil
+}
+
+// readBackupInfo reads the metadata of a backup; if the file does not exist the error satisfies os.IsNotExist
+func readBackupInfo(p vfs.Path) (*backupInfo, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading backup metadata %s: %v", p, err)
+	}
+
+	info := &backupInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("error parsing backup metadata %s: %v", p, err)
+	}
+	return info, nil
+}
+
+// AddRestoreCommand writes a command into the control directory of the store, telling etcd-manager to restore the named backup.
+// It returns the location of the command.
+func (s *BackupStore) AddRestoreCommand(backupName string) (vfs.Path, error) {
+	if backupName == "" || strings.Contains(backupName, "/") || backupName == ControlDir {
+		return nil, fmt.Errorf("invalid backup name %q", backupName)
+	}
+
+	info, err := readBackupInfo(s.basedir.Join(backupName, MetaFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("backup %q not found for etcd cluster %q", backupName, s.etcdCluster.Name)
+		}
+		return nil, err
+	}
+
+	spec := &clusterSpec{
+		MemberCount: int32(len(s.etcdCluster.Members)),
+		EtcdVersion: s.etcdCluster.Version,
+	}
+	if spec.EtcdVersion == "" {
+		spec.EtcdVersion = info.EtcdVersion
+	}
+	if spec.EtcdVersion == "" {
+		return nil, fmt.Errorf("cannot determine the etcd version to restore backup %q into", backupName)
+	}
+
+	now := time.Now()
+	cmd := &command{
+		Timestamp: now.UnixNano(),
+		RestoreBackup: &restoreBackupCommand{
+			ClusterSpec: spec,
+			Backup:      backupName,
+		},
+	}
+
+	data, err := json.MarshalIndent(cmd, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error serializing restore command: %v", err)
+	}
+
+	p := s.basedir.Join(ControlDir, strconv.FormatInt(now.UnixNano(), 10), CommandFilename)
+	if err := p.CreateFile(bytes.NewReader(data), nil); err != nil {
+		return nil, fmt.Errorf("error writing restore command to %s: %v", p, err)
+	}
+
+	return p, nil
+}
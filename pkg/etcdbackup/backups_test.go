@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func buildBackupStore(t *testing.T) *BackupStore {
+	vfs.Context.ResetMemfsContext(true)
+
+	etcdCluster := &kops.EtcdClusterSpec{
+		Name:     "main",
+		Provider: kops.EtcdProviderTypeManager,
+		Version:  "3.2.24",
+		Members: []*kops.EtcdMemberSpec{
+			{Name: "a"},
+			{Name: "b"},
+			{Name: "c"},
+		},
+		Backups: &kops.EtcdBackupSpec{
+			BackupStore: "memfs://clusters.example.com/example.com/backups/etcd/main",
+		},
+	}
+
+	s, err := NewBackupStore(etcdCluster)
+	if err != nil {
+		t.Fatalf("error building backup store: %v", err)
+	}
+	return s
+}
+
+func writeFile(t *testing.T, p vfs.Path, data string) {
+	if err := p.WriteFile(bytes.NewReader([]byte(data)), nil); err != nil {
+		t.Fatalf("error writing %s: %v", p, err)
+	}
+}
+
+func TestListBackups(t *testing.T) {
+	s := buildBackupStore(t)
+
+	backups, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("error listing empty backup store: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Fatalf("expected no backups, found %d", len(backups))
+	}
+
+	writeFile(t, s.Path().Join("2018-08-03T10:00:00Z-000002", MetaFilename), `{"etcdVersion": "3.2.24", "timestamp": "1533290400000000000"}`)
+	writeFile(t, s.Path().Join("2018-08-03T10:00:00Z-000002", "etcd.backup.gz"), "backup")
+	writeFile(t, s.Path().Join("2018-08-03T09:00:00Z-000001", MetaFilename), `{"etcdVersion": "2.2.1"}`)
+	writeFile(t, s.Path().Join("2018-08-03T11:00:00Z-000003", MetaFilename), `not json`)
+	// An incomplete backup, which has no metadata
+	writeFile(t, s.Path().Join("2018-08-03T12:00:00Z-000004", "etcd.backup.gz"), "backup")
+	writeFile(t, s.Path().Join(ControlDir, "etcd-cluster-spec"), `{"member_count": 3}`)
+
+	backups, err = s.ListBackups()
+	if err != nil {
+		t.Fatalf("error listing backups: %v", err)
+	}
+
+	var names []string
+	var versions []string
+	for _, b := range backups {
+		if b.EtcdCluster != "main" {
+			t.Errorf("unexpected etcd cluster for backup %q: %q", b.Name, b.EtcdCluster)
+		}
+		names = append(names, b.Name)
+		versions = append(versions, b.EtcdVersion)
+	}
+
+	expectedNames := []string{"2018-08-03T09:00:00Z-000001", "2018-08-03T10:00:00Z-000002", "2018-08-03T11:00:00Z-000003"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("unexpected backups: expected=%v actual=%v", expectedNames, names)
+	}
+	expectedVersions := []string{"2.2.1", "3.2.24", ""}
+	if !reflect.DeepEqual(versions, expectedVersions) {
+		t.Errorf("unexpected etcd versions: expected=%v actual=%v", expectedVersions, versions)
+	}
+}
+
+func TestAddRestoreCommand(t *testing.T) {
+	s := buildBackupStore(t)
+
+	backupName := "2018-08-03T10:00:00Z-000002"
+	writeFile(t, s.Path().Join(backupName, MetaFilename), `{"etcdVersion": "3.2.24"}`)
+
+	for _, name := range []string{"", "control", "a/b", "2018-08-03T09:00:00Z-000001"} {
+		if _, err := s.AddRestoreCommand(name); err == nil {
+			t.Errorf("expected error restoring backup %q", name)
+		}
+	}
+
+	p, err := s.AddRestoreCommand(backupName)
+	if err != nil {
+		t.Fatalf("error adding restore command: %v", err)
+	}
+
+	data, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("error reading restore command: %v", err)
+	}
+
+	actual := &command{}
+	if err := json.Unmarshal(data, actual); err != nil {
+		t.Fatalf("error parsing restore command: %v", err)
+	}
+	if actual.Timestamp == 0 {
+		t.Errorf("expected the restore command to have a timestamp")
+	}
+
+	expected := &restoreBackupCommand{
+		ClusterSpec: &clusterSpec{MemberCount: 3, EtcdVersion: "3.2.24"},
+		Backup:      backupName,
+	}
+	if !reflect.DeepEqual(actual.RestoreBackup, expected) {
+		t.Errorf("unexpected restore command: %s", string(data))
+	}
+
+	commands, err := s.Path().Join(ControlDir).ReadTree()
+	if err != nil {
+		t.Fatalf("error listing control directory: %v", err)
+	}
+	if len(commands) != 1 || commands[0].Base() != CommandFilename {
+		t.Errorf("expected a single command in the control directory, found %v", commands)
+	}
+}
+
+func TestNewBackupStoreRequiresEtcdManager(t *testing.T) {
+	etcdCluster := &kops.EtcdClusterSpec{
+		Name:     "main",
+		Provider: kops.EtcdProviderTypeLegacy,
+		Backups: &kops.EtcdBackupSpec{
+			BackupStore: "memfs://clusters.example.com/example.com/backups/etcd/main",
+		},
+	}
+	if _, err := NewBackupStore(etcdCluster); err == nil {
+		t.Fatalf("expected error building backup store for legacy etcd")
+	}
+}
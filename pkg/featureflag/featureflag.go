@@ -44,6 +44,9 @@ var ExperimentalClusterDNS = New("ExperimentalClusterDNS", Bool(false))
 // KeepLaunchConfigurations can be set to prevent garbage collection of old launch configurations
 var KeepLaunchConfigurations = New("KeepLaunchConfigurations", Bool(false))
 
+// EnableLaunchTemplates builds EC2 launch templates rather than launch configurations for AWS instance groups
+var EnableLaunchTemplates = New("EnableLaunchTemplates", Bool(false))
+
 // DNSPreCreate controls whether we pre-create DNS records.
 var DNSPreCreate = New("DNSPreCreate", Bool(true))
 
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/pkg/featureflag"
+	"k8s.io/kops/pkg/validation"
+)
+
+// canaryCheckInterval is the interval at which the health checks are run while the canary instances soak
+var canaryCheckInterval = 30 * time.Second
+
+// CanaryHealthChecks are the checks run against the cluster while the canary instances soak, in addition to cluster validation
+type CanaryHealthChecks struct {
+	// PodsReadyNamespaces are namespaces in which every pod must be ready
+	PodsReadyNamespaces []string
+	// HTTPProbes are URLs which must return a successful status
+	HTTPProbes []string
+}
+
+// CanaryReport is the outcome of the canary stage of a rolling update
+type CanaryReport struct {
+	// Instances are the IDs of the canary instances which were replaced, by instance group
+	Instances map[string][]string
+	// Soaked is how long the health checks were run for
+	Soaked time.Duration
+	// Passed is true once the health checks have passed for the whole soak period
+	Passed bool
+	// Failures are the failed checks which stopped the rolling update, if any
+	Failures []*validation.ValidationError
+}
+
+// canaryState tracks the canary instances of each instance group
+type canaryState struct {
+	mutex sync.Mutex
+	// updating is true while the canary instances are being replaced
+	updating bool
+	report   CanaryReport
+}
+
+// CanaryReport returns the outcome of the canary stage, or nil if the rolling update did not reach it
+func (c *RollingUpdateCluster) CanaryReport() *CanaryReport {
+	if c.canary == nil {
+		return nil
+	}
+
+	c.canary.mutex.Lock()
+	defer c.canary.mutex.Unlock()
+
+	report := c.canary.report
+	return &report
+}
+
+// rollingUpdateCanaries replaces the canary instances of each group, then runs the health checks for the soak period
+func (c *RollingUpdateCluster) rollingUpdateCanaries(groups map[string]*cloudinstances.CloudInstanceGroup, cluster *api.Cluster, instanceGroups *api.InstanceGroupList) error {
+	c.canary = &canaryState{
+		updating: true,
+		report:   CanaryReport{Instances: make(map[string][]string)},
+	}
+
+	glog.Infof("Updating the canary instances of each instance group")
+	if err := c.rollingUpdateGroups(groups, cluster, instanceGroups); err != nil {
+		return fmt.Errorf("error updating canary instances: %v", err)
+	}
+
+	c.canary.mutex.Lock()
+	c.canary.updating = false
+	c.canary.mutex.Unlock()
+
+	glog.Infof("Checking the health of the cluster for %v", c.CanarySoak)
+	start := time.Now()
+	for {
+		failures := c.checkCanaries(cluster, instanceGroups)
+
+		c.canary.mutex.Lock()
+		c.canary.report.Soaked = time.Since(start)
+		c.canary.report.Failures = failures
+		c.canary.mutex.Unlock()
+
+		if len(failures) != 0 {
+			return fmt.Errorf("cluster failed health checks after updating the canary instances, stopping rolling-update")
+		}
+		if time.Since(start) >= c.CanarySoak {
+			break
+		}
+		time.Sleep(canaryCheckInterval)
+	}
+
+	c.canary.mutex.Lock()
+	c.canary.report.Passed = true
+	c.canary.mutex.Unlock()
+
+	glog.Infof("Canary instances are healthy, continuing rolling-update")
+	return nil
+}
+
+// canaryUpdate limits the instances to update to the canaries while they are being replaced, and excludes them afterwards
+func (c *RollingUpdateCluster) canaryUpdate(groupName string, update []*cloudinstances.CloudInstanceGroupMember) ([]*cloudinstances.CloudInstanceGroupMember, error) {
+	if c.canary == nil {
+		return update, nil
+	}
+
+	c.canary.mutex.Lock()
+	defer c.canary.mutex.Unlock()
+
+	if !c.canary.updating {
+		canaries := make(map[string]bool)
+		for _, id := range c.canary.report.Instances[groupName] {
+			canaries[id] = true
+		}
+		var remaining []*cloudinstances.CloudInstanceGroupMember
+		for _, u := range update {
+			if !canaries[u.ID] {
+				remaining = append(remaining, u)
+			}
+		}
+		return remaining, nil
+	}
+
+	count, err := intstr.GetValueFromIntOrPercent(c.Canary, len(update), true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid canary %q: %v", c.Canary.String(), err)
+	}
+	if count > len(update) {
+		count = len(update)
+	}
+
+	canaries := prioritizeUpdate(update)[:count]
+	for _, u := range canaries {
+		c.canary.report.Instances[groupName] = append(c.canary.report.Instances[groupName], u.ID)
+	}
+	return canaries, nil
+}
+
+// updatingCanaries returns true while the canary instances are being replaced
+func (c *RollingUpdateCluster) updatingCanaries() bool {
+	if c.canary == nil {
+		return false
+	}
+
+	c.canary.mutex.Lock()
+	defer c.canary.mutex.Unlock()
+
+	return c.canary.updating
+}
+
+// checkCanaries validates the cluster and runs the health checks, returning the failures
+func (c *RollingUpdateCluster) checkCanaries(cluster *api.Cluster, instanceGroups *api.InstanceGroupList) []*validation.ValidationError {
+	var failures []*validation.ValidationError
+
+	if c.CloudOnly {
+		glog.Warningf("Not validating cluster or checking pods as cloudonly flag is set.")
+	} else {
+		if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+			result, err := validation.ValidateCluster(cluster, instanceGroups, c.K8sClient)
+			if err != nil {
+				failures = append(failures, &validation.ValidationError{
+					Kind:    "Cluster",
+					Name:    cluster.ObjectMeta.Name,
+					Message: fmt.Sprintf("error validating cluster: %v", err),
+				})
+			} else {
+				failures = append(failures, result.Failures...)
+			}
+		}
+
+		for _, namespace := range c.CanaryChecks.PodsReadyNamespaces {
+			failures = append(failures, c.checkPodsReady(namespace)...)
+		}
+	}
+
+	for _, url := range c.CanaryChecks.HTTPProbes {
+		if failure := validation.CheckHTTPEndpoint(url); failure != nil {
+			failures = append(failures, failure)
+		}
+	}
+
+	return failures
+}
+
+// checkPodsReady returns a failure for each pod in the namespace which is not ready, ignoring completed pods
+func (c *RollingUpdateCluster) checkPodsReady(namespace string) []*validation.ValidationError {
+	pods, err := c.K8sClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return []*validation.ValidationError{{
+			Kind:    "Namespace",
+			Name:    namespace,
+			Message: fmt.Sprintf("error listing pods: %v", err),
+		}}
+	}
+
+	var failures []*validation.ValidationError
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+
+		ready := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			failures = append(failures, &validation.ValidationError{
+				Kind:    "Pod",
+				Name:    namespace + "/" + pod.Name,
+				Message: fmt.Sprintf("pod %q in namespace %q is not ready (phase %s)", pod.Name, namespace, pod.Status.Phase),
+			})
+		}
+	}
+	return failures
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func newCanaryTestRollingUpdate(t *testing.T, probeStatus int) (*RollingUpdateCluster, map[string]*cloudinstances.CloudInstanceGroup, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(probeStatus)
+	}))
+
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+
+	canary := intstr.FromString("25%")
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		CloudOnly:       true,
+		Canary:          &canary,
+		CanarySoak:      5 * time.Millisecond,
+		CanaryChecks: CanaryHealthChecks{
+			HTTPProbes: []string{server.URL + "/healthz"},
+		},
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{
+		"node-canary": newSurgeTestGroup(c, "node-canary", nil, "i-00000001", "i-00000002", "i-00000003", "i-00000004"),
+	}
+
+	interval := canaryCheckInterval
+	canaryCheckInterval = time.Millisecond
+	return c, groups, func() {
+		canaryCheckInterval = interval
+		server.Close()
+	}
+}
+
+func remainingInstances(t *testing.T, c *RollingUpdateCluster, name string) []string {
+	asgGroups, err := c.Cloud.(awsup.AWSCloud).Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		t.Fatalf("error describing autoscaling group: %v", err)
+	}
+
+	var ids []string
+	for _, group := range asgGroups.AutoScalingGroups {
+		for _, instance := range group.Instances {
+			ids = append(ids, aws.StringValue(instance.InstanceId))
+		}
+	}
+	return ids
+}
+
+func TestRollingUpdateCanary(t *testing.T) {
+	c, groups, cleanup := newCanaryTestRollingUpdate(t, http.StatusOK)
+	defer cleanup()
+
+	if err := c.RollingUpdate(groups, &kopsapi.Cluster{}, &kopsapi.InstanceGroupList{}); err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	if remaining := remainingInstances(t, c, "node-canary"); len(remaining) != 0 {
+		t.Errorf("expected all instances to be replaced, remaining %v", remaining)
+	}
+
+	report := c.CanaryReport()
+	if report == nil {
+		t.Fatalf("expected a canary report")
+	}
+	if !reflect.DeepEqual(report.Instances, map[string][]string{"node-canary": {"i-00000001"}}) {
+		t.Errorf("unexpected canary instances %v", report.Instances)
+	}
+	if !report.Passed || len(report.Failures) != 0 || report.Soaked < c.CanarySoak {
+		t.Errorf("expected health checks to pass for the soak period, got %+v", report)
+	}
+}
+
+func TestRollingUpdateCanaryFailed(t *testing.T) {
+	c, groups, cleanup := newCanaryTestRollingUpdate(t, http.StatusServiceUnavailable)
+	defer cleanup()
+
+	if err := c.RollingUpdate(groups, &kopsapi.Cluster{}, &kopsapi.InstanceGroupList{}); err == nil {
+		t.Fatalf("expected rolling update to stop when health checks fail")
+	}
+
+	expected := []string{"i-00000002", "i-00000003", "i-00000004"}
+	if remaining := remainingInstances(t, c, "node-canary"); !reflect.DeepEqual(remaining, expected) {
+		t.Errorf("expected only the canary instance to be replaced, remaining %v", remaining)
+	}
+
+	report := c.CanaryReport()
+	if report == nil || report.Passed || len(report.Failures) != 1 || report.Failures[0].Kind != "HTTPEndpoint" {
+		t.Errorf("expected the HTTP probe to be reported as failed, got %+v", report)
+	}
+}
+
+func TestCheckPodsReady(t *testing.T) {
+	pod := func(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: name},
+			Status: corev1.PodStatus{
+				Phase:      phase,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+
+	c := &RollingUpdateCluster{
+		K8sClient: fake.NewSimpleClientset(
+			pod("ready", corev1.PodRunning, corev1.ConditionTrue),
+			pod("not-ready", corev1.PodRunning, corev1.ConditionFalse),
+			pod("pending", corev1.PodPending, corev1.ConditionFalse),
+			pod("completed", corev1.PodSucceeded, corev1.ConditionFalse),
+		),
+	}
+
+	var names []string
+	for _, failure := range c.checkPodsReady("app") {
+		names = append(names, failure.Name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"app/not-ready", "app/pending"}) {
+		t.Errorf("unexpected pods not ready %v", names)
+	}
+}
@@ -0,0 +1,390 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kubernetes/pkg/kubectl/cmd"
+)
+
+// evictionRetryInterval is the time to wait before retrying an eviction refused because of a PodDisruptionBudget
+var evictionRetryInterval = 5 * time.Second
+
+// podDeletionPollInterval is the interval at which we check whether evicted pods have terminated
+var podDeletionPollInterval = time.Second
+
+// DefaultDrainTimeout is the maximum time to wait for the pods of a node to be evicted, when no timeout is set
+const DefaultDrainTimeout = 15 * time.Minute
+
+// BlockedEviction is a pod whose eviction was refused because it would violate a PodDisruptionBudget
+type BlockedEviction struct {
+	// Node is the name of the node being drained
+	Node string
+	// Namespace is the namespace of the pod
+	Namespace string
+	// Name is the name of the pod
+	Name string
+	// Budgets are the names of the PodDisruptionBudgets selecting the pod
+	Budgets []string
+	// Waited is how long the eviction was refused for
+	Waited time.Duration
+	// Evicted is false if the pod had still not been evicted when the drain stopped
+	Evicted bool
+}
+
+// drainBlockedError is returned when a node runs pods which must not be evicted.
+// Unlike other drain errors, it stops the rolling update even if FailOnDrainError is false.
+type drainBlockedError struct {
+	node string
+	pods []string
+}
+
+func (e *drainBlockedError) Error() string {
+	return fmt.Sprintf("node %q is running pods which block draining: %s", e.node, strings.Join(e.pods, ", "))
+}
+
+// resolveDrainSettings merges the drain settings with the overrides from the command line, and applies the defaults
+func resolveDrainSettings(drain *api.DrainSpec, overrides *api.DrainSpec) *api.DrainSpec {
+	resolved := mergeDrainSpec(drain, overrides)
+	if resolved.Timeout == nil {
+		resolved.Timeout = &metav1.Duration{Duration: DefaultDrainTimeout}
+	}
+	if resolved.DeleteLocalData == nil {
+		deleteLocalData := true
+		resolved.DeleteLocalData = &deleteLocalData
+	}
+	return resolved
+}
+
+// mergeDrainSpec returns the drain settings of base, overridden by the fields which are set in overrides
+func mergeDrainSpec(base *api.DrainSpec, overrides *api.DrainSpec) *api.DrainSpec {
+	merged := &api.DrainSpec{}
+	if base != nil {
+		*merged = *base
+	}
+	if overrides == nil {
+		return merged
+	}
+
+	if overrides.Timeout != nil {
+		merged.Timeout = overrides.Timeout
+	}
+	if overrides.GracePeriodSeconds != nil {
+		merged.GracePeriodSeconds = overrides.GracePeriodSeconds
+	}
+	if overrides.DeleteLocalData != nil {
+		merged.DeleteLocalData = overrides.DeleteLocalData
+	}
+	if overrides.SkipPodSelector != "" {
+		merged.SkipPodSelector = overrides.SkipPodSelector
+	}
+	if overrides.BlockingPodSelector != "" {
+		merged.BlockingPodSelector = overrides.BlockingPodSelector
+	}
+	return merged
+}
+
+// parsePodSelector parses a pod label selector; an empty selector matches no pods
+func parsePodSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return labels.Nothing(), nil
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod selector %q: %v", selector, err)
+	}
+	return parsed, nil
+}
+
+// recordBlockedEviction adds a pod to the report of evictions blocked by PodDisruptionBudgets
+func (c *RollingUpdateCluster) recordBlockedEviction(blocked *BlockedEviction) {
+	c.blockedEvictionsMutex.Lock()
+	defer c.blockedEvictionsMutex.Unlock()
+
+	c.blockedEvictions = append(c.blockedEvictions, blocked)
+}
+
+// updateBlockedEviction records how long an eviction has been blocked for, and whether it succeeded
+func (c *RollingUpdateCluster) updateBlockedEviction(blocked *BlockedEviction, waited time.Duration, evicted bool) {
+	c.blockedEvictionsMutex.Lock()
+	defer c.blockedEvictionsMutex.Unlock()
+
+	blocked.Waited = waited
+	blocked.Evicted = evicted
+}
+
+// BlockedEvictions returns the pods whose eviction was refused because of a PodDisruptionBudget during the rolling update
+func (c *RollingUpdateCluster) BlockedEvictions() []BlockedEviction {
+	c.blockedEvictionsMutex.Lock()
+	defer c.blockedEvictionsMutex.Unlock()
+
+	var blocked []BlockedEviction
+	for _, b := range c.blockedEvictions {
+		blocked = append(blocked, *b)
+	}
+	return blocked
+}
+
+// drainNode cordons the node and evicts its pods, except for mirror pods, daemonset pods and pods skipped by the settings
+func (r *RollingUpdateInstanceGroup) drainNode(rollingUpdateData *RollingUpdateCluster, nodeName string, drain *api.DrainSpec) error {
+	k8sClient := rollingUpdateData.K8sClient
+
+	skipSelector, err := parsePodSelector(drain.SkipPodSelector)
+	if err != nil {
+		return err
+	}
+	blockingSelector, err := parsePodSelector(drain.BlockingPodSelector)
+	if err != nil {
+		return err
+	}
+
+	if err := cordonNode(k8sClient, nodeName, true); err != nil {
+		return err
+	}
+
+	podList, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("error listing pods on node %q: %v", nodeName, err)
+	}
+
+	var pods []corev1.Pod
+	var blocking []string
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		if _, found := pod.Annotations[corev1.MirrorPodAnnotationKey]; found {
+			continue
+		}
+		if controllerRef := metav1.GetControllerOf(&pod); controllerRef != nil && controllerRef.Kind == "DaemonSet" {
+			continue
+		}
+
+		podLabels := labels.Set(pod.Labels)
+		switch {
+		case blockingSelector.Matches(podLabels):
+			blocking = append(blocking, pod.Namespace+"/"+pod.Name)
+		case skipSelector.Matches(podLabels):
+			glog.Infof("Not evicting pod %s/%s from node %q, as it matches the skip selector", pod.Namespace, pod.Name, nodeName)
+		case hasLocalData(&pod) && !fi.BoolValue(drain.DeleteLocalData):
+			blocking = append(blocking, pod.Namespace+"/"+pod.Name+" (emptyDir local data)")
+		default:
+			pods = append(pods, pod)
+		}
+	}
+
+	if len(blocking) != 0 {
+		// Leave the node as we found it, as we are not going to replace it
+		if err := cordonNode(k8sClient, nodeName, false); err != nil {
+			glog.Warningf("error uncordoning node %q: %v", nodeName, err)
+		}
+		return &drainBlockedError{node: nodeName, pods: blocking}
+	}
+
+	if len(pods) == 0 {
+		return nil
+	}
+
+	policyGroupVersion, err := cmd.SupportEviction(k8sClient)
+	if err != nil {
+		return fmt.Errorf("error checking for eviction support: %v", err)
+	}
+
+	var deadline time.Time
+	if drain.Timeout != nil && drain.Timeout.Duration > 0 {
+		deadline = time.Now().Add(drain.Timeout.Duration)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(pods))
+	for i := range pods {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = r.evictPod(rollingUpdateData, &pods[i], policyGroupVersion, drain, deadline)
+		}(i)
+	}
+	wg.Wait()
+
+	var remaining []string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if err != errDrainTimeout {
+			return err
+		}
+		remaining = append(remaining, pods[i].Namespace+"/"+pods[i].Name)
+	}
+	if len(remaining) != 0 {
+		return fmt.Errorf("drain did not complete within %v, pods not evicted: %s", drain.Timeout.Duration, strings.Join(remaining, ", "))
+	}
+
+	return nil
+}
+
+// errDrainTimeout is returned by evictPod when the pod is not evicted before the deadline
+var errDrainTimeout = fmt.Errorf("timed out evicting pod")
+
+// evictPod evicts a pod (or deletes it if the cluster doesn't support eviction) and waits for it to terminate,
+// retrying for as long as a PodDisruptionBudget refuses the eviction
+func (r *RollingUpdateInstanceGroup) evictPod(rollingUpdateData *RollingUpdateCluster, pod *corev1.Pod, policyGroupVersion string, drain *api.DrainSpec, deadline time.Time) error {
+	k8sClient := rollingUpdateData.K8sClient
+	expired := func() bool {
+		return !deadline.IsZero() && time.Now().After(deadline)
+	}
+
+	deleteOptions := &metav1.DeleteOptions{}
+	if drain.GracePeriodSeconds != nil {
+		deleteOptions.GracePeriodSeconds = drain.GracePeriodSeconds
+	}
+
+	var blocked *BlockedEviction
+	var blockedSince time.Time
+	for {
+		var err error
+		if policyGroupVersion != "" {
+			err = k8sClient.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policyv1beta1.Eviction{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: policyGroupVersion,
+					Kind:       cmd.EvictionKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pod.Name,
+					Namespace: pod.Namespace,
+				},
+				DeleteOptions: deleteOptions,
+			})
+		} else {
+			err = k8sClient.CoreV1().Pods(pod.Namespace).Delete(pod.Name, deleteOptions)
+		}
+
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("error evicting pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+
+		// The eviction would violate a PodDisruptionBudget
+		if blocked == nil {
+			blockedSince = time.Now()
+			blocked = &BlockedEviction{
+				Node:      pod.Spec.NodeName,
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Budgets:   findDisruptionBudgets(k8sClient, pod),
+			}
+			rollingUpdateData.recordBlockedEviction(blocked)
+			glog.Warningf("Eviction of pod %s/%s would violate its PodDisruptionBudget %s, retrying", pod.Namespace, pod.Name, strings.Join(blocked.Budgets, ", "))
+		}
+		rollingUpdateData.updateBlockedEviction(blocked, time.Since(blockedSince), false)
+
+		if expired() {
+			return errDrainTimeout
+		}
+		time.Sleep(evictionRetryInterval)
+	}
+
+	if blocked != nil {
+		rollingUpdateData.updateBlockedEviction(blocked, time.Since(blockedSince), true)
+	}
+
+	for {
+		p, err := k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error waiting for pod %s/%s to terminate: %v", pod.Namespace, pod.Name, err)
+		}
+		if expired() {
+			return errDrainTimeout
+		}
+		time.Sleep(podDeletionPollInterval)
+	}
+}
+
+// cordonNode marks the node as unschedulable, or schedulable again
+func cordonNode(k8sClient kubernetes.Interface, nodeName string, unschedulable bool) error {
+	node, err := k8sClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting node %q: %v", nodeName, err)
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = unschedulable
+	if _, err := k8sClient.CoreV1().Nodes().Update(node); err != nil {
+		if unschedulable {
+			return fmt.Errorf("error cordoning node %q: %v", nodeName, err)
+		}
+		return fmt.Errorf("error uncordoning node %q: %v", nodeName, err)
+	}
+	return nil
+}
+
+// hasLocalData returns true if the pod uses emptyDir volumes, whose data is lost when it is evicted
+func hasLocalData(pod *corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findDisruptionBudgets returns the names of the PodDisruptionBudgets selecting the pod, for the report of blocked evictions
+func findDisruptionBudgets(k8sClient kubernetes.Interface, pod *corev1.Pod) []string {
+	budgets, err := k8sClient.PolicyV1beta1().PodDisruptionBudgets(pod.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Warningf("error listing PodDisruptionBudgets in namespace %q: %v", pod.Namespace, err)
+		return nil
+	}
+
+	var names []string
+	for _, budget := range budgets.Items {
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil {
+			glog.Warningf("ignoring PodDisruptionBudget %s/%s with invalid selector: %v", budget.Namespace, budget.Name, err)
+			continue
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+			names = append(names, budget.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
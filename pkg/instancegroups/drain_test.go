@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	policyclient "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// drainTestCluster is a fake kubernetes cluster, whose evictions are refused for the pods in blockedPods
+type drainTestCluster struct {
+	k8sClient *drainTestClient
+
+	mutex sync.Mutex
+	// blockedPods is the number of times the eviction of each pod is refused; -1 refuses it forever
+	blockedPods map[string]int
+	evicted     []string
+}
+
+// drainTestClient is a fake clientset whose evictions are handled by the drainTestCluster,
+// as the fake clientset doesn't pass the eviction to its reactors
+type drainTestClient struct {
+	*fake.Clientset
+	cluster *drainTestCluster
+}
+
+func (c *drainTestClient) PolicyV1beta1() policyclient.PolicyV1beta1Interface {
+	return &drainTestPolicyClient{PolicyV1beta1Interface: c.Clientset.PolicyV1beta1(), cluster: c.cluster}
+}
+
+type drainTestPolicyClient struct {
+	policyclient.PolicyV1beta1Interface
+	cluster *drainTestCluster
+}
+
+func (c *drainTestPolicyClient) Evictions(namespace string) policyclient.EvictionInterface {
+	return c.cluster
+}
+
+func newDrainTestCluster(t *testing.T, objects ...runtime.Object) *drainTestCluster {
+	c := &drainTestCluster{
+		blockedPods: make(map[string]int),
+	}
+
+	objects = append(objects,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+		&policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: policyv1beta1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		},
+	)
+	c.k8sClient = &drainTestClient{Clientset: fake.NewSimpleClientset(objects...), cluster: c}
+	c.k8sClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "policy/v1beta1",
+		},
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods/eviction", Kind: "Eviction"}},
+		},
+	}
+
+	return c
+}
+
+// Evict refuses the eviction of the pods in blockedPods, and deletes the other pods
+func (c *drainTestCluster) Evict(eviction *policyv1beta1.Eviction) error {
+	c.mutex.Lock()
+	if n := c.blockedPods[eviction.Name]; n != 0 {
+		if n > 0 {
+			c.blockedPods[eviction.Name] = n - 1
+		}
+		c.mutex.Unlock()
+		return apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	}
+	c.evicted = append(c.evicted, eviction.Namespace+"/"+eviction.Name)
+	c.mutex.Unlock()
+
+	return c.k8sClient.CoreV1().Pods(eviction.Namespace).Delete(eviction.Name, eviction.DeleteOptions)
+}
+
+func (c *drainTestCluster) Evicted() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	evicted := append([]string{}, c.evicted...)
+	sort.Strings(evicted)
+	return evicted
+}
+
+func (c *drainTestCluster) Unschedulable(t *testing.T) bool {
+	node, err := c.k8sClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting node: %v", err)
+	}
+	return node.Spec.Unschedulable
+}
+
+func testPod(name string, nodeName string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Labels:    labels,
+			UID:       types.UID("uid-" + name),
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+		},
+	}
+}
+
+func newDrainTestRollingUpdate(t *testing.T, c *drainTestCluster) (*RollingUpdateCluster, *RollingUpdateInstanceGroup) {
+	rollingUpdateData := &RollingUpdateCluster{
+		Cloud:     awsup.BuildMockAWSCloud("us-east-1", "abc"),
+		K8sClient: c.k8sClient,
+	}
+	r := &RollingUpdateInstanceGroup{Cloud: rollingUpdateData.Cloud}
+	return rollingUpdateData, r
+}
+
+func setDrainTestIntervals() func() {
+	retryInterval, pollInterval := evictionRetryInterval, podDeletionPollInterval
+	evictionRetryInterval = time.Millisecond
+	podDeletionPollInterval = time.Millisecond
+	return func() {
+		evictionRetryInterval, podDeletionPollInterval = retryInterval, pollInterval
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	defer setDrainTestIntervals()()
+
+	daemonSetPod := testPod("daemonset", "node-a", nil)
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: fi.Bool(true)}}
+	mirrorPod := testPod("mirror", "node-a", nil)
+	mirrorPod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}
+	localDataPod := testPod("local-data", "node-a", nil)
+	localDataPod.Spec.Volumes = []corev1.Volume{{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+
+	c := newDrainTestCluster(t,
+		testPod("web-1", "node-a", map[string]string{"app": "web"}),
+		testPod("batch-1", "node-a", map[string]string{"app": "batch"}),
+		testPod("skipped", "node-a", map[string]string{"kops.k8s.io/skip-drain": "true"}),
+		testPod("other-node", "node-b", nil),
+		daemonSetPod,
+		mirrorPod,
+		localDataPod,
+	)
+	c.blockedPods["web-1"] = 2
+
+	rollingUpdateData, r := newDrainTestRollingUpdate(t, c)
+	drain := resolveDrainSettings(&kopsapi.DrainSpec{SkipPodSelector: "kops.k8s.io/skip-drain=true"}, nil)
+	if err := r.drainNode(rollingUpdateData, "node-a", drain); err != nil {
+		t.Fatalf("error draining node: %v", err)
+	}
+
+	expected := []string{"default/batch-1", "default/local-data", "default/web-1"}
+	if evicted := c.Evicted(); !reflect.DeepEqual(evicted, expected) {
+		t.Errorf("unexpected evicted pods %v, expected %v", evicted, expected)
+	}
+	if !c.Unschedulable(t) {
+		t.Errorf("expected node to be cordoned")
+	}
+
+	blocked := rollingUpdateData.BlockedEvictions()
+	if len(blocked) != 1 {
+		t.Fatalf("expected one blocked eviction, got %v", blocked)
+	}
+	if blocked[0].Name != "web-1" || blocked[0].Node != "node-a" || !blocked[0].Evicted || !reflect.DeepEqual(blocked[0].Budgets, []string{"web"}) {
+		t.Errorf("unexpected blocked eviction %+v", blocked[0])
+	}
+}
+
+func TestDrainNodeTimeout(t *testing.T) {
+	defer setDrainTestIntervals()()
+
+	c := newDrainTestCluster(t,
+		testPod("web-1", "node-a", map[string]string{"app": "web"}),
+		testPod("batch-1", "node-a", nil),
+	)
+	c.blockedPods["web-1"] = -1
+
+	rollingUpdateData, r := newDrainTestRollingUpdate(t, c)
+	drain := resolveDrainSettings(&kopsapi.DrainSpec{Timeout: &metav1.Duration{Duration: 20 * time.Millisecond}}, nil)
+	err := r.drainNode(rollingUpdateData, "node-a", drain)
+	if err == nil || !strings.Contains(err.Error(), "default/web-1") {
+		t.Fatalf("expected timeout evicting web-1, got %v", err)
+	}
+	if _, ok := err.(*drainBlockedError); ok {
+		t.Errorf("timeout should not be a drainBlockedError")
+	}
+
+	if evicted := c.Evicted(); !reflect.DeepEqual(evicted, []string{"default/batch-1"}) {
+		t.Errorf("unexpected evicted pods %v", evicted)
+	}
+
+	blocked := rollingUpdateData.BlockedEvictions()
+	if len(blocked) != 1 || blocked[0].Evicted || blocked[0].Waited == 0 {
+		t.Errorf("unexpected blocked evictions %+v", blocked)
+	}
+}
+
+func TestDrainNodeBlocked(t *testing.T) {
+	defer setDrainTestIntervals()()
+
+	localDataPod := testPod("local-data", "node-a", nil)
+	localDataPod.Spec.Volumes = []corev1.Volume{{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+
+	grid := []struct {
+		Drain    *kopsapi.DrainSpec
+		Expected string
+	}{
+		{
+			Drain:    &kopsapi.DrainSpec{BlockingPodSelector: "app=database"},
+			Expected: "default/database-1",
+		},
+		{
+			Drain:    &kopsapi.DrainSpec{DeleteLocalData: fi.Bool(false)},
+			Expected: "default/local-data (emptyDir local data)",
+		},
+	}
+
+	for _, g := range grid {
+		c := newDrainTestCluster(t,
+			testPod("web-1", "node-a", map[string]string{"app": "web"}),
+			testPod("database-1", "node-a", map[string]string{"app": "database"}),
+			localDataPod.DeepCopy(),
+		)
+
+		rollingUpdateData, r := newDrainTestRollingUpdate(t, c)
+		err := r.drainNode(rollingUpdateData, "node-a", resolveDrainSettings(g.Drain, nil))
+		blockedErr, ok := err.(*drainBlockedError)
+		if !ok {
+			t.Errorf("expected drainBlockedError, got %v", err)
+			continue
+		}
+		if !reflect.DeepEqual(blockedErr.pods, []string{g.Expected}) {
+			t.Errorf("unexpected blocking pods %v, expected %q", blockedErr.pods, g.Expected)
+		}
+
+		if evicted := c.Evicted(); len(evicted) != 0 {
+			t.Errorf("unexpected evicted pods %v", evicted)
+		}
+		if c.Unschedulable(t) {
+			t.Errorf("expected node to be uncordoned")
+		}
+	}
+}
+
+func TestResolveDrainSettings(t *testing.T) {
+	cluster := &kopsapi.DrainSpec{
+		Timeout:         &metav1.Duration{Duration: time.Minute},
+		SkipPodSelector: "a=b",
+	}
+	overrides := &kopsapi.DrainSpec{
+		Timeout:            &metav1.Duration{Duration: time.Second},
+		GracePeriodSeconds: fi.Int64(10),
+	}
+
+	actual := resolveDrainSettings(cluster, overrides)
+	expected := &kopsapi.DrainSpec{
+		Timeout:            &metav1.Duration{Duration: time.Second},
+		GracePeriodSeconds: fi.Int64(10),
+		DeleteLocalData:    fi.Bool(true),
+		SkipPodSelector:    "a=b",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected drain settings %+v, expected %+v", actual, expected)
+	}
+
+	actual = resolveDrainSettings(nil, nil)
+	if actual.Timeout == nil || actual.Timeout.Duration != DefaultDrainTimeout {
+		t.Errorf("expected default drain timeout %v, got %v", DefaultDrainTimeout, actual.Timeout)
+	}
+
+	actual = resolveDrainSettings(nil, &kopsapi.DrainSpec{Timeout: &metav1.Duration{}})
+	if actual.Timeout == nil || actual.Timeout.Duration != 0 {
+		t.Errorf("expected a drain timeout of 0 to be kept, got %v", actual.Timeout)
+	}
+}
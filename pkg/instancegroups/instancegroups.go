@@ -32,9 +32,6 @@ import (
 	"k8s.io/kops/pkg/featureflag"
 	"k8s.io/kops/pkg/validation"
 	"k8s.io/kops/upup/pkg/fi"
-	"k8s.io/kubernetes/pkg/kubectl/cmd"
-	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
-	"k8s.io/kubernetes/pkg/kubectl/genericclioptions"
 )
 
 // RollingUpdateInstanceGroup is the AWS ASG backing an InstanceGroup.
@@ -100,10 +97,6 @@ func promptInteractive(upgradedHostId, upgradedHostName string) (stopPrompting b
 	return stopPrompting, err
 }
 
-// TODO: Temporarily increase size of ASG?
-// TODO: Remove from ASG first so status is immediately updated?
-// TODO: Batch termination, like a rolling-update
-
 // RollingUpdate performs a rolling update on a list of ec2 instances.
 func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpdateCluster, cluster *api.Cluster, instanceGroupList *api.InstanceGroupList, isBastion bool, sleepAfterTerminate time.Duration, validationTimeout time.Duration) (err error) {
 
@@ -121,10 +114,17 @@ func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpd
 		return fmt.Errorf("rollingUpdate is missing the InstanceGroupList")
 	}
 
+	noneReady := len(r.CloudGroup.Ready) == 0
+	numInstances := len(r.CloudGroup.Ready) + len(r.CloudGroup.NeedUpdate)
 	update := r.CloudGroup.NeedUpdate
 	if rollingUpdateData.Force {
 		update = append(update, r.CloudGroup.Ready...)
 	}
+	update = rollingUpdateData.Progress.filterUpdate(r.CloudGroup.InstanceGroup.ObjectMeta.Name, r.CloudGroup, update)
+	update, err = rollingUpdateData.canaryUpdate(r.CloudGroup.InstanceGroup.ObjectMeta.Name, update)
+	if err != nil {
+		return err
+	}
 
 	if len(update) == 0 {
 		return nil
@@ -145,90 +145,259 @@ func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpd
 		}
 	}
 
-	for _, u := range update {
-		instanceId := u.ID
+	settings := resolveSettings(cluster, r.CloudGroup.InstanceGroup, numInstances)
+	drain := resolveDrainSettings(settings.Drain, rollingUpdateData.Drain)
 
-		nodeName := ""
-		if u.Node != nil {
-			nodeName = u.Node.Name
+	maxSurge := settings.MaxSurge.IntValue()
+	if maxSurge > len(update) {
+		maxSurge = len(update)
+	}
+	maxConcurrency := maxSurge + settings.MaxUnavailable.IntValue()
+
+	if maxConcurrency == 0 {
+		glog.Infof("Rolling updates for InstanceGroup %s are disabled", r.CloudGroup.InstanceGroup.ObjectMeta.Name)
+		return nil
+	}
+
+	if r.CloudGroup.InstanceGroup.IsMaster() && maxSurge != 0 {
+		// Masters are incapable of surging because they rely on registering themselves through
+		// the local apiserver. That apiserver depends on the local etcd, which relies on being
+		// joined to the etcd cluster.
+		maxSurge = 0
+		maxConcurrency = settings.MaxUnavailable.IntValue()
+		if maxConcurrency == 0 {
+			maxConcurrency = 1
 		}
+	}
 
-		if isBastion {
-			// We don't want to validate for bastions - they aren't part of the cluster
-		} else if rollingUpdateData.CloudOnly {
+	if rollingUpdateData.Interactive {
+		if maxSurge > 1 {
+			maxSurge = 1
+		}
+		maxConcurrency = 1
+	}
 
-			glog.Warning("Not draining cluster nodes as 'cloudonly' flag is set.")
+	update = prioritizeUpdate(update)
 
-		} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+	if maxSurge > 0 && !rollingUpdateData.CloudOnly {
+		// We detach from the end of the list, so that instances detached by an earlier (interrupted) run count towards the surge
+		for numSurge := 1; numSurge <= maxSurge; numSurge++ {
+			u := update[len(update)-numSurge]
+			if u.Detached {
+				continue
+			}
 
-			if u.Node != nil {
-				glog.Infof("Draining the node: %q.", nodeName)
-
-				if err = r.DrainNode(u, rollingUpdateData); err != nil {
-					if rollingUpdateData.FailOnDrainError {
-						return fmt.Errorf("failed to drain node %q: %v", nodeName, err)
-					} else {
-						glog.Infof("Ignoring error draining node %q: %v", nodeName, err)
-					}
-				}
-			} else {
-				glog.Warningf("Skipping drain of instance %q, because it is not registered in kubernetes", instanceId)
+			if err = r.DetachInstance(u); err != nil {
+				return err
 			}
-		}
 
-		// We unregister the node before deleting it; if the replacement comes up with the same name it would otherwise still be cordoned
-		// (It often seems like GCE tries to re-use names)
-		if !isBastion && !rollingUpdateData.CloudOnly {
-			if u.Node == nil {
-				glog.Warningf("no kubernetes Node associated with %s, skipping node deletion", instanceId)
-			} else {
-				glog.Infof("deleting node %q from kubernetes", nodeName)
-				if err := r.deleteNode(u.Node, rollingUpdateData); err != nil {
-					return fmt.Errorf("error deleting node %q: %v", nodeName, err)
+			// If noneReady, wait until after one instance is detached and its replacement validates
+			// before detaching more, in case the current spec does not result in usable nodes.
+			if numSurge == maxSurge || noneReady {
+				// Wait for the minimum interval
+				glog.Infof("waiting for %v after detaching instance", sleepAfterTerminate)
+				time.Sleep(sleepAfterTerminate)
+
+				if err = r.maybeValidate(rollingUpdateData, cluster, instanceGroupList, isBastion, validationTimeout, " after detaching instance"); err != nil {
+					return err
 				}
+				noneReady = false
 			}
 		}
+	}
 
-		if err = r.DeleteInstance(u); err != nil {
-			glog.Errorf("error deleting instance %q, node %q: %v", instanceId, nodeName, err)
-			return err
-		}
-
-		// Wait for the minimum interval
-		glog.Infof("waiting for %v after terminating instance", sleepAfterTerminate)
-		time.Sleep(sleepAfterTerminate)
+	terminateChan := make(chan error, maxConcurrency)
+	runningDrains := 0
 
-		if isBastion {
-			glog.Infof("Deleted a bastion instance, %s, and continuing with rolling-update.", instanceId)
+	for uIdx, u := range update {
+		go func(m *cloudinstances.CloudInstanceGroupMember) {
+			terminateChan <- r.drainTerminateAndWait(m, rollingUpdateData, drain, isBastion, sleepAfterTerminate)
+		}(u)
+		runningDrains++
 
+		// Wait until after one instance is deleted and its replacement validates before draining concurrently,
+		// in case the current spec does not result in usable nodes.
+		if runningDrains < maxConcurrency && (!noneReady || uIdx > 0) {
 			continue
-		} else if rollingUpdateData.CloudOnly {
-			glog.Warningf("Not validating cluster as cloudonly flag is set.")
-
-		} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
-			glog.Info("Validating the cluster.")
+		}
 
-			if err = r.ValidateClusterWithDuration(rollingUpdateData, cluster, instanceGroupList, validationTimeout); err != nil {
+		err = <-terminateChan
+		runningDrains--
+		if err != nil {
+			return waitForPendingBeforeReturningError(runningDrains, terminateChan, err)
+		}
 
-				if rollingUpdateData.FailOnValidate {
-					glog.Errorf("Cluster did not validate within %s", validationTimeout)
-					return fmt.Errorf("error validating cluster after removing a node: %v", err)
-				}
+		if isBastion {
+			glog.Infof("Deleted a bastion instance, %s, and continuing with rolling-update.", u.ID)
+			continue
+		}
 
-				glog.Warningf("Cluster validation failed after removing instance, proceeding since fail-on-validate is set to false: %v", err)
-			}
+		if err = r.maybeValidate(rollingUpdateData, cluster, instanceGroupList, isBastion, validationTimeout, " after removing a node"); err != nil {
+			return waitForPendingBeforeReturningError(runningDrains, terminateChan, err)
 		}
 
 		if rollingUpdateData.Interactive {
+			nodeName := ""
+			if u.Node != nil {
+				nodeName = u.Node.Name
+			}
+
 			stopPrompting, err := promptInteractive(u.ID, nodeName)
 			if err != nil {
-				return err
+				return waitForPendingBeforeReturningError(runningDrains, terminateChan, err)
 			}
 			if stopPrompting {
 				// Is a pointer to a struct, changes here push back into the original
 				rollingUpdateData.Interactive = false
 			}
 		}
+
+		// Collect any other drains that have completed in the meantime, without waiting
+	sweep:
+		for runningDrains > 0 {
+			select {
+			case err = <-terminateChan:
+				runningDrains--
+				if err != nil {
+					return waitForPendingBeforeReturningError(runningDrains, terminateChan, err)
+				}
+			default:
+				break sweep
+			}
+		}
+	}
+
+	if runningDrains > 0 {
+		for runningDrains > 0 {
+			err = <-terminateChan
+			runningDrains--
+			if err != nil {
+				return waitForPendingBeforeReturningError(runningDrains, terminateChan, err)
+			}
+		}
+
+		if err = r.maybeValidate(rollingUpdateData, cluster, instanceGroupList, isBastion, validationTimeout, " after removing a node"); err != nil {
+			return err
+		}
+	}
+
+	if !rollingUpdateData.updatingCanaries() {
+		rollingUpdateData.Progress.completed(r.CloudGroup.InstanceGroup.ObjectMeta.Name)
+	}
+	return nil
+}
+
+// waitForPendingBeforeReturningError waits for any in-flight drains to complete, so that we don't
+// leave them running in the background, and then returns the original error
+func waitForPendingBeforeReturningError(runningDrains int, terminateChan chan error, err error) error {
+	for runningDrains > 0 {
+		<-terminateChan
+		runningDrains--
+	}
+	return err
+}
+
+// prioritizeUpdate returns the instances to update in priority order: attached instances before
+// detached instances, otherwise preserving the original order
+func prioritizeUpdate(update []*cloudinstances.CloudInstanceGroupMember) []*cloudinstances.CloudInstanceGroupMember {
+	result := make([]*cloudinstances.CloudInstanceGroupMember, 0, len(update))
+	var detached []*cloudinstances.CloudInstanceGroupMember
+	for _, u := range update {
+		if u.Detached {
+			detached = append(detached, u)
+		} else {
+			result = append(result, u)
+		}
+	}
+	return append(result, detached...)
+}
+
+// drainTerminateAndWait drains the node of an instance, deletes the instance and then waits for the
+// minimum interval; it is safe to call concurrently for different instances
+func (r *RollingUpdateInstanceGroup) drainTerminateAndWait(u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster, drain *api.DrainSpec, isBastion bool, sleepAfterTerminate time.Duration) error {
+	instanceId := u.ID
+	groupName := r.CloudGroup.InstanceGroup.ObjectMeta.Name
+
+	nodeName := ""
+	if u.Node != nil {
+		nodeName = u.Node.Name
+	}
+
+	if isBastion {
+		// We don't want to drain bastions - they aren't part of the cluster
+	} else if rollingUpdateData.CloudOnly {
+
+		glog.Warning("Not draining cluster nodes as 'cloudonly' flag is set.")
+
+	} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+
+		if u.Node != nil {
+			glog.Infof("Draining the node: %q.", nodeName)
+
+			rollingUpdateData.Progress.cordoned(groupName, u)
+			if err := r.DrainNode(u, rollingUpdateData, drain); err != nil {
+				// Pods which block draining stop the update, as deleting the instance would stop them
+				if _, blocked := err.(*drainBlockedError); blocked {
+					// The drain uncordoned the node
+					rollingUpdateData.Progress.uncordoned(nodeName)
+					return fmt.Errorf("failed to drain node %q: %v", nodeName, err)
+				}
+				if rollingUpdateData.FailOnDrainError {
+					return fmt.Errorf("failed to drain node %q: %v", nodeName, err)
+				} else {
+					glog.Infof("Ignoring error draining node %q: %v", nodeName, err)
+				}
+			}
+		} else {
+			glog.Warningf("Skipping drain of instance %q, because it is not registered in kubernetes", instanceId)
+		}
+	}
+
+	// We unregister the node before deleting it; if the replacement comes up with the same name it would otherwise still be cordoned
+	// (It often seems like GCE tries to re-use names)
+	if !isBastion && !rollingUpdateData.CloudOnly {
+		if u.Node == nil {
+			glog.Warningf("no kubernetes Node associated with %s, skipping node deletion", instanceId)
+		} else {
+			glog.Infof("deleting node %q from kubernetes", nodeName)
+			if err := r.deleteNode(u.Node, rollingUpdateData); err != nil {
+				return fmt.Errorf("error deleting node %q: %v", nodeName, err)
+			}
+		}
+	}
+
+	if err := r.DeleteInstance(u); err != nil {
+		glog.Errorf("error deleting instance %q, node %q: %v", instanceId, nodeName, err)
+		return err
+	}
+	rollingUpdateData.Progress.deleted(groupName, u)
+
+	// Wait for the minimum interval
+	glog.Infof("waiting for %v after terminating instance", sleepAfterTerminate)
+	time.Sleep(sleepAfterTerminate)
+
+	return nil
+}
+
+// maybeValidate validates the cluster, unless validation is turned off for this instance group
+func (r *RollingUpdateInstanceGroup) maybeValidate(rollingUpdateData *RollingUpdateCluster, cluster *api.Cluster, instanceGroupList *api.InstanceGroupList, isBastion bool, validationTimeout time.Duration, operation string) error {
+	if isBastion {
+		// We don't want to validate for bastions - they aren't part of the cluster
+	} else if rollingUpdateData.CloudOnly {
+		glog.Warningf("Not validating cluster as cloudonly flag is set.")
+
+	} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+		glog.Info("Validating the cluster.")
+
+		if err := r.ValidateClusterWithDuration(rollingUpdateData, cluster, instanceGroupList, validationTimeout); err != nil {
+
+			if rollingUpdateData.FailOnValidate {
+				glog.Errorf("Cluster did not validate within %s", validationTimeout)
+				return fmt.Errorf("error validating cluster%s: %v", operation, err)
+			}
+
+			glog.Warningf("Cluster validation failed%s, proceeding since fail-on-validate is set to false: %v", operation, err)
+		}
 	}
 
 	return nil
@@ -313,44 +482,46 @@ func (r *RollingUpdateInstanceGroup) DeleteInstance(u *cloudinstances.CloudInsta
 
 }
 
-// DrainNode drains a K8s node.
-func (r *RollingUpdateInstanceGroup) DrainNode(u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster) error {
-	if rollingUpdateData.ClientGetter == nil {
-		return fmt.Errorf("ClientGetter not set")
+// DetachInstance detaches a Cloud Instance from its group, so that the group launches a replacement for it.
+func (r *RollingUpdateInstanceGroup) DetachInstance(u *cloudinstances.CloudInstanceGroupMember) error {
+	id := u.ID
+	nodeName := ""
+	if u.Node != nil {
+		nodeName = u.Node.Name
 	}
-
-	if u.Node.Name == "" {
-		return fmt.Errorf("node name not set")
+	if nodeName != "" {
+		glog.Infof("Detaching instance %q, node %q, in group %q.", id, nodeName, r.CloudGroup.HumanName)
+	} else {
+		glog.Infof("Detaching instance %q, in group %q.", id, r.CloudGroup.HumanName)
 	}
-	f := cmdutil.NewFactory(rollingUpdateData.ClientGetter)
 
-	streams := genericclioptions.IOStreams{
-		Out:    os.Stdout,
-		ErrOut: os.Stderr,
+	if err := r.Cloud.DetachInstance(u); err != nil {
+		if nodeName != "" {
+			return fmt.Errorf("error detaching instance %q, node %q: %v", id, nodeName, err)
+		} else {
+			return fmt.Errorf("error detaching instance %q: %v", id, err)
+		}
 	}
 
-	drain := cmd.NewCmdDrain(f, streams)
-	args := []string{u.Node.Name}
-	options := cmd.NewDrainOptions(f, streams)
+	u.Detached = true
 
-	// Override some options
-	options.IgnoreDaemonsets = true
-	options.Force = true
-	options.DeleteLocalData = true
-	options.GracePeriodSeconds = -1
+	return nil
+}
 
-	err := options.Complete(f, drain, args)
-	if err != nil {
-		return fmt.Errorf("error setting up drain: %v", err)
+// DrainNode drains a K8s node.
+func (r *RollingUpdateInstanceGroup) DrainNode(u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster, drain *api.DrainSpec) error {
+	if rollingUpdateData.K8sClient == nil {
+		return fmt.Errorf("K8sClient not set")
 	}
 
-	err = options.RunCordonOrUncordon(true)
-	if err != nil {
-		return fmt.Errorf("error cordoning node node: %v", err)
+	if u.Node.Name == "" {
+		return fmt.Errorf("node name not set")
 	}
 
-	err = options.RunDrain()
-	if err != nil {
+	if err := r.drainNode(rollingUpdateData, u.Node.Name, drain); err != nil {
+		if _, blocked := err.(*drainBlockedError); blocked {
+			return err
+		}
 		return fmt.Errorf("error draining node: %v", err)
 	}
 
@@ -0,0 +1,337 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kops/pkg/acls"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// Progress is the progress of a rolling update, persisted in the state store so that an interrupted
+// rolling update can be resumed.  A nil Progress records nothing.
+// The caller must hold the state store lock while the progress is read and recorded, so that two rolling
+// updates don't overwrite each other's progress.
+type Progress struct {
+	path vfs.Path
+	acl  vfs.ACL
+
+	// mutex guards state, which is updated concurrently by the drains
+	mutex sync.Mutex
+	state ProgressState
+}
+
+// ProgressState is the persisted progress of a rolling update
+type ProgressState struct {
+	// StartedAt is when the rolling update was started
+	StartedAt time.Time `json:"startedAt"`
+	// Force is true if the rolling update replaces instances which don't need updating
+	Force bool `json:"force,omitempty"`
+	// InstanceGroups is the progress of each instance group being updated
+	InstanceGroups map[string]*InstanceGroupProgress `json:"instanceGroups"`
+}
+
+// InstanceGroupProgress is the progress of the rolling update of an instance group
+type InstanceGroupProgress struct {
+	// Complete is true once every instance of the group has been updated
+	Complete bool `json:"complete,omitempty"`
+	// Pending are the IDs of the instances still to be updated
+	Pending []string `json:"pending,omitempty"`
+	// Cordoned maps the IDs of instances whose nodes were cordoned, but which were not yet deleted, to the node names
+	Cordoned map[string]string `json:"cordoned,omitempty"`
+}
+
+// NewProgress starts recording the progress of a new rolling update of the cluster, replacing that of any previous update
+func NewProgress(cluster *api.Cluster, force bool) (*Progress, error) {
+	p, acl, err := progressPath(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Progress{
+		path: p,
+		acl:  acl,
+		state: ProgressState{
+			StartedAt:      time.Now().UTC(),
+			Force:          force,
+			InstanceGroups: make(map[string]*InstanceGroupProgress),
+		},
+	}, nil
+}
+
+// ReadProgress reads the progress of an interrupted rolling update of the cluster,
+// returning nil if there is no rolling update to resume
+func ReadProgress(cluster *api.Cluster) (*Progress, error) {
+	p, acl, err := progressPath(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading rolling update progress from %s: %v", p, err)
+	}
+
+	progress := &Progress{path: p, acl: acl}
+	if err := json.Unmarshal(data, &progress.state); err != nil {
+		return nil, fmt.Errorf("error parsing rolling update progress from %s: %v", p, err)
+	}
+	if progress.state.InstanceGroups == nil {
+		progress.state.InstanceGroups = make(map[string]*InstanceGroupProgress)
+	}
+	return progress, nil
+}
+
+func progressPath(cluster *api.Cluster) (vfs.Path, vfs.ACL, error) {
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := configBase.Join(registry.PathRollingUpdate)
+	acl, err := acls.GetACL(p, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, acl, nil
+}
+
+// StartedAt returns when the rolling update was started
+func (p *Progress) StartedAt() time.Time {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.state.StartedAt
+}
+
+// Force returns true if the rolling update replaces instances which don't need updating
+func (p *Progress) Force() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.state.Force
+}
+
+// CordonedNodes returns the names of the nodes which were cordoned, but whose instances were not deleted, sorted by name
+func (p *Progress) CordonedNodes() []string {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var nodes []string
+	for _, group := range p.state.InstanceGroups {
+		for _, nodeName := range group.Cordoned {
+			nodes = append(nodes, nodeName)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Uncordon uncordons the nodes which were cordoned, but whose instances were not deleted, by the interrupted rolling update
+func (p *Progress) Uncordon(k8sClient kubernetes.Interface) error {
+	for _, nodeName := range p.CordonedNodes() {
+		_, err := k8sClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error getting node %q: %v", nodeName, err)
+			}
+			glog.Infof("Node %q no longer exists", nodeName)
+		} else {
+			if err := cordonNode(k8sClient, nodeName, false); err != nil {
+				return err
+			}
+			glog.Infof("Uncordoned node %q", nodeName)
+		}
+		p.uncordoned(nodeName)
+	}
+	return nil
+}
+
+// filterUpdate returns the instances of the group still to be updated.  Once the group has started, these are the instances
+// recorded as pending, so that the replacement instances are not updated again when the update is resumed with --force.
+func (p *Progress) filterUpdate(groupName string, cloudGroup *cloudinstances.CloudInstanceGroup, update []*cloudinstances.CloudInstanceGroupMember) []*cloudinstances.CloudInstanceGroupMember {
+	if p == nil {
+		return update
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	group := p.state.InstanceGroups[groupName]
+	if group == nil {
+		if len(update) == 0 {
+			return update
+		}
+		group = &InstanceGroupProgress{}
+		for _, u := range update {
+			group.Pending = append(group.Pending, u.ID)
+		}
+		p.state.InstanceGroups[groupName] = group
+		p.record()
+		return update
+	}
+
+	if group.Complete {
+		glog.Infof("Rolling update of InstanceGroup %q was already completed", groupName)
+		return nil
+	}
+
+	pending := make(map[string]bool)
+	for _, id := range group.Pending {
+		pending[id] = true
+	}
+
+	var filtered []*cloudinstances.CloudInstanceGroupMember
+	for _, members := range [][]*cloudinstances.CloudInstanceGroupMember{cloudGroup.NeedUpdate, cloudGroup.Ready} {
+		for _, u := range members {
+			if pending[u.ID] {
+				filtered = append(filtered, u)
+				delete(pending, u.ID)
+			}
+		}
+	}
+	glog.Infof("Resuming rolling update of InstanceGroup %q with %d instances remaining", groupName, len(filtered))
+	return filtered
+}
+
+// cordoned records that the node of an instance is about to be cordoned
+func (p *Progress) cordoned(groupName string, u *cloudinstances.CloudInstanceGroupMember) {
+	if p == nil || u.Node == nil || u.Node.Name == "" {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	group := p.group(groupName)
+	if group.Cordoned == nil {
+		group.Cordoned = make(map[string]string)
+	}
+	group.Cordoned[u.ID] = u.Node.Name
+	p.record()
+}
+
+// uncordoned records that a node is no longer cordoned by the rolling update
+func (p *Progress) uncordoned(nodeName string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, group := range p.state.InstanceGroups {
+		for id, name := range group.Cordoned {
+			if name == nodeName {
+				delete(group.Cordoned, id)
+			}
+		}
+	}
+	p.record()
+}
+
+// deleted records that an instance has been deleted
+func (p *Progress) deleted(groupName string, u *cloudinstances.CloudInstanceGroupMember) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	group := p.group(groupName)
+	delete(group.Cordoned, u.ID)
+	var pending []string
+	for _, id := range group.Pending {
+		if id != u.ID {
+			pending = append(pending, id)
+		}
+	}
+	group.Pending = pending
+	p.record()
+}
+
+// completed records that the update of an instance group has completed
+func (p *Progress) completed(groupName string) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	group := p.group(groupName)
+	group.Complete = true
+	group.Pending = nil
+	p.record()
+}
+
+// Finish removes the progress file, once the rolling update has completed
+func (p *Progress) Finish() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := p.path.Remove(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing rolling update progress %s: %v", p.path, err)
+	}
+	return nil
+}
+
+func (p *Progress) group(groupName string) *InstanceGroupProgress {
+	group := p.state.InstanceGroups[groupName]
+	if group == nil {
+		group = &InstanceGroupProgress{}
+		p.state.InstanceGroups[groupName] = group
+	}
+	return group
+}
+
+// record persists the progress, only warning on failure as the rolling update itself can continue; the caller must hold the mutex
+func (p *Progress) record() {
+	data, err := json.MarshalIndent(&p.state, "", "  ")
+	if err != nil {
+		glog.Warningf("error serializing rolling update progress: %v", err)
+		return
+	}
+	if err := p.path.WriteFile(bytes.NewReader(data), p.acl); err != nil {
+		glog.Warningf("unable to record the progress of the rolling update to %s: %v", p.path, err)
+	}
+}
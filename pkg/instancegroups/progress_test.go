@@ -0,0 +1,209 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func newProgressTestCluster() *kopsapi.Cluster {
+	vfs.Context.ResetMemfsContext(true)
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+	cluster.Spec.ConfigBase = "memfs://state/test.k8s.local"
+	return cluster
+}
+
+func progressTestMembers(ids ...string) []*cloudinstances.CloudInstanceGroupMember {
+	var members []*cloudinstances.CloudInstanceGroupMember
+	for _, id := range ids {
+		members = append(members, &cloudinstances.CloudInstanceGroupMember{
+			ID:   id,
+			Node: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-" + id}},
+		})
+	}
+	return members
+}
+
+func memberIDs(members []*cloudinstances.CloudInstanceGroupMember) []string {
+	var ids []string
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func readTestProgress(t *testing.T, cluster *kopsapi.Cluster) *Progress {
+	progress, err := ReadProgress(cluster)
+	if err != nil {
+		t.Fatalf("error reading progress: %v", err)
+	}
+	return progress
+}
+
+func TestProgress(t *testing.T) {
+	cluster := newProgressTestCluster()
+
+	if progress := readTestProgress(t, cluster); progress != nil {
+		t.Fatalf("expected no progress before the rolling update, got %+v", progress.state)
+	}
+
+	progress, err := NewProgress(cluster, true)
+	if err != nil {
+		t.Fatalf("error creating progress: %v", err)
+	}
+
+	members := progressTestMembers("a", "b", "c")
+	group := &cloudinstances.CloudInstanceGroup{NeedUpdate: members[:2], Ready: members[2:]}
+	update := progress.filterUpdate("nodes", group, members)
+	if ids := memberIDs(update); !reflect.DeepEqual(ids, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected instances to update %v", ids)
+	}
+
+	progress.cordoned("nodes", members[0])
+	progress.deleted("nodes", members[0])
+	progress.cordoned("nodes", members[1])
+
+	// The rolling update is interrupted here; a replacement for a has joined the group
+	resumed := readTestProgress(t, cluster)
+	if resumed == nil {
+		t.Fatalf("expected progress to be recorded")
+	}
+	if !resumed.Force() {
+		t.Errorf("expected force to be recorded")
+	}
+	if cordoned := resumed.CordonedNodes(); !reflect.DeepEqual(cordoned, []string{"node-b"}) {
+		t.Errorf("unexpected cordoned nodes %v", cordoned)
+	}
+
+	remaining := progressTestMembers("b", "c", "d")
+	group = &cloudinstances.CloudInstanceGroup{NeedUpdate: remaining[:1], Ready: remaining[1:]}
+	update = resumed.filterUpdate("nodes", group, remaining)
+	if ids := memberIDs(update); !reflect.DeepEqual(ids, []string{"b", "c"}) {
+		t.Errorf("unexpected instances to update on resume %v", ids)
+	}
+
+	resumed.completed("nodes")
+	if update := readTestProgress(t, cluster).filterUpdate("nodes", group, remaining); len(update) != 0 {
+		t.Errorf("expected no instances to update in a completed group, got %v", memberIDs(update))
+	}
+
+	if err := resumed.Finish(); err != nil {
+		t.Fatalf("error finishing progress: %v", err)
+	}
+	if progress := readTestProgress(t, cluster); progress != nil {
+		t.Errorf("expected progress to be removed, got %+v", progress.state)
+	}
+}
+
+func TestProgressUncordon(t *testing.T) {
+	cluster := newProgressTestCluster()
+
+	k8sClient := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	})
+
+	progress, err := NewProgress(cluster, false)
+	if err != nil {
+		t.Fatalf("error creating progress: %v", err)
+	}
+	members := progressTestMembers("a", "b")
+	progress.filterUpdate("nodes", &cloudinstances.CloudInstanceGroup{NeedUpdate: members}, members)
+	progress.cordoned("nodes", members[0])
+	progress.cordoned("nodes", members[1])
+
+	resumed := readTestProgress(t, cluster)
+	if err := resumed.Uncordon(k8sClient); err != nil {
+		t.Fatalf("error uncordoning nodes: %v", err)
+	}
+
+	node, err := k8sClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting node: %v", err)
+	}
+	if node.Spec.Unschedulable {
+		t.Errorf("expected node-a to be uncordoned")
+	}
+	if cordoned := readTestProgress(t, cluster).CordonedNodes(); len(cordoned) != 0 {
+		t.Errorf("expected no cordoned nodes to be recorded, got %v", cordoned)
+	}
+}
+
+func TestRollingUpdateResume(t *testing.T) {
+	cluster := newProgressTestCluster()
+
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	mockcloud.MockEC2 = &mockec2.MockEC2{}
+
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(),
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{
+		"node-resume": newSurgeTestGroup(c, "node-resume", nil, "i-00000001", "i-00000002", "i-00000003"),
+	}
+
+	// An interrupted rolling update, which had only i-00000002 left to replace
+	interrupted, err := NewProgress(cluster, false)
+	if err != nil {
+		t.Fatalf("error creating progress: %v", err)
+	}
+	interrupted.filterUpdate("node-resume", groups["node-resume"], progressTestMembers("i-00000002"))
+
+	c.Progress = readTestProgress(t, cluster)
+	if err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{}); err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-resume")},
+	})
+	for _, group := range asgGroups.AutoScalingGroups {
+		var ids []string
+		for _, instance := range group.Instances {
+			ids = append(ids, aws.StringValue(instance.InstanceId))
+		}
+		if !reflect.DeepEqual(ids, []string{"i-00000001", "i-00000003"}) {
+			t.Errorf("expected only i-00000002 to be replaced, remaining instances %v", ids)
+		}
+	}
+
+	if !readTestProgress(t, cluster).state.InstanceGroups["node-resume"].Complete {
+		t.Errorf("expected the instance group to be recorded as complete")
+	}
+}
@@ -22,11 +22,11 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	api "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/cloudinstances"
 	"k8s.io/kops/upup/pkg/fi"
-	"k8s.io/kubernetes/pkg/kubectl/genericclioptions"
 )
 
 // RollingUpdateCluster is a struct containing cluster information for a rolling update.
@@ -45,7 +45,6 @@ type RollingUpdateCluster struct {
 	Force bool
 
 	K8sClient        kubernetes.Interface
-	ClientGetter     genericclioptions.RESTClientGetter
 	FailOnDrainError bool
 	FailOnValidate   bool
 	CloudOnly        bool
@@ -56,6 +55,28 @@ type RollingUpdateCluster struct {
 
 	// ValidationTimeout is the maximum time to wait for the cluster to validate, once we start validation
 	ValidationTimeout time.Duration
+
+	// Drain overrides the drain settings of the cluster and instance groups
+	Drain *api.DrainSpec
+
+	// Progress records the progress of the update in the state store, so that it can be resumed if interrupted
+	Progress *Progress
+
+	// Canary is the number or percentage of the instances of each instance group to update first, before checking
+	// the health of the cluster for CanarySoak and updating the rest; if nil, the instances are updated in one go
+	Canary *intstr.IntOrString
+	// CanarySoak is how long to check the health of the cluster for, after updating the canary instances
+	CanarySoak time.Duration
+	// CanaryChecks are run after updating the canary instances, in addition to validating the cluster
+	CanaryChecks CanaryHealthChecks
+
+	// canary tracks the canary instances, once the update of the canaries has started
+	canary *canaryState
+
+	// blockedEvictionsMutex guards blockedEvictions
+	blockedEvictionsMutex sync.Mutex
+	// blockedEvictions are the pods whose eviction was refused because of a PodDisruptionBudget
+	blockedEvictions []*BlockedEviction
 }
 
 // RollingUpdate performs a rolling update on a K8s Cluster.
@@ -65,6 +86,22 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 		return nil
 	}
 
+	if c.Canary != nil {
+		if err := c.rollingUpdateCanaries(groups, cluster, instanceGroups); err != nil {
+			return err
+		}
+	}
+
+	if err := c.rollingUpdateGroups(groups, cluster, instanceGroups); err != nil {
+		return err
+	}
+
+	glog.Infof("Rolling update completed for cluster %q!", c.ClusterName)
+	return nil
+}
+
+// rollingUpdateGroups updates the bastions first, then the masters one group at a time, and then the nodes
+func (c *RollingUpdateCluster) rollingUpdateGroups(groups map[string]*cloudinstances.CloudInstanceGroup, cluster *api.Cluster, instanceGroups *api.InstanceGroupList) error {
 	var resultsMutex sync.Mutex
 	results := make(map[string]error)
 
@@ -181,6 +218,5 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 		}
 	}
 
-	glog.Infof("Rolling update completed for cluster %q!", c.ClusterName)
 	return nil
 }
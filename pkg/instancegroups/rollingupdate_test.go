@@ -25,8 +25,10 @@ import (
 
 	"k8s.io/api/core/v1"
 	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockec2"
 	kopsapi "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/cloudinstances"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
@@ -679,3 +681,127 @@ func TestRollingUpdateUnknownRole(t *testing.T) {
 		}
 	}
 }
+
+func newSurgeTestGroup(c *RollingUpdateCluster, name string, rollingUpdate *kopsapi.RollingUpdate, instanceIds ...string) *cloudinstances.CloudInstanceGroup {
+	cloud := c.Cloud.(awsup.AWSCloud)
+	cloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(5),
+		DesiredCapacity:      aws.Int64(int64(len(instanceIds))),
+	})
+
+	group := &cloudinstances.CloudInstanceGroup{
+		HumanName: name,
+		InstanceGroup: &kopsapi.InstanceGroup{
+			ObjectMeta: v1meta.ObjectMeta{
+				Name: name,
+			},
+			Spec: kopsapi.InstanceGroupSpec{
+				Role:          kopsapi.InstanceGroupRoleNode,
+				RollingUpdate: rollingUpdate,
+			},
+		},
+		Raw: &autoscaling.Group{AutoScalingGroupName: aws.String(name)},
+	}
+
+	var ids []*string
+	for _, id := range instanceIds {
+		ids = append(ids, aws.String(id))
+		group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+			ID:                 id,
+			Node:               &v1.Node{},
+			CloudInstanceGroup: group,
+		})
+	}
+	cloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String(name),
+		InstanceIds:          ids,
+	})
+
+	return group
+}
+
+func TestRollingUpdateMaxSurge(t *testing.T) {
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	mockcloud.MockEC2 = &mockec2.MockEC2{}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+	cluster.Spec.CloudProvider = "aws"
+
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(),
+	}
+
+	surge := intstr.FromInt(2)
+	groups := map[string]*cloudinstances.CloudInstanceGroup{
+		"node-surge": newSurgeTestGroup(c, "node-surge", &kopsapi.RollingUpdate{MaxSurge: &surge}, "i-00000001", "i-00000002", "i-00000003"),
+	}
+	members := append([]*cloudinstances.CloudInstanceGroupMember{}, groups["node-surge"].NeedUpdate...)
+
+	err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{})
+	if err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	for i, member := range members {
+		expected := i >= 1
+		if member.Detached != expected {
+			t.Errorf("instance %s: expected detached=%v, got %v", member.ID, expected, member.Detached)
+		}
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-surge")},
+	})
+	for _, group := range asgGroups.AutoScalingGroups {
+		if len(group.Instances) != 0 {
+			t.Errorf("Expected 0 instances got: %v in %v", len(group.Instances), group)
+		}
+		if aws.Int64Value(group.DesiredCapacity) != 3 {
+			t.Errorf("Expected desired capacity to be unchanged, got: %v", aws.Int64Value(group.DesiredCapacity))
+		}
+	}
+}
+
+func TestRollingUpdateDisabled(t *testing.T) {
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+	zero := intstr.FromInt(0)
+	cluster.Spec.RollingUpdate = &kopsapi.RollingUpdate{MaxUnavailable: &zero}
+
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(),
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{
+		"node-disabled": newSurgeTestGroup(c, "node-disabled", nil, "i-00000001", "i-00000002"),
+	}
+
+	err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{})
+	if err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-disabled")},
+	})
+	for _, group := range asgGroups.AutoScalingGroups {
+		if len(group.Instances) != 2 {
+			t.Errorf("Expected 2 instances got: %v in %v", len(group.Instances), group)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	api "k8s.io/kops/pkg/apis/kops"
+)
+
+// resolveSettings merges the rolling update settings of the instance group over those of the cluster,
+// applies the defaults and converts any percentages into an absolute number of instances
+func resolveSettings(cluster *api.Cluster, group *api.InstanceGroup, numInstances int) api.RollingUpdate {
+	rollingUpdate := api.RollingUpdate{}
+	if cluster.Spec.RollingUpdate != nil {
+		rollingUpdate = *cluster.Spec.RollingUpdate
+	}
+	if group.Spec.RollingUpdate != nil {
+		if group.Spec.RollingUpdate.MaxUnavailable != nil {
+			rollingUpdate.MaxUnavailable = group.Spec.RollingUpdate.MaxUnavailable
+		}
+		if group.Spec.RollingUpdate.MaxSurge != nil {
+			rollingUpdate.MaxSurge = group.Spec.RollingUpdate.MaxSurge
+		}
+		if group.Spec.RollingUpdate.Drain != nil {
+			rollingUpdate.Drain = mergeDrainSpec(rollingUpdate.Drain, group.Spec.RollingUpdate.Drain)
+		}
+	}
+
+	if rollingUpdate.MaxSurge != nil && api.CloudProviderID(cluster.Spec.CloudProvider) != api.CloudProviderAWS {
+		// Surging relies on detaching instances from their group, which only AWS supports
+		if surge, _ := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, numInstances, true); surge > 0 {
+			glog.Warningf("maxSurge is not supported on cloud provider %q, ignoring it for InstanceGroup %s", cluster.Spec.CloudProvider, group.ObjectMeta.Name)
+		}
+		rollingUpdate.MaxSurge = nil
+	}
+
+	if rollingUpdate.MaxSurge == nil {
+		zero := intstr.FromInt(0)
+		rollingUpdate.MaxSurge = &zero
+	}
+
+	if rollingUpdate.MaxSurge.Type == intstr.String {
+		surge, _ := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, numInstances, true)
+		surgeInt := intstr.FromInt(surge)
+		rollingUpdate.MaxSurge = &surgeInt
+	}
+
+	maxSurge := rollingUpdate.MaxSurge.IntValue()
+
+	if rollingUpdate.MaxUnavailable == nil {
+		// Without surging we must take instances down to make progress
+		unavailable := intstr.FromInt(0)
+		if maxSurge == 0 {
+			unavailable = intstr.FromInt(1)
+		}
+		rollingUpdate.MaxUnavailable = &unavailable
+	}
+
+	if rollingUpdate.MaxUnavailable.Type == intstr.String {
+		unavailable, _ := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxUnavailable, numInstances, false)
+		if unavailable <= 0 {
+			// While we round down, percentages should resolve to a minimum of 1
+			unavailable = 1
+		}
+		unavailableInt := intstr.FromInt(unavailable)
+		rollingUpdate.MaxUnavailable = &unavailableInt
+	}
+
+	return rollingUpdate
+}
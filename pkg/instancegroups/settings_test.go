@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+)
+
+func TestResolveSettings(t *testing.T) {
+	intOrString := func(s string) *intstr.IntOrString {
+		v := intstr.Parse(s)
+		return &v
+	}
+
+	grid := []struct {
+		CloudProvider string
+		Cluster       *kopsapi.RollingUpdate
+		Group         *kopsapi.RollingUpdate
+		NumInstances  int
+
+		ExpectedMaxUnavailable int
+		ExpectedMaxSurge       int
+	}{
+		{
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 1,
+			ExpectedMaxSurge:       0,
+		},
+		{
+			Cluster:                &kopsapi.RollingUpdate{MaxSurge: intOrString("2")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 0,
+			ExpectedMaxSurge:       2,
+		},
+		{
+			Cluster:                &kopsapi.RollingUpdate{MaxSurge: intOrString("2"), MaxUnavailable: intOrString("3")},
+			Group:                  &kopsapi.RollingUpdate{MaxSurge: intOrString("1")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 3,
+			ExpectedMaxSurge:       1,
+		},
+		{
+			Group:                  &kopsapi.RollingUpdate{MaxUnavailable: intOrString("0")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 0,
+			ExpectedMaxSurge:       0,
+		},
+		{
+			// Surge percentages round up
+			Group:                  &kopsapi.RollingUpdate{MaxSurge: intOrString("25%")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 0,
+			ExpectedMaxSurge:       3,
+		},
+		{
+			// Unavailable percentages round down
+			Group:                  &kopsapi.RollingUpdate{MaxUnavailable: intOrString("25%")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 2,
+			ExpectedMaxSurge:       0,
+		},
+		{
+			// ... but never below 1
+			Group:                  &kopsapi.RollingUpdate{MaxUnavailable: intOrString("25%")},
+			NumInstances:           3,
+			ExpectedMaxUnavailable: 1,
+			ExpectedMaxSurge:       0,
+		},
+		{
+			// Clouds that cannot detach instances fall back to maxUnavailable
+			CloudProvider:          "gce",
+			Cluster:                &kopsapi.RollingUpdate{MaxSurge: intOrString("2")},
+			NumInstances:           10,
+			ExpectedMaxUnavailable: 1,
+			ExpectedMaxSurge:       0,
+		},
+	}
+
+	for i, g := range grid {
+		cluster := &kopsapi.Cluster{}
+		cluster.Spec.CloudProvider = "aws"
+		if g.CloudProvider != "" {
+			cluster.Spec.CloudProvider = g.CloudProvider
+		}
+		cluster.Spec.RollingUpdate = g.Cluster
+		group := &kopsapi.InstanceGroup{}
+		group.Spec.RollingUpdate = g.Group
+
+		actual := resolveSettings(cluster, group, g.NumInstances)
+		if actual.MaxUnavailable.IntValue() != g.ExpectedMaxUnavailable {
+			t.Errorf("test case %d: expected maxUnavailable %d, got %s", i, g.ExpectedMaxUnavailable, actual.MaxUnavailable.String())
+		}
+		if actual.MaxSurge.IntValue() != g.ExpectedMaxSurge {
+			t.Errorf("test case %d: expected maxSurge %d, got %s", i, g.ExpectedMaxSurge, actual.MaxSurge.String())
+		}
+	}
+}
@@ -0,0 +1,505 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyrotation
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// Stage is a step of a staged keypair rotation
+type Stage string
+
+const (
+	// StageTrust adds a new key to the keyset, trusted alongside the current primary but not yet used for issuing
+	StageTrust Stage = "Trust"
+	// StagePromote reissues the certificates signed by the old key with the new key, then makes the new key the primary
+	StagePromote Stage = "Promote"
+	// StageCleanup removes the old key, once every node has been rolled
+	StageCleanup Stage = "Cleanup"
+)
+
+// RotatableKeypairs are the keypairs that can be rotated: the kubernetes CA (which also issues the etcd certificates),
+// the apiserver aggregator CA, and the master keypair whose key signs service account tokens
+var RotatableKeypairs = []string{fi.CertificateId_CA, "apiserver-aggregator-ca", "master"}
+
+// Rotation runs the stages of the rotation of a keypair in the keystore
+type Rotation struct {
+	// Keystore is the keystore holding the keypair
+	Keystore fi.CAStore
+	// Name is the name of the keypair being rotated
+	Name string
+	// Signer is the CA issuing the keypair, when it is not itself a CA
+	Signer string
+}
+
+// NewRotation builds a Rotation for the named keypair, checking that it can be rotated
+func NewRotation(keystore fi.CAStore, name string) (*Rotation, error) {
+	found := false
+	for _, k := range RotatableKeypairs {
+		if k == name {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("rotation of keypair %q is not supported; supported keypairs are %v", name, RotatableKeypairs)
+	}
+
+	return &Rotation{
+		Keystore: keystore,
+		Name:     name,
+		Signer:   fi.CertificateId_CA,
+	}, nil
+}
+
+// Status is the progress of a rotation, as recorded in the keyset
+type Status struct {
+	// Keyset is the certificate keyset of the keypair
+	Keyset *kops.Keyset
+	// Next is the stage which will be run next
+	Next Stage
+	// PrimaryId is the id of the key currently used for issuing
+	PrimaryId string
+	// NewId is the id of the key being rotated to, if the Trust stage has run
+	NewId string
+	// OldIds are the ids of the keys being rotated away from, if the Trust stage has run
+	OldIds []string
+}
+
+// FindStatus reads the keyset to determine the next stage of the rotation
+func (r *Rotation) FindStatus() (*Status, error) {
+	keyset, err := r.Keystore.FindCertificateKeyset(r.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keyset %q: %v", r.Name, err)
+	}
+	if keyset == nil || len(keyset.Spec.Keys) == 0 {
+		return nil, fmt.Errorf("keypair %q not found", r.Name)
+	}
+
+	privateKeyset, err := r.Keystore.FindPrivateKeyset(r.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private keyset %q: %v", r.Name, err)
+	}
+
+	// Certificates added without a private key (e.g. with AddCert) are not part of the rotation
+	hasPrivateKey := make(map[string]bool)
+	if privateKeyset != nil {
+		for _, item := range privateKeyset.Spec.Keys {
+			hasPrivateKey[item.Id] = true
+		}
+	}
+
+	status, err := buildStatus(keyset, hasPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Next == StageCleanup {
+		// Removing the old keys would leave any certificate still signed by them untrusted, so if a Promote
+		// did not reissue every certificate we go back to reissuing them
+		issued, err := r.findIssuedByKeys(keyset, status.OldIds)
+		if err != nil {
+			return nil, err
+		}
+		if len(issued) != 0 {
+			glog.Warningf("Keypairs %v are still signed by the old keys of %q; they will be reissued", issued, r.Name)
+			status.Next = StagePromote
+			status.NewId = status.PrimaryId
+		}
+	}
+
+	return status, nil
+}
+
+// buildStatus infers the next stage: an unpinned keyset starts a rotation, a key newer than the pinned primary is
+// waiting to be promoted, and keys older than the pinned primary are waiting to be removed
+func buildStatus(keyset *kops.Keyset, hasPrivateKey map[string]bool) (*Status, error) {
+	primary := fi.FindPrimary(keyset)
+	if primary == nil {
+		return nil, fmt.Errorf("keyset %q has no primary key", keyset.Name)
+	}
+
+	status := &Status{
+		Keyset:    keyset,
+		PrimaryId: primary.Id,
+	}
+
+	if keyset.Spec.PrimaryId == "" {
+		status.Next = StageTrust
+		return status, nil
+	}
+
+	primaryVersion, ok := big.NewInt(0).SetString(primary.Id, 10)
+	if !ok {
+		return nil, fmt.Errorf("keyset %q has primary with non-integer id %q", keyset.Name, primary.Id)
+	}
+
+	var newer []string
+	for _, item := range keyset.Spec.Keys {
+		if item.Id == primary.Id || !hasPrivateKey[item.Id] {
+			continue
+		}
+		version, ok := big.NewInt(0).SetString(item.Id, 10)
+		if !ok {
+			glog.Warningf("Ignoring key item with non-integer version: %q", item.Id)
+			continue
+		}
+		if version.Cmp(primaryVersion) > 0 {
+			newer = append(newer, item.Id)
+		} else {
+			status.OldIds = append(status.OldIds, item.Id)
+		}
+	}
+	sort.Strings(status.OldIds)
+
+	switch {
+	case len(newer) > 1:
+		return nil, fmt.Errorf("keyset %q has more than one key waiting to be promoted: %v", keyset.Name, newer)
+	case len(newer) == 1:
+		status.Next = StagePromote
+		status.NewId = newer[0]
+		// The key being rotated away from is the current primary
+		status.OldIds = []string{primary.Id}
+	case len(status.OldIds) != 0:
+		status.Next = StageCleanup
+	default:
+		// The pin was left behind, e.g. by an interrupted cleanup; start a new rotation
+		status.Next = StageTrust
+	}
+
+	return status, nil
+}
+
+// Trust generates a new key, trusted alongside the current primary; it returns the id of the new key
+func (r *Rotation) Trust(status *Status) (string, error) {
+	if status.Next != StageTrust {
+		return "", fmt.Errorf("keypair %q is not ready to start a rotation; next stage is %s", r.Name, status.Next)
+	}
+
+	cert, _, _, err := r.Keystore.FindKeypair(r.Name)
+	if err != nil {
+		return "", fmt.Errorf("error reading keypair %q: %v", r.Name, err)
+	}
+	if cert == nil {
+		return "", fmt.Errorf("keypair %q not found", r.Name)
+	}
+
+	// We pin the primary before adding the new key, because otherwise the newest key would be the primary
+	if err := r.Keystore.SetPrimaryKeysetItem(status.Keyset, status.PrimaryId); err != nil {
+		return "", err
+	}
+
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	template := buildTemplate(cert.Certificate)
+	template.SerialNumber = pki.BuildPKISerial(time.Now().UnixNano())
+
+	var newCert *pki.Certificate
+	if cert.IsCA {
+		newCert, err = pki.SignNewCertificate(privateKey, template, nil, nil)
+	} else {
+		newCert, err = r.issue(r.Signer, template, privateKey)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error issuing certificate for %q: %v", r.Name, err)
+	}
+
+	if err := r.Keystore.StoreKeypair(r.Name, newCert, privateKey); err != nil {
+		return "", fmt.Errorf("error storing keypair %q: %v", r.Name, err)
+	}
+
+	return newCert.Certificate.SerialNumber.String(), nil
+}
+
+// Promote makes the new key the primary.  For a CA, the certificates signed by the old CA are first reissued
+// with the new key, keeping their private keys; the new key is only made the primary once every certificate has
+// been reissued, so that a Promote which fails can be run again.  It returns the names of the reissued keypairs.
+func (r *Rotation) Promote(status *Status) ([]string, error) {
+	if status.Next != StagePromote {
+		return nil, fmt.Errorf("keypair %q has no key waiting to be promoted; next stage is %s", r.Name, status.Next)
+	}
+
+	newCert, newKey, err := r.findKey(status.Keyset, status.NewId)
+	if err != nil {
+		return nil, err
+	}
+
+	var issued []string
+	if newCert.IsCA {
+		issued, err = r.findIssuedByKeys(status.Keyset, status.OldIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var reissued []string
+	for _, name := range issued {
+		cert, privateKey, _, err := r.Keystore.FindKeypair(name)
+		if err != nil {
+			return reissued, fmt.Errorf("error reading keypair %q: %v", name, err)
+		}
+		if privateKey == nil {
+			glog.Warningf("Not reissuing %q, which has no private key", name)
+			continue
+		}
+
+		template := buildTemplate(cert.Certificate)
+		template.SerialNumber = pki.BuildPKISerial(time.Now().UnixNano())
+
+		// We sign with the new key explicitly, as it is not yet the primary
+		reissuedCert, err := pki.SignNewCertificate(privateKey, template, newCert.Certificate, newKey)
+		if err != nil {
+			return reissued, fmt.Errorf("error reissuing %q: %v", name, err)
+		}
+
+		if err := r.Keystore.StoreKeypair(name, reissuedCert, privateKey); err != nil {
+			return reissued, fmt.Errorf("error storing keypair %q: %v", name, err)
+		}
+		reissued = append(reissued, name)
+	}
+
+	if err := r.Keystore.SetPrimaryKeysetItem(status.Keyset, status.NewId); err != nil {
+		return reissued, err
+	}
+
+	return reissued, nil
+}
+
+// Cleanup removes the old keys, and for a CA the certificates they issued which have since been reissued,
+// and then unpins the primary.  It returns the ids of the removed keys.
+func (r *Rotation) Cleanup(status *Status) ([]string, error) {
+	if status.Next != StageCleanup {
+		return nil, fmt.Errorf("keypair %q has no old keys to remove; next stage is %s", r.Name, status.Next)
+	}
+
+	issued, err := r.findIssuedByKeys(status.Keyset, status.OldIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(issued) != 0 {
+		return nil, fmt.Errorf("keypairs %v are still signed by the old keys of %q; run the Promote stage again to reissue them", issued, r.Name)
+	}
+
+	oldCerts, err := r.keysetCertificates(status.Keyset, status.OldIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, oldCert := range oldCerts {
+		if !oldCert.IsCA {
+			continue
+		}
+		if err := r.removeIssuedBy(oldCert); err != nil {
+			return nil, err
+		}
+	}
+
+	var removed []string
+	for _, id := range status.OldIds {
+		if err := r.Keystore.DeleteKeysetItem(status.Keyset, id); err != nil {
+			return removed, fmt.Errorf("error removing %s/%s: %v", r.Name, id, err)
+		}
+		removed = append(removed, id)
+	}
+
+	if err := r.Keystore.SetPrimaryKeysetItem(status.Keyset, ""); err != nil {
+		return removed, err
+	}
+
+	return removed, nil
+}
+
+// issue signs a certificate with the current primary of the signer
+func (r *Rotation) issue(signer string, template *x509.Certificate, privateKey *pki.PrivateKey) (*pki.Certificate, error) {
+	caCert, caKey, _, err := r.Keystore.FindKeypair(signer)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA %q: %v", signer, err)
+	}
+	if caCert == nil || caKey == nil {
+		return nil, fmt.Errorf("CA %q not found", signer)
+	}
+
+	return pki.SignNewCertificate(privateKey, template, caCert.Certificate, caKey)
+}
+
+// findKey returns the certificate and private key of a key of the keyset
+func (r *Rotation) findKey(keyset *kops.Keyset, id string) (*pki.Certificate, *pki.PrivateKey, error) {
+	certs, err := r.keysetCertificates(keyset, []string{id})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("certificate %s/%s not found", r.Name, id)
+	}
+
+	privateKeyset, err := r.Keystore.FindPrivateKeyset(r.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading private keyset %q: %v", r.Name, err)
+	}
+	if privateKeyset != nil {
+		for _, item := range privateKeyset.Spec.Keys {
+			if item.Id != id || len(item.PrivateMaterial) == 0 {
+				continue
+			}
+			privateKey, err := pki.ParsePEMPrivateKey(item.PrivateMaterial)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error parsing private key %s/%s: %v", r.Name, id, err)
+			}
+			return certs[0], privateKey, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("private key %s/%s not found", r.Name, id)
+}
+
+// keysetCertificates parses the certificates of the keys of the keyset with the given ids
+func (r *Rotation) keysetCertificates(keyset *kops.Keyset, ids []string) ([]*pki.Certificate, error) {
+	var certs []*pki.Certificate
+	for _, item := range keyset.Spec.Keys {
+		for _, id := range ids {
+			if item.Id != id || len(item.PublicMaterial) == 0 {
+				continue
+			}
+			cert, err := pki.ParsePEMCertificate(item.PublicMaterial)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing certificate %s/%s: %v", r.Name, id, err)
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return certs, nil
+}
+
+// findIssuedByKeys returns the names of the keypairs whose primary certificate was signed by one of the keys
+// of the keyset with the given ids, when they are CAs
+func (r *Rotation) findIssuedByKeys(keyset *kops.Keyset, ids []string) ([]string, error) {
+	certs, err := r.keysetCertificates(keyset, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var issued []string
+	for _, cert := range certs {
+		if !cert.IsCA {
+			continue
+		}
+		names, err := r.findIssued(cert)
+		if err != nil {
+			return nil, err
+		}
+		issued = append(issued, names...)
+	}
+	sort.Strings(issued)
+
+	return issued, nil
+}
+
+// findIssued returns the names of the keypairs whose primary certificate was signed by the CA
+func (r *Rotation) findIssued(ca *pki.Certificate) ([]string, error) {
+	keysets, err := r.Keystore.ListKeysets()
+	if err != nil {
+		return nil, fmt.Errorf("error listing keysets: %v", err)
+	}
+
+	var issued []string
+	for _, keyset := range keysets {
+		if keyset.Spec.Type != kops.SecretTypeKeypair || keyset.Name == r.Name {
+			continue
+		}
+
+		cert, err := r.Keystore.FindCert(keyset.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificate %q: %v", keyset.Name, err)
+		}
+		if cert == nil || cert.IsCA {
+			continue
+		}
+		if cert.Certificate.CheckSignatureFrom(ca.Certificate) == nil {
+			issued = append(issued, keyset.Name)
+		}
+	}
+	sort.Strings(issued)
+
+	return issued, nil
+}
+
+// removeIssuedBy removes the superseded certificates signed by the CA; primary certificates are kept,
+// because a keypair we failed to reissue should not be left without a certificate
+func (r *Rotation) removeIssuedBy(ca *pki.Certificate) error {
+	keysets, err := r.Keystore.ListKeysets()
+	if err != nil {
+		return fmt.Errorf("error listing keysets: %v", err)
+	}
+
+	for _, keyset := range keysets {
+		if keyset.Spec.Type != kops.SecretTypeKeypair || keyset.Name == r.Name {
+			continue
+		}
+
+		o, err := r.Keystore.FindCertificateKeyset(keyset.Name)
+		if err != nil {
+			return fmt.Errorf("error reading keyset %q: %v", keyset.Name, err)
+		}
+		if o == nil {
+			continue
+		}
+
+		primary := fi.FindPrimary(o)
+		for _, item := range o.Spec.Keys {
+			if (primary != nil && item.Id == primary.Id) || len(item.PublicMaterial) == 0 {
+				continue
+			}
+			cert, err := pki.ParsePEMCertificate(item.PublicMaterial)
+			if err != nil {
+				return fmt.Errorf("error parsing certificate %s/%s: %v", keyset.Name, item.Id, err)
+			}
+			if cert.IsCA || cert.Certificate.CheckSignatureFrom(ca.Certificate) != nil {
+				continue
+			}
+			glog.V(2).Infof("Removing certificate %s/%s issued by the old CA", keyset.Name, item.Id)
+			if err := r.Keystore.DeleteKeysetItem(o, item.Id); err != nil {
+				return fmt.Errorf("error removing %s/%s: %v", keyset.Name, item.Id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildTemplate copies the identity and usage of an existing certificate, for issuing a replacement
+func buildTemplate(cert *x509.Certificate) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:               cert.Subject,
+		DNSNames:              cert.DNSNames,
+		EmailAddresses:        cert.EmailAddresses,
+		IPAddresses:           cert.IPAddresses,
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  cert.IsCA,
+	}
+}
@@ -0,0 +1,356 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyrotation
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func buildKeystore(t *testing.T) *fi.VFSCAStore {
+	vfs.Context.ResetMemfsContext(true)
+
+	basedir, err := vfs.Context.BuildVfsPath("memfs://keystore")
+	if err != nil {
+		t.Fatalf("error building vfs path: %v", err)
+	}
+
+	keystore := fi.NewVFSCAStore(&kops.Cluster{}, basedir, true)
+
+	// Generate predictable sequence numbers for the initial keypairs
+	var n int64
+	keystore.SerialGenerator = func() *big.Int {
+		n++
+		return big.NewInt(n)
+	}
+
+	return keystore
+}
+
+func createKeypair(t *testing.T, keystore fi.CAStore, name string, template *x509.Certificate) *pki.Certificate {
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	cert, err := keystore.CreateKeypair(fi.CertificateId_CA, name, template, privateKey)
+	if err != nil {
+		t.Fatalf("error creating keypair %q: %v", name, err)
+	}
+	return cert
+}
+
+func findStatus(t *testing.T, r *Rotation, expected Stage) *Status {
+	status, err := r.FindStatus()
+	if err != nil {
+		t.Fatalf("error finding rotation status: %v", err)
+	}
+	if status.Next != expected {
+		t.Fatalf("unexpected next stage: expected=%s actual=%s", expected, status.Next)
+	}
+	return status
+}
+
+func TestRotateCA(t *testing.T) {
+	keystore := buildKeystore(t)
+
+	oldCA := createKeypair(t, keystore, fi.CertificateId_CA, fi.BuildCAX509Template())
+	createKeypair(t, keystore, "kubelet", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kubelet", Organization: []string{"system:nodes"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	_, kubeletKey, _, err := keystore.FindKeypair("kubelet")
+	if err != nil {
+		t.Fatalf("error reading kubelet keypair: %v", err)
+	}
+
+	r, err := NewRotation(keystore, fi.CertificateId_CA)
+	if err != nil {
+		t.Fatalf("error building rotation: %v", err)
+	}
+
+	var newId string
+	{
+		status := findStatus(t, r, StageTrust)
+		newId, err = r.Trust(status)
+		if err != nil {
+			t.Fatalf("error running Trust: %v", err)
+		}
+
+		pool, err := keystore.FindCertificatePool(fi.CertificateId_CA)
+		if err != nil {
+			t.Fatalf("error reading CA pool: %v", err)
+		}
+		if len(pool.All()) != 2 {
+			t.Fatalf("expected the new CA to be trusted, found %d certificates", len(pool.All()))
+		}
+		if !reflect.DeepEqual(pool.Primary.Certificate.Raw, oldCA.Certificate.Raw) {
+			t.Fatalf("expected the old CA to remain the primary")
+		}
+	}
+
+	{
+		status := findStatus(t, r, StagePromote)
+		if status.NewId != newId {
+			t.Fatalf("unexpected key to promote: expected=%s actual=%s", newId, status.NewId)
+		}
+
+		reissued, err := r.Promote(status)
+		if err != nil {
+			t.Fatalf("error running Promote: %v", err)
+		}
+		if !reflect.DeepEqual(reissued, []string{"kubelet"}) {
+			t.Fatalf("unexpected reissued keypairs: %v", reissued)
+		}
+
+		newCA, err := keystore.FindCert(fi.CertificateId_CA)
+		if err != nil {
+			t.Fatalf("error reading CA: %v", err)
+		}
+		if newCA.Certificate.SerialNumber.String() != newId {
+			t.Fatalf("expected the new CA to be the primary, was %s", newCA.Certificate.SerialNumber)
+		}
+
+		cert, key, _, err := keystore.FindKeypair("kubelet")
+		if err != nil {
+			t.Fatalf("error reading kubelet keypair: %v", err)
+		}
+		if err := cert.Certificate.CheckSignatureFrom(newCA.Certificate); err != nil {
+			t.Fatalf("expected kubelet to be reissued by the new CA: %v", err)
+		}
+		if !reflect.DeepEqual(key.Key, kubeletKey.Key) {
+			t.Fatalf("expected kubelet private key to be kept")
+		}
+	}
+
+	{
+		status := findStatus(t, r, StageCleanup)
+		oldId := oldCA.Certificate.SerialNumber.String()
+		if !reflect.DeepEqual(status.OldIds, []string{oldId}) {
+			t.Fatalf("unexpected keys to remove: %v", status.OldIds)
+		}
+
+		removed, err := r.Cleanup(status)
+		if err != nil {
+			t.Fatalf("error running Cleanup: %v", err)
+		}
+		if !reflect.DeepEqual(removed, []string{oldId}) {
+			t.Fatalf("unexpected removed keys: %v", removed)
+		}
+
+		keyset, err := keystore.FindCertificateKeyset(fi.CertificateId_CA)
+		if err != nil {
+			t.Fatalf("error reading CA keyset: %v", err)
+		}
+		if len(keyset.Spec.Keys) != 1 || keyset.Spec.Keys[0].Id != newId || keyset.Spec.PrimaryId != "" {
+			t.Fatalf("expected only the unpinned new CA to remain, was %v (primary %q)", keyset.Spec.Keys, keyset.Spec.PrimaryId)
+		}
+
+		kubelet, err := keystore.FindCertificateKeyset("kubelet")
+		if err != nil {
+			t.Fatalf("error reading kubelet keyset: %v", err)
+		}
+		if len(kubelet.Spec.Keys) != 1 {
+			t.Fatalf("expected the kubelet certificate issued by the old CA to be removed, found %d", len(kubelet.Spec.Keys))
+		}
+	}
+
+	findStatus(t, r, StageTrust)
+}
+
+// failingKeystore fails to store the named keypair
+type failingKeystore struct {
+	fi.CAStore
+	fail string
+}
+
+func (k *failingKeystore) StoreKeypair(name string, cert *pki.Certificate, privateKey *pki.PrivateKey) error {
+	if name == k.fail {
+		return fmt.Errorf("injected failure storing %q", name)
+	}
+	return k.CAStore.StoreKeypair(name, cert, privateKey)
+}
+
+// checkSignedBy checks that the primary certificate of each keypair is signed by the primary CA
+func checkSignedBy(t *testing.T, keystore fi.CAStore, names ...string) {
+	ca, err := keystore.FindCert(fi.CertificateId_CA)
+	if err != nil {
+		t.Fatalf("error reading CA: %v", err)
+	}
+	for _, name := range names {
+		cert, err := keystore.FindCert(name)
+		if err != nil {
+			t.Fatalf("error reading certificate %q: %v", name, err)
+		}
+		if err := cert.Certificate.CheckSignatureFrom(ca.Certificate); err != nil {
+			t.Errorf("expected %q to be signed by the primary CA: %v", name, err)
+		}
+	}
+}
+
+func TestRotateCAPromoteFailure(t *testing.T) {
+	keystore := buildKeystore(t)
+
+	oldCA := createKeypair(t, keystore, fi.CertificateId_CA, fi.BuildCAX509Template())
+	for _, name := range []string{"kube-proxy", "kubelet"} {
+		createKeypair(t, keystore, name, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: name},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+	}
+
+	r, err := NewRotation(keystore, fi.CertificateId_CA)
+	if err != nil {
+		t.Fatalf("error building rotation: %v", err)
+	}
+	newId, err := r.Trust(findStatus(t, r, StageTrust))
+	if err != nil {
+		t.Fatalf("error running Trust: %v", err)
+	}
+
+	// The second keypair fails to be reissued, so the old CA must remain the primary
+	r.Keystore = &failingKeystore{CAStore: keystore, fail: "kubelet"}
+	if _, err := r.Promote(findStatus(t, r, StagePromote)); err == nil {
+		t.Fatalf("expected Promote to fail")
+	}
+	r.Keystore = keystore
+
+	primary, err := keystore.FindCert(fi.CertificateId_CA)
+	if err != nil {
+		t.Fatalf("error reading CA: %v", err)
+	}
+	if !reflect.DeepEqual(primary.Certificate.Raw, oldCA.Certificate.Raw) {
+		t.Fatalf("expected the old CA to remain the primary after a failed Promote")
+	}
+	checkSignedBy(t, keystore, "kubelet")
+
+	// Running Promote again reissues the remaining keypair
+	status := findStatus(t, r, StagePromote)
+	if status.NewId != newId {
+		t.Fatalf("unexpected key to promote: expected=%s actual=%s", newId, status.NewId)
+	}
+	reissued, err := r.Promote(status)
+	if err != nil {
+		t.Fatalf("error running Promote again: %v", err)
+	}
+	if !reflect.DeepEqual(reissued, []string{"kubelet"}) {
+		t.Fatalf("unexpected reissued keypairs: %v", reissued)
+	}
+
+	if _, err := r.Cleanup(findStatus(t, r, StageCleanup)); err != nil {
+		t.Fatalf("error running Cleanup: %v", err)
+	}
+	checkSignedBy(t, keystore, "kube-proxy", "kubelet")
+}
+
+func TestRotateCAPinnedBeforeReissue(t *testing.T) {
+	keystore := buildKeystore(t)
+
+	createKeypair(t, keystore, fi.CertificateId_CA, fi.BuildCAX509Template())
+	createKeypair(t, keystore, "kubelet", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kubelet"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	r, err := NewRotation(keystore, fi.CertificateId_CA)
+	if err != nil {
+		t.Fatalf("error building rotation: %v", err)
+	}
+	newId, err := r.Trust(findStatus(t, r, StageTrust))
+	if err != nil {
+		t.Fatalf("error running Trust: %v", err)
+	}
+
+	// The new key is pinned without reissuing kubelet, so the old key cannot be removed yet
+	status := findStatus(t, r, StagePromote)
+	if err := keystore.SetPrimaryKeysetItem(status.Keyset, newId); err != nil {
+		t.Fatalf("error pinning the new key: %v", err)
+	}
+
+	status = findStatus(t, r, StagePromote)
+	if status.NewId != newId {
+		t.Fatalf("unexpected key to promote: expected=%s actual=%s", newId, status.NewId)
+	}
+
+	cleanup := *status
+	cleanup.Next = StageCleanup
+	if _, err := r.Cleanup(&cleanup); err == nil {
+		t.Fatalf("expected Cleanup to refuse while kubelet is signed by the old key")
+	}
+
+	reissued, err := r.Promote(status)
+	if err != nil {
+		t.Fatalf("error running Promote: %v", err)
+	}
+	if !reflect.DeepEqual(reissued, []string{"kubelet"}) {
+		t.Fatalf("unexpected reissued keypairs: %v", reissued)
+	}
+	checkSignedBy(t, keystore, "kubelet")
+
+	findStatus(t, r, StageCleanup)
+}
+
+func TestRotateUnsupported(t *testing.T) {
+	keystore := buildKeystore(t)
+
+	if _, err := NewRotation(keystore, "kubelet"); err == nil {
+		t.Fatalf("expected error rotating a keypair which is not a CA or the service account key")
+	}
+}
+
+func TestBuildStatus(t *testing.T) {
+	grid := []struct {
+		primaryId string
+		ids       []string
+		expected  Stage
+	}{
+		{"", []string{"10"}, StageTrust},
+		{"", []string{"10", "20"}, StageTrust},
+		{"10", []string{"10", "20"}, StagePromote},
+		{"20", []string{"10", "20"}, StageCleanup},
+		{"20", []string{"20"}, StageTrust},
+	}
+
+	for _, g := range grid {
+		keyset := &kops.Keyset{}
+		keyset.Spec.PrimaryId = g.primaryId
+		hasPrivateKey := make(map[string]bool)
+		for _, id := range g.ids {
+			keyset.Spec.Keys = append(keyset.Spec.Keys, kops.KeysetItem{Id: id})
+			hasPrivateKey[id] = true
+		}
+		// A certificate added without a private key is never part of the rotation
+		keyset.Spec.Keys = append(keyset.Spec.Keys, kops.KeysetItem{Id: "1"})
+
+		status, err := buildStatus(keyset, hasPrivateKey)
+		if err != nil {
+			t.Errorf("unexpected error for primary %q and keys %v: %v", g.primaryId, g.ids, err)
+			continue
+		}
+		if status.Next != g.expected {
+			t.Errorf("unexpected stage for primary %q and keys %v: expected=%s actual=%s", g.primaryId, g.ids, g.expected, status.Next)
+		}
+	}
+}
@@ -26,7 +26,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 )
 
-func BuildKubecfg(cluster *kops.Cluster, keyStore fi.Keystore, secretStore fi.SecretStore, status kops.StatusStore) (*KubeconfigBuilder, error) {
+func BuildKubecfg(cluster *kops.Cluster, keyStore fi.CAStore, secretStore fi.SecretStore, status kops.StatusStore) (*KubeconfigBuilder, error) {
 	clusterName := cluster.ObjectMeta.Name
 
 	master := cluster.Spec.MasterPublicName
@@ -87,15 +87,17 @@ func BuildKubecfg(cluster *kops.Cluster, keyStore fi.Keystore, secretStore fi.Se
 
 	// add the CA Cert to the kubeconfig only if we didn't specify a SSL cert for the LB
 	if cluster.Spec.API == nil || cluster.Spec.API.LoadBalancer == nil || cluster.Spec.API.LoadBalancer.SSLCertificate == "" {
-		cert, _, _, err := keyStore.FindKeypair(fi.CertificateId_CA)
+		// We include all the trusted CAs, so the kubeconfig keeps working while the CA is rotated
+		pool, err := keyStore.FindCertificatePool(fi.CertificateId_CA)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching CA keypair: %v", err)
+			return nil, fmt.Errorf("error fetching CA certificates: %v", err)
 		}
-		if cert != nil {
-			b.CACert, err = cert.AsBytes()
+		if pool != nil && pool.Primary != nil {
+			data, err := pool.AsString()
 			if err != nil {
 				return nil, err
 			}
+			b.CACert = []byte(data)
 		} else {
 			return nil, fmt.Errorf("cannot find CA certificate")
 		}
@@ -23,6 +23,7 @@ import (
 	"github.com/golang/glog"
 
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/featureflag"
 	"k8s.io/kops/pkg/model"
 	"k8s.io/kops/pkg/model/defaults"
 	"k8s.io/kops/upup/pkg/fi"
@@ -51,8 +52,9 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 	for _, ig := range b.InstanceGroups {
 		name := b.AutoscalingGroupName(ig)
 
-		// LaunchConfiguration
+		// LaunchConfiguration (or LaunchTemplate)
 		var launchConfiguration *awstasks.LaunchConfiguration
+		var launchTemplate *awstasks.LaunchTemplate
 		{
 			volumeSize := fi.Int32Value(ig.Spec.RootVolumeSize)
 			if volumeSize == 0 {
@@ -185,9 +187,19 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 				}
 				t.AssociatePublicIP = &associatePublicIP
 			}
-			c.AddTask(t)
 
-			launchConfiguration = t
+			if ig.Spec.MixedInstancesPolicy != nil {
+				// A mixed instances policy can only be used with a launch template; the spot price is set on the policy
+				launchTemplate = buildLaunchTemplate(t)
+				launchTemplate.SpotPrice = ""
+				c.AddTask(launchTemplate)
+			} else if featureflag.EnableLaunchTemplates.Enabled() {
+				launchTemplate = buildLaunchTemplate(t)
+				c.AddTask(launchTemplate)
+			} else {
+				c.AddTask(t)
+				launchConfiguration = t
+			}
 		}
 
 		// AutoscalingGroup
@@ -209,6 +221,7 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 				},
 
 				LaunchConfiguration: launchConfiguration,
+				LaunchTemplate:      launchTemplate,
 			}
 
 			minSize := int32(1)
@@ -227,6 +240,17 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 			t.MinSize = i64(int64(minSize))
 			t.MaxSize = i64(int64(maxSize))
 
+			if spec := ig.Spec.MixedInstancesPolicy; spec != nil {
+				t.MixedInstanceOverrides = spec.Instances
+				if len(t.MixedInstanceOverrides) == 0 {
+					t.MixedInstanceOverrides = strings.Split(ig.Spec.MachineType, ",")
+				}
+				t.MixedOnDemandBase = spec.OnDemandBase
+				t.MixedOnDemandAboveBase = spec.OnDemandAboveBase
+				t.MixedSpotAllocationStrategy = spec.SpotAllocationStrategy
+				t.MixedSpotMaxPrice = ig.Spec.MaxPrice
+			}
+
 			subnets, err := b.GatherSubnets(ig)
 			if err != nil {
 				return err
@@ -283,3 +307,29 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 
 	return nil
 }
+
+// buildLaunchTemplate builds a LaunchTemplate with the same instance configuration as the LaunchConfiguration
+func buildLaunchTemplate(lc *awstasks.LaunchConfiguration) *awstasks.LaunchTemplate {
+	return &awstasks.LaunchTemplate{
+		Name:      lc.Name,
+		Lifecycle: lc.Lifecycle,
+
+		UserData: lc.UserData,
+
+		ImageID:            lc.ImageID,
+		InstanceType:       lc.InstanceType,
+		SSHKey:             lc.SSHKey,
+		SecurityGroups:     lc.SecurityGroups,
+		AssociatePublicIP:  lc.AssociatePublicIP,
+		IAMInstanceProfile: lc.IAMInstanceProfile,
+		InstanceMonitoring: lc.InstanceMonitoring,
+
+		RootVolumeSize:         lc.RootVolumeSize,
+		RootVolumeType:         lc.RootVolumeType,
+		RootVolumeIops:         lc.RootVolumeIops,
+		RootVolumeOptimization: lc.RootVolumeOptimization,
+
+		SpotPrice: lc.SpotPrice,
+		Tenancy:   lc.Tenancy,
+	}
+}
@@ -72,6 +72,20 @@ func (b *BootstrapScript) buildEnvironmentVariables(cluster *kops.Cluster) (map[
 		env["S3_SECRET_ACCESS_KEY"] = os.Getenv("S3_SECRET_ACCESS_KEY")
 	}
 
+	// Nodes read a state store in Azure Blob Storage with a SAS token, never the key of the storage account,
+	// which would give anyone able to read the user data full access to the account
+	if strings.HasPrefix(cluster.Spec.ConfigBase, "azureblob://") {
+		sasToken := os.Getenv("AZURE_STORAGE_NODE_SAS_TOKEN")
+		if sasToken == "" {
+			return nil, fmt.Errorf("AZURE_STORAGE_NODE_SAS_TOKEN must be set to a SAS token with read and list permissions on the state store container, for the nodes to read the state store")
+		}
+		env["AZURE_STORAGE_ACCOUNT"] = os.Getenv("AZURE_STORAGE_ACCOUNT")
+		env["AZURE_STORAGE_SAS_TOKEN"] = sasToken
+		if os.Getenv("AZURE_STORAGE_ENDPOINT") != "" {
+			env["AZURE_STORAGE_ENDPOINT"] = os.Getenv("AZURE_STORAGE_ENDPOINT")
+		}
+	}
+
 	if kops.CloudProviderID(cluster.Spec.CloudProvider) == kops.CloudProviderDO {
 		doToken := os.Getenv("DIGITALOCEAN_ACCESS_TOKEN")
 		if doToken != "" {
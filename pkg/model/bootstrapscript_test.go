@@ -54,6 +54,35 @@ func Test_ProxyFunc(t *testing.T) {
 	}
 }
 
+func TestBootstrapEnvironmentAzureBlob(t *testing.T) {
+	for _, name := range []string{"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_KEY", "AZURE_STORAGE_NODE_SAS_TOKEN"} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	os.Setenv("AZURE_STORAGE_ACCOUNT", "kopsstate")
+	os.Setenv("AZURE_STORAGE_KEY", "c2VjcmV0")
+	os.Setenv("AZURE_STORAGE_NODE_SAS_TOKEN", "")
+
+	b := &BootstrapScript{}
+	cluster := &kops.Cluster{}
+	cluster.Spec.ConfigBase = "azureblob://kops-state/example.com"
+
+	if _, err := b.buildEnvironmentVariables(cluster); err == nil {
+		t.Fatalf("expected an error without a SAS token for the nodes")
+	}
+
+	os.Setenv("AZURE_STORAGE_NODE_SAS_TOKEN", "sv=2018-03-28&sp=rl&sig=abc")
+	env, err := b.buildEnvironmentVariables(cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if env["AZURE_STORAGE_SAS_TOKEN"] != "sv=2018-03-28&sp=rl&sig=abc" || env["AZURE_STORAGE_ACCOUNT"] != "kopsstate" {
+		t.Errorf("SAS token not passed to the nodes: %v", env)
+	}
+	if _, found := env["AZURE_STORAGE_KEY"]; found {
+		t.Errorf("storage account key passed to the nodes")
+	}
+}
+
 func TestBootstrapUserData(t *testing.T) {
 	cs := []struct {
 		Role               kops.InstanceGroupRole
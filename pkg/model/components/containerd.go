@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/loader"
+)
+
+const (
+	// DefaultContainerdVersion is the containerd release installed when none is specified
+	DefaultContainerdVersion = "1.2.4"
+	// DefaultRuncVersion is the runc release installed alongside containerd when none is specified
+	DefaultRuncVersion = "1.0.0-rc6"
+)
+
+// ContainerdOptionsBuilder adds options for containerd to the model
+type ContainerdOptionsBuilder struct {
+	*OptionsContext
+}
+
+var _ loader.OptionsBuilder = &ContainerdOptionsBuilder{}
+
+// BuildOptions is responsible for filling in the default settings for the containerd daemon
+func (b *ContainerdOptionsBuilder) BuildOptions(o interface{}) error {
+	clusterSpec := o.(*kops.ClusterSpec)
+
+	if clusterSpec.ContainerRuntime != kops.ContainerRuntimeContainerd {
+		return nil
+	}
+
+	if clusterSpec.Containerd == nil {
+		clusterSpec.Containerd = &kops.ContainerdConfig{}
+	}
+
+	containerd := clusterSpec.Containerd
+
+	if fi.StringValue(containerd.Version) == "" {
+		containerd.Version = fi.String(DefaultContainerdVersion)
+	}
+	if fi.StringValue(containerd.RuncVersion) == "" {
+		containerd.RuncVersion = fi.String(DefaultRuncVersion)
+	}
+	if containerd.LogLevel == nil {
+		containerd.LogLevel = fi.String("info")
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/util/stringorslice"
+)
+
+// RoleAllowsKMSDecrypt returns true if the IAM policy kops builds for the instance group role, together with the
+// additionalPolicies of the cluster, allows kms:Decrypt on the KMS key keyARN.
+// Policies attached to the role by other means are not considered.
+func RoleAllowsKMSDecrypt(cluster *kops.Cluster, role kops.InstanceGroupRole, keyARN string) (bool, error) {
+	p := &Policy{
+		Version: PolicyDefaultVersion,
+	}
+
+	if role == kops.InstanceGroupRoleMaster {
+		var kmsKeys []string
+		for _, e := range cluster.Spec.EtcdClusters {
+			for _, m := range e.Members {
+				if m.KmsKeyId != nil {
+					kmsKeys = append(kmsKeys, *m.KmsKeyId)
+				}
+			}
+		}
+		if len(kmsKeys) != 0 {
+			addKMSIAMPolicies(p, stringorslice.Slice(kmsKeys), cluster.Spec.IAM != nil && cluster.Spec.IAM.Legacy)
+		}
+	}
+
+	if cluster.Spec.AdditionalPolicies != nil {
+		additionalPolicy := (*cluster.Spec.AdditionalPolicies)[strings.ToLower(string(role))]
+		if additionalPolicy != "" {
+			statements, err := ParseStatements(additionalPolicy)
+			if err != nil {
+				return false, fmt.Errorf("additionalPolicy %q is invalid: %v", strings.ToLower(string(role)), err)
+			}
+			p.Statement = append(p.Statement, statements...)
+		}
+	}
+
+	return p.Allows("kms:Decrypt", keyARN), nil
+}
+
+// Allows returns true if the policy allows the action on the resource: an Allow statement matches them,
+// and no Deny statement does.  Wildcards are matched as IAM does; conditions are not evaluated.
+func (p *Policy) Allows(action string, resource string) bool {
+	allowed := false
+	for _, s := range p.Statement {
+		if !matchesAny(s.Action.Value(), action, true) || !matchesAny(s.Resource.Value(), resource, false) {
+			continue
+		}
+		switch s.Effect {
+		case StatementEffectDeny:
+			return false
+		case StatementEffectAllow:
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+// matchesAny returns true if the value matches any of the IAM patterns, where * matches any sequence of characters
+// and ? any single character.  Actions are matched ignoring case.
+func matchesAny(patterns []string, value string, ignoreCase bool) bool {
+	for _, pattern := range patterns {
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.Replace(expr, `\*`, ".*", -1)
+		expr = strings.Replace(expr, `\?`, ".", -1)
+		expr = "^" + expr + "$"
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		if regexp.MustCompile(expr).MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
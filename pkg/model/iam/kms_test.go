@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iam
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+func TestRoleAllowsKMSDecrypt(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+	grid := []struct {
+		Description string
+		Role        kops.InstanceGroupRole
+		Additional  map[string]string
+		EtcdKey     string
+		Expected    bool
+	}{
+		{
+			Description: "no additional policies",
+			Role:        kops.InstanceGroupRoleNode,
+			Expected:    false,
+		},
+		{
+			Description: "decrypt on the key",
+			Role:        kops.InstanceGroupRoleNode,
+			Additional: map[string]string{
+				"node": `[{"Effect": "Allow", "Action": ["kms:Decrypt"], "Resource": ["` + keyARN + `"]}]`,
+			},
+			Expected: true,
+		},
+		{
+			Description: "wildcards",
+			Role:        kops.InstanceGroupRoleNode,
+			Additional: map[string]string{
+				"node": `[{"Effect": "Allow", "Action": "KMS:*", "Resource": "arn:aws:kms:us-east-1:123456789012:key/*"}]`,
+			},
+			Expected: true,
+		},
+		{
+			Description: "another key",
+			Role:        kops.InstanceGroupRoleNode,
+			Additional: map[string]string{
+				"node": `[{"Effect": "Allow", "Action": "kms:Decrypt", "Resource": "arn:aws:kms:us-east-1:123456789012:key/other"}]`,
+			},
+			Expected: false,
+		},
+		{
+			Description: "policy of another role",
+			Role:        kops.InstanceGroupRoleNode,
+			Additional: map[string]string{
+				"master": `[{"Effect": "Allow", "Action": "kms:Decrypt", "Resource": "*"}]`,
+			},
+			Expected: false,
+		},
+		{
+			Description: "denied",
+			Role:        kops.InstanceGroupRoleNode,
+			Additional: map[string]string{
+				"node": `[{"Effect": "Allow", "Action": "kms:*", "Resource": "*"}, {"Effect": "Deny", "Action": "kms:Decrypt", "Resource": "` + keyARN + `"}]`,
+			},
+			Expected: false,
+		},
+		{
+			Description: "etcd key on masters",
+			Role:        kops.InstanceGroupRoleMaster,
+			EtcdKey:     keyARN,
+			Expected:    true,
+		},
+		{
+			Description: "etcd key on nodes",
+			Role:        kops.InstanceGroupRoleNode,
+			EtcdKey:     keyARN,
+			Expected:    false,
+		},
+	}
+
+	for _, g := range grid {
+		cluster := &kops.Cluster{}
+		cluster.Spec.IAM = &kops.IAMSpec{}
+		if g.Additional != nil {
+			cluster.Spec.AdditionalPolicies = &g.Additional
+		}
+		if g.EtcdKey != "" {
+			cluster.Spec.EtcdClusters = []*kops.EtcdClusterSpec{
+				{
+					Name: "main",
+					Members: []*kops.EtcdMemberSpec{
+						{Name: "a", KmsKeyId: &g.EtcdKey},
+					},
+				},
+			}
+		}
+
+		actual, err := RoleAllowsKMSDecrypt(cluster, g.Role, keyARN)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", g.Description, err)
+			continue
+		}
+		if actual != g.Expected {
+			t.Errorf("%s: expected %v, got %v", g.Description, g.Expected, actual)
+		}
+	}
+}
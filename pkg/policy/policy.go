@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// Checker is a policy which is checked against the completed cluster and instance group specs, before they are applied
+type Checker interface {
+	Check(cluster *kops.Cluster, instanceGroups []*kops.InstanceGroup) *Result
+}
+
+// Result holds the policy violations
+type Result struct {
+	// Errors are violations which prevent the cluster from being applied
+	Errors field.ErrorList
+	// Warnings are violations which are only reported
+	Warnings field.ErrorList
+}
+
+// Merge adds the violations of another result
+func (r *Result) Merge(other *Result) {
+	if other == nil {
+		return
+	}
+	r.Errors = append(r.Errors, other.Errors...)
+	r.Warnings = append(r.Warnings, other.Warnings...)
+}
+
+// Policy is the set of rules stored in the state store, in the policy file next to the cluster config
+type Policy struct {
+	Rules []*Rule `json:"rules,omitempty"`
+}
+
+var _ Checker = &Policy{}
+
+// ReadPolicy reads the policy of the cluster from the state store, returning nil if there is no policy
+func ReadPolicy(cluster *kops.Cluster) (*Policy, error) {
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadPolicyFile(configBase.Join(registry.PathPolicy))
+}
+
+// ReadPolicyFile reads a policy from the given file, returning nil if the file does not exist
+func ReadPolicyFile(p vfs.Path) (*Policy, error) {
+	policy := &Policy{}
+	err := registry.ReadConfigDeprecated(p, policy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading policy %s: %v", p, err)
+	}
+
+	if errs := policy.Validate(); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid policy %s: %v", p, errs.ToAggregate())
+	}
+
+	return policy, nil
+}
+
+// Validate checks that the rules of the policy are well-formed
+func (p *Policy) Validate() field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := make(map[string]bool)
+	for i, rule := range p.Rules {
+		fieldPath := field.NewPath("rules").Index(i)
+		if rule.Name != "" {
+			if names[rule.Name] {
+				allErrs = append(allErrs, field.Duplicate(fieldPath.Child("name"), rule.Name))
+			}
+			names[rule.Name] = true
+		}
+		allErrs = append(allErrs, rule.validate(fieldPath)...)
+	}
+
+	return allErrs
+}
+
+// Check evaluates every rule against the cluster and the instance groups
+func (p *Policy) Check(cluster *kops.Cluster, instanceGroups []*kops.InstanceGroup) *Result {
+	result := &Result{}
+
+	for _, rule := range p.Rules {
+		var errs field.ErrorList
+		switch rule.Kind {
+		case "", RuleKindCluster:
+			errs = rule.check(cluster.Spec, field.NewPath("spec"))
+
+		case RuleKindInstanceGroup:
+			for _, ig := range instanceGroups {
+				if rule.Role != "" && rule.Role != ig.Spec.Role {
+					continue
+				}
+				errs = append(errs, rule.check(ig.Spec, field.NewPath("instanceGroup").Key(ig.ObjectMeta.Name).Child("spec"))...)
+			}
+		}
+
+		if rule.Severity == SeverityWarning {
+			result.Warnings = append(result.Warnings, errs...)
+		} else {
+			result.Errors = append(result.Errors, errs...)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const testPolicy = `
+rules:
+- name: private-api
+  field: api.loadBalancer.type
+  allowed: [Internal]
+- name: etcd-tls
+  field: etcdClusters[*].enableEtcdTLS
+  allowed: [true]
+- name: root-volume
+  kind: InstanceGroup
+  field: rootVolumeSize
+  minimum: 50
+- name: ssh-access
+  description: SSH must not be open to the world
+  severity: Warning
+  field: sshAccess
+  forbidden: [0.0.0.0/0]
+`
+
+func readTestPolicy(t *testing.T, data string) *Policy {
+	vfs.Context.ResetMemfsContext(true)
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.ConfigBase = "memfs://state/cluster.example.com"
+
+	if data != "" {
+		p, err := vfs.Context.BuildVfsPath(cluster.Spec.ConfigBase + "/policy")
+		if err != nil {
+			t.Fatalf("error building vfs path: %v", err)
+		}
+		if err := p.WriteFile(bytes.NewReader([]byte(data)), nil); err != nil {
+			t.Fatalf("error writing policy: %v", err)
+		}
+	}
+
+	policy, err := ReadPolicy(cluster)
+	if err != nil {
+		t.Fatalf("error reading policy: %v", err)
+	}
+	return policy
+}
+
+func errorStrings(errs field.ErrorList) []string {
+	var s []string
+	for _, err := range errs {
+		s = append(s, err.Field+" "+string(err.Type))
+	}
+	sort.Strings(s)
+	return s
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := readTestPolicy(t, testPolicy)
+	if policy == nil || len(policy.Rules) != 4 {
+		t.Fatalf("unexpected policy: %v", policy)
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.API = &kops.AccessSpec{LoadBalancer: &kops.LoadBalancerAccessSpec{Type: kops.LoadBalancerTypePublic}}
+	cluster.Spec.EtcdClusters = []*kops.EtcdClusterSpec{
+		{Name: "main", EnableEtcdTLS: true},
+		{Name: "events"},
+	}
+	cluster.Spec.SSHAccess = []string{"10.0.0.0/8", "0.0.0.0/0"}
+
+	instanceGroups := []*kops.InstanceGroup{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master"}, Spec: kops.InstanceGroupSpec{RootVolumeSize: fi.Int32(64)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "nodes"}, Spec: kops.InstanceGroupSpec{RootVolumeSize: fi.Int32(20)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "bastions"}},
+	}
+
+	result := policy.Check(cluster, instanceGroups)
+
+	expectedErrors := []string{
+		"instanceGroup[bastions].spec.rootVolumeSize FieldValueRequired",
+		"instanceGroup[nodes].spec.rootVolumeSize FieldValueInvalid",
+		"spec.api.loadBalancer.type FieldValueInvalid",
+		"spec.etcdClusters[1].enableEtcdTLS FieldValueRequired",
+	}
+	if actual := errorStrings(result.Errors); !reflect.DeepEqual(actual, expectedErrors) {
+		t.Errorf("unexpected errors: %v", actual)
+	}
+
+	expectedWarnings := []string{
+		"spec.sshAccess[1] FieldValueInvalid",
+	}
+	if actual := errorStrings(result.Warnings); !reflect.DeepEqual(actual, expectedWarnings) {
+		t.Errorf("unexpected warnings: %v", actual)
+	}
+}
+
+func TestPolicyCheckCompliant(t *testing.T) {
+	policy := readTestPolicy(t, testPolicy)
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.API = &kops.AccessSpec{LoadBalancer: &kops.LoadBalancerAccessSpec{Type: kops.LoadBalancerTypeInternal}}
+	cluster.Spec.EtcdClusters = []*kops.EtcdClusterSpec{
+		{Name: "main", EnableEtcdTLS: true},
+	}
+
+	instanceGroups := []*kops.InstanceGroup{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nodes"}, Spec: kops.InstanceGroupSpec{RootVolumeSize: fi.Int32(50)}},
+	}
+
+	result := policy.Check(cluster, instanceGroups)
+	if len(result.Errors) != 0 || len(result.Warnings) != 0 {
+		t.Fatalf("unexpected violations: %v %v", result.Errors, result.Warnings)
+	}
+}
+
+func TestReadPolicyMissing(t *testing.T) {
+	if policy := readTestPolicy(t, ""); policy != nil {
+		t.Fatalf("expected no policy, got %v", policy)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	grid := []struct {
+		rule     Rule
+		expected []string
+	}{
+		{
+			rule: Rule{Field: "sshAccess", Forbidden: []interface{}{"0.0.0.0/0"}},
+		},
+		{
+			rule:     Rule{Field: "sshAccess"},
+			expected: []string{"rules[0] FieldValueRequired"},
+		},
+		{
+			rule:     Rule{Field: "etcdClusters[0].name", Required: true},
+			expected: []string{"rules[0].field FieldValueInvalid"},
+		},
+		{
+			rule:     Rule{Field: "sshAccess", Required: true, Role: kops.InstanceGroupRoleNode, Severity: "Fatal"},
+			expected: []string{"rules[0].role FieldValueInvalid", "rules[0].severity FieldValueNotSupported"},
+		},
+	}
+
+	for _, g := range grid {
+		p := &Policy{Rules: []*Rule{&g.rule}}
+		actual := errorStrings(p.Validate())
+		if !reflect.DeepEqual(actual, g.expected) {
+			t.Errorf("unexpected validation errors for %+v: expected=%v actual=%v", g.rule, g.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// RuleKind is the kind of object a rule is checked against
+type RuleKind string
+
+const (
+	RuleKindCluster       RuleKind = "Cluster"
+	RuleKindInstanceGroup RuleKind = "InstanceGroup"
+)
+
+// Severity is whether a violation of a rule is an error or a warning
+type Severity string
+
+const (
+	SeverityError   Severity = "Error"
+	SeverityWarning Severity = "Warning"
+)
+
+// Rule constrains the value of a field of the cluster or instance group spec.
+// The field is a dotted path in the spec, as it is written in yaml, e.g. api.loadBalancer.type;
+// a list can be expanded with [*], e.g. etcdClusters[*].enableEtcdTLS.
+// If the field is a list, the constraints apply to each of its items.
+type Rule struct {
+	// Name identifies the rule
+	Name string `json:"name,omitempty"`
+	// Description is included in the violations of the rule
+	Description string `json:"description,omitempty"`
+
+	// Kind is the kind of object the rule is checked against; defaults to Cluster
+	Kind RuleKind `json:"kind,omitempty"`
+	// Role restricts an InstanceGroup rule to the instance groups with this role
+	Role kops.InstanceGroupRole `json:"role,omitempty"`
+	// Severity is Error (the default), or Warning
+	Severity Severity `json:"severity,omitempty"`
+
+	// Field is the path of the field in the spec
+	Field string `json:"field"`
+
+	// Required is true if the field must be set
+	Required bool `json:"required,omitempty"`
+	// Allowed is the list of allowed values; if set, the field must be set to one of them
+	Allowed []interface{} `json:"allowed,omitempty"`
+	// Forbidden is the list of values the field must not have
+	Forbidden []interface{} `json:"forbidden,omitempty"`
+	// Minimum is the minimum value of a numeric field; if set, the field must be set
+	Minimum *int64 `json:"minimum,omitempty"`
+	// Maximum is the maximum value of a numeric field; if set, the field must be set
+	Maximum *int64 `json:"maximum,omitempty"`
+}
+
+// fieldSegment is a component of the field path of a rule
+type fieldSegment struct {
+	name   string
+	expand bool
+}
+
+func parseField(s string) ([]fieldSegment, error) {
+	var segments []fieldSegment
+	for _, token := range strings.Split(s, ".") {
+		segment := fieldSegment{name: token}
+		if strings.HasSuffix(token, "[*]") {
+			segment.name = strings.TrimSuffix(token, "[*]")
+			segment.expand = true
+		}
+		if segment.name == "" || strings.ContainsAny(segment.name, "[]*") {
+			return nil, fmt.Errorf("invalid field %q", s)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+func (r *Rule) validate(fieldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch r.Kind {
+	case "", RuleKindCluster:
+		if r.Role != "" {
+			allErrs = append(allErrs, field.Invalid(fieldPath.Child("role"), r.Role, "role can only be set for InstanceGroup rules"))
+		}
+	case RuleKindInstanceGroup:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fieldPath.Child("kind"), r.Kind, []string{string(RuleKindCluster), string(RuleKindInstanceGroup)}))
+	}
+
+	switch r.Severity {
+	case "", SeverityError, SeverityWarning:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fieldPath.Child("severity"), r.Severity, []string{string(SeverityError), string(SeverityWarning)}))
+	}
+
+	if r.Field == "" {
+		allErrs = append(allErrs, field.Required(fieldPath.Child("field"), ""))
+	} else if _, err := parseField(r.Field); err != nil {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("field"), r.Field, err.Error()))
+	}
+
+	if !r.Required && len(r.Allowed) == 0 && len(r.Forbidden) == 0 && r.Minimum == nil && r.Maximum == nil {
+		allErrs = append(allErrs, field.Required(fieldPath, "rule must set one of required, allowed, forbidden, minimum or maximum"))
+	}
+
+	return allErrs
+}
+
+// check evaluates the rule against a spec, returning the violations
+func (r *Rule) check(spec interface{}, specPath *field.Path) field.ErrorList {
+	segments, err := parseField(r.Field)
+	if err != nil {
+		// Rules are validated when they are read
+		return field.ErrorList{field.InternalError(specPath, err)}
+	}
+
+	// We evaluate against the serialized form, so fields are named as they are written in yaml
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return field.ErrorList{field.InternalError(specPath, fmt.Errorf("error serializing spec: %v", err))}
+	}
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return field.ErrorList{field.InternalError(specPath, fmt.Errorf("error parsing spec: %v", err))}
+	}
+
+	var allErrs field.ErrorList
+	for _, m := range resolveField(obj, specPath, segments) {
+		allErrs = append(allErrs, r.checkValue(m.path, m.value)...)
+	}
+	return allErrs
+}
+
+func (r *Rule) detail(s string) string {
+	detail := s
+	if r.Description != "" {
+		detail = r.Description + ": " + s
+	}
+	if r.Name != "" {
+		detail = fmt.Sprintf("policy %q: %s", r.Name, detail)
+	}
+	return detail
+}
+
+func (r *Rule) checkValue(fieldPath *field.Path, value interface{}) field.ErrorList {
+	if value == nil {
+		if r.Required || len(r.Allowed) != 0 || r.Minimum != nil || r.Maximum != nil {
+			return field.ErrorList{field.Required(fieldPath, r.detail("must be set"))}
+		}
+		return nil
+	}
+
+	if items, ok := value.([]interface{}); ok {
+		var allErrs field.ErrorList
+		for i, item := range items {
+			allErrs = append(allErrs, r.checkValue(fieldPath.Index(i), item)...)
+		}
+		return allErrs
+	}
+
+	var allErrs field.ErrorList
+
+	s := valueString(value)
+	if len(r.Allowed) != 0 && !containsValue(r.Allowed, s) {
+		allErrs = append(allErrs, field.Invalid(fieldPath, value, r.detail("must be one of "+valuesString(r.Allowed))))
+	}
+	if containsValue(r.Forbidden, s) {
+		allErrs = append(allErrs, field.Invalid(fieldPath, value, r.detail("is forbidden")))
+	}
+
+	if r.Minimum != nil || r.Maximum != nil {
+		n, ok := value.(float64)
+		if !ok {
+			allErrs = append(allErrs, field.Invalid(fieldPath, value, r.detail("must be a number")))
+		} else {
+			if r.Minimum != nil && n < float64(*r.Minimum) {
+				allErrs = append(allErrs, field.Invalid(fieldPath, value, r.detail(fmt.Sprintf("must be at least %d", *r.Minimum))))
+			}
+			if r.Maximum != nil && n > float64(*r.Maximum) {
+				allErrs = append(allErrs, field.Invalid(fieldPath, value, r.detail(fmt.Sprintf("must be at most %d", *r.Maximum))))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// fieldMatch is a value found at a field path; value is nil if the field is not set
+type fieldMatch struct {
+	path  *field.Path
+	value interface{}
+}
+
+func resolveField(obj interface{}, fieldPath *field.Path, segments []fieldSegment) []fieldMatch {
+	if len(segments) == 0 {
+		return []fieldMatch{{path: fieldPath, value: obj}}
+	}
+
+	segment := segments[0]
+	fieldPath = fieldPath.Child(segment.name)
+
+	var value interface{}
+	if m, ok := obj.(map[string]interface{}); ok {
+		value = m[segment.name]
+	} else if obj != nil {
+		glog.V(2).Infof("policy field %s is not an object", fieldPath)
+	}
+
+	if value == nil {
+		// Report the field which is not set, not its parent
+		for _, s := range segments[1:] {
+			fieldPath = fieldPath.Child(s.name)
+		}
+		return []fieldMatch{{path: fieldPath}}
+	}
+
+	if !segment.expand {
+		return resolveField(value, fieldPath, segments[1:])
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return []fieldMatch{{path: fieldPath, value: value}}
+	}
+	var matches []fieldMatch
+	for i, item := range items {
+		matches = append(matches, resolveField(item, fieldPath.Index(i), segments[1:])...)
+	}
+	return matches
+}
+
+// valueString normalizes a value, so values from the spec and from the policy compare equal
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func containsValue(values []interface{}, s string) bool {
+	for _, v := range values {
+		if valueString(v) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func valuesString(values []interface{}) string {
+	var l []string
+	for _, v := range values {
+		l = append(l, valueString(v))
+	}
+	return "[" + strings.Join(l, ", ") + "]"
+}
@@ -43,6 +43,7 @@ import (
 
 const (
 	TypeAutoscalingLaunchConfig = "autoscaling-config"
+	TypeLaunchTemplate          = "launch-template"
 	TypeNatGateway              = "nat-gateway"
 	TypeElasticIp               = "elastic-ip"
 	TypeLoadBalancer            = "load-balancer"
@@ -1159,7 +1160,24 @@ func ListAutoScalingGroups(cloud fi.Cloud, clusterName string) ([]*resources.Res
 			}
 			blocks = append(blocks, "subnet:"+subnet)
 		}
-		blocks = append(blocks, TypeAutoscalingLaunchConfig+":"+aws.StringValue(asg.LaunchConfigurationName))
+		launchTemplate := asg.LaunchTemplate
+		if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+			launchTemplate = asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+		}
+		if launchTemplate != nil {
+			// Launch templates are not tagged, so we delete the template referenced by the autoscaling group
+			id := aws.StringValue(launchTemplate.LaunchTemplateId)
+			blocks = append(blocks, TypeLaunchTemplate+":"+id)
+
+			resourceTrackers = append(resourceTrackers, &resources.Resource{
+				Name:    aws.StringValue(launchTemplate.LaunchTemplateName),
+				ID:      id,
+				Type:    TypeLaunchTemplate,
+				Deleter: DeleteLaunchTemplate,
+			})
+		} else {
+			blocks = append(blocks, TypeAutoscalingLaunchConfig+":"+aws.StringValue(asg.LaunchConfigurationName))
+		}
 
 		resourceTracker.Blocks = blocks
 
@@ -1365,6 +1383,21 @@ func DeleteAutoscalingLaunchConfiguration(cloud fi.Cloud, r *resources.Resource)
 	return nil
 }
 
+func DeleteLaunchTemplate(cloud fi.Cloud, r *resources.Resource) error {
+	c := cloud.(awsup.AWSCloud)
+
+	id := r.ID
+	glog.V(2).Infof("Deleting LaunchTemplate %q", id)
+	request := &ec2.DeleteLaunchTemplateInput{
+		LaunchTemplateId: &id,
+	}
+	_, err := c.EC2().DeleteLaunchTemplate(request)
+	if err != nil {
+		return fmt.Errorf("error deleting LaunchTemplate %q: %v", id, err)
+	}
+	return nil
+}
+
 func DeleteELB(cloud fi.Cloud, r *resources.Resource) error {
 	c := cloud.(awsup.AWSCloud)
 
@@ -95,6 +95,12 @@ func (t *Templates) loadFrom(base vfs.Path) error {
 	return nil
 }
 
+// Render executes a template which was not loaded from the base path, such as a user-supplied manifest,
+// with the same functions and cluster spec as the templates which were loaded
+func (t *Templates) Render(key string, data string) (string, error) {
+	return t.executeTemplate(key, data)
+}
+
 func (l *Templates) executeTemplate(key string, d string) (string, error) {
 	t := template.New(key)
 
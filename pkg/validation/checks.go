@@ -0,0 +1,268 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	apisvalidation "k8s.io/kops/pkg/apis/kops/validation"
+)
+
+// httpEndpointTimeout is the timeout of each HTTP endpoint check
+const httpEndpointTimeout = 10 * time.Second
+
+// readValidationChecks returns the extra checks of the cluster spec, along with those in the validation file in the state store
+func readValidationChecks(cluster *kops.Cluster) (*kops.ClusterValidationSpec, error) {
+	checks := &kops.ClusterValidationSpec{}
+	if cluster.Spec.Validation != nil {
+		*checks = *cluster.Spec.Validation
+	}
+
+	if cluster.Spec.ConfigBase == "" {
+		return checks, nil
+	}
+
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	p := configBase.Join(registry.PathValidation)
+	file := &kops.ClusterValidationSpec{}
+	if err := registry.ReadConfigDeprecated(p, file); err != nil {
+		if os.IsNotExist(err) {
+			return checks, nil
+		}
+		return nil, fmt.Errorf("error reading validation checks %s: %v", p, err)
+	}
+
+	if errs := apisvalidation.ValidateClusterValidationSpec(file, field.NewPath("validation")); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid validation checks %s: %v", p, errs.ToAggregate())
+	}
+
+	checks.Deployments = append(checks.Deployments, file.Deployments...)
+	checks.DaemonSets = append(checks.DaemonSets, file.DaemonSets...)
+	checks.CustomResourceDefinitions = append(checks.CustomResourceDefinitions, file.CustomResourceDefinitions...)
+	checks.Nodes = append(checks.Nodes, file.Nodes...)
+	checks.HTTPEndpoints = append(checks.HTTPEndpoints, file.HTTPEndpoints...)
+
+	return checks, nil
+}
+
+// collectCheckFailures runs the extra checks against the cluster
+func (v *ValidationCluster) collectCheckFailures(checks *kops.ClusterValidationSpec, client kubernetes.Interface) error {
+	for _, ref := range checks.Deployments {
+		if err := v.collectDeploymentFailures(ref, client); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range checks.DaemonSets {
+		if err := v.collectDaemonSetFailures(ref, client); err != nil {
+			return err
+		}
+	}
+
+	if len(checks.CustomResourceDefinitions) != 0 {
+		if err := v.collectCustomResourceDefinitionFailures(checks.CustomResourceDefinitions, client); err != nil {
+			return err
+		}
+	}
+
+	for _, nodes := range checks.Nodes {
+		if err := v.collectNodesFailures(nodes, client); err != nil {
+			return err
+		}
+	}
+
+	for _, endpoint := range checks.HTTPEndpoints {
+		if failure := CheckHTTPEndpoint(endpoint); failure != nil {
+			v.addError(failure)
+		}
+	}
+
+	return nil
+}
+
+func namespaceOrDefault(ref kops.ValidationObjectRef) string {
+	if ref.Namespace == "" {
+		return "kube-system"
+	}
+	return ref.Namespace
+}
+
+func (v *ValidationCluster) collectDeploymentFailures(ref kops.ValidationObjectRef, client kubernetes.Interface) error {
+	namespace := namespaceOrDefault(ref)
+	name := namespace + "/" + ref.Name
+
+	deployment, err := client.ExtensionsV1beta1().Deployments(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			v.addError(&ValidationError{
+				Kind:    "Deployment",
+				Name:    name,
+				Message: fmt.Sprintf("deployment %q not found", name),
+			})
+			return nil
+		}
+		return fmt.Errorf("error getting Deployment %q: %v", name, err)
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.UpdatedReplicas < desired || status.AvailableReplicas < desired {
+		v.addError(&ValidationError{
+			Kind: "Deployment",
+			Name: name,
+			Message: fmt.Sprintf("deployment %q has %d updated and %d available of %d desired replicas",
+				name, status.UpdatedReplicas, status.AvailableReplicas, desired),
+		})
+	}
+	return nil
+}
+
+func (v *ValidationCluster) collectDaemonSetFailures(ref kops.ValidationObjectRef, client kubernetes.Interface) error {
+	namespace := namespaceOrDefault(ref)
+	name := namespace + "/" + ref.Name
+
+	daemonSet, err := client.ExtensionsV1beta1().DaemonSets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			v.addError(&ValidationError{
+				Kind:    "DaemonSet",
+				Name:    name,
+				Message: fmt.Sprintf("daemonset %q not found", name),
+			})
+			return nil
+		}
+		return fmt.Errorf("error getting DaemonSet %q: %v", name, err)
+	}
+
+	status := daemonSet.Status
+	if status.UpdatedNumberScheduled < status.DesiredNumberScheduled || status.NumberAvailable < status.DesiredNumberScheduled {
+		v.addError(&ValidationError{
+			Kind: "DaemonSet",
+			Name: name,
+			Message: fmt.Sprintf("daemonset %q has %d updated and %d available of %d desired pods",
+				name, status.UpdatedNumberScheduled, status.NumberAvailable, status.DesiredNumberScheduled),
+		})
+	}
+	return nil
+}
+
+// collectCustomResourceDefinitionFailures checks that the API server serves each CRD, named <plural>.<group>, in any version
+func (v *ValidationCluster) collectCustomResourceDefinitionFailures(names []string, client kubernetes.Interface) error {
+	groups, err := client.Discovery().ServerGroups()
+	if err != nil {
+		return fmt.Errorf("error listing API groups: %v", err)
+	}
+
+	for _, name := range names {
+		plural := name
+		group := ""
+		if i := strings.Index(name, "."); i != -1 {
+			plural = name[:i]
+			group = name[i+1:]
+		}
+
+		served := false
+		for _, g := range groups.Groups {
+			if g.Name != group {
+				continue
+			}
+			for _, version := range g.Versions {
+				resources, err := client.Discovery().ServerResourcesForGroupVersion(version.GroupVersion)
+				if err != nil {
+					return fmt.Errorf("error listing resources of %s: %v", version.GroupVersion, err)
+				}
+				for _, resource := range resources.APIResources {
+					if resource.Name == plural {
+						served = true
+					}
+				}
+			}
+		}
+
+		if !served {
+			v.addError(&ValidationError{
+				Kind:    "CustomResourceDefinition",
+				Name:    name,
+				Message: fmt.Sprintf("custom resource definition %q is not served", name),
+			})
+		}
+	}
+	return nil
+}
+
+func (v *ValidationCluster) collectNodesFailures(nodes kops.ValidationNodesSpec, client kubernetes.Interface) error {
+	nodeList, err := client.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: nodes.Selector})
+	if err != nil {
+		return fmt.Errorf("error listing nodes matching %q: %v", nodes.Selector, err)
+	}
+
+	ready := int32(0)
+	for i := range nodeList.Items {
+		if isNodeReady(&nodeList.Items[i]) {
+			ready++
+		}
+	}
+
+	if ready < nodes.MinReady {
+		v.addError(&ValidationError{
+			Kind:    "Nodes",
+			Name:    nodes.Selector,
+			Message: fmt.Sprintf("%d nodes matching %q are ready, %d required", ready, nodes.Selector, nodes.MinReady),
+		})
+	}
+	return nil
+}
+
+// CheckHTTPEndpoint returns a failure unless a GET of the endpoint returns a successful (2xx) status
+func CheckHTTPEndpoint(endpoint string) *ValidationError {
+	client := &http.Client{Timeout: httpEndpointTimeout}
+	response, err := client.Get(endpoint)
+	if err != nil {
+		return &ValidationError{
+			Kind:    "HTTPEndpoint",
+			Name:    endpoint,
+			Message: fmt.Sprintf("error requesting %s: %v", endpoint, err),
+		}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &ValidationError{
+			Kind:    "HTTPEndpoint",
+			Name:    endpoint,
+			Message: fmt.Sprintf("%s returned status %d", endpoint, response.StatusCode),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func readTestValidationChecks(t *testing.T, spec *kopsapi.ClusterValidationSpec, data string) (*kopsapi.ClusterValidationSpec, error) {
+	vfs.Context.ResetMemfsContext(true)
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Spec.ConfigBase = "memfs://state/cluster.example.com"
+	cluster.Spec.Validation = spec
+
+	if data != "" {
+		p, err := vfs.Context.BuildVfsPath(cluster.Spec.ConfigBase + "/validation")
+		if err != nil {
+			t.Fatalf("error building vfs path: %v", err)
+		}
+		if err := p.WriteFile(bytes.NewReader([]byte(data)), nil); err != nil {
+			t.Fatalf("error writing validation checks: %v", err)
+		}
+	}
+
+	return readValidationChecks(cluster)
+}
+
+func Test_ReadValidationChecks(t *testing.T) {
+	spec := &kopsapi.ClusterValidationSpec{
+		Deployments: []kopsapi.ValidationObjectRef{{Name: "dns-controller"}},
+	}
+
+	checks, err := readTestValidationChecks(t, spec, "")
+	if err != nil {
+		t.Fatalf("error reading validation checks: %v", err)
+	}
+	if !reflect.DeepEqual(checks, spec) {
+		t.Errorf("expected only the checks of the cluster spec, got %+v", checks)
+	}
+
+	checks, err = readTestValidationChecks(t, spec, `
+deployments:
+- namespace: ingress
+  name: nginx-ingress
+httpEndpoints:
+- https://app.example.com/healthz
+`)
+	if err != nil {
+		t.Fatalf("error reading validation checks: %v", err)
+	}
+	expected := &kopsapi.ClusterValidationSpec{
+		Deployments: []kopsapi.ValidationObjectRef{
+			{Name: "dns-controller"},
+			{Namespace: "ingress", Name: "nginx-ingress"},
+		},
+		HTTPEndpoints: []string{"https://app.example.com/healthz"},
+	}
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("expected the checks of the file to be added, got %+v", checks)
+	}
+	if len(spec.Deployments) != 1 {
+		t.Errorf("expected the cluster spec not to be changed, got %+v", spec)
+	}
+
+	if _, err := readTestValidationChecks(t, nil, "nodes:\n- selector: 'app in (web'\n"); err == nil {
+		t.Errorf("expected an error for an invalid validation file")
+	}
+}
+
+func Test_CollectCheckFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+		case "/ready":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	replicas := int32(2)
+	node := func(name string, ig string, ready v1.ConditionStatus) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kops.k8s.io/instancegroup": ig}},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: ready}},
+			},
+		}
+	}
+
+	client := fake.NewSimpleClientset(
+		&extensionsv1beta1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "dns-controller"},
+			Spec:       extensionsv1beta1.DeploymentSpec{Replicas: &replicas},
+			Status:     extensionsv1beta1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		&extensionsv1beta1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ingress", Name: "nginx-ingress"},
+			Spec:       extensionsv1beta1.DeploymentSpec{Replicas: &replicas},
+			Status:     extensionsv1beta1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: 1},
+		},
+		&extensionsv1beta1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "fluentd"},
+			Status:     extensionsv1beta1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+		},
+		node("node-1", "nodes", v1.ConditionTrue),
+		node("node-2", "nodes", v1.ConditionFalse),
+		node("node-3", "gpu", v1.ConditionTrue),
+	)
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "certmanager.k8s.io/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "certificates"}, {Name: "issuers"}},
+		},
+	}
+
+	checks := &kopsapi.ClusterValidationSpec{
+		Deployments: []kopsapi.ValidationObjectRef{
+			{Name: "dns-controller"},
+			{Namespace: "ingress", Name: "nginx-ingress"},
+			{Namespace: "ingress", Name: "missing"},
+		},
+		DaemonSets: []kopsapi.ValidationObjectRef{
+			{Namespace: "logging", Name: "fluentd"},
+		},
+		CustomResourceDefinitions: []string{
+			"certificates.certmanager.k8s.io",
+			"clusterissuers.certmanager.k8s.io",
+			"prometheuses.monitoring.coreos.com",
+		},
+		Nodes: []kopsapi.ValidationNodesSpec{
+			{Selector: "kops.k8s.io/instancegroup=gpu", MinReady: 1},
+			{Selector: "kops.k8s.io/instancegroup=nodes", MinReady: 2},
+		},
+		HTTPEndpoints: []string{
+			server.URL + "/healthz",
+			server.URL + "/ready",
+			server.URL + "/broken",
+		},
+	}
+
+	v := &ValidationCluster{}
+	if err := v.collectCheckFailures(checks, client); err != nil {
+		t.Fatalf("unexpected error running validation checks: %v", err)
+	}
+
+	var failures []string
+	for _, failure := range v.Failures {
+		failures = append(failures, failure.Kind+" "+failure.Name)
+	}
+	sort.Strings(failures)
+	expected := []string{
+		"CustomResourceDefinition clusterissuers.certmanager.k8s.io",
+		"CustomResourceDefinition prometheuses.monitoring.coreos.com",
+		"Deployment ingress/missing",
+		"Deployment ingress/nginx-ingress",
+		"HTTPEndpoint " + server.URL + "/broken",
+		"Nodes kops.k8s.io/instancegroup=nodes",
+	}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("unexpected failures %v, expected %v", failures, expected)
+		printDebug(t, v)
+	}
+}
@@ -152,6 +152,14 @@ func ValidateCluster(cluster *kops.Cluster, instanceGroupList *kops.InstanceGrou
 		return nil, fmt.Errorf("cannot get pod health for %q: %v", clusterName, err)
 	}
 
+	checks, err := readValidationChecks(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err = v.collectCheckFailures(checks, k8sClient); err != nil {
+		return nil, fmt.Errorf("cannot run validation checks for %q: %v", clusterName, err)
+	}
+
 	return v, nil
 }
 
@@ -101,6 +101,10 @@ type CAStore interface {
 
 	// DeleteKeysetItem will delete the specified item from the Keyset
 	DeleteKeysetItem(item *kops.Keyset, id string) error
+
+	// SetPrimaryKeysetItem makes the specified item the primary of the Keyset, used for issuing;
+	// the other items remain trusted.  An empty id reverts to the item with the highest id.
+	SetPrimaryKeysetItem(item *kops.Keyset, id string) error
 }
 
 // SSHCredentialStore holds SSHCredential objects
@@ -125,6 +125,8 @@ type keyset struct {
 	format  KeysetFormat
 	items   map[string]*keysetItem
 	primary *keysetItem
+	// primaryId pins the primary to a specific item, rather than the item with the highest id
+	primaryId string
 }
 
 // keysetItem is a parsed KeysetItem
@@ -165,6 +167,7 @@ func parseKeyset(o *kops.Keyset) (*keyset, error) {
 		keyset.items[key.Id] = ki
 	}
 
+	keyset.primaryId = o.Spec.PrimaryId
 	keyset.primary = keyset.findPrimary()
 
 	return keyset, nil
@@ -190,6 +193,13 @@ func (c *ClientsetCAStore) loadKeyset(name string) (*keyset, error) {
 
 // findPrimary returns the primary keysetItem in the keyset
 func (k *keyset) findPrimary() *keysetItem {
+	if k.primaryId != "" {
+		if item := k.items[k.primaryId]; item != nil {
+			return item
+		}
+		glog.Warningf("Ignoring primaryId %q which is not in the keyset", k.primaryId)
+	}
+
 	var primary *keysetItem
 	var primaryVersion *big.Int
 
@@ -210,6 +220,15 @@ func (k *keyset) findPrimary() *keysetItem {
 
 // FindPrimary returns the primary KeysetItem in the Keyset
 func FindPrimary(keyset *kops.Keyset) *kops.KeysetItem {
+	if keyset.Spec.PrimaryId != "" {
+		for i := range keyset.Spec.Keys {
+			if keyset.Spec.Keys[i].Id == keyset.Spec.PrimaryId {
+				return &keyset.Spec.Keys[i]
+			}
+		}
+		glog.Warningf("Ignoring primaryId %q which is not in keyset %q", keyset.Spec.PrimaryId, keyset.Name)
+	}
+
 	var primary *kops.KeysetItem
 	var primaryVersion *big.Int
 	for i := range keyset.Spec.Keys {
@@ -532,6 +551,9 @@ func DeleteKeysetItem(client kopsinternalversion.KeysetInterface, name string, k
 		}
 	} else {
 		keyset.Spec.Keys = newKeys
+		if keyset.Spec.PrimaryId == id {
+			keyset.Spec.PrimaryId = ""
+		}
 		if _, err := client.Update(keyset); err != nil {
 			return fmt.Errorf("error updating Keyset %q: %v", name, err)
 		}
@@ -539,6 +561,36 @@ func DeleteKeysetItem(client kopsinternalversion.KeysetInterface, name string, k
 	return nil
 }
 
+// SetPrimaryKeysetItem pins the primary of the keyset to the specified key; an empty id unpins it
+func SetPrimaryKeysetItem(client kopsinternalversion.KeysetInterface, name string, keysetType kops.KeysetType, id string) error {
+	keyset, err := client.Get(name, v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error reading Keyset %q: %v", name, err)
+	}
+
+	if keyset.Spec.Type != keysetType {
+		return fmt.Errorf("mismatch on Keyset type on %q", name)
+	}
+
+	if id != "" {
+		found := false
+		for _, ki := range keyset.Spec.Keys {
+			if ki.Id == id {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("KeysetItem %q not found in Keyset %q", id, name)
+		}
+	}
+
+	keyset.Spec.PrimaryId = id
+	if _, err := client.Update(keyset); err != nil {
+		return fmt.Errorf("error updating Keyset %q: %v", name, err)
+	}
+	return nil
+}
+
 // addSshCredential saves the specified SSH Credential to the registry, doing an update or insert
 func (c *ClientsetCAStore) addSshCredential(name string, publicKey string) error {
 	create := false
@@ -650,6 +702,17 @@ func (c *ClientsetCAStore) DeleteKeysetItem(item *kops.Keyset, id string) error
 	}
 }
 
+// SetPrimaryKeysetItem implements CAStore::SetPrimaryKeysetItem
+func (c *ClientsetCAStore) SetPrimaryKeysetItem(item *kops.Keyset, id string) error {
+	switch item.Spec.Type {
+	case kops.SecretTypeKeypair:
+		client := c.clientset.Keysets(c.namespace)
+		return SetPrimaryKeysetItem(client, item.Name, kops.SecretTypeKeypair, id)
+	default:
+		return fmt.Errorf("setting the primary of keystore items of type %v not supported", item.Spec.Type)
+	}
+}
+
 // DeleteSSHCredential implements SSHCredentialStore::DeleteSSHCredential
 func (c *ClientsetCAStore) DeleteSSHCredential(item *kops.SSHCredential) error {
 	return c.deleteSSHCredential(item.Name)
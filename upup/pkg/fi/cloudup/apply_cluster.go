@@ -18,6 +18,7 @@ package cloudup
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -47,6 +48,7 @@ import (
 	"k8s.io/kops/pkg/model/openstackmodel"
 	"k8s.io/kops/pkg/model/spotinstmodel"
 	"k8s.io/kops/pkg/model/vspheremodel"
+	"k8s.io/kops/pkg/policy"
 	"k8s.io/kops/pkg/resources/digitalocean"
 	"k8s.io/kops/pkg/templates"
 	"k8s.io/kops/upup/models"
@@ -124,6 +126,9 @@ type ApplyClusterCmd struct {
 	// DryRun is true if this is only a dry run
 	DryRun bool
 
+	// DryRunOutput is where the dry run report is printed; if nil it is printed to stdout
+	DryRunOutput io.Writer
+
 	// RunTasksOptions defines parameters for task execution, e.g. retry interval
 	RunTasksOptions *fi.RunTasksOptions
 
@@ -138,6 +143,9 @@ type ApplyClusterCmd struct {
 	// that is re-mapped.
 	LifecycleOverrides map[string]fi.Lifecycle
 
+	// Policies are checked against the completed specs, together with the policy in the state store
+	Policies []policy.Checker
+
 	// TaskMap is the map of tasks that we built (output)
 	TaskMap map[string]fi.Task
 }
@@ -220,6 +228,11 @@ func (c *ApplyClusterCmd) Run() error {
 		return err
 	}
 
+	err = c.checkPolicies()
+	if err != nil {
+		return err
+	}
+
 	err = c.validateKopsVersion()
 	if err != nil {
 		return err
@@ -824,7 +837,11 @@ func (c *ApplyClusterCmd) Run() error {
 		shouldPrecreateDNS = false
 
 	case TargetDryRun:
-		target = fi.NewDryRunTarget(assetBuilder, os.Stdout)
+		out := c.DryRunOutput
+		if out == nil {
+			out = os.Stdout
+		}
+		target = fi.NewDryRunTarget(assetBuilder, out)
 		dryRun = true
 
 		// Avoid making changes on a dry-run
@@ -912,6 +929,34 @@ func (c *ApplyClusterCmd) upgradeSpecs(assetBuilder *assets.AssetBuilder) error
 	return nil
 }
 
+// checkPolicies checks the completed specs against the policies, failing if any policy reports an error
+func (c *ApplyClusterCmd) checkPolicies() error {
+	var checkers []policy.Checker
+	checkers = append(checkers, c.Policies...)
+
+	p, err := policy.ReadPolicy(c.Cluster)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		checkers = append(checkers, p)
+	}
+
+	result := &policy.Result{}
+	for _, checker := range checkers {
+		result.Merge(checker.Check(c.Cluster, c.InstanceGroups))
+	}
+
+	for _, warning := range result.Warnings {
+		glog.Warningf("policy violation: %v", warning)
+	}
+	if len(result.Errors) != 0 {
+		return fmt.Errorf("cluster violates policy: %v", result.Errors.ToAggregate())
+	}
+
+	return nil
+}
+
 // validateKopsVersion ensures that kops meet the version requirements / recommendations in the channel
 func (c *ApplyClusterCmd) validateKopsVersion() error {
 	kopsVersion, err := semver.ParseTolerant(kopsbase.Version)
@@ -1092,6 +1137,14 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 		c.Assets = append(c.Assets, cniAssetHashString+"@"+cniAsset.String())
 	}
 
+	if usesContainerd(c.Cluster) {
+		containerdAssets, err := findContainerdAssets(c.Cluster, assetBuilder)
+		if err != nil {
+			return err
+		}
+		c.Assets = append(c.Assets, containerdAssets...)
+	}
+
 	// TODO figure out if we can only do this for CoreOS only and GCE Container OS
 	// TODO It is very difficult to pre-determine what OS an ami is, and if that OS needs socat
 	// At this time we just copy the socat and conntrack binaries to all distros.
@@ -1171,14 +1224,11 @@ func (c *ApplyClusterCmd) BuildNodeUpConfig(assetBuilder *assets.AssetBuilder, i
 		return nil, err
 	}
 
+	// The addons of the channels in the cluster spec are included in the bootstrap channel
 	channels := []string{
 		configBase.Join("addons", "bootstrap-channel.yaml").Path(),
 	}
 
-	for i := range c.Cluster.Spec.Addons {
-		channels = append(channels, c.Cluster.Spec.Addons[i].Manifest)
-	}
-
 	role := ig.Spec.Role
 	if role == "" {
 		return nil, fmt.Errorf("cannot determine role for instance group: %v", ig.ObjectMeta.Name)
@@ -21,6 +21,7 @@ import (
 
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -47,7 +48,21 @@ type AutoscalingGroup struct {
 	Granularity *string
 	Metrics     []string
 
+	// LaunchConfiguration is the launch configuration for the group; exactly one of LaunchConfiguration and LaunchTemplate should be set
 	LaunchConfiguration *LaunchConfiguration
+	// LaunchTemplate is the (versioned) launch template for the group
+	LaunchTemplate *LaunchTemplate
+
+	// MixedInstanceOverrides is the list of instance types for a mixed instances policy; the policy requires a LaunchTemplate
+	MixedInstanceOverrides []string
+	// MixedOnDemandBase is the minimum number of on-demand instances in the group
+	MixedOnDemandBase *int64
+	// MixedOnDemandAboveBase is the percentage of on-demand instances above MixedOnDemandBase
+	MixedOnDemandAboveBase *int64
+	// MixedSpotAllocationStrategy is how spot instances are allocated across the instance types
+	MixedSpotAllocationStrategy *string
+	// MixedSpotMaxPrice is the max price for spot instances; the on-demand price is used if not set
+	MixedSpotMaxPrice *string
 
 	SuspendProcesses *[]string
 }
@@ -136,8 +151,31 @@ func (e *AutoscalingGroup) Find(c *fi.Context) (*AutoscalingGroup, error) {
 		}
 	}
 
-	if fi.StringValue(g.LaunchConfigurationName) == "" {
-		glog.Warningf("autoscaling Group %q had no LaunchConfiguration", fi.StringValue(g.AutoScalingGroupName))
+	launchTemplate := g.LaunchTemplate
+	if g.MixedInstancesPolicy != nil {
+		if g.MixedInstancesPolicy.LaunchTemplate != nil {
+			launchTemplate = g.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+			for _, override := range g.MixedInstancesPolicy.LaunchTemplate.Overrides {
+				actual.MixedInstanceOverrides = append(actual.MixedInstanceOverrides, aws.StringValue(override.InstanceType))
+			}
+		}
+		if distribution := g.MixedInstancesPolicy.InstancesDistribution; distribution != nil {
+			actual.MixedOnDemandBase = distribution.OnDemandBaseCapacity
+			actual.MixedOnDemandAboveBase = distribution.OnDemandPercentageAboveBaseCapacity
+			actual.MixedSpotAllocationStrategy = distribution.SpotAllocationStrategy
+			actual.MixedSpotMaxPrice = distribution.SpotMaxPrice
+		}
+	}
+
+	if launchTemplate != nil {
+		version, err := strconv.ParseInt(aws.StringValue(launchTemplate.Version), 10, 64)
+		if err != nil {
+			// e.g. $Latest; we always set an explicit version, so treat this as a change
+			glog.V(2).Infof("autoscaling Group %q uses LaunchTemplate version %q", fi.StringValue(g.AutoScalingGroupName), aws.StringValue(launchTemplate.Version))
+		}
+		actual.LaunchTemplate = &LaunchTemplate{ID: launchTemplate.LaunchTemplateId, Version: aws.Int64(version)}
+	} else if fi.StringValue(g.LaunchConfigurationName) == "" {
+		glog.Warningf("autoscaling Group %q had no LaunchConfiguration or LaunchTemplate", fi.StringValue(g.AutoScalingGroupName))
 	} else {
 		actual.LaunchConfiguration = &LaunchConfiguration{ID: g.LaunchConfigurationName}
 	}
@@ -175,6 +213,12 @@ func (e *AutoscalingGroup) Run(c *fi.Context) error {
 }
 
 func (s *AutoscalingGroup) CheckChanges(a, e, changes *AutoscalingGroup) error {
+	if e.LaunchConfiguration != nil && e.LaunchTemplate != nil {
+		return fmt.Errorf("only one of LaunchConfiguration and LaunchTemplate can be set")
+	}
+	if e.useMixedInstancesPolicy() && e.LaunchTemplate == nil {
+		return fmt.Errorf("a mixed instances policy requires a LaunchTemplate")
+	}
 	if a != nil {
 		if e.Name == nil {
 			return fi.RequiredField("Name")
@@ -208,7 +252,13 @@ func (_ *AutoscalingGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Autos
 
 		request := &autoscaling.CreateAutoScalingGroupInput{}
 		request.AutoScalingGroupName = e.Name
-		request.LaunchConfigurationName = e.LaunchConfiguration.ID
+		if e.useMixedInstancesPolicy() {
+			request.MixedInstancesPolicy = e.buildMixedInstancesPolicy()
+		} else if e.LaunchTemplate != nil {
+			request.LaunchTemplate = e.buildLaunchTemplateSpecification()
+		} else {
+			request.LaunchConfigurationName = e.LaunchConfiguration.ID
+		}
 		request.MinSize = e.MinSize
 		request.MaxSize = e.MaxSize
 
@@ -258,6 +308,23 @@ func (_ *AutoscalingGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Autos
 			request.LaunchConfigurationName = e.LaunchConfiguration.ID
 			changes.LaunchConfiguration = nil
 		}
+		if e.useMixedInstancesPolicy() {
+			if changes.LaunchTemplate != nil || changes.MixedInstanceOverrides != nil || changes.MixedOnDemandBase != nil ||
+				changes.MixedOnDemandAboveBase != nil || changes.MixedSpotAllocationStrategy != nil || changes.MixedSpotMaxPrice != nil {
+				// The policy is replaced as a whole
+				request.MixedInstancesPolicy = e.buildMixedInstancesPolicy()
+				changes.LaunchTemplate = nil
+				changes.MixedInstanceOverrides = nil
+				changes.MixedOnDemandBase = nil
+				changes.MixedOnDemandAboveBase = nil
+				changes.MixedSpotAllocationStrategy = nil
+				changes.MixedSpotMaxPrice = nil
+			}
+		} else if changes.LaunchTemplate != nil {
+			// Setting the launch template also migrates the group away from any launch configuration
+			request.LaunchTemplate = e.buildLaunchTemplateSpecification()
+			changes.LaunchTemplate = nil
+		}
 		if changes.MinSize != nil {
 			request.MinSize = e.MinSize
 			changes.MinSize = nil
@@ -360,6 +427,40 @@ func (_ *AutoscalingGroup) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *Autos
 	return nil // We have
 }
 
+// buildLaunchTemplateSpecification returns the reference to the version of the launch template the group should use
+func (e *AutoscalingGroup) buildLaunchTemplateSpecification() *autoscaling.LaunchTemplateSpecification {
+	return &autoscaling.LaunchTemplateSpecification{
+		LaunchTemplateId: e.LaunchTemplate.ID,
+		Version:          aws.String(e.LaunchTemplate.VersionString()),
+	}
+}
+
+// useMixedInstancesPolicy returns true if the group should be created with a mixed instances policy
+func (e *AutoscalingGroup) useMixedInstancesPolicy() bool {
+	return len(e.MixedInstanceOverrides) != 0
+}
+
+// buildMixedInstancesPolicy returns the mixed instances policy for the group, referencing the version of the launch template
+func (e *AutoscalingGroup) buildMixedInstancesPolicy() *autoscaling.MixedInstancesPolicy {
+	policy := &autoscaling.MixedInstancesPolicy{
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandBaseCapacity:                e.MixedOnDemandBase,
+			OnDemandPercentageAboveBaseCapacity: e.MixedOnDemandAboveBase,
+			SpotAllocationStrategy:              e.MixedSpotAllocationStrategy,
+			SpotMaxPrice:                        e.MixedSpotMaxPrice,
+		},
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			LaunchTemplateSpecification: e.buildLaunchTemplateSpecification(),
+		},
+	}
+	for _, instanceType := range e.MixedInstanceOverrides {
+		policy.LaunchTemplate.Overrides = append(policy.LaunchTemplate.Overrides, &autoscaling.LaunchTemplateOverrides{
+			InstanceType: aws.String(instanceType),
+		})
+	}
+	return policy
+}
+
 // processCompare returns processes that exist in a but not in b
 func processCompare(a *[]string, b *[]string) []*string {
 	notInB := []*string{}
@@ -402,27 +503,93 @@ type terraformASGTag struct {
 	Value             *string `json:"value"`
 	PropagateAtLaunch *bool   `json:"propagate_at_launch"`
 }
+type terraformAutoscalingLaunchTemplateSpecification struct {
+	ID      *terraform.Literal `json:"id,omitempty"`
+	Version *terraform.Literal `json:"version,omitempty"`
+}
+
+type terraformMixedInstancesPolicyLaunchTemplateSpecification struct {
+	LaunchTemplateID *terraform.Literal `json:"launch_template_id,omitempty"`
+	Version          *terraform.Literal `json:"version,omitempty"`
+}
+
+type terraformMixedInstancesPolicyLaunchTemplateOverride struct {
+	InstanceType *string `json:"instance_type,omitempty"`
+}
+
+type terraformMixedInstancesPolicyLaunchTemplate struct {
+	LaunchTemplateSpecification *terraformMixedInstancesPolicyLaunchTemplateSpecification `json:"launch_template_specification,omitempty"`
+	Overrides                   []*terraformMixedInstancesPolicyLaunchTemplateOverride    `json:"override,omitempty"`
+}
+
+type terraformMixedInstancesPolicyInstancesDistribution struct {
+	OnDemandBaseCapacity                *int64  `json:"on_demand_base_capacity,omitempty"`
+	OnDemandPercentageAboveBaseCapacity *int64  `json:"on_demand_percentage_above_base_capacity,omitempty"`
+	SpotAllocationStrategy              *string `json:"spot_allocation_strategy,omitempty"`
+	SpotMaxPrice                        *string `json:"spot_max_price,omitempty"`
+}
+
+type terraformMixedInstancesPolicy struct {
+	LaunchTemplate        *terraformMixedInstancesPolicyLaunchTemplate        `json:"launch_template,omitempty"`
+	InstancesDistribution *terraformMixedInstancesPolicyInstancesDistribution `json:"instances_distribution,omitempty"`
+}
+
 type terraformAutoscalingGroup struct {
-	Name                    *string              `json:"name,omitempty"`
-	LaunchConfigurationName *terraform.Literal   `json:"launch_configuration,omitempty"`
-	MaxSize                 *int64               `json:"max_size,omitempty"`
-	MinSize                 *int64               `json:"min_size,omitempty"`
-	VPCZoneIdentifier       []*terraform.Literal `json:"vpc_zone_identifier,omitempty"`
-	Tags                    []*terraformASGTag   `json:"tag,omitempty"`
-	MetricsGranularity      *string              `json:"metrics_granularity,omitempty"`
-	EnabledMetrics          []*string            `json:"enabled_metrics,omitempty"`
-	SuspendedProcesses      []*string            `json:"suspended_processes,omitempty"`
+	Name                    *string                                          `json:"name,omitempty"`
+	LaunchConfigurationName *terraform.Literal                               `json:"launch_configuration,omitempty"`
+	LaunchTemplate          *terraformAutoscalingLaunchTemplateSpecification `json:"launch_template,omitempty"`
+	MixedInstancesPolicy    *terraformMixedInstancesPolicy                   `json:"mixed_instances_policy,omitempty"`
+	MaxSize                 *int64                                           `json:"max_size,omitempty"`
+	MinSize                 *int64                                           `json:"min_size,omitempty"`
+	VPCZoneIdentifier       []*terraform.Literal                             `json:"vpc_zone_identifier,omitempty"`
+	Tags                    []*terraformASGTag                               `json:"tag,omitempty"`
+	MetricsGranularity      *string                                          `json:"metrics_granularity,omitempty"`
+	EnabledMetrics          []*string                                        `json:"enabled_metrics,omitempty"`
+	SuspendedProcesses      []*string                                        `json:"suspended_processes,omitempty"`
 }
 
 func (_ *AutoscalingGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *AutoscalingGroup) error {
 
 	tf := &terraformAutoscalingGroup{
-		Name:                    e.Name,
-		MinSize:                 e.MinSize,
-		MaxSize:                 e.MaxSize,
-		LaunchConfigurationName: e.LaunchConfiguration.TerraformLink(),
-		MetricsGranularity:      e.Granularity,
-		EnabledMetrics:          aws.StringSlice(e.Metrics),
+		Name:               e.Name,
+		MinSize:            e.MinSize,
+		MaxSize:            e.MaxSize,
+		MetricsGranularity: e.Granularity,
+		EnabledMetrics:     aws.StringSlice(e.Metrics),
+	}
+
+	var securityGroups []*SecurityGroup
+	if e.useMixedInstancesPolicy() {
+		launchTemplate := &terraformMixedInstancesPolicyLaunchTemplate{
+			LaunchTemplateSpecification: &terraformMixedInstancesPolicyLaunchTemplateSpecification{
+				LaunchTemplateID: e.LaunchTemplate.TerraformLink(),
+				Version:          e.LaunchTemplate.TerraformVersionLink(),
+			},
+		}
+		for _, instanceType := range e.MixedInstanceOverrides {
+			launchTemplate.Overrides = append(launchTemplate.Overrides, &terraformMixedInstancesPolicyLaunchTemplateOverride{
+				InstanceType: fi.String(instanceType),
+			})
+		}
+		tf.MixedInstancesPolicy = &terraformMixedInstancesPolicy{
+			LaunchTemplate: launchTemplate,
+			InstancesDistribution: &terraformMixedInstancesPolicyInstancesDistribution{
+				OnDemandBaseCapacity:                e.MixedOnDemandBase,
+				OnDemandPercentageAboveBaseCapacity: e.MixedOnDemandAboveBase,
+				SpotAllocationStrategy:              e.MixedSpotAllocationStrategy,
+				SpotMaxPrice:                        e.MixedSpotMaxPrice,
+			},
+		}
+		securityGroups = e.LaunchTemplate.SecurityGroups
+	} else if e.LaunchTemplate != nil {
+		tf.LaunchTemplate = &terraformAutoscalingLaunchTemplateSpecification{
+			ID:      e.LaunchTemplate.TerraformLink(),
+			Version: e.LaunchTemplate.TerraformVersionLink(),
+		}
+		securityGroups = e.LaunchTemplate.SecurityGroups
+	} else {
+		tf.LaunchConfigurationName = e.LaunchConfiguration.TerraformLink()
+		securityGroups = e.LaunchConfiguration.SecurityGroups
 	}
 
 	for _, s := range e.Subnets {
@@ -445,9 +612,9 @@ func (_ *AutoscalingGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, c
 		})
 	}
 
-	if e.LaunchConfiguration != nil {
+	if e.LaunchConfiguration != nil || e.LaunchTemplate != nil {
 		// Create TF output variable with security group ids
-		// This is in the launch configuration, but the ASG has the information about the instance group type
+		// This is in the launch configuration (or template), but the ASG has the information about the instance group type
 
 		role := ""
 		for k := range e.Tags {
@@ -461,7 +628,7 @@ func (_ *AutoscalingGroup) RenderTerraform(t *terraform.TerraformTarget, a, e, c
 		}
 
 		if role != "" {
-			for _, sg := range e.LaunchConfiguration.SecurityGroups {
+			for _, sg := range securityGroups {
 				if err := t.AddOutputVariableArray(role+"_security_group_ids", sg.TerraformLink()); err != nil {
 					return err
 				}
@@ -505,14 +672,42 @@ type cloudformationASGMetricsCollection struct {
 	Granularity *string   `json:"Granularity"`
 	Metrics     []*string `json:"Metrics"`
 }
+type cloudformationAutoscalingLaunchTemplateSpecification struct {
+	LaunchTemplateId *cloudformation.Literal `json:"LaunchTemplateId,omitempty"`
+	Version          *cloudformation.Literal `json:"Version,omitempty"`
+}
+
+type cloudformationMixedInstancesPolicyLaunchTemplateOverride struct {
+	InstanceType *string `json:"InstanceType,omitempty"`
+}
+
+type cloudformationMixedInstancesPolicyLaunchTemplate struct {
+	LaunchTemplateSpecification *cloudformationAutoscalingLaunchTemplateSpecification       `json:"LaunchTemplateSpecification,omitempty"`
+	Overrides                   []*cloudformationMixedInstancesPolicyLaunchTemplateOverride `json:"Overrides,omitempty"`
+}
+
+type cloudformationMixedInstancesPolicyInstancesDistribution struct {
+	OnDemandBaseCapacity                *int64  `json:"OnDemandBaseCapacity,omitempty"`
+	OnDemandPercentageAboveBaseCapacity *int64  `json:"OnDemandPercentageAboveBaseCapacity,omitempty"`
+	SpotAllocationStrategy              *string `json:"SpotAllocationStrategy,omitempty"`
+	SpotMaxPrice                        *string `json:"SpotMaxPrice,omitempty"`
+}
+
+type cloudformationMixedInstancesPolicy struct {
+	LaunchTemplate        *cloudformationMixedInstancesPolicyLaunchTemplate        `json:"LaunchTemplate,omitempty"`
+	InstancesDistribution *cloudformationMixedInstancesPolicyInstancesDistribution `json:"InstancesDistribution,omitempty"`
+}
+
 type cloudformationAutoscalingGroup struct {
-	Name                    *string                               `json:"AutoScalingGroupName,omitempty"`
-	LaunchConfigurationName *cloudformation.Literal               `json:"LaunchConfigurationName,omitempty"`
-	MaxSize                 *int64                                `json:"MaxSize,omitempty"`
-	MinSize                 *int64                                `json:"MinSize,omitempty"`
-	VPCZoneIdentifier       []*cloudformation.Literal             `json:"VPCZoneIdentifier,omitempty"`
-	Tags                    []*cloudformationASGTag               `json:"Tags,omitempty"`
-	MetricsCollection       []*cloudformationASGMetricsCollection `json:"MetricsCollection,omitempty"`
+	Name                    *string                                               `json:"AutoScalingGroupName,omitempty"`
+	LaunchConfigurationName *cloudformation.Literal                               `json:"LaunchConfigurationName,omitempty"`
+	LaunchTemplate          *cloudformationAutoscalingLaunchTemplateSpecification `json:"LaunchTemplate,omitempty"`
+	MixedInstancesPolicy    *cloudformationMixedInstancesPolicy                   `json:"MixedInstancesPolicy,omitempty"`
+	MaxSize                 *int64                                                `json:"MaxSize,omitempty"`
+	MinSize                 *int64                                                `json:"MinSize,omitempty"`
+	VPCZoneIdentifier       []*cloudformation.Literal                             `json:"VPCZoneIdentifier,omitempty"`
+	Tags                    []*cloudformationASGTag                               `json:"Tags,omitempty"`
+	MetricsCollection       []*cloudformationASGMetricsCollection                 `json:"MetricsCollection,omitempty"`
 
 	LoadBalancerNames []*cloudformation.Literal `json:"LoadBalancerNames,omitempty"`
 	TargetGroupARNs   []*cloudformation.Literal `json:"TargetGroupARNs,omitempty"`
@@ -529,7 +724,36 @@ func (_ *AutoscalingGroup) RenderCloudformation(t *cloudformation.Cloudformation
 				Metrics:     aws.StringSlice(e.Metrics),
 			},
 		},
-		LaunchConfigurationName: e.LaunchConfiguration.CloudformationLink(),
+	}
+
+	if e.useMixedInstancesPolicy() {
+		launchTemplate := &cloudformationMixedInstancesPolicyLaunchTemplate{
+			LaunchTemplateSpecification: &cloudformationAutoscalingLaunchTemplateSpecification{
+				LaunchTemplateId: e.LaunchTemplate.CloudformationLink(),
+				Version:          e.LaunchTemplate.CloudformationVersionLink(),
+			},
+		}
+		for _, instanceType := range e.MixedInstanceOverrides {
+			launchTemplate.Overrides = append(launchTemplate.Overrides, &cloudformationMixedInstancesPolicyLaunchTemplateOverride{
+				InstanceType: fi.String(instanceType),
+			})
+		}
+		tf.MixedInstancesPolicy = &cloudformationMixedInstancesPolicy{
+			LaunchTemplate: launchTemplate,
+			InstancesDistribution: &cloudformationMixedInstancesPolicyInstancesDistribution{
+				OnDemandBaseCapacity:                e.MixedOnDemandBase,
+				OnDemandPercentageAboveBaseCapacity: e.MixedOnDemandAboveBase,
+				SpotAllocationStrategy:              e.MixedSpotAllocationStrategy,
+				SpotMaxPrice:                        e.MixedSpotMaxPrice,
+			},
+		}
+	} else if e.LaunchTemplate != nil {
+		tf.LaunchTemplate = &cloudformationAutoscalingLaunchTemplateSpecification{
+			LaunchTemplateId: e.LaunchTemplate.CloudformationLink(),
+			Version:          e.LaunchTemplate.CloudformationVersionLink(),
+		}
+	} else {
+		tf.LaunchConfigurationName = e.LaunchConfiguration.CloudformationLink()
 	}
 
 	for _, s := range e.Subnets {
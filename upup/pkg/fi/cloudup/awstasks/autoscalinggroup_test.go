@@ -196,3 +196,51 @@ func TestProcessCompare(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildMixedInstancesPolicy(t *testing.T) {
+	asg := &AutoscalingGroup{
+		Name: aws.String("MyASGName"),
+		LaunchTemplate: &LaunchTemplate{
+			ID:      aws.String("lt-1234"),
+			Version: aws.Int64(3),
+		},
+		MixedInstanceOverrides:      []string{"m4.large", "m5.large"},
+		MixedOnDemandBase:           aws.Int64(1),
+		MixedOnDemandAboveBase:      aws.Int64(20),
+		MixedSpotAllocationStrategy: aws.String("lowest-price"),
+	}
+
+	expected := &autoscaling.MixedInstancesPolicy{
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandBaseCapacity:                aws.Int64(1),
+			OnDemandPercentageAboveBaseCapacity: aws.Int64(20),
+			SpotAllocationStrategy:              aws.String("lowest-price"),
+		},
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			LaunchTemplateSpecification: &autoscaling.LaunchTemplateSpecification{
+				LaunchTemplateId: aws.String("lt-1234"),
+				Version:          aws.String("3"),
+			},
+			Overrides: []*autoscaling.LaunchTemplateOverrides{
+				{InstanceType: aws.String("m4.large")},
+				{InstanceType: aws.String("m5.large")},
+			},
+		},
+	}
+
+	expectedYAML, err := yaml.Marshal(expected)
+	if err != nil {
+		t.Fatalf("unexpected error converting expected policy to yaml: %v", err)
+	}
+
+	actualYAML, err := yaml.Marshal(asg.buildMixedInstancesPolicy())
+	if err != nil {
+		t.Fatalf("unexpected error converting actual policy to yaml: %v", err)
+	}
+
+	if string(expectedYAML) != string(actualYAML) {
+		diffString := diff.FormatDiff(string(expectedYAML), string(actualYAML))
+		t.Errorf("actual policy differed from expected.")
+		t.Logf("diff:\n%s\n", diffString)
+	}
+}
@@ -231,12 +231,16 @@ func buildEphemeralDevices(instanceTypeName *string) (map[string]*BlockDeviceMap
 }
 
 func (e *LaunchConfiguration) buildRootDevice(cloud awsup.AWSCloud) (map[string]*BlockDeviceMapping, error) {
-	imageID := fi.StringValue(e.ImageID)
-	image, err := cloud.ResolveImage(imageID)
+	return buildRootDevice(cloud, e.ImageID, e.RootVolumeSize, e.RootVolumeType, e.RootVolumeIops)
+}
+
+// buildRootDevice returns the block device mapping for the root volume of the image
+func buildRootDevice(cloud awsup.AWSCloud, imageID *string, volumeSize *int64, volumeType *string, volumeIops *int64) (map[string]*BlockDeviceMapping, error) {
+	image, err := cloud.ResolveImage(fi.StringValue(imageID))
 	if err != nil {
-		return nil, fmt.Errorf("unable to resolve image: %q: %v", imageID, err)
+		return nil, fmt.Errorf("unable to resolve image: %q: %v", fi.StringValue(imageID), err)
 	} else if image == nil {
-		return nil, fmt.Errorf("unable to resolve image: %q: not found", imageID)
+		return nil, fmt.Errorf("unable to resolve image: %q: not found", fi.StringValue(imageID))
 	}
 
 	rootDeviceName := aws.StringValue(image.RootDeviceName)
@@ -245,9 +249,9 @@ func (e *LaunchConfiguration) buildRootDevice(cloud awsup.AWSCloud) (map[string]
 
 	rootDeviceMapping := &BlockDeviceMapping{
 		EbsDeleteOnTermination: aws.Bool(true),
-		EbsVolumeSize:          e.RootVolumeSize,
-		EbsVolumeType:          e.RootVolumeType,
-		EbsVolumeIops:          e.RootVolumeIops,
+		EbsVolumeSize:          volumeSize,
+		EbsVolumeType:          volumeType,
+		EbsVolumeIops:          volumeIops,
 	}
 
 	blockDeviceMappings[rootDeviceName] = rootDeviceMapping
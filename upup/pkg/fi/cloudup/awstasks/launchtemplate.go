@@ -0,0 +1,787 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+)
+
+// LaunchTemplate is an EC2 launch template; rather than creating a new object on every change
+// (as we do for a LaunchConfiguration) we create a new version of the template.
+//go:generate fitask -type=LaunchTemplate
+type LaunchTemplate struct {
+	Name      *string
+	Lifecycle *fi.Lifecycle
+
+	UserData *fi.ResourceHolder
+
+	ImageID            *string
+	InstanceType       *string
+	SSHKey             *SSHKey
+	SecurityGroups     []*SecurityGroup
+	AssociatePublicIP  *bool
+	IAMInstanceProfile *IAMInstanceProfile
+	InstanceMonitoring *bool
+
+	// RootVolumeSize is the size of the EBS root volume to use, in GB
+	RootVolumeSize *int64
+	// RootVolumeType is the type of the EBS root volume to use (e.g. gp2)
+	RootVolumeType *string
+	// If volume type is io1, then we need to specify the number of Iops.
+	RootVolumeIops *int64
+	// RootVolumeOptimization enables EBS optimization for an instance
+	RootVolumeOptimization *bool
+
+	// SpotPrice is set to the spot-price bid if this is a spot pricing request
+	SpotPrice string
+
+	// Tenancy. Can be either default or dedicated.
+	Tenancy *string
+
+	// ID is the id of the launch template
+	ID *string
+	// Version is the version of the launch template that matches our spec
+	Version *int64
+}
+
+var _ fi.CompareWithID = &LaunchTemplate{}
+
+var _ fi.ProducesDeletions = &LaunchTemplate{}
+
+// CompareWithID includes the version, so that consumers (i.e. the AutoscalingGroup) see a change when we create a new version
+func (e *LaunchTemplate) CompareWithID() *string {
+	if e.ID == nil {
+		return nil
+	}
+	return fi.String(fi.StringValue(e.ID) + ":" + e.VersionString())
+}
+
+// VersionString returns the version of the launch template, as used by the autoscaling API
+func (e *LaunchTemplate) VersionString() string {
+	return strconv.FormatInt(fi.Int64Value(e.Version), 10)
+}
+
+// findLaunchTemplate returns the launch template with our name, or nil if it does not exist
+func (e *LaunchTemplate) findLaunchTemplate(cloud awsup.AWSCloud) (*ec2.LaunchTemplate, error) {
+	request := &ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{
+			awsup.NewEC2Filter("launch-template-name", fi.StringValue(e.Name)),
+		},
+	}
+
+	response, err := cloud.EC2().DescribeLaunchTemplates(request)
+	if err != nil {
+		return nil, fmt.Errorf("error listing LaunchTemplates: %v", err)
+	}
+
+	if response == nil || len(response.LaunchTemplates) == 0 {
+		return nil, nil
+	}
+
+	if len(response.LaunchTemplates) != 1 {
+		return nil, fmt.Errorf("found multiple LaunchTemplates with name %q", fi.StringValue(e.Name))
+	}
+
+	return response.LaunchTemplates[0], nil
+}
+
+func (e *LaunchTemplate) Find(c *fi.Context) (*LaunchTemplate, error) {
+	cloud := c.Cloud.(awsup.AWSCloud)
+
+	lt, err := e.findLaunchTemplate(cloud)
+	if err != nil {
+		return nil, err
+	}
+	if lt == nil {
+		return nil, nil
+	}
+
+	// We compare against the latest version; we always create versions in order, so this is the one we last applied
+	versionsResponse, err := cloud.EC2().DescribeLaunchTemplateVersions(&ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId: lt.LaunchTemplateId,
+		Versions:         []*string{aws.String(strconv.FormatInt(aws.Int64Value(lt.LatestVersionNumber), 10))},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing versions of LaunchTemplate %q: %v", aws.StringValue(lt.LaunchTemplateName), err)
+	}
+	if len(versionsResponse.LaunchTemplateVersions) != 1 {
+		return nil, fmt.Errorf("unable to find version %d of LaunchTemplate %q", aws.Int64Value(lt.LatestVersionNumber), aws.StringValue(lt.LaunchTemplateName))
+	}
+	version := versionsResponse.LaunchTemplateVersions[0]
+	data := version.LaunchTemplateData
+
+	glog.V(2).Infof("found existing LaunchTemplate: %q version %d", aws.StringValue(lt.LaunchTemplateName), aws.Int64Value(version.VersionNumber))
+
+	actual := &LaunchTemplate{
+		Name:                   e.Name,
+		ID:                     lt.LaunchTemplateId,
+		Version:                version.VersionNumber,
+		ImageID:                data.ImageId,
+		InstanceType:           data.InstanceType,
+		RootVolumeOptimization: data.EbsOptimized,
+	}
+
+	if data.Monitoring != nil {
+		actual.InstanceMonitoring = data.Monitoring.Enabled
+	}
+
+	if data.Placement != nil {
+		actual.Tenancy = data.Placement.Tenancy
+	}
+
+	if data.InstanceMarketOptions != nil && data.InstanceMarketOptions.SpotOptions != nil {
+		actual.SpotPrice = aws.StringValue(data.InstanceMarketOptions.SpotOptions.MaxPrice)
+	}
+
+	if data.KeyName != nil {
+		actual.SSHKey = &SSHKey{Name: data.KeyName}
+	}
+
+	if data.IamInstanceProfile != nil {
+		actual.IAMInstanceProfile = &IAMInstanceProfile{Name: data.IamInstanceProfile.Name}
+	}
+
+	securityGroups := []*SecurityGroup{}
+	for _, ni := range data.NetworkInterfaces {
+		actual.AssociatePublicIP = ni.AssociatePublicIpAddress
+		for _, sgID := range ni.Groups {
+			securityGroups = append(securityGroups, &SecurityGroup{ID: sgID})
+		}
+	}
+	sort.Sort(OrderSecurityGroupsById(securityGroups))
+
+	actual.SecurityGroups = securityGroups
+
+	// Find the root volume
+	for _, b := range data.BlockDeviceMappings {
+		if b.Ebs == nil || b.Ebs.SnapshotId != nil {
+			// Not the root
+			continue
+		}
+		_, bdm := BlockDeviceMappingFromLaunchTemplate(b)
+		actual.RootVolumeSize = bdm.EbsVolumeSize
+		actual.RootVolumeType = bdm.EbsVolumeType
+		actual.RootVolumeIops = bdm.EbsVolumeIops
+	}
+
+	if data.UserData != nil {
+		userData, err := base64.StdEncoding.DecodeString(aws.StringValue(data.UserData))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding UserData: %v", err)
+		}
+		actual.UserData = fi.WrapResource(fi.NewStringResource(string(userData)))
+	}
+
+	// Avoid spurious changes on ImageId
+	if e.ImageID != nil && actual.ImageID != nil && *actual.ImageID != *e.ImageID {
+		image, err := cloud.ResolveImage(*e.ImageID)
+		if err != nil {
+			glog.Warningf("unable to resolve image: %q: %v", *e.ImageID, err)
+		} else if image == nil {
+			glog.Warningf("unable to resolve image: %q: not found", *e.ImageID)
+		} else if aws.StringValue(image.ImageId) == *actual.ImageID {
+			glog.V(4).Infof("Returning matching ImageId as expected name: %q -> %q", *actual.ImageID, *e.ImageID)
+			actual.ImageID = e.ImageID
+		}
+	}
+
+	// Avoid spurious changes
+	actual.Lifecycle = e.Lifecycle
+
+	if e.ID == nil {
+		e.ID = actual.ID
+	}
+	if e.Version == nil {
+		e.Version = actual.Version
+	}
+
+	return actual, nil
+}
+
+func (e *LaunchTemplate) Run(c *fi.Context) error {
+	// TODO: Make Normalize a standard method
+	e.Normalize()
+
+	return fi.DefaultDeltaRunMethod(e, c)
+}
+
+func (e *LaunchTemplate) Normalize() {
+	// We need to sort our arrays consistently, so we don't get spurious changes
+	sort.Stable(OrderSecurityGroupsById(e.SecurityGroups))
+}
+
+func (s *LaunchTemplate) CheckChanges(a, e, changes *LaunchTemplate) error {
+	if e.ImageID == nil {
+		return fi.RequiredField("ImageID")
+	}
+	if e.InstanceType == nil {
+		return fi.RequiredField("InstanceType")
+	}
+
+	if a != nil {
+		if e.Name == nil {
+			return fi.RequiredField("Name")
+		}
+	}
+	return nil
+}
+
+func (e *LaunchTemplate) buildRootDevice(cloud awsup.AWSCloud) (map[string]*BlockDeviceMapping, error) {
+	return buildRootDevice(cloud, e.ImageID, e.RootVolumeSize, e.RootVolumeType, e.RootVolumeIops)
+}
+
+// buildLaunchTemplateData builds the data for a new version of the launch template
+func (e *LaunchTemplate) buildLaunchTemplateData(cloud awsup.AWSCloud) (*ec2.RequestLaunchTemplateData, error) {
+	if e.ImageID == nil {
+		return nil, fi.RequiredField("ImageID")
+	}
+	image, err := cloud.ResolveImage(*e.ImageID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &ec2.RequestLaunchTemplateData{}
+	data.ImageId = image.ImageId
+	data.InstanceType = e.InstanceType
+	data.EbsOptimized = e.RootVolumeOptimization
+
+	if e.SSHKey != nil {
+		data.KeyName = e.SSHKey.Name
+	}
+
+	if e.Tenancy != nil {
+		data.Placement = &ec2.LaunchTemplatePlacementRequest{Tenancy: e.Tenancy}
+	}
+
+	// The security groups must be set on the network interface when we set AssociatePublicIpAddress
+	ni := &ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+		AssociatePublicIpAddress: e.AssociatePublicIP,
+		DeleteOnTermination:      aws.Bool(true),
+		DeviceIndex:              aws.Int64(0),
+	}
+	for _, sg := range e.SecurityGroups {
+		ni.Groups = append(ni.Groups, sg.ID)
+	}
+	data.NetworkInterfaces = []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{ni}
+
+	if e.SpotPrice != "" {
+		data.InstanceMarketOptions = &ec2.LaunchTemplateInstanceMarketOptionsRequest{
+			MarketType: aws.String("spot"),
+			SpotOptions: &ec2.LaunchTemplateSpotMarketOptionsRequest{
+				MaxPrice: aws.String(e.SpotPrice),
+			},
+		}
+	}
+
+	// Build up the actual block device mappings
+	{
+		rootDevices, err := e.buildRootDevice(cloud)
+		if err != nil {
+			return nil, err
+		}
+
+		ephemeralDevices, err := buildEphemeralDevices(e.InstanceType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, deviceName := range sets.StringKeySet(rootDevices).List() {
+			data.BlockDeviceMappings = append(data.BlockDeviceMappings, rootDevices[deviceName].ToLaunchTemplate(deviceName))
+		}
+		for _, deviceName := range sets.StringKeySet(ephemeralDevices).List() {
+			data.BlockDeviceMappings = append(data.BlockDeviceMappings, ephemeralDevices[deviceName].ToLaunchTemplate(deviceName))
+		}
+	}
+
+	if e.UserData != nil {
+		d, err := e.UserData.AsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("error rendering LaunchTemplate UserData: %v", err)
+		}
+		data.UserData = aws.String(base64.StdEncoding.EncodeToString(d))
+	}
+	if e.IAMInstanceProfile != nil {
+		data.IamInstanceProfile = &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Name: e.IAMInstanceProfile.Name,
+		}
+	}
+	if e.InstanceMonitoring != nil {
+		data.Monitoring = &ec2.LaunchTemplatesMonitoringRequest{Enabled: e.InstanceMonitoring}
+	} else {
+		data.Monitoring = &ec2.LaunchTemplatesMonitoringRequest{Enabled: fi.Bool(false)}
+	}
+
+	return data, nil
+}
+
+func (_ *LaunchTemplate) RenderAWS(t *awsup.AWSAPITarget, a, e, changes *LaunchTemplate) error {
+	data, err := e.buildLaunchTemplateData(t.Cloud)
+	if err != nil {
+		return err
+	}
+
+	attempt := 0
+	maxAttempts := 10
+	for {
+		attempt++
+
+		if a == nil {
+			glog.V(2).Infof("Creating LaunchTemplate with Name:%q", fi.StringValue(e.Name))
+
+			response, err := t.Cloud.EC2().CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
+				LaunchTemplateName: e.Name,
+				LaunchTemplateData: data,
+			})
+			if err == nil {
+				e.ID = response.LaunchTemplate.LaunchTemplateId
+				e.Version = response.LaunchTemplate.LatestVersionNumber
+				break
+			}
+			if !isIAMInstanceProfileNotReady(err) || attempt > maxAttempts {
+				return fmt.Errorf("error creating LaunchTemplate: %v", err)
+			}
+		} else {
+			glog.V(2).Infof("Creating new version of LaunchTemplate %q", fi.StringValue(e.Name))
+
+			response, err := t.Cloud.EC2().CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersionInput{
+				LaunchTemplateId:   a.ID,
+				LaunchTemplateData: data,
+			})
+			if err == nil {
+				e.ID = a.ID
+				e.Version = response.LaunchTemplateVersion.VersionNumber
+				break
+			}
+			if !isIAMInstanceProfileNotReady(err) || attempt > maxAttempts {
+				return fmt.Errorf("error creating new version of LaunchTemplate: %v", err)
+			}
+		}
+
+		glog.Infof("waiting for IAM instance profile %q to be ready", fi.StringValue(e.IAMInstanceProfile.Name))
+		time.Sleep(10 * time.Second)
+	}
+
+	// We keep the default version in sync, so that anything launching from the template without a version gets our spec
+	if _, err := t.Cloud.EC2().ModifyLaunchTemplate(&ec2.ModifyLaunchTemplateInput{
+		LaunchTemplateId: e.ID,
+		DefaultVersion:   aws.String(e.VersionString()),
+	}); err != nil {
+		return fmt.Errorf("error setting default version of LaunchTemplate %q: %v", fi.StringValue(e.Name), err)
+	}
+
+	return nil
+}
+
+// isIAMInstanceProfileNotReady returns true if the error indicates that the instance profile has not yet propagated
+func isIAMInstanceProfileNotReady(err error) bool {
+	code := awsup.AWSErrorCode(err)
+	if code != "InvalidParameterValue" && code != "ValidationError" {
+		return false
+	}
+	message := awsup.AWSErrorMessage(err)
+	return strings.Contains(message, "not authorized") || strings.Contains(message, "Invalid IamInstance") || strings.Contains(message, "iamInstanceProfile")
+}
+
+type terraformLaunchTemplateIAMProfile struct {
+	Name *terraform.Literal `json:"name,omitempty"`
+}
+
+type terraformLaunchTemplateNetworkInterface struct {
+	AssociatePublicIPAddress *bool                `json:"associate_public_ip_address,omitempty"`
+	DeleteOnTermination      *bool                `json:"delete_on_termination,omitempty"`
+	SecurityGroups           []*terraform.Literal `json:"security_groups,omitempty"`
+}
+
+type terraformLaunchTemplateBlockDeviceEBS struct {
+	VolumeType          *string `json:"volume_type,omitempty"`
+	VolumeSize          *int64  `json:"volume_size,omitempty"`
+	IOPS                *int64  `json:"iops,omitempty"`
+	DeleteOnTermination *bool   `json:"delete_on_termination,omitempty"`
+}
+
+type terraformLaunchTemplateBlockDevice struct {
+	DeviceName  *string                                `json:"device_name,omitempty"`
+	VirtualName *string                                `json:"virtual_name,omitempty"`
+	EBS         *terraformLaunchTemplateBlockDeviceEBS `json:"ebs,omitempty"`
+}
+
+type terraformLaunchTemplateSpotOptions struct {
+	MaxPrice *string `json:"max_price,omitempty"`
+}
+
+type terraformLaunchTemplateMarketOptions struct {
+	MarketType  *string                             `json:"market_type,omitempty"`
+	SpotOptions *terraformLaunchTemplateSpotOptions `json:"spot_options,omitempty"`
+}
+
+type terraformLaunchTemplatePlacement struct {
+	Tenancy *string `json:"tenancy,omitempty"`
+}
+
+type terraformLaunchTemplateMonitoring struct {
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+type terraformLaunchTemplate struct {
+	NamePrefix            *string                                    `json:"name_prefix,omitempty"`
+	ImageID               *string                                    `json:"image_id,omitempty"`
+	InstanceType          *string                                    `json:"instance_type,omitempty"`
+	KeyName               *terraform.Literal                         `json:"key_name,omitempty"`
+	IAMInstanceProfile    *terraformLaunchTemplateIAMProfile         `json:"iam_instance_profile,omitempty"`
+	NetworkInterfaces     []*terraformLaunchTemplateNetworkInterface `json:"network_interfaces,omitempty"`
+	UserData              *terraform.Literal                         `json:"user_data,omitempty"`
+	BlockDeviceMappings   []*terraformLaunchTemplateBlockDevice      `json:"block_device_mappings,omitempty"`
+	EBSOptimized          *bool                                      `json:"ebs_optimized,omitempty"`
+	InstanceMarketOptions *terraformLaunchTemplateMarketOptions      `json:"instance_market_options,omitempty"`
+	Placement             *terraformLaunchTemplatePlacement          `json:"placement,omitempty"`
+	Monitoring            *terraformLaunchTemplateMonitoring         `json:"monitoring,omitempty"`
+	Lifecycle             *terraform.Lifecycle                       `json:"lifecycle,omitempty"`
+}
+
+func (_ *LaunchTemplate) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *LaunchTemplate) error {
+	cloud := t.Cloud.(awsup.AWSCloud)
+
+	if e.ImageID == nil {
+		return fi.RequiredField("ImageID")
+	}
+	image, err := cloud.ResolveImage(*e.ImageID)
+	if err != nil {
+		return err
+	}
+
+	tf := &terraformLaunchTemplate{
+		NamePrefix:   fi.String(*e.Name + "-"),
+		ImageID:      image.ImageId,
+		InstanceType: e.InstanceType,
+		EBSOptimized: e.RootVolumeOptimization,
+	}
+
+	if e.SpotPrice != "" {
+		tf.InstanceMarketOptions = &terraformLaunchTemplateMarketOptions{
+			MarketType:  fi.String("spot"),
+			SpotOptions: &terraformLaunchTemplateSpotOptions{MaxPrice: fi.String(e.SpotPrice)},
+		}
+	}
+
+	if e.SSHKey != nil {
+		tf.KeyName = e.SSHKey.TerraformLink()
+	}
+
+	if e.Tenancy != nil {
+		tf.Placement = &terraformLaunchTemplatePlacement{Tenancy: e.Tenancy}
+	}
+
+	ni := &terraformLaunchTemplateNetworkInterface{
+		AssociatePublicIPAddress: e.AssociatePublicIP,
+		DeleteOnTermination:      fi.Bool(true),
+	}
+	for _, sg := range e.SecurityGroups {
+		ni.SecurityGroups = append(ni.SecurityGroups, sg.TerraformLink())
+	}
+	tf.NetworkInterfaces = []*terraformLaunchTemplateNetworkInterface{ni}
+
+	{
+		rootDevices, err := e.buildRootDevice(cloud)
+		if err != nil {
+			return err
+		}
+
+		ephemeralDevices, err := buildEphemeralDevices(e.InstanceType)
+		if err != nil {
+			return err
+		}
+
+		for _, deviceName := range sets.StringKeySet(rootDevices).List() {
+			bdm := rootDevices[deviceName]
+			tf.BlockDeviceMappings = append(tf.BlockDeviceMappings, &terraformLaunchTemplateBlockDevice{
+				DeviceName: fi.String(deviceName),
+				EBS: &terraformLaunchTemplateBlockDeviceEBS{
+					VolumeType:          bdm.EbsVolumeType,
+					VolumeSize:          bdm.EbsVolumeSize,
+					IOPS:                bdm.EbsVolumeIops,
+					DeleteOnTermination: fi.Bool(true),
+				},
+			})
+		}
+
+		for _, deviceName := range sets.StringKeySet(ephemeralDevices).List() {
+			bdm := ephemeralDevices[deviceName]
+			tf.BlockDeviceMappings = append(tf.BlockDeviceMappings, &terraformLaunchTemplateBlockDevice{
+				VirtualName: bdm.VirtualName,
+				DeviceName:  fi.String(deviceName),
+			})
+		}
+	}
+
+	if e.UserData != nil {
+		// Unlike a launch configuration, a launch template expects the user data to be base64 encoded
+		d, err := e.UserData.AsBytes()
+		if err != nil {
+			return fmt.Errorf("error rendering LaunchTemplate UserData: %v", err)
+		}
+		userData := fi.NewStringResource(base64.StdEncoding.EncodeToString(d))
+		tf.UserData, err = t.AddFile("aws_launch_template", *e.Name, "user_data", userData)
+		if err != nil {
+			return err
+		}
+	}
+	if e.IAMInstanceProfile != nil {
+		tf.IAMInstanceProfile = &terraformLaunchTemplateIAMProfile{Name: e.IAMInstanceProfile.TerraformLink()}
+	}
+	if e.InstanceMonitoring != nil {
+		tf.Monitoring = &terraformLaunchTemplateMonitoring{Enabled: e.InstanceMonitoring}
+	} else {
+		tf.Monitoring = &terraformLaunchTemplateMonitoring{Enabled: fi.Bool(false)}
+	}
+	// So that we can replace templates
+	tf.Lifecycle = &terraform.Lifecycle{CreateBeforeDestroy: fi.Bool(true)}
+
+	return t.RenderResource("aws_launch_template", *e.Name, tf)
+}
+
+func (e *LaunchTemplate) TerraformLink() *terraform.Literal {
+	return terraform.LiteralProperty("aws_launch_template", *e.Name, "id")
+}
+
+// TerraformVersionLink returns a link to the latest version of the launch template
+func (e *LaunchTemplate) TerraformVersionLink() *terraform.Literal {
+	return terraform.LiteralProperty("aws_launch_template", *e.Name, "latest_version")
+}
+
+type cloudformationLaunchTemplateIAMProfile struct {
+	Name *cloudformation.Literal `json:"Name,omitempty"`
+}
+
+type cloudformationLaunchTemplateNetworkInterface struct {
+	AssociatePublicIPAddress *bool                     `json:"AssociatePublicIpAddress,omitempty"`
+	DeleteOnTermination      *bool                     `json:"DeleteOnTermination,omitempty"`
+	DeviceIndex              *int64                    `json:"DeviceIndex,omitempty"`
+	Groups                   []*cloudformation.Literal `json:"Groups,omitempty"`
+}
+
+type cloudformationLaunchTemplateBlockDeviceEBS struct {
+	VolumeType          *string `json:"VolumeType,omitempty"`
+	VolumeSize          *int64  `json:"VolumeSize,omitempty"`
+	IOPS                *int64  `json:"Iops,omitempty"`
+	DeleteOnTermination *bool   `json:"DeleteOnTermination,omitempty"`
+}
+
+type cloudformationLaunchTemplateBlockDevice struct {
+	DeviceName  *string                                     `json:"DeviceName,omitempty"`
+	VirtualName *string                                     `json:"VirtualName,omitempty"`
+	EBS         *cloudformationLaunchTemplateBlockDeviceEBS `json:"Ebs,omitempty"`
+}
+
+type cloudformationLaunchTemplateSpotOptions struct {
+	MaxPrice *string `json:"MaxPrice,omitempty"`
+}
+
+type cloudformationLaunchTemplateMarketOptions struct {
+	MarketType  *string                                  `json:"MarketType,omitempty"`
+	SpotOptions *cloudformationLaunchTemplateSpotOptions `json:"SpotOptions,omitempty"`
+}
+
+type cloudformationLaunchTemplatePlacement struct {
+	Tenancy *string `json:"Tenancy,omitempty"`
+}
+
+type cloudformationLaunchTemplateMonitoring struct {
+	Enabled *bool `json:"Enabled,omitempty"`
+}
+
+type cloudformationLaunchTemplateData struct {
+	ImageID               *string                                         `json:"ImageId,omitempty"`
+	InstanceType          *string                                         `json:"InstanceType,omitempty"`
+	KeyName               *string                                         `json:"KeyName,omitempty"`
+	IAMInstanceProfile    *cloudformationLaunchTemplateIAMProfile         `json:"IamInstanceProfile,omitempty"`
+	NetworkInterfaces     []*cloudformationLaunchTemplateNetworkInterface `json:"NetworkInterfaces,omitempty"`
+	UserData              *string                                         `json:"UserData,omitempty"`
+	BlockDeviceMappings   []*cloudformationLaunchTemplateBlockDevice      `json:"BlockDeviceMappings,omitempty"`
+	EBSOptimized          *bool                                           `json:"EbsOptimized,omitempty"`
+	InstanceMarketOptions *cloudformationLaunchTemplateMarketOptions      `json:"InstanceMarketOptions,omitempty"`
+	Placement             *cloudformationLaunchTemplatePlacement          `json:"Placement,omitempty"`
+	Monitoring            *cloudformationLaunchTemplateMonitoring         `json:"Monitoring,omitempty"`
+}
+
+type cloudformationLaunchTemplate struct {
+	LaunchTemplateName *string                           `json:"LaunchTemplateName,omitempty"`
+	LaunchTemplateData *cloudformationLaunchTemplateData `json:"LaunchTemplateData,omitempty"`
+}
+
+func (_ *LaunchTemplate) RenderCloudformation(t *cloudformation.CloudformationTarget, a, e, changes *LaunchTemplate) error {
+	cloud := t.Cloud.(awsup.AWSCloud)
+
+	if e.ImageID == nil {
+		return fi.RequiredField("ImageID")
+	}
+	image, err := cloud.ResolveImage(*e.ImageID)
+	if err != nil {
+		return err
+	}
+
+	data := &cloudformationLaunchTemplateData{
+		ImageID:      image.ImageId,
+		InstanceType: e.InstanceType,
+		EBSOptimized: e.RootVolumeOptimization,
+	}
+
+	if e.SpotPrice != "" {
+		data.InstanceMarketOptions = &cloudformationLaunchTemplateMarketOptions{
+			MarketType:  fi.String("spot"),
+			SpotOptions: &cloudformationLaunchTemplateSpotOptions{MaxPrice: fi.String(e.SpotPrice)},
+		}
+	}
+
+	if e.SSHKey != nil {
+		if e.SSHKey.Name == nil {
+			return fmt.Errorf("SSHKey Name not set")
+		}
+		data.KeyName = e.SSHKey.Name
+	}
+
+	if e.Tenancy != nil {
+		data.Placement = &cloudformationLaunchTemplatePlacement{Tenancy: e.Tenancy}
+	}
+
+	ni := &cloudformationLaunchTemplateNetworkInterface{
+		AssociatePublicIPAddress: e.AssociatePublicIP,
+		DeleteOnTermination:      fi.Bool(true),
+		DeviceIndex:              fi.Int64(0),
+	}
+	for _, sg := range e.SecurityGroups {
+		ni.Groups = append(ni.Groups, sg.CloudformationLink())
+	}
+	data.NetworkInterfaces = []*cloudformationLaunchTemplateNetworkInterface{ni}
+
+	{
+		rootDevices, err := e.buildRootDevice(cloud)
+		if err != nil {
+			return err
+		}
+
+		ephemeralDevices, err := buildEphemeralDevices(e.InstanceType)
+		if err != nil {
+			return err
+		}
+
+		for _, deviceName := range sets.StringKeySet(rootDevices).List() {
+			bdm := rootDevices[deviceName]
+			data.BlockDeviceMappings = append(data.BlockDeviceMappings, &cloudformationLaunchTemplateBlockDevice{
+				DeviceName: fi.String(deviceName),
+				EBS: &cloudformationLaunchTemplateBlockDeviceEBS{
+					VolumeType:          bdm.EbsVolumeType,
+					VolumeSize:          bdm.EbsVolumeSize,
+					IOPS:                bdm.EbsVolumeIops,
+					DeleteOnTermination: fi.Bool(true),
+				},
+			})
+		}
+
+		for _, deviceName := range sets.StringKeySet(ephemeralDevices).List() {
+			bdm := ephemeralDevices[deviceName]
+			data.BlockDeviceMappings = append(data.BlockDeviceMappings, &cloudformationLaunchTemplateBlockDevice{
+				VirtualName: bdm.VirtualName,
+				DeviceName:  fi.String(deviceName),
+			})
+		}
+	}
+
+	if e.UserData != nil {
+		d, err := e.UserData.AsBytes()
+		if err != nil {
+			return fmt.Errorf("error rendering LaunchTemplate UserData: %v", err)
+		}
+		data.UserData = aws.String(base64.StdEncoding.EncodeToString(d))
+	}
+
+	if e.IAMInstanceProfile != nil {
+		data.IAMInstanceProfile = &cloudformationLaunchTemplateIAMProfile{Name: e.IAMInstanceProfile.CloudformationLink()}
+	}
+
+	if e.InstanceMonitoring != nil {
+		data.Monitoring = &cloudformationLaunchTemplateMonitoring{Enabled: e.InstanceMonitoring}
+	} else {
+		data.Monitoring = &cloudformationLaunchTemplateMonitoring{Enabled: fi.Bool(false)}
+	}
+
+	cf := &cloudformationLaunchTemplate{
+		LaunchTemplateName: e.Name,
+		LaunchTemplateData: data,
+	}
+
+	return t.RenderResource("AWS::EC2::LaunchTemplate", *e.Name, cf)
+}
+
+func (e *LaunchTemplate) CloudformationLink() *cloudformation.Literal {
+	return cloudformation.Ref("AWS::EC2::LaunchTemplate", *e.Name)
+}
+
+// CloudformationVersionLink returns a link to the latest version of the launch template
+func (e *LaunchTemplate) CloudformationVersionLink() *cloudformation.Literal {
+	return cloudformation.GetAtt("AWS::EC2::LaunchTemplate", *e.Name, "LatestVersionNumber")
+}
+
+// FindDeletions cleans up after a migration from a LaunchConfiguration: once the AutoscalingGroup
+// no longer references them, we delete the launch configurations that were created for it.
+func (e *LaunchTemplate) FindDeletions(c *fi.Context) ([]fi.Deletion, error) {
+	var removals []fi.Deletion
+
+	lc := &LaunchConfiguration{Name: e.Name}
+	configurations, err := lc.findLaunchConfigurations(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(configurations) == 0 {
+		return nil, nil
+	}
+
+	// The AutoscalingGroup shares our name; it is updated after us, so on the first run it
+	// will still be using a launch configuration, which we can't delete yet
+	inUse := ""
+	g, err := findAutoscalingGroup(c.Cloud.(awsup.AWSCloud), fi.StringValue(e.Name))
+	if err != nil {
+		return nil, err
+	}
+	if g != nil {
+		inUse = aws.StringValue(g.LaunchConfigurationName)
+	}
+
+	for _, configuration := range configurations {
+		if aws.StringValue(configuration.LaunchConfigurationName) == inUse {
+			continue
+		}
+		removals = append(removals, &deleteLaunchConfiguration{lc: configuration})
+	}
+
+	glog.V(2).Infof("will delete launch configurations replaced by launch template: %v", removals)
+
+	return removals, nil
+}
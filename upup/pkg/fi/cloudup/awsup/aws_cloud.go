@@ -84,6 +84,9 @@ const TagNameKopsRole = "kubernetes.io/kops/role"
 // TagNameClusterOwnershipPrefix is the AWS tag used for ownership
 const TagNameClusterOwnershipPrefix = "kubernetes.io/cluster/"
 
+// TagNameDetachedInstance is the AWS tag used to record the ASG an instance was detached from during a rolling update
+const TagNameDetachedInstance = "kops.k8s.io/detached-from-asg"
+
 const (
 	WellKnownAccountKopeio             = "383156758163"
 	WellKnownAccountRedhat             = "309956199498"
@@ -362,8 +365,25 @@ func deleteGroup(c AWSCloud, g *cloudinstances.CloudInstanceGroup) error {
 		}
 	}
 
+	// Delete LaunchTemplate
+	launchTemplate := asg.LaunchTemplate
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		launchTemplate = asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	if launchTemplate != nil {
+		id := aws.StringValue(launchTemplate.LaunchTemplateId)
+		glog.V(2).Infof("Deleting launch template %q", id)
+		request := &ec2.DeleteLaunchTemplateInput{
+			LaunchTemplateId: launchTemplate.LaunchTemplateId,
+		}
+		_, err := c.EC2().DeleteLaunchTemplate(request)
+		if err != nil {
+			return fmt.Errorf("error deleting launch template %q: %v", id, err)
+		}
+	}
+
 	// Delete LaunchConfig
-	{
+	if template != "" {
 		glog.V(2).Infof("Deleting autoscaling launch configuration %q", template)
 		request := &autoscaling.DeleteLaunchConfigurationInput{
 			LaunchConfigurationName: aws.String(template),
@@ -394,16 +414,65 @@ func deleteInstance(c AWSCloud, i *cloudinstances.CloudInstanceGroupMember) erro
 		return fmt.Errorf("id was not set on CloudInstanceGroupMember: %v", i)
 	}
 
-	request := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
-		InstanceId:                     aws.String(id),
+	if i.Detached {
+		// The instance is no longer part of the ASG, so we terminate it directly
+		request := &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		}
+
+		if _, err := c.EC2().TerminateInstances(request); err != nil {
+			return fmt.Errorf("error deleting detached instance %q: %v", id, err)
+		}
+	} else {
+		request := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(id),
+			ShouldDecrementDesiredCapacity: aws.Bool(false),
+		}
+
+		if _, err := c.Autoscaling().TerminateInstanceInAutoScalingGroup(request); err != nil {
+			return fmt.Errorf("error deleting instance %q: %v", id, err)
+		}
+	}
+
+	glog.V(8).Infof("deleted aws ec2 instance %q", id)
+
+	return nil
+}
+
+// DetachInstance causes an aws instance to no longer be counted against the ASG's size limits
+func (c *awsCloudImplementation) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	if c.spotinst != nil {
+		return fmt.Errorf("detaching instances is not supported with spotinst")
+	}
+
+	return detachInstance(c, i)
+}
+
+func detachInstance(c AWSCloud, i *cloudinstances.CloudInstanceGroupMember) error {
+	id := i.ID
+	if id == "" {
+		return fmt.Errorf("id was not set on CloudInstanceGroupMember: %v", i)
+	}
+
+	asgName := i.CloudInstanceGroup.HumanName
+
+	// We tag the instance first, so that we can still find it (and terminate it) if the rolling update is interrupted
+	if err := c.CreateTags(id, map[string]string{TagNameDetachedInstance: asgName}); err != nil {
+		return fmt.Errorf("error tagging instance %q: %v", id, err)
+	}
+
+	// Because we don't decrement the desired capacity, the ASG will launch a replacement instance
+	request := &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(asgName),
+		InstanceIds:                    []*string{aws.String(id)},
 		ShouldDecrementDesiredCapacity: aws.Bool(false),
 	}
 
-	if _, err := c.Autoscaling().TerminateInstanceInAutoScalingGroup(request); err != nil {
-		return fmt.Errorf("error deleting instance %q: %v", id, err)
+	if _, err := c.Autoscaling().DetachInstances(request); err != nil {
+		return fmt.Errorf("error detaching instance %q: %v", id, err)
 	}
 
-	glog.V(8).Infof("deleted aws ec2 instance %q", id)
+	glog.V(8).Infof("detached aws ec2 instance %q from autoscaling group %q", id, asgName)
 
 	return nil
 }
@@ -537,8 +606,21 @@ func matchesAsgTags(tags map[string]string, actual []*autoscaling.TagDescription
 	return true
 }
 
+// launchSpecName returns a string identifying the launch configuration or launch template version,
+// so that we can tell whether an instance was launched from the current spec of its autoscaling group
+func launchSpecName(launchConfigurationName *string, launchTemplate *autoscaling.LaunchTemplateSpecification) string {
+	if launchTemplate != nil {
+		return aws.StringValue(launchTemplate.LaunchTemplateId) + ":" + aws.StringValue(launchTemplate.Version)
+	}
+	return aws.StringValue(launchConfigurationName)
+}
+
 func awsBuildCloudInstanceGroup(c AWSCloud, ig *kops.InstanceGroup, g *autoscaling.Group, nodeMap map[string]*v1.Node) (*cloudinstances.CloudInstanceGroup, error) {
-	newLaunchConfigName := aws.StringValue(g.LaunchConfigurationName)
+	groupLaunchTemplate := g.LaunchTemplate
+	if g.MixedInstancesPolicy != nil && g.MixedInstancesPolicy.LaunchTemplate != nil {
+		groupLaunchTemplate = g.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	newLaunchConfigName := launchSpecName(g.LaunchConfigurationName, groupLaunchTemplate)
 
 	cg := &cloudinstances.CloudInstanceGroup{
 		HumanName:     aws.StringValue(g.AutoScalingGroupName),
@@ -554,7 +636,18 @@ func awsBuildCloudInstanceGroup(c AWSCloud, ig *kops.InstanceGroup, g *autoscali
 			glog.Warningf("ignoring instance with no instance id: %s", i)
 			continue
 		}
-		err := cg.NewCloudInstanceGroupMember(instanceId, newLaunchConfigName, aws.StringValue(i.LaunchConfigurationName), nodeMap)
+		err := cg.NewCloudInstanceGroupMember(instanceId, newLaunchConfigName, launchSpecName(i.LaunchConfigurationName, i.LaunchTemplate), nodeMap)
+		if err != nil {
+			return nil, fmt.Errorf("error creating cloud instance group member: %v", err)
+		}
+	}
+
+	detached, err := findDetachedInstances(c, g)
+	if err != nil {
+		return nil, fmt.Errorf("error finding detached instances in autoscaling group %q: %v", aws.StringValue(g.AutoScalingGroupName), err)
+	}
+	for _, instanceId := range detached {
+		err := cg.NewDetachedCloudInstanceGroupMember(instanceId, nodeMap)
 		if err != nil {
 			return nil, fmt.Errorf("error creating cloud instance group member: %v", err)
 		}
@@ -563,6 +656,31 @@ func awsBuildCloudInstanceGroup(c AWSCloud, ig *kops.InstanceGroup, g *autoscali
 	return cg, nil
 }
 
+// findDetachedInstances returns the ids of running instances that were detached from the ASG by a rolling update
+func findDetachedInstances(c AWSCloud, g *autoscaling.Group) ([]string, error) {
+	request := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			NewEC2Filter("tag:"+TagNameDetachedInstance, aws.StringValue(g.AutoScalingGroupName)),
+			NewEC2Filter("instance-state-name", "pending", "running", "stopping", "stopped"),
+		},
+	}
+
+	var instanceIds []string
+	err := c.EC2().DescribeInstancesPages(request, func(p *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, r := range p.Reservations {
+			for _, i := range r.Instances {
+				instanceIds = append(instanceIds, aws.StringValue(i.InstanceId))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return instanceIds, nil
+}
+
 func (c *awsCloudImplementation) Tags() map[string]string {
 	// Defensive copy
 	tags := make(map[string]string)
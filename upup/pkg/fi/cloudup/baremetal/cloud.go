@@ -70,3 +70,10 @@ func (c *Cloud) DeleteInstance(instance *cloudinstances.CloudInstanceGroupMember
 	glog.V(8).Infof("baremetal cloud provider DeleteInstance not implemented yet")
 	return fmt.Errorf("baremetal cloud provider does not support deleting cloud instances at this time")
 }
+
+//DetachInstance is not implemented yet.
+//Baremetal may not support this.
+func (c *Cloud) DetachInstance(instance *cloudinstances.CloudInstanceGroupMember) error {
+	glog.V(8).Infof("baremetal cloud provider DetachInstance not implemented yet")
+	return fmt.Errorf("baremetal cloud provider does not support surging")
+}
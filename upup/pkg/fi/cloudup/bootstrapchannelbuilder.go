@@ -18,8 +18,11 @@ package cloudup
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 
 	channelsapi "k8s.io/kops/channels/pkg/api"
+	"k8s.io/kops/channels/pkg/channels"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/assets"
 	"k8s.io/kops/pkg/featureflag"
@@ -27,6 +30,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/fitasks"
 	"k8s.io/kops/upup/pkg/fi/utils"
+	"k8s.io/kops/util/pkg/vfs"
 )
 
 // BootstrapChannelBuilder is responsible for handling the addons in channels
@@ -46,6 +50,11 @@ func (b *BootstrapChannelBuilder) Build(c *fi.ModelBuilderContext) error {
 		return err
 	}
 
+	userManifests, err := b.addUserAddons(addons)
+	if err != nil {
+		return err
+	}
+
 	addonsYAML, err := utils.YamlMarshal(addons)
 	if err != nil {
 		return fmt.Errorf("error serializing addons yaml: %v", err)
@@ -87,9 +96,137 @@ func (b *BootstrapChannelBuilder) Build(c *fi.ModelBuilderContext) error {
 		}
 	}
 
+	for key, manifest := range userManifests {
+		name := b.cluster.ObjectMeta.Name + "-addons-" + key
+
+		tasks[name] = &fitasks.ManagedFile{
+			Contents:  fi.WrapResource(fi.NewBytesResource(manifest.contents)),
+			Lifecycle: b.Lifecycle,
+			Location:  fi.String(manifest.location),
+			Name:      fi.String(name),
+		}
+	}
+
 	return nil
 }
 
+// userAddonManifest is the manifest of an addon from a channel in the cluster spec, written alongside the bootstrap channel
+type userAddonManifest struct {
+	// location is the path of the manifest, relative to the addons directory
+	location string
+	contents []byte
+}
+
+// userAddonChannel returns the location of a channel in spec.addons; as for channels apply channel,
+// a name with no slashes, such as kubernetes-dashboard, is a well-known channel in the kops repository
+func userAddonChannel(manifest string) string {
+	if strings.Contains(manifest, "/") {
+		return manifest
+	}
+	return channels.WellKnownChannelLocation(manifest)
+}
+
+// addUserAddons adds the addons of the channels in the cluster spec to the bootstrap channel, returning their
+// manifests by key.  Manifests whose name ends in .template are templated like the kops addons, and the images of
+// all manifests are remapped.  An addon with the same name as a kops addon, or as an addon of another channel, is an error.
+func (b *BootstrapChannelBuilder) addUserAddons(addons *channelsapi.Addons) (map[string]*userAddonManifest, error) {
+	// owners records the channel defining each addon name
+	owners := make(map[string]string)
+	for _, addon := range addons.Spec.Addons {
+		owners[fi.StringValue(addon.Name)] = ""
+	}
+
+	manifests := make(map[string]*userAddonManifest)
+	// locations records the manifest locations already used, which entries of the same version and id would share
+	locations := make(map[string]bool)
+	for _, spec := range b.cluster.Spec.Addons {
+		channel := userAddonChannel(spec.Manifest)
+		channelLocation, err := url.Parse(channel)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse addon channel %q as url: %v", channel, err)
+		}
+
+		data, err := vfs.Context.ReadFile(channel)
+		if err != nil {
+			return nil, fmt.Errorf("error reading addon channel %q: %v", channel, err)
+		}
+		userAddons := &channelsapi.Addons{}
+		if err := utils.YamlUnmarshal(data, userAddons); err != nil {
+			return nil, fmt.Errorf("error parsing addon channel %q: %v", channel, err)
+		}
+
+		for _, userAddon := range userAddons.Spec.Addons {
+			name := userAddons.ObjectMeta.Name
+			if userAddon.Name != nil {
+				name = *userAddon.Name
+			}
+			if name == "" {
+				return nil, fmt.Errorf("addon in channel %q has no name", channel)
+			}
+
+			if owner, found := owners[name]; found && owner != channel {
+				if owner == "" {
+					return nil, fmt.Errorf("addon %q in channel %q has the same name as an addon managed by kops", name, channel)
+				}
+				return nil, fmt.Errorf("addon %q in channel %q is also defined in channel %q", name, channel, owner)
+			}
+			owners[name] = channel
+
+			if userAddon.Manifest == nil || *userAddon.Manifest == "" {
+				return nil, fmt.Errorf("addon %q in channel %q has no manifest", name, channel)
+			}
+
+			id := "v" + fi.StringValue(userAddon.Version)
+			if userAddon.Id != "" {
+				id += "-" + userAddon.Id
+			}
+			location := name + "/" + id + ".yaml"
+			if locations[location] {
+				return nil, fmt.Errorf("addon %q in channel %q has more than one entry for version %q with id %q; entries for the same version need different ids", name, channel, fi.StringValue(userAddon.Version), userAddon.Id)
+			}
+			locations[location] = true
+
+			manifestURL, err := url.Parse(*userAddon.Manifest)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse manifest %q of addon %q as url: %v", *userAddon.Manifest, name, err)
+			}
+			if !manifestURL.IsAbs() {
+				manifestURL = channelLocation.ResolveReference(manifestURL)
+			}
+
+			manifestBytes, err := vfs.Context.ReadFile(manifestURL.String())
+			if err != nil {
+				return nil, fmt.Errorf("error reading manifest %q of addon %q: %v", manifestURL, name, err)
+			}
+
+			if strings.HasSuffix(manifestURL.Path, ".template") {
+				rendered, err := b.templates.Render(manifestURL.String(), string(manifestBytes))
+				if err != nil {
+					return nil, fmt.Errorf("error templating manifest of addon %q: %v", name, err)
+				}
+				manifestBytes = []byte(rendered)
+			}
+
+			manifestBytes, err = b.assetBuilder.RemapManifest(manifestBytes)
+			if err != nil {
+				return nil, fmt.Errorf("error remapping manifest of addon %q: %v", name, err)
+			}
+
+			addon := *userAddon
+			addon.Name = fi.String(name)
+			addon.Manifest = fi.String(location)
+			addons.Spec.Addons = append(addons.Spec.Addons, &addon)
+
+			manifests[name+"-"+id] = &userAddonManifest{
+				location: "addons/" + location,
+				contents: manifestBytes,
+			}
+		}
+	}
+
+	return manifests, nil
+}
+
 func (b *BootstrapChannelBuilder) buildManifest() (*channelsapi.Addons, map[string]string, error) {
 	addons := &channelsapi.Addons{}
 	addons.Kind = "Addons"
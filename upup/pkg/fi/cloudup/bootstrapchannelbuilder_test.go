@@ -17,6 +17,7 @@ limitations under the License.
 package cloudup
 
 import (
+	"bytes"
 	"io/ioutil"
 	"path"
 	"strings"
@@ -48,9 +49,158 @@ func TestBootstrapChannelBuilder_BuildTasks(t *testing.T) {
 	runChannelBuilderTest(t, "cilium")
 }
 
+// testUserChannel is the addon channel referenced by the cluster spec of the tests
+var testUserChannel = map[string]string{
+	"memfs://somebucket/example.yaml": `
+kind: Addons
+metadata:
+  name: example
+spec:
+  addons:
+  - name: example.addons.example.com
+    version: 1.0.0
+    selector:
+      k8s-addon: example.addons.example.com
+    manifest: example/v1.0.0.yaml.template
+    dependencies:
+    - core.addons.k8s.io
+`,
+	"memfs://somebucket/example/v1.0.0.yaml.template": `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: example
+  namespace: kube-system
+  annotations:
+    cluster: {{ ClusterName }}
+spec:
+  containers:
+  - name: example
+    image: example/app:1.0.0
+`,
+}
+
+func TestBootstrapChannelBuilder_UserAddons(t *testing.T) {
+	h := testutils.NewIntegrationTestHarness(t)
+	defer h.Close()
+
+	h.SetupMockAWS()
+
+	cluster := loadChannelBuilderTestCluster(t, "tests/bootstrapchannelbuilder/simple")
+	cluster.Spec.Assets = &api.Assets{ContainerProxy: fi.String("proxy.example.com")}
+
+	tasks, err := tryBuildChannelBuilderTasks(t, cluster, testUserChannel)
+	if err != nil {
+		t.Fatalf("error from BootstrapChannelBuilder Build: %v", err)
+	}
+
+	name := cluster.ObjectMeta.Name + "-addons-example.addons.example.com-v1.0.0"
+	task, ok := tasks[name].(*fitasks.ManagedFile)
+	if !ok {
+		t.Fatalf("manifest task not found (%q)", name)
+	}
+	if fi.StringValue(task.Location) != "addons/example.addons.example.com/v1.0.0.yaml" {
+		t.Errorf("unexpected location of user addon manifest %q", fi.StringValue(task.Location))
+	}
+	manifest, err := task.Contents.AsString()
+	if err != nil {
+		t.Fatalf("error getting manifest as string: %v", err)
+	}
+	if !strings.Contains(manifest, "cluster: minimal.example.com") {
+		t.Errorf("expected user addon manifest to be templated, got:\n%s", manifest)
+	}
+	if !strings.Contains(manifest, "image: proxy.example.com/example/app:1.0.0") {
+		t.Errorf("expected user addon images to be remapped, got:\n%s", manifest)
+	}
+
+	grid := []struct {
+		Channel string
+		Error   string
+	}{
+		{
+			Channel: "kind: Addons\nspec:\n  addons:\n  - name: core.addons.k8s.io\n    manifest: core.yaml\n",
+			Error:   "addon \"core.addons.k8s.io\" in channel \"memfs://somebucket/example.yaml\" has the same name as an addon managed by kops",
+		},
+		{
+			Channel: "kind: Addons\nspec:\n  addons:\n  - name: example\n",
+			Error:   "addon \"example\" in channel \"memfs://somebucket/example.yaml\" has no manifest",
+		},
+		{
+			Channel: "kind: Addons\nspec:\n  addons:\n  - name: example\n    version: 1.0.0\n    manifest: example/v1.0.0.yaml.template\n  - name: example\n    version: 1.0.0\n    manifest: example/v1.0.0.yaml.template\n",
+			Error:   "addon \"example\" in channel \"memfs://somebucket/example.yaml\" has more than one entry for version \"1.0.0\" with id \"\"; entries for the same version need different ids",
+		},
+	}
+	for _, g := range grid {
+		files := map[string]string{
+			"memfs://somebucket/example.yaml":                 g.Channel,
+			"memfs://somebucket/example/v1.0.0.yaml.template": testUserChannel["memfs://somebucket/example/v1.0.0.yaml.template"],
+		}
+		_, err := tryBuildChannelBuilderTasks(t, cluster, files)
+		if err == nil || err.Error() != g.Error {
+			t.Errorf("expected error %q, got %v", g.Error, err)
+		}
+	}
+
+	cluster.Spec.Addons = append(cluster.Spec.Addons, api.AddonSpec{Manifest: "memfs://otherbucket/example.yaml"})
+	files := map[string]string{"memfs://otherbucket/example.yaml": testUserChannel["memfs://somebucket/example.yaml"]}
+	for k, v := range testUserChannel {
+		files[k] = v
+	}
+	_, err = tryBuildChannelBuilderTasks(t, cluster, files)
+	expected := "addon \"example.addons.example.com\" in channel \"memfs://otherbucket/example.yaml\" is also defined in channel \"memfs://somebucket/example.yaml\""
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	// Names with no slashes are expanded like channels apply channel does
+	channels := map[string]string{
+		"kubernetes-dashboard":            "https://raw.githubusercontent.com/kubernetes/kops/master/addons/kubernetes-dashboard/addon.yaml",
+		"memfs://somebucket/example.yaml": "memfs://somebucket/example.yaml",
+		"addons/example.yaml":             "addons/example.yaml",
+	}
+	for manifest, expected := range channels {
+		if actual := userAddonChannel(manifest); actual != expected {
+			t.Errorf("expected channel %q to be read from %q, got %q", manifest, expected, actual)
+		}
+	}
+}
+
 func runChannelBuilderTest(t *testing.T, key string) {
 	basedir := path.Join("tests/bootstrapchannelbuilder/", key)
 
+	cluster := loadChannelBuilderTestCluster(t, basedir)
+	tasks, err := tryBuildChannelBuilderTasks(t, cluster, testUserChannel)
+	if err != nil {
+		t.Fatalf("error from BootstrapChannelBuilder Build: %v", err)
+	}
+
+	name := cluster.ObjectMeta.Name + "-addons-bootstrap"
+	manifestTask := tasks[name]
+	if manifestTask == nil {
+		t.Fatalf("manifest task not found (%q)", name)
+	}
+
+	manifestFileTask := manifestTask.(*fitasks.ManagedFile)
+	actualManifest, err := manifestFileTask.Contents.AsString()
+	if err != nil {
+		t.Fatalf("error getting manifest as string: %v", err)
+	}
+
+	expectedManifestPath := path.Join(basedir, "manifest.yaml")
+	expectedManifest, err := ioutil.ReadFile(expectedManifestPath)
+	if err != nil {
+		t.Fatalf("error reading file %q: %v", expectedManifestPath, err)
+	}
+
+	if strings.TrimSpace(string(expectedManifest)) != strings.TrimSpace(actualManifest) {
+		diffString := diff.FormatDiff(string(expectedManifest), actualManifest)
+		t.Logf("diff:\n%s\n", diffString)
+
+		t.Fatalf("manifest differed from expected for test %q", key)
+	}
+}
+
+func loadChannelBuilderTestCluster(t *testing.T, basedir string) *api.Cluster {
 	clusterYamlPath := path.Join(basedir, "cluster.yaml")
 	clusterYaml, err := ioutil.ReadFile(clusterYamlPath)
 	if err != nil {
@@ -70,8 +220,11 @@ func runChannelBuilderTest(t *testing.T, key string) {
 	if err != nil {
 		t.Fatalf("error from PopulateClusterSpec: %v", err)
 	}
-	cluster = fullSpec
+	return fullSpec
+}
 
+// tryBuildChannelBuilderTasks runs the BootstrapChannelBuilder for the cluster, after writing the given files to memfs
+func tryBuildChannelBuilderTasks(t *testing.T, cluster *api.Cluster, files map[string]string) (map[string]fi.Task, error) {
 	templates, err := templates.LoadTemplates(cluster, models.NewAssetPath("cloudup/resources"))
 	if err != nil {
 		t.Fatalf("error building templates: %v", err)
@@ -79,6 +232,16 @@ func runChannelBuilderTest(t *testing.T, key string) {
 
 	vfs.Context.ResetMemfsContext(true)
 
+	for location, contents := range files {
+		p, err := vfs.Context.BuildVfsPath(location)
+		if err != nil {
+			t.Fatalf("error building vfspath: %v", err)
+		}
+		if err := p.WriteFile(bytes.NewReader([]byte(contents)), nil); err != nil {
+			t.Fatalf("error writing %s: %v", location, err)
+		}
+	}
+
 	basePath, err := vfs.Context.BuildVfsPath("memfs://tests")
 	if err != nil {
 		t.Errorf("error building vfspath: %v", err)
@@ -103,32 +266,5 @@ func runChannelBuilderTest(t *testing.T, key string) {
 		Tasks: make(map[string]fi.Task),
 	}
 	err = bcb.Build(context)
-	if err != nil {
-		t.Fatalf("error from BootstrapChannelBuilder Build: %v", err)
-	}
-
-	name := cluster.ObjectMeta.Name + "-addons-bootstrap"
-	manifestTask := context.Tasks[name]
-	if manifestTask == nil {
-		t.Fatalf("manifest task not found (%q)", name)
-	}
-
-	manifestFileTask := manifestTask.(*fitasks.ManagedFile)
-	actualManifest, err := manifestFileTask.Contents.AsString()
-	if err != nil {
-		t.Fatalf("error getting manifest as string: %v", err)
-	}
-
-	expectedManifestPath := path.Join(basedir, "manifest.yaml")
-	expectedManifest, err := ioutil.ReadFile(expectedManifestPath)
-	if err != nil {
-		t.Fatalf("error reading file %q: %v", expectedManifestPath, err)
-	}
-
-	if strings.TrimSpace(string(expectedManifest)) != strings.TrimSpace(actualManifest) {
-		diffString := diff.FormatDiff(string(expectedManifest), actualManifest)
-		t.Logf("diff:\n%s\n", diffString)
-
-		t.Fatalf("manifest differed from expected for test %q", key)
-	}
+	return context.Tasks, err
 }
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudup
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/golang/glog"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+const (
+	// containerdAssetURLTemplate is the containerd release tarball, which holds bin/containerd, bin/containerd-shim and bin/ctr
+	containerdAssetURLTemplate = "https://github.com/containerd/containerd/releases/download/v%s/containerd-%s.linux-amd64.tar.gz"
+	// runcAssetURLTemplate is the statically linked runc binary
+	runcAssetURLTemplate = "https://github.com/opencontainers/runc/releases/download/v%s/runc.amd64"
+
+	// Environment variables for overriding the containerd and runc urls
+	ENV_VAR_CONTAINERD_URL               = "CONTAINERD_URL"
+	ENV_VAR_CONTAINERD_ASSET_HASH_STRING = "CONTAINERD_ASSET_HASH_STRING"
+	ENV_VAR_RUNC_URL                     = "RUNC_URL"
+	ENV_VAR_RUNC_ASSET_HASH_STRING       = "RUNC_ASSET_HASH_STRING"
+)
+
+func usesContainerd(c *api.Cluster) bool {
+	return c.Spec.ContainerRuntime == api.ContainerRuntimeContainerd
+}
+
+// findContainerdAssets returns the containerd and runc assets, in the "hash@url" form used for nodeup assets
+func findContainerdAssets(c *api.Cluster, assetBuilder *assets.AssetBuilder) ([]string, error) {
+	if c.Spec.Containerd == nil {
+		return nil, fmt.Errorf("containerd configuration not set")
+	}
+
+	var assets []string
+
+	containerdVersion := fi.StringValue(c.Spec.Containerd.Version)
+	if containerdVersion == "" {
+		return nil, fmt.Errorf("containerd version not set")
+	}
+	containerdAsset, err := findContainerdAsset("containerd", fmt.Sprintf(containerdAssetURLTemplate, containerdVersion, containerdVersion), ENV_VAR_CONTAINERD_URL, ENV_VAR_CONTAINERD_ASSET_HASH_STRING, assetBuilder)
+	if err != nil {
+		return nil, err
+	}
+	assets = append(assets, containerdAsset)
+
+	runcVersion := fi.StringValue(c.Spec.Containerd.RuncVersion)
+	if runcVersion == "" {
+		return nil, fmt.Errorf("runc version not set")
+	}
+	runcAsset, err := findContainerdAsset("runc", fmt.Sprintf(runcAssetURLTemplate, runcVersion), ENV_VAR_RUNC_URL, ENV_VAR_RUNC_ASSET_HASH_STRING, assetBuilder)
+	if err != nil {
+		return nil, err
+	}
+	assets = append(assets, runcAsset)
+
+	return assets, nil
+}
+
+// findContainerdAsset resolves a single asset, honoring the url and hash overrides in the environment
+func findContainerdAsset(name string, defaultURL string, urlEnvVar string, hashEnvVar string, assetBuilder *assets.AssetBuilder) (string, error) {
+	assetURL := defaultURL
+	if s := os.Getenv(urlEnvVar); s != "" {
+		glog.Infof("Using %s asset %q, as set in %s", name, s, urlEnvVar)
+		assetURL = s
+	}
+
+	u, err := url.Parse(assetURL)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse %q as a URL: %v", assetURL, err)
+	}
+
+	if hash := os.Getenv(hashEnvVar); hash != "" {
+		glog.Infof("Using %s asset hash %q, as set in %s", name, hash, hashEnvVar)
+
+		u, err = assetBuilder.RemapFileAndSHAValue(u, hash)
+		if err != nil {
+			return "", err
+		}
+		return hash + "@" + u.String(), nil
+	}
+
+	u, hash, err := assetBuilder.RemapFileAndSHA(u)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine hash for %s asset (you can set it with %s): %v", name, hashEnvVar, err)
+	}
+	return hash.Hex() + "@" + u.String(), nil
+}
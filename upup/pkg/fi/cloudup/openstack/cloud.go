@@ -209,6 +209,10 @@ func (c *openstackCloud) DeleteInstance(i *cloudinstances.CloudInstanceGroupMemb
 	return fmt.Errorf("openstackCloud::DeleteInstance not implemented")
 }
 
+func (c *openstackCloud) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	return fmt.Errorf("openstackCloud::DetachInstance not implemented")
+}
+
 func (c *openstackCloud) DeleteGroup(g *cloudinstances.CloudInstanceGroup) error {
 	return fmt.Errorf("openstackCloud::DeleteGroup not implemented")
 }
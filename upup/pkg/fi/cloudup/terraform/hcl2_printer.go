@@ -0,0 +1,622 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hcl2Printer writes terraform configuration in the HCL2 syntax of terraform 0.12.
+//
+// Unlike HCL1, HCL2 distinguishes nested blocks from attributes holding an object or a map.
+// We rely on the shape of the terraform structs of the tasks: a struct (or a list of structs) is written
+// as a (repeated) nested block, and a map as an attribute.  Field names and omission follow the json tags,
+// so the output has the same content as the HCL1 output.
+type hcl2Printer struct {
+	b bytes.Buffer
+}
+
+var literalType = reflect.TypeOf(Literal{})
+
+// jsonDocumentAttributes are the attributes which hold JSON policy documents; these are written with jsonencode,
+// so that they are reviewable.  Other strings are always written as they are, even if they happen to be JSON.
+var jsonDocumentAttributes = map[string]bool{
+	// aws_iam_role
+	"assume_role_policy": true,
+	// aws_iam_role_policy
+	"policy": true,
+	// alicloud_ram_role, alicloud_ram_policy
+	"document": true,
+}
+
+// hcl2Attribute is an attribute or a nested block in a body
+type hcl2Attribute struct {
+	key   string
+	value reflect.Value
+}
+
+// writeBlock writes a block, with its labels, e.g. resource "aws_vpc" "name" { ... }
+func (p *hcl2Printer) writeBlock(indent int, blockType string, labels []string, body reflect.Value) error {
+	p.writeIndent(indent)
+	p.b.WriteString(blockType)
+	for _, label := range labels {
+		p.b.WriteString(" ")
+		p.b.WriteString(quoteHCL2String(label))
+	}
+	p.b.WriteString(" {\n")
+	if err := p.writeBody(indent+1, body); err != nil {
+		return err
+	}
+	p.writeIndent(indent)
+	p.b.WriteString("}\n")
+	return nil
+}
+
+// writeBody writes the attributes and nested blocks of a struct or a map
+func (p *hcl2Printer) writeBody(indent int, v reflect.Value) error {
+	attributes, err := bodyAttributes(v)
+	if err != nil {
+		return err
+	}
+
+	// Attributes are written first, then the nested blocks.  Consecutive single-line attributes are
+	// aligned as terraform fmt does; multi-line attributes and blocks are separated by blank lines.
+	var chunks []string
+	var keys []string
+	var values []string
+	flush := func() {
+		if len(keys) != 0 {
+			chunks = append(chunks, alignedAttributes(indent, keys, values))
+		}
+		keys = nil
+		values = nil
+	}
+
+	var blocks []hcl2Attribute
+	for _, a := range attributes {
+		if isBlock(a.value) {
+			blocks = append(blocks, a)
+			continue
+		}
+
+		expr, err := p.attributeExpression(indent, a)
+		if err != nil {
+			return fmt.Errorf("error writing %q: %v", a.key, err)
+		}
+		if strings.Contains(expr, "\n") {
+			flush()
+			chunks = append(chunks, alignedAttributes(indent, []string{attributeKey(a.key)}, []string{expr}))
+			continue
+		}
+		keys = append(keys, attributeKey(a.key))
+		values = append(values, expr)
+	}
+	flush()
+
+	for _, a := range blocks {
+		var items []reflect.Value
+		if v := indirect(a.value); v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, v.Index(i))
+			}
+		} else {
+			items = append(items, v)
+		}
+
+		for _, item := range items {
+			child := &hcl2Printer{}
+			if err := child.writeBlock(indent, a.key, nil, item); err != nil {
+				return err
+			}
+			chunks = append(chunks, child.b.String())
+		}
+	}
+
+	p.b.WriteString(strings.Join(chunks, "\n"))
+	return nil
+}
+
+// alignedAttributes writes attributes, with their values aligned
+func alignedAttributes(indent int, keys []string, values []string) string {
+	width := 0
+	for _, k := range keys {
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+
+	var b bytes.Buffer
+	for i, k := range keys {
+		b.WriteString(strings.Repeat("  ", indent))
+		b.WriteString(k)
+		b.WriteString(strings.Repeat(" ", width-len(k)))
+		b.WriteString(" = ")
+		b.WriteString(values[i])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// bodyAttributes returns the non-empty attributes of a struct (in field order, following the json tags) or a map (in key order)
+func bodyAttributes(v reflect.Value) ([]hcl2Attribute, error) {
+	v = indirect(v)
+
+	var attributes []hcl2Attribute
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				// Not exported
+				continue
+			}
+
+			name, omitEmpty := parseJSONTag(f)
+			if name == "-" {
+				continue
+			}
+
+			fv := v.Field(i)
+			if f.Anonymous && name == "" {
+				embedded, err := bodyAttributes(fv)
+				if err != nil {
+					return nil, err
+				}
+				attributes = append(attributes, embedded...)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			if isNull(fv) || (omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			attributes = append(attributes, hcl2Attribute{key: name, value: fv})
+		}
+
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, k := range keys {
+			mv := v.MapIndex(k)
+			if isNull(mv) {
+				continue
+			}
+			attributes = append(attributes, hcl2Attribute{key: k.String(), value: mv})
+		}
+
+	case reflect.Invalid:
+
+	default:
+		return nil, fmt.Errorf("unexpected type for terraform body: %v", v.Type())
+	}
+
+	return attributes, nil
+}
+
+// attributeExpression returns the HCL2 expression for the value of an attribute
+func (p *hcl2Printer) attributeExpression(indent int, a hcl2Attribute) (string, error) {
+	if jsonDocumentAttributes[a.key] {
+		if v := indirect(a.value); v.IsValid() && v.Kind() == reflect.String {
+			return jsonDocumentHCL2Expression(indent, v.String()), nil
+		}
+	}
+	return p.expression(indent, a.value)
+}
+
+// expression returns the HCL2 expression for a value; it is indented for the given level when it spans lines
+func (p *hcl2Printer) expression(indent int, v reflect.Value) (string, error) {
+	if v.IsValid() && v.Type() == reflect.PtrTo(literalType) {
+		return literalHCL2Expression(v.Interface().(*Literal).value), nil
+	}
+
+	v = indirect(v)
+	if !v.IsValid() {
+		return "null", nil
+	}
+	if v.Type() == literalType {
+		l := v.Interface().(Literal)
+		return literalHCL2Expression(l.value), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return quoteHCL2String(v.String()), nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+
+	case reflect.Slice, reflect.Array:
+		var items []string
+		for i := 0; i < v.Len(); i++ {
+			item, err := p.expression(indent, v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+
+	case reflect.Map, reflect.Struct:
+		attributes, err := bodyAttributes(v)
+		if err != nil {
+			return "", err
+		}
+		var keys []string
+		var values []string
+		for _, a := range attributes {
+			value, err := p.expression(indent+1, a.value)
+			if err != nil {
+				return "", err
+			}
+			keys = append(keys, attributeKey(a.key))
+			values = append(values, value)
+		}
+		return objectHCL2Expression(indent, keys, values), nil
+
+	default:
+		return "", fmt.Errorf("unhandled type %v", v.Type())
+	}
+}
+
+func objectHCL2Expression(indent int, keys []string, values []string) string {
+	if len(keys) == 0 {
+		return "{}"
+	}
+	return "{\n" + alignedAttributes(indent+1, keys, values) + strings.Repeat("  ", indent) + "}"
+}
+
+// jsonDocumentHCL2Expression writes a string holding a JSON document with jsonencode; anything else is written as a string
+func jsonDocumentHCL2Expression(indent int, s string) string {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var obj map[string]interface{}
+		if err := decoder.Decode(&obj); err == nil && !decoder.More() {
+			return "jsonencode(" + jsonHCL2Expression(indent, obj) + ")"
+		}
+	}
+	return quoteHCL2String(s)
+}
+
+// jsonHCL2Expression writes a decoded JSON value as an HCL2 expression
+func jsonHCL2Expression(indent int, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return quoteHCL2String(v)
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, jsonHCL2Expression(indent, item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var values []string
+		for i, k := range keys {
+			values = append(values, jsonHCL2Expression(indent+1, v[k]))
+			keys[i] = attributeKey(k)
+		}
+		return objectHCL2Expression(indent, keys, values)
+	default:
+		return quoteHCL2String(fmt.Sprintf("%v", v))
+	}
+}
+
+// literalHCL2Expression converts a Literal, which uses the HCL1 interpolation syntax, to an HCL2 expression.
+// A literal which is a single interpolation, such as ${aws_vpc.main.id}, becomes a first-class expression;
+// anything else is written as a template.
+func literalHCL2Expression(s string) string {
+	if strings.HasPrefix(s, "${") {
+		if end := findInterpolationEnd(s, 2); end == len(s)-1 {
+			return s[2:end]
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("\"")
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			// An escaped interpolation; it has the same meaning in HCL2
+			b.WriteString("$${")
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			end := findInterpolationEnd(s, i+2)
+			if end == -1 {
+				// Not terminated; write it as text
+				b.WriteString("$${")
+				i++
+				continue
+			}
+			b.WriteString(s[i : end+1])
+			i = end
+		default:
+			writeHCL2StringChar(&b, s, i)
+		}
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+// findInterpolationEnd returns the index of the brace closing the interpolation whose content starts at start, or -1
+func findInterpolationEnd(s string, start int) int {
+	depth := 0
+	inQuote := false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inQuote {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inQuote = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inQuote = true
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// quoteHCL2String writes a string which is not a template
+func quoteHCL2String(s string) string {
+	var b bytes.Buffer
+	b.WriteString("\"")
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "${") {
+			b.WriteString("$${")
+			i++
+			continue
+		}
+		writeHCL2StringChar(&b, s, i)
+	}
+	b.WriteString("\"")
+	return b.String()
+}
+
+func writeHCL2StringChar(b *bytes.Buffer, s string, i int) {
+	c := s[i]
+	switch c {
+	case '\\':
+		b.WriteString("\\\\")
+	case '"':
+		b.WriteString("\\\"")
+	case '\n':
+		b.WriteString("\\n")
+	case '\r':
+		b.WriteString("\\r")
+	case '\t':
+		b.WriteString("\\t")
+	case '%':
+		if strings.HasPrefix(s[i:], "%{") {
+			// Template directives are new in HCL2
+			b.WriteString("%%")
+		} else {
+			b.WriteByte(c)
+		}
+	default:
+		if c < 0x20 {
+			fmt.Fprintf(b, "\\u%04x", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+}
+
+// attributeKey quotes a key unless it is a valid identifier
+func attributeKey(k string) string {
+	for i, c := range k {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			continue
+		}
+		if i != 0 && (c == '-' || (c >= '0' && c <= '9')) {
+			continue
+		}
+		return quoteHCL2String(k)
+	}
+	if k == "" {
+		return `""`
+	}
+	return k
+}
+
+// isBlock returns true if the value is written as nested block(s): a struct, or a list of structs
+func isBlock(v reflect.Value) bool {
+	v = indirect(v)
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return v.Type() != literalType
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return false
+		}
+		t := v.Type().Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		return t.Kind() == reflect.Struct && t != literalType
+	}
+	return false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isNull(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	case reflect.Invalid:
+		return true
+	}
+	return false
+}
+
+// isEmptyValue mirrors encoding/json, for omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func parseJSONTag(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "" {
+		return "", false
+	}
+	tokens := strings.Split(tag, ",")
+	omitEmpty := false
+	for _, option := range tokens[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return tokens[0], omitEmpty
+}
+
+func (p *hcl2Printer) writeIndent(indent int) {
+	p.b.WriteString(strings.Repeat("  ", indent))
+}
+
+// hcl2Print writes the terraform configuration, which has the same layout as the HCL1 (json) configuration
+func hcl2Print(data map[string]interface{}, requiredProviders map[string]string) ([]byte, error) {
+	p := &hcl2Printer{}
+
+	// Blocks are in the same order as in the HCL1 output
+	for _, section := range []struct {
+		blockType string
+		labels    int
+	}{
+		{"locals", 0},
+		{"output", 1},
+		{"provider", 1},
+		{"resource", 2},
+	} {
+		v, found := data[section.blockType]
+		if !found {
+			continue
+		}
+		if err := p.writeLabeledBlocks(section.blockType, reflect.ValueOf(v), section.labels, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.b.Len() != 0 {
+		p.b.WriteString("\n")
+	}
+	p.b.WriteString("terraform {\n")
+	if err := p.writeBody(1, reflect.ValueOf(data["terraform"])); err != nil {
+		return nil, err
+	}
+	if len(requiredProviders) != 0 {
+		p.b.WriteString("\n")
+		if err := p.writeBlock(1, "required_providers", nil, reflect.ValueOf(requiredProviders)); err != nil {
+			return nil, err
+		}
+	}
+	p.b.WriteString("}\n")
+
+	return p.b.Bytes(), nil
+}
+
+// writeLabeledBlocks writes a block for each entry of nested maps, keyed by the block labels
+func (p *hcl2Printer) writeLabeledBlocks(blockType string, v reflect.Value, depth int, labels []string) error {
+	if depth == 0 {
+		if p.b.Len() != 0 {
+			p.b.WriteString("\n")
+		}
+		return p.writeBlock(0, blockType, labels, v)
+	}
+
+	v = indirect(v)
+	if v.Kind() != reflect.Map {
+		return fmt.Errorf("unexpected type for %s: %v", blockType, v.Type())
+	}
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, k := range keys {
+		blockLabels := append(append([]string{}, labels...), k.String())
+		if err := p.writeLabeledBlocks(blockType, v.MapIndex(k), depth-1, blockLabels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/diff"
+)
+
+func TestLiteralHCL2Expression(t *testing.T) {
+	grid := []struct {
+		literal  *Literal
+		expected string
+	}{
+		{
+			literal:  LiteralProperty("aws_vpc", "main.example.com", "id"),
+			expected: `aws_vpc.main-example-com.id`,
+		},
+		{
+			literal:  LiteralExpression(`${file("${path.module}/data/aws_iam_role_masters_policy")}`),
+			expected: `file("${path.module}/data/aws_iam_role_masters_policy")`,
+		},
+		{
+			literal:  LiteralExpression(`arn:aws:iam::${aws_iam_role.masters.name}/*`),
+			expected: `"arn:aws:iam::${aws_iam_role.masters.name}/*"`,
+		},
+		{
+			literal:  LiteralFromStringValue(`say "hi" %{ok} $${escaped}`),
+			expected: `"say \"hi\" %%{ok} $${escaped}"`,
+		},
+	}
+
+	for _, g := range grid {
+		actual := literalHCL2Expression(g.literal.value)
+		if actual != g.expected {
+			t.Errorf("unexpected expression for %q: expected=%s actual=%s", g.literal.value, g.expected, actual)
+		}
+	}
+}
+
+func TestJSONDocumentHCL2Expression(t *testing.T) {
+	grid := []struct {
+		s        string
+		expected string
+	}{
+		{
+			s:        "plain ${not-a-reference}",
+			expected: `"plain $${not-a-reference}"`,
+		},
+		{
+			s:        `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["ec2:*"], "Resource": "*"}]}`,
+			expected: "jsonencode({\n  Statement = [{\n    Action   = [\"ec2:*\"]\n    Effect   = \"Allow\"\n    Resource = \"*\"\n  }]\n  Version   = \"2012-10-17\"\n})",
+		},
+		{
+			s:        "{not json}",
+			expected: `"{not json}"`,
+		},
+	}
+
+	for _, g := range grid {
+		actual := jsonDocumentHCL2Expression(0, g.s)
+		if actual != g.expected {
+			t.Errorf("unexpected expression for %q: expected=%s actual=%s", g.s, g.expected, actual)
+		}
+	}
+}
+
+type testHCL2Tag struct {
+	Key               string `json:"key"`
+	Value             string `json:"value"`
+	PropagateAtLaunch bool   `json:"propagate_at_launch"`
+}
+
+type testHCL2BlockDevice struct {
+	VolumeSize *int64 `json:"volume_size,omitempty"`
+}
+
+type testHCL2Resource struct {
+	Name                *string              `json:"name,omitempty"`
+	LaunchConfiguration *Literal             `json:"launch_configuration,omitempty"`
+	MaxSize             *int64               `json:"max_size,omitempty"`
+	Subnets             []*Literal           `json:"vpc_zone_identifier,omitempty"`
+	Tags                []*testHCL2Tag       `json:"tag,omitempty"`
+	RootBlockDevice     *testHCL2BlockDevice `json:"root_block_device,omitempty"`
+	Labels              map[string]string    `json:"labels,omitempty"`
+	Lifecycle           *Lifecycle           `json:"lifecycle,omitempty"`
+}
+
+func TestHCL2Print(t *testing.T) {
+	name := "nodes.example.com"
+	maxSize := int64(2)
+	volumeSize := int64(64)
+	createBeforeDestroy := true
+
+	data := map[string]interface{}{
+		"terraform": map[string]interface{}{
+			"required_version": ">= 0.12.0",
+		},
+		"locals": map[string]interface{}{
+			"cluster_name": LiteralFromStringValue("example.com"),
+			"subnet_ids":   []*Literal{LiteralProperty("aws_subnet", "us-test-1a.example.com", "id")},
+		},
+		"output": map[string]interface{}{
+			"cluster_name": map[string]interface{}{
+				"value": LiteralFromStringValue("example.com"),
+			},
+		},
+		"provider": map[string]map[string]interface{}{
+			"aws": {"region": "us-test-1"},
+		},
+		"resource": map[string]map[string]interface{}{
+			"aws_autoscaling_group": {
+				"nodes-example-com": &testHCL2Resource{
+					Name:                &name,
+					LaunchConfiguration: LiteralProperty("aws_launch_configuration", "nodes.example.com", "id"),
+					MaxSize:             &maxSize,
+					Subnets:             []*Literal{LiteralProperty("aws_subnet", "us-test-1a.example.com", "id")},
+					Tags: []*testHCL2Tag{
+						{Key: "KubernetesCluster", Value: "example.com", PropagateAtLaunch: true},
+						{Key: "k8s.io/role/node", Value: "1", PropagateAtLaunch: true},
+					},
+					RootBlockDevice: &testHCL2BlockDevice{VolumeSize: &volumeSize},
+					Labels:          map[string]string{"k8s.io/role": "node", "Name": "nodes"},
+					Lifecycle:       &Lifecycle{CreateBeforeDestroy: &createBeforeDestroy},
+				},
+			},
+		},
+	}
+
+	actual, err := hcl2Print(data, map[string]string{"aws": ">= 2.7.0"})
+	if err != nil {
+		t.Fatalf("error printing HCL2: %v", err)
+	}
+
+	expected := `locals {
+  cluster_name = "example.com"
+  subnet_ids   = [aws_subnet.us-test-1a-example-com.id]
+}
+
+output "cluster_name" {
+  value = "example.com"
+}
+
+provider "aws" {
+  region = "us-test-1"
+}
+
+resource "aws_autoscaling_group" "nodes-example-com" {
+  name                 = "nodes.example.com"
+  launch_configuration = aws_launch_configuration.nodes-example-com.id
+  max_size             = 2
+  vpc_zone_identifier  = [aws_subnet.us-test-1a-example-com.id]
+
+  labels = {
+    Name          = "nodes"
+    "k8s.io/role" = "node"
+  }
+
+  tag {
+    key                 = "KubernetesCluster"
+    value               = "example.com"
+    propagate_at_launch = true
+  }
+
+  tag {
+    key                 = "k8s.io/role/node"
+    value               = "1"
+    propagate_at_launch = true
+  }
+
+  root_block_device {
+    volume_size = 64
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+terraform {
+  required_version = ">= 0.12.0"
+
+  required_providers {
+    aws = ">= 2.7.0"
+  }
+}
+`
+	if string(actual) != expected {
+		t.Log(diff.FormatDiff(expected, string(actual)))
+		t.Fatalf("unexpected HCL2 output")
+	}
+}
+
+type testHCL2Policy struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Policy      *string `json:"policy,omitempty"`
+}
+
+func TestHCL2PrintJSONDocuments(t *testing.T) {
+	name := "nodes.example.com"
+	description := `{"not": "a policy"}`
+	policy := `{"Version": "2012-10-17"}`
+
+	data := map[string]interface{}{
+		"resource": map[string]map[string]interface{}{
+			"aws_iam_role_policy": {
+				"nodes-example-com": &testHCL2Policy{
+					Name:        &name,
+					Description: &description,
+					Policy:      &policy,
+				},
+			},
+		},
+	}
+
+	actual, err := hcl2Print(data, nil)
+	if err != nil {
+		t.Fatalf("error printing HCL2: %v", err)
+	}
+
+	expected := `resource "aws_iam_role_policy" "nodes-example-com" {
+  name        = "nodes.example.com"
+  description = "{\"not\": \"a policy\"}"
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+  })
+}
+
+terraform {
+}
+`
+	if string(actual) != expected {
+		t.Log(diff.FormatDiff(expected, string(actual)))
+		t.Fatalf("unexpected HCL2 output")
+	}
+}
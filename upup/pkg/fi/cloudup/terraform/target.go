@@ -159,6 +159,21 @@ func (t *TerraformTarget) AddOutputVariableArray(key string, literal *Literal) e
 	return nil
 }
 
+// hcl2ProviderVersions are the first versions of the providers which support terraform 0.12
+var hcl2ProviderVersions = map[string]string{
+	"aws":     ">= 2.7.0",
+	"google":  ">= 2.5.0",
+	"vsphere": ">= 1.11.0",
+}
+
+// tfGetVersion returns the version of terraform the output is written for
+func tfGetVersion(c *kops.TargetSpec) string {
+	if c != nil && c.Terraform != nil && c.Terraform.Version != "" {
+		return c.Terraform.Version
+	}
+	return kops.TerraformVersion011
+}
+
 // tfGetProviderExtraConfig is a helper function to get extra config with safety checks on the pointers.
 func tfGetProviderExtraConfig(c *kops.TargetSpec) map[string]string {
 	if c != nil &&
@@ -255,9 +270,15 @@ func (t *TerraformTarget) Finish(taskMap map[string]fi.Task) error {
 		}
 	}
 
-	// See https://github.com/kubernetes/kops/pull/2424 for why we require 0.9.3
+	version := tfGetVersion(t.clusterSpecTarget)
+
 	terraformConfiguration := make(map[string]interface{})
-	terraformConfiguration["required_version"] = ">= 0.9.3"
+	if version == kops.TerraformVersion012 {
+		terraformConfiguration["required_version"] = ">= 0.12.0"
+	} else {
+		// See https://github.com/kubernetes/kops/pull/2424 for why we require 0.9.3
+		terraformConfiguration["required_version"] = ">= 0.9.3"
+	}
 
 	data := make(map[string]interface{})
 	data["terraform"] = terraformConfiguration
@@ -279,7 +300,21 @@ func (t *TerraformTarget) Finish(taskMap map[string]fi.Task) error {
 
 	useJson := false
 
-	if useJson {
+	if version == kops.TerraformVersion012 {
+		requiredProviders := make(map[string]string)
+		for name := range providersByName {
+			if v := hcl2ProviderVersions[name]; v != "" {
+				requiredProviders[name] = v
+			}
+		}
+
+		b, err := hcl2Print(data, requiredProviders)
+		if err != nil {
+			return fmt.Errorf("error writing terraform data to output: %v", err)
+		}
+
+		t.files["kubernetes.tf"] = b
+	} else if useJson {
 		t.files["kubernetes.tf"] = jsonBytes
 	} else {
 		f, err := hcl_parser.Parse(jsonBytes)
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"sort"
+)
+
+// PlanAction is the kind of change which would be made to a task
+type PlanAction string
+
+const (
+	PlanActionCreate PlanAction = "create"
+	PlanActionUpdate PlanAction = "update"
+)
+
+// Plan is a machine-readable form of the changes collected by a DryRunTarget
+type Plan struct {
+	// Changes are the tasks which would be created or updated
+	Changes []*PlanChange `json:"changes,omitempty"`
+	// Deletions are the items which would be deleted
+	Deletions []*PlanDeletion `json:"deletions,omitempty"`
+}
+
+// PlanChange is a task which would be created or updated
+type PlanChange struct {
+	// Type is the type of the task, e.g. LaunchConfiguration
+	Type string `json:"type"`
+	// Name is the name of the task
+	Name string `json:"name"`
+	// Lifecycle is the lifecycle of the task, if it has one
+	Lifecycle Lifecycle `json:"lifecycle,omitempty"`
+	// Action is whether the task would be created or updated
+	Action PlanAction `json:"action"`
+	// Fields are the fields which would be set (on create) or changed (on update)
+	Fields []*PlanField `json:"fields,omitempty"`
+}
+
+// PlanField is a field of a task which would be set or changed
+type PlanField struct {
+	Name string `json:"name"`
+	// Before is the current value; it is empty when the task would be created
+	Before string `json:"before,omitempty"`
+	// After is the value the field would have
+	After string `json:"after,omitempty"`
+}
+
+// PlanDeletion is an item which would be deleted
+type PlanDeletion struct {
+	// Type is the name of the task which found the item to delete
+	Type string `json:"type"`
+	// Item describes the item which would be deleted
+	Item string `json:"item"`
+}
+
+// BuildPlan returns the changes and deletions collected by the target, in the same order as PrintReport
+func (t *DryRunTarget) BuildPlan(taskMap map[string]Task) (*Plan, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	plan := &Plan{}
+
+	var creates []*render
+	var updates []*render
+	for _, r := range t.changes {
+		if r.aIsNil {
+			creates = append(creates, r)
+		} else {
+			updates = append(updates, r)
+		}
+	}
+
+	// Give everything a consistent ordering
+	sort.Sort(ByTaskKey(creates))
+	sort.Sort(ByTaskKey(updates))
+
+	for _, r := range creates {
+		c := buildPlanChange(taskMap, r, PlanActionCreate)
+		for _, change := range buildCreateList(r.changes) {
+			c.Fields = append(c.Fields, &PlanField{Name: change.FieldName, After: change.After})
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+
+	for _, r := range updates {
+		changeList, err := buildChangeList(r.a, r.e, r.changes)
+		if err != nil {
+			return nil, err
+		}
+		c := buildPlanChange(taskMap, r, PlanActionUpdate)
+		for _, change := range changeList {
+			c.Fields = append(c.Fields, &PlanField{Name: change.FieldName, Before: change.Before, After: change.After})
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+
+	deletions := append([]Deletion(nil), t.deletions...)
+	sort.Sort(DeletionByTaskName(deletions))
+	for _, d := range deletions {
+		plan.Deletions = append(plan.Deletions, &PlanDeletion{Type: d.TaskName(), Item: d.Item()})
+	}
+
+	return plan, nil
+}
+
+func buildPlanChange(taskMap map[string]Task, r *render, action PlanAction) *PlanChange {
+	c := &PlanChange{
+		Type:   getTaskName(r.changes),
+		Name:   idForTask(taskMap, r.e),
+		Action: action,
+	}
+	if hl, ok := r.e.(HasLifecycle); ok {
+		if lifecycle := hl.GetLifecycle(); lifecycle != nil {
+			c.Lifecycle = *lifecycle
+		}
+	}
+	return c
+}
@@ -69,7 +69,10 @@ type DeletionByTaskName []Deletion
 func (a DeletionByTaskName) Len() int      { return len(a) }
 func (a DeletionByTaskName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a DeletionByTaskName) Less(i, j int) bool {
-	return a[i].TaskName() < a[i].TaskName()
+	if a[i].TaskName() != a[j].TaskName() {
+		return a[i].TaskName() < a[j].TaskName()
+	}
+	return a[i].Item() < a[j].Item()
 }
 
 var _ Target = &DryRunTarget{}
@@ -151,56 +154,8 @@ func (t *DryRunTarget) PrintReport(taskMap map[string]Task, out io.Writer) error
 				taskName := getTaskName(r.changes)
 				fmt.Fprintf(b, "  %s/%s\n", taskName, idForTask(taskMap, r.e))
 
-				changes := reflect.ValueOf(r.changes)
-				if changes.Kind() == reflect.Ptr && !changes.IsNil() {
-					changes = changes.Elem()
-				}
-
-				if changes.Kind() == reflect.Struct {
-					for i := 0; i < changes.NumField(); i++ {
-
-						field := changes.Field(i)
-
-						fieldName := changes.Type().Field(i).Name
-						if changes.Type().Field(i).PkgPath != "" {
-							// Not exported
-							continue
-						}
-
-						fieldValue := reflectutils.ValueAsString(field)
-
-						shouldPrint := true
-						if fieldName == "Name" {
-							// The field name is already printed above, no need to repeat it.
-							shouldPrint = false
-						}
-						if fieldName == "Lifecycle" {
-							// Lifecycle is a "system" field; no need to show it
-							shouldPrint = false
-						}
-						if fieldValue == "<nil>" || fieldValue == "<resource>" {
-							// Uninformative
-							shouldPrint = false
-						}
-						if fieldValue == "id:<nil>" {
-							// Uninformative, but we can often print the name instead
-							name := ""
-							if field.CanInterface() {
-								hasName, ok := field.Interface().(HasName)
-								if ok {
-									name = StringValue(hasName.GetName())
-								}
-							}
-							if name != "" {
-								fieldValue = "name:" + name
-							} else {
-								shouldPrint = false
-							}
-						}
-						if shouldPrint {
-							fmt.Fprintf(b, "  \t%-20s\t%s\n", fieldName, fieldValue)
-						}
-					}
+				for _, change := range buildCreateList(r.changes) {
+					fmt.Fprintf(b, "  \t%-20s\t%s\n", change.FieldName, change.Description)
 				}
 
 				fmt.Fprintf(b, "\n")
@@ -276,6 +231,67 @@ func (t *DryRunTarget) PrintReport(taskMap map[string]Task, out io.Writer) error
 type change struct {
 	FieldName   string
 	Description string
+
+	// Before and After are the actual and expected values of the field
+	Before string
+	After  string
+}
+
+// buildCreateList returns the informative fields of a task which will be created
+func buildCreateList(changes Task) []change {
+	var changeList []change
+
+	valC := reflect.ValueOf(changes)
+	if valC.Kind() == reflect.Ptr && !valC.IsNil() {
+		valC = valC.Elem()
+	}
+
+	if valC.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < valC.NumField(); i++ {
+		field := valC.Field(i)
+
+		fieldName := valC.Type().Field(i).Name
+		if valC.Type().Field(i).PkgPath != "" {
+			// Not exported
+			continue
+		}
+
+		if fieldName == "Name" {
+			// The field name is already printed above, no need to repeat it.
+			continue
+		}
+		if fieldName == "Lifecycle" {
+			// Lifecycle is a "system" field; no need to show it
+			continue
+		}
+
+		fieldValue := reflectutils.ValueAsString(field)
+		if fieldValue == "<nil>" || fieldValue == "<resource>" {
+			// Uninformative
+			continue
+		}
+		if fieldValue == "id:<nil>" {
+			// Uninformative, but we can often print the name instead
+			name := ""
+			if field.CanInterface() {
+				hasName, ok := field.Interface().(HasName)
+				if ok {
+					name = StringValue(hasName.GetName())
+				}
+			}
+			if name == "" {
+				continue
+			}
+			fieldValue = "name:" + name
+		}
+
+		changeList = append(changeList, change{FieldName: fieldName, Description: fieldValue, After: fieldValue})
+	}
+
+	return changeList
 }
 
 func buildChangeList(a, e, changes Task) ([]change, error) {
@@ -322,6 +338,8 @@ func buildChangeList(a, e, changes Task) ([]change, error) {
 			fieldValE := valE.Field(i)
 
 			description := ""
+			before := ""
+			after := ""
 			ignored := false
 			if fieldValE.CanInterface() {
 				fieldValA := valA.Field(i)
@@ -334,17 +352,21 @@ func buildChangeList(a, e, changes Task) ([]change, error) {
 					resE, okE := tryResourceAsString(fieldValE)
 					if okA && okE {
 						description = diff.FormatDiff(resA, resE)
+						before = resA
+						after = resE
 					}
 				}
 
 				if !ignored && description == "" {
-					description = fmt.Sprintf(" %v -> %v", reflectutils.ValueAsString(fieldValA), reflectutils.ValueAsString(fieldValE))
+					before = reflectutils.ValueAsString(fieldValA)
+					after = reflectutils.ValueAsString(fieldValE)
+					description = fmt.Sprintf(" %v -> %v", before, after)
 				}
 			}
 			if ignored {
 				continue
 			}
-			changeList = append(changeList, change{FieldName: valC.Type().Field(i).Name, Description: description})
+			changeList = append(changeList, change{FieldName: valC.Type().Field(i).Name, Description: description, Before: before, After: after})
 		}
 	} else {
 		return nil, fmt.Errorf("unhandled change type: %v", valC.Type())
@@ -19,6 +19,8 @@ package fi
 import (
 	"reflect"
 	"testing"
+
+	"k8s.io/kops/pkg/assets"
 )
 
 func Test_tryResourceAsString(t *testing.T) {
@@ -48,3 +50,107 @@ func Test_tryResourceAsString(t *testing.T) {
 		}
 	}
 }
+
+type testPlanTask struct {
+	Name      *string
+	Lifecycle *Lifecycle
+
+	Size     *int64
+	Contents Resource
+}
+
+var _ HasLifecycle = &testPlanTask{}
+
+func (e *testPlanTask) Run(c *Context) error {
+	return nil
+}
+
+func (e *testPlanTask) GetLifecycle() *Lifecycle {
+	return e.Lifecycle
+}
+
+func (e *testPlanTask) SetLifecycle(lifecycle Lifecycle) {
+	e.Lifecycle = &lifecycle
+}
+
+type testPlanDeletion struct {
+	item string
+}
+
+func (d *testPlanDeletion) Delete(target Target) error {
+	return nil
+}
+
+func (d *testPlanDeletion) TaskName() string {
+	return "testPlanTask"
+}
+
+func (d *testPlanDeletion) Item() string {
+	return d.item
+}
+
+func TestBuildPlan(t *testing.T) {
+	lifecycle := LifecycleSync
+
+	created := &testPlanTask{Name: String("created"), Lifecycle: &lifecycle, Size: Int64(10), Contents: NewStringResource("hello")}
+	updatedA := &testPlanTask{Name: String("updated"), Lifecycle: &lifecycle, Size: Int64(1)}
+	updatedE := &testPlanTask{Name: String("updated"), Lifecycle: &lifecycle, Size: Int64(2)}
+
+	taskMap := map[string]Task{
+		"testPlanTask/created": created,
+		"testPlanTask/updated": updatedE,
+	}
+
+	target := NewDryRunTarget(&assets.AssetBuilder{}, nil)
+	var nilTask *testPlanTask
+	if err := target.Render(nilTask, created, created); err != nil {
+		t.Fatalf("error rendering: %v", err)
+	}
+	if err := target.Render(updatedA, updatedE, &testPlanTask{Size: Int64(2)}); err != nil {
+		t.Fatalf("error rendering: %v", err)
+	}
+	target.Delete(&testPlanDeletion{item: "b"})
+	target.Delete(&testPlanDeletion{item: "a"})
+
+	plan, err := target.BuildPlan(taskMap)
+	if err != nil {
+		t.Fatalf("error building plan: %v", err)
+	}
+
+	expected := &Plan{
+		Changes: []*PlanChange{
+			{
+				Type:      "testPlanTask",
+				Name:      "created",
+				Lifecycle: LifecycleSync,
+				Action:    PlanActionCreate,
+				// The contents are a resource, which is not shown on create
+				Fields: []*PlanField{{Name: "Size", After: "10"}},
+			},
+			{
+				Type:      "testPlanTask",
+				Name:      "updated",
+				Lifecycle: LifecycleSync,
+				Action:    PlanActionUpdate,
+				Fields:    []*PlanField{{Name: "Size", Before: "1", After: "2"}},
+			},
+		},
+		Deletions: []*PlanDeletion{
+			{Type: "testPlanTask", Item: "a"},
+			{Type: "testPlanTask", Item: "b"},
+		},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		for _, c := range plan.Changes {
+			t.Logf("change: %+v", *c)
+			for _, f := range c.Fields {
+				t.Logf("  field: %+v", *f)
+			}
+		}
+		for _, d := range plan.Deletions {
+			t.Logf("deletion: %+v", *d)
+		}
+		t.Fatalf("unexpected plan")
+	}
+}
@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/golang/glog"
+	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/backoff"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/nodeup/cloudinit"
@@ -31,24 +33,35 @@ import (
 	"k8s.io/kops/util/pkg/hashing"
 )
 
-const dockerService = "docker.service"
+const (
+	dockerService     = "docker.service"
+	containerdService = "containerd.service"
+)
 
 // LoadImageTask is responsible for downloading a docker image
 type LoadImageTask struct {
 	Source string
 	Hash   string
+	// Runtime is the container runtime the image is loaded into: docker (the default) or containerd
+	Runtime string
+	// ContainerdBinDir is the directory holding the containerd binaries, used to locate ctr
+	ContainerdBinDir string
 }
 
 var _ fi.Task = &LoadImageTask{}
 var _ fi.HasDependencies = &LoadImageTask{}
 
 func (t *LoadImageTask) GetDependencies(tasks map[string]fi.Task) []fi.Task {
-	// LoadImageTask depends on the docker service to ensure we
-	// sideload images after docker is completely updated and
+	// LoadImageTask depends on the container runtime service to ensure we
+	// sideload images after the runtime is completely updated and
 	// configured.
+	serviceName := dockerService
+	if t.Runtime == kops.ContainerRuntimeContainerd {
+		serviceName = containerdService
+	}
 	var deps []fi.Task
 	for _, v := range tasks {
-		if svc, ok := v.(*Service); ok && svc.Name == dockerService {
+		if svc, ok := v.(*Service); ok && svc.Name == serviceName {
 			deps = append(deps, v)
 		}
 	}
@@ -88,15 +101,19 @@ func (_ *LoadImageTask) RenderLocal(t *local.LocalTarget, a, e, changes *LoadIma
 		return err
 	}
 
-	// Load the image into docker
+	// Load the image into the container runtime
 	args := []string{"docker", "load", "-i", localFile}
+	if e.Runtime == kops.ContainerRuntimeContainerd {
+		// The kubelet only sees images in the k8s.io namespace
+		args = []string{filepath.Join(e.ContainerdBinDir, "ctr"), "--namespace", "k8s.io", "images", "import", localFile}
+	}
 	human := strings.Join(args, " ")
 
 	glog.Infof("running command %s", human)
 	cmd := exec.Command(args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("error loading docker image with '%s': %v: %s", human, err, string(output))
+		return fmt.Errorf("error loading image with '%s': %v: %s", human, err, string(output))
 	}
 
 	return nil
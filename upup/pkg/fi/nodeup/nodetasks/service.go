@@ -68,7 +68,7 @@ func (p *Service) GetDependencies(tasks map[string]fi.Task) []fi.Task {
 		// We assume that services depend on everything except for
 		// LoadImageTask. If there are any LoadImageTasks (e.g. we're
 		// launching a custom Kubernetes build), they all depend on
-		// the "docker.service" Service task.
+		// the container runtime Service task.
 		switch v.(type) {
 		case *File, *Package, *UpdatePackages, *UserTask, *GroupTask, *MountDiskTask:
 			deps = append(deps, v)
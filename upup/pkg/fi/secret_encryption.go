@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/envelope"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// EncryptSecretData encrypts the contents of a file holding secrets or private keys,
+// if encryption of the state store is enabled with KOPS_STATE_ENCRYPTION
+func EncryptSecretData(data []byte) ([]byte, error) {
+	provider, err := envelope.ProviderFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return data, nil
+	}
+	return envelope.Encrypt(provider, data)
+}
+
+// ReadSecretFile reads a file holding secrets or private keys, decrypting it if it is encrypted.
+// Plaintext files are returned as they are; they are only encrypted by EncryptSecretFile.
+func ReadSecretFile(p vfs.Path) ([]byte, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	return decryptSecretData(data)
+}
+
+// decryptSecretData decrypts the contents of a file written by EncryptSecretData; plaintext is returned unchanged
+func decryptSecretData(data []byte) ([]byte, error) {
+	provider, err := envelope.ProviderFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Decrypt(provider, data)
+}
+
+// EncryptSecretFile rewrites a plaintext file holding secrets or private keys, encrypted with the provider.
+// The file is only replaced if it is unchanged since it was read.
+// It returns false if the file was already encrypted.
+func EncryptSecretFile(p vfs.Path, cluster *kops.Cluster, provider envelope.KeyProvider) (bool, error) {
+	data, version, err := vfs.ReadFileWithVersion(p)
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %v", p, err)
+	}
+	if envelope.IsEncrypted(data) {
+		return false, nil
+	}
+
+	encrypted, err := envelope.Encrypt(provider, data)
+	if err != nil {
+		return false, fmt.Errorf("error encrypting %s: %v", p, err)
+	}
+	acl, err := acls.GetACL(p, cluster)
+	if err != nil {
+		return false, err
+	}
+	if _, err := vfs.WriteFileIfMatch(p, bytes.NewReader(encrypted), acl, version); err != nil {
+		return false, fmt.Errorf("error writing %s: %v", p, err)
+	}
+	return true, nil
+}
+
+// PlaintextSecretFiles returns the files holding secrets or private keys which are not encrypted:
+// the secrets in secretStore, and the private keys and keyset bundles in keyStore
+func PlaintextSecretFiles(keyStore vfs.Path, secretStore vfs.Path) ([]vfs.Path, error) {
+	var plaintext []vfs.Path
+	for _, dir := range []vfs.Path{keyStore.Join("private"), secretStore} {
+		files, err := dir.ReadTree()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error listing %s: %v", dir, err)
+		}
+		for _, p := range files {
+			data, err := p.ReadFile()
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %v", p, err)
+			}
+			if !envelope.IsEncrypted(data) {
+				plaintext = append(plaintext, p)
+			}
+		}
+	}
+	return plaintext, nil
+}
@@ -199,11 +199,12 @@ func (c *VFSSecretStore) ReplaceSecret(id string, secret *fi.Secret) (*fi.Secret
 }
 
 func (c *VFSSecretStore) loadSecret(p vfs.Path) (*fi.Secret, error) {
-	data, err := p.ReadFile()
+	data, err := fi.ReadSecretFile(p)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
+		return nil, fmt.Errorf("error reading secret from %q: %v", p, err)
 	}
 	s := &fi.Secret{}
 	err = json.Unmarshal(data, s)
@@ -219,6 +220,9 @@ func createSecret(s *fi.Secret, p vfs.Path, acl vfs.ACL, replace bool) error {
 	if err != nil {
 		return fmt.Errorf("error serializing secret: %v", err)
 	}
+	if data, err = fi.EncryptSecretData(data); err != nil {
+		return fmt.Errorf("error encrypting secret: %v", err)
+	}
 
 	rs := bytes.NewReader(data)
 	if replace {
@@ -247,33 +247,61 @@ func (c *VFSCAStore) parseKeysetYaml(data []byte) (*kops.Keyset, KeysetFormat, e
 // Returns (nil, nil) if the file is not found
 // Bundles avoid the need for a list-files permission, which can be tricky on e.g. GCE
 func (c *VFSCAStore) loadKeysetBundle(p vfs.Path) (*keyset, error) {
-	data, err := p.ReadFile()
+	keyset, _, err := c.loadKeysetBundleWithVersion(p)
+	return keyset, err
+}
+
+// loadKeysetBundleWithVersion is loadKeysetBundle, but also returns the version of the bundle file.
+// A bundle holding private keys is decrypted if it is encrypted.
+func (c *VFSCAStore) loadKeysetBundleWithVersion(p vfs.Path) (*keyset, string, error) {
+	data, version, err := vfs.ReadFileWithVersion(p)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, "", nil
 		} else {
-			return nil, fmt.Errorf("unable to read bundle %q: %v", p, err)
+			return nil, "", fmt.Errorf("unable to read bundle %q: %v", p, err)
 		}
 	}
 
-	o, format, err := c.parseKeysetYaml(data)
+	plaintext, err := decryptSecretData(data)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing bundle %q: %v", p, err)
+		return nil, "", fmt.Errorf("unable to decrypt bundle %q: %v", p, err)
+	}
+
+	o, format, err := c.parseKeysetYaml(plaintext)
+	if err != nil {
+		return nil, "", fmt.Errorf("error parsing bundle %q: %v", p, err)
 	}
 
 	keyset, err := parseKeyset(o)
 	if err != nil {
-		return nil, fmt.Errorf("error mapping bundle %q: %v", p, err)
+		return nil, "", fmt.Errorf("error mapping bundle %q: %v", p, err)
 	}
 
 	keyset.format = format
-	return keyset, nil
+	return keyset, version, nil
+}
+
+// loadKeysetPrimaryId returns the pinned primary recorded in the keyset bundle, if any, and the version of the bundle.
+// The individual files don't record the primary, so we read it back from the bundle when rewriting it.
+// The bundle must be rewritten with that version, so that a concurrent change to the keyset is not lost;
+// it is read before the individual files, so that any change after it is detected.
+func (c *VFSCAStore) loadKeysetPrimaryId(p vfs.Path) (string, string, error) {
+	bundle, version, err := c.loadKeysetBundleWithVersion(p.Join("keyset.yaml"))
+	if err != nil {
+		return "", "", err
+	}
+	if bundle == nil {
+		return "", version, nil
+	}
+	return bundle.primaryId, version, nil
 }
 
 func (k *keyset) ToAPIObject(name string, includePrivateKeyMaterial bool) (*kops.Keyset, error) {
 	o := &kops.Keyset{}
 	o.Name = name
 	o.Spec.Type = kops.SecretTypeKeypair
+	o.Spec.PrimaryId = k.primaryId
 
 	for _, ki := range k.items {
 		oki := kops.KeysetItem{
@@ -302,8 +330,9 @@ func (k *keyset) ToAPIObject(name string, includePrivateKeyMaterial bool) (*kops
 	return o, nil
 }
 
-// writeKeysetBundle writes a keyset bundle to VFS
-func (c *VFSCAStore) writeKeysetBundle(p vfs.Path, name string, keyset *keyset, includePrivateKeyMaterial bool) error {
+// writeKeysetBundle writes a keyset bundle to VFS, if it is still at expectedVersion (empty if it did not exist).
+// If the bundle was changed since it was read, a *vfs.VersionConflictError is returned.
+func (c *VFSCAStore) writeKeysetBundle(p vfs.Path, name string, keyset *keyset, includePrivateKeyMaterial bool, expectedVersion string) error {
 	p = p.Join("keyset.yaml")
 
 	o, err := keyset.ToAPIObject(name, includePrivateKeyMaterial)
@@ -315,12 +344,23 @@ func (c *VFSCAStore) writeKeysetBundle(p vfs.Path, name string, keyset *keyset,
 	if err != nil {
 		return err
 	}
+	if includePrivateKeyMaterial {
+		if objectData, err = EncryptSecretData(objectData); err != nil {
+			return err
+		}
+	}
 
 	acl, err := acls.GetACL(p, c.cluster)
 	if err != nil {
 		return err
 	}
-	return p.WriteFile(bytes.NewReader(objectData), acl)
+	if _, err := vfs.WriteFileIfMatch(p, bytes.NewReader(objectData), acl, expectedVersion); err != nil {
+		if vfs.IsVersionConflict(err) {
+			return err
+		}
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	return nil
 }
 
 // serializeKeysetBundle converts a keyset bundle to yaml, for writing to VFS
@@ -692,6 +732,9 @@ func mirrorKeyset(cluster *kops.Cluster, basedir vfs.Path, keyset *kops.Keyset)
 			if err != nil {
 				return err
 			}
+			if data, err = EncryptSecretData(data); err != nil {
+				return err
+			}
 			p := basedir.Join("private", keyset.Name, "keyset.yaml")
 			acl, err := acls.GetACL(p, cluster)
 			if err != nil {
@@ -881,7 +924,7 @@ func (c *VFSCAStore) loadPrivateKeys(p vfs.Path, useBundle bool) (*keyset, error
 }
 
 func (c *VFSCAStore) loadOnePrivateKey(p vfs.Path) (*pki.PrivateKey, error) {
-	data, err := p.ReadFile()
+	data, err := ReadSecretFile(p)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -961,6 +1004,11 @@ func (c *VFSCAStore) storePrivateKey(name string, ki *keysetItem) error {
 	// Write the bundle
 	{
 		p := c.buildPrivateKeyPoolPath(name)
+		primaryId, version, err := c.loadKeysetPrimaryId(p)
+		if err != nil {
+			return err
+		}
+
 		ks, err := c.loadPrivateKeys(p, false)
 		if err != nil {
 			return err
@@ -973,9 +1021,10 @@ func (c *VFSCAStore) storePrivateKey(name string, ki *keysetItem) error {
 			ks.items = make(map[string]*keysetItem)
 		}
 		ks.items[ki.id] = ki
+		ks.primaryId = primaryId
 
-		if err := c.writeKeysetBundle(p, name, ks, true); err != nil {
-			return fmt.Errorf("error writing bundle: %v", err)
+		if err := c.writeKeysetBundle(p, name, ks, true, version); err != nil {
+			return err
 		}
 	}
 
@@ -985,13 +1034,17 @@ func (c *VFSCAStore) storePrivateKey(name string, ki *keysetItem) error {
 		if _, err := ki.privateKey.WriteTo(&data); err != nil {
 			return err
 		}
+		encrypted, err := EncryptSecretData(data.Bytes())
+		if err != nil {
+			return err
+		}
 
 		p := c.buildPrivateKeyPath(name, ki.id)
 		acl, err := acls.GetACL(p, c.cluster)
 		if err != nil {
 			return err
 		}
-		return p.WriteFile(bytes.NewReader(data.Bytes()), acl)
+		return p.WriteFile(bytes.NewReader(encrypted), acl)
 	}
 }
 
@@ -1003,6 +1056,11 @@ func (c *VFSCAStore) storeCertificate(name string, ki *keysetItem) error {
 	// Write the bundle
 	{
 		p := c.buildCertificatePoolPath(name)
+		primaryId, version, err := c.loadKeysetPrimaryId(p)
+		if err != nil {
+			return err
+		}
+
 		ks, err := c.loadCertificates(p, false)
 		if err != nil {
 			return err
@@ -1015,9 +1073,10 @@ func (c *VFSCAStore) storeCertificate(name string, ki *keysetItem) error {
 			ks.items = make(map[string]*keysetItem)
 		}
 		ks.items[ki.id] = ki
+		ks.primaryId = primaryId
 
-		if err := c.writeKeysetBundle(p, name, ks, false); err != nil {
-			return fmt.Errorf("error writing bundle: %v", err)
+		if err := c.writeKeysetBundle(p, name, ks, false, version); err != nil {
+			return err
 		}
 	}
 
@@ -1041,6 +1100,11 @@ func (c *VFSCAStore) deletePrivateKey(name string, id string) (bool, error) {
 	// Update the bundle
 	{
 		p := c.buildPrivateKeyPoolPath(name)
+		primaryId, version, err := c.loadKeysetPrimaryId(p)
+		if err != nil {
+			return false, err
+		}
+
 		ks, err := c.loadPrivateKeys(p, false)
 		if err != nil {
 			return false, err
@@ -1051,8 +1115,13 @@ func (c *VFSCAStore) deletePrivateKey(name string, id string) (bool, error) {
 		}
 		delete(ks.items, id)
 
-		if err := c.writeKeysetBundle(p, name, ks, true); err != nil {
-			return false, fmt.Errorf("error writing bundle: %v", err)
+		ks.primaryId = primaryId
+		if ks.primaryId == id {
+			ks.primaryId = ""
+		}
+
+		if err := c.writeKeysetBundle(p, name, ks, true, version); err != nil {
+			return false, err
 		}
 	}
 
@@ -1070,7 +1139,12 @@ func (c *VFSCAStore) deletePrivateKey(name string, id string) (bool, error) {
 func (c *VFSCAStore) deleteCertificate(name string, id string) (bool, error) {
 	// Update the bundle
 	{
-		p := c.buildPrivateKeyPoolPath(name)
+		p := c.buildCertificatePoolPath(name)
+		primaryId, version, err := c.loadKeysetPrimaryId(p)
+		if err != nil {
+			return false, err
+		}
+
 		ks, err := c.loadCertificates(p, false)
 		if err != nil {
 			return false, err
@@ -1081,8 +1155,13 @@ func (c *VFSCAStore) deleteCertificate(name string, id string) (bool, error) {
 		}
 		delete(ks.items, id)
 
-		if err := c.writeKeysetBundle(p, name, ks, false); err != nil {
-			return false, fmt.Errorf("error writing bundle: %v", err)
+		ks.primaryId = primaryId
+		if ks.primaryId == id {
+			ks.primaryId = ""
+		}
+
+		if err := c.writeKeysetBundle(p, name, ks, false, version); err != nil {
+			return false, err
 		}
 	}
 
@@ -1152,7 +1231,7 @@ func (c *VFSCAStore) FindSSHPublicKeys(name string) ([]*kops.SSHCredential, erro
 }
 
 func (c *VFSCAStore) loadData(p vfs.Path) (*pki.PrivateKey, error) {
-	data, err := p.ReadFile()
+	data, err := ReadSecretFile(p)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -1196,6 +1275,57 @@ func (c *VFSCAStore) DeleteKeysetItem(item *kops.Keyset, id string) error {
 	}
 }
 
+// SetPrimaryKeysetItem implements CAStore::SetPrimaryKeysetItem
+func (c *VFSCAStore) SetPrimaryKeysetItem(item *kops.Keyset, id string) error {
+	switch item.Spec.Type {
+	case kops.SecretTypeKeypair:
+		_, certificatesVersion, err := c.loadKeysetPrimaryId(c.buildCertificatePoolPath(item.Name))
+		if err != nil {
+			return err
+		}
+		_, privateKeysVersion, err := c.loadKeysetPrimaryId(c.buildPrivateKeyPoolPath(item.Name))
+		if err != nil {
+			return err
+		}
+
+		certificates, err := c.loadCertificates(c.buildCertificatePoolPath(item.Name), false)
+		if err != nil {
+			return fmt.Errorf("error loading certificates: %v", err)
+		}
+		if certificates == nil {
+			return fmt.Errorf("keyset %q not found", item.Name)
+		}
+		if id != "" && certificates.items[id] == nil {
+			return fmt.Errorf("keyset item %q not found in keyset %q", id, item.Name)
+		}
+
+		privateKeys, err := c.loadPrivateKeys(c.buildPrivateKeyPoolPath(item.Name), false)
+		if err != nil {
+			return fmt.Errorf("error loading private keys: %v", err)
+		}
+		if privateKeys != nil {
+			privateKeys.primaryId = id
+			if err := c.writeKeysetBundle(c.buildPrivateKeyPoolPath(item.Name), item.Name, privateKeys, true, privateKeysVersion); err != nil {
+				return err
+			}
+		}
+
+		certificates.primaryId = id
+		if err := c.writeKeysetBundle(c.buildCertificatePoolPath(item.Name), item.Name, certificates, false, certificatesVersion); err != nil {
+			return err
+		}
+
+		c.mutex.Lock()
+		delete(c.cachedCAs, item.Name)
+		c.mutex.Unlock()
+
+		return nil
+
+	default:
+		return fmt.Errorf("setting the primary of keystore items of type %v not supported", item.Spec.Type)
+	}
+}
+
 func (c *VFSCAStore) DeleteSSHCredential(item *kops.SSHCredential) error {
 	if item.Spec.PublicKey == "" {
 		return fmt.Errorf("must specific public key to delete SSHCredential")
@@ -17,12 +17,21 @@ limitations under the License.
 package fi
 
 import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"k8s.io/kops/pkg/envelope"
 	"k8s.io/kops/pkg/pki"
 	"k8s.io/kops/util/pkg/vfs"
 )
@@ -215,3 +224,164 @@ spec:
 	}
 
 }
+
+func TestVFSCAStoreWriteKeysetBundleConflict(t *testing.T) {
+	vfs.Context.ResetMemfsContext(true)
+
+	basePath, err := vfs.Context.BuildVfsPath("memfs://tests")
+	if err != nil {
+		t.Fatalf("error building vfspath: %v", err)
+	}
+
+	s := &VFSCAStore{
+		basedir:   basePath,
+		cachedCAs: make(map[string]*cachedEntry),
+	}
+
+	p := s.buildCertificatePoolPath("ca")
+	ks := &keyset{items: make(map[string]*keysetItem)}
+
+	if err := s.writeKeysetBundle(p, "ca", ks, false, ""); err != nil {
+		t.Fatalf("error creating bundle: %v", err)
+	}
+	if err := s.writeKeysetBundle(p, "ca", ks, false, ""); !vfs.IsVersionConflict(err) {
+		t.Fatalf("expected conflict creating existing bundle, got %v", err)
+	}
+
+	_, version, err := s.loadKeysetPrimaryId(p)
+	if err != nil {
+		t.Fatalf("error reading bundle: %v", err)
+	}
+
+	ks.primaryId = "1"
+	if err := s.writeKeysetBundle(p, "ca", ks, false, version); err != nil {
+		t.Fatalf("error updating bundle: %v", err)
+	}
+
+	// The bundle has changed since version was read
+	ks.primaryId = "2"
+	if err := s.writeKeysetBundle(p, "ca", ks, false, version); !vfs.IsVersionConflict(err) {
+		t.Fatalf("expected conflict updating changed bundle, got %v", err)
+	}
+
+	primaryId, _, err := s.loadKeysetPrimaryId(p)
+	if err != nil {
+		t.Fatalf("error reading bundle: %v", err)
+	}
+	if primaryId != "1" {
+		t.Fatalf("expected primary 1 after conflict, was %q", primaryId)
+	}
+}
+
+func TestVFSCAStoreEncryption(t *testing.T) {
+	vfs.Context.ResetMemfsContext(true)
+
+	basePath, err := vfs.Context.BuildVfsPath("memfs://tests")
+	if err != nil {
+		t.Fatalf("error building vfspath: %v", err)
+	}
+
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	template := BuildCAX509Template()
+	template.Subject = pkix.Name{CommonName: "kubernetes"}
+	template.SerialNumber = big.NewInt(1)
+	cert, err := pki.SignNewCertificate(privateKey, template, nil, nil)
+	if err != nil {
+		t.Fatalf("error signing certificate: %v", err)
+	}
+
+	// Write a plaintext keystore, as before encryption was enabled
+	os.Unsetenv(envelope.EnvStateEncryption)
+	s := &VFSCAStore{
+		basedir:   basePath,
+		cachedCAs: make(map[string]*cachedEntry),
+	}
+	if err := s.StoreKeypair("ca", cert, privateKey); err != nil {
+		t.Fatalf("error from StoreKeypair: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "castore")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyfile := filepath.Join(dir, "state.key")
+	if err := ioutil.WriteFile(keyfile, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))), 0600); err != nil {
+		t.Fatalf("error writing keyfile: %v", err)
+	}
+	os.Setenv(envelope.EnvStateEncryption, "keyfile:"+keyfile)
+	defer os.Unsetenv(envelope.EnvStateEncryption)
+
+	// Reading the keystore does not encrypt it
+	s = &VFSCAStore{
+		basedir:   basePath,
+		cachedCAs: make(map[string]*cachedEntry),
+	}
+	if _, err := s.FindPrivateKey("ca"); err != nil {
+		t.Fatalf("error reading private key: %v", err)
+	}
+
+	plaintext, err := PlaintextSecretFiles(basePath, basePath.Join("secrets"))
+	if err != nil {
+		t.Fatalf("error listing plaintext files: %v", err)
+	}
+	var plaintextPaths []string
+	for _, p := range plaintext {
+		plaintextPaths = append(plaintextPaths, p.Path())
+	}
+	sort.Strings(plaintextPaths)
+	if expected := []string{"memfs://tests/private/ca/1.key", "memfs://tests/private/ca/keyset.yaml"}; !reflect.DeepEqual(plaintextPaths, expected) {
+		t.Fatalf("expected plaintext files %v, got %v", expected, plaintextPaths)
+	}
+
+	provider, err := envelope.ProviderFromEnvironment()
+	if err != nil {
+		t.Fatalf("error building key provider: %v", err)
+	}
+	for _, p := range plaintext {
+		if encrypted, err := EncryptSecretFile(p, nil, provider); err != nil || !encrypted {
+			t.Fatalf("error encrypting %s: encrypted=%v, %v", p, encrypted, err)
+		}
+		if encrypted, err := EncryptSecretFile(p, nil, provider); err != nil || encrypted {
+			t.Fatalf("expected %s to already be encrypted: encrypted=%v, %v", p, encrypted, err)
+		}
+	}
+
+	encrypted := map[string]bool{
+		"memfs://tests/private/ca/keyset.yaml": true,
+		"memfs://tests/private/ca/1.key":       true,
+		"memfs://tests/issued/ca/keyset.yaml":  false,
+		"memfs://tests/issued/ca/1.crt":        false,
+	}
+	for p, expected := range encrypted {
+		path, err := vfs.Context.BuildVfsPath(p)
+		if err != nil {
+			t.Fatalf("error building vfspath: %v", err)
+		}
+		data, err := path.ReadFile()
+		if err != nil {
+			t.Fatalf("error reading %s: %v", p, err)
+		}
+		if envelope.IsEncrypted(data) != expected {
+			t.Errorf("expected %s encrypted=%v, was %q", p, expected, data)
+		}
+	}
+
+	// The encrypted keystore can still be read
+	s = &VFSCAStore{
+		basedir:   basePath,
+		cachedCAs: make(map[string]*cachedEntry),
+	}
+	found, err := s.FindPrivateKey("ca")
+	if err != nil {
+		t.Fatalf("error reading encrypted private key: %v", err)
+	}
+	expected, _ := privateKey.AsString()
+	actual, _ := found.AsString()
+	if actual != expected {
+		t.Fatalf("private key did not round-trip")
+	}
+}
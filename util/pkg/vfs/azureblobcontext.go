@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// azureStorageAPIVersion is the version of the Blob service REST API we use
+const azureStorageAPIVersion = "2018-03-28"
+
+// AzureBlobClient is a client for the Azure Blob Storage REST API,
+// authenticated with the Shared Key of the storage account or with a shared access signature (SAS)
+type AzureBlobClient struct {
+	account string
+	key     []byte
+	// sasToken is the query of a shared access signature, used instead of the key when set
+	sasToken   url.Values
+	endpoint   *url.URL
+	httpClient *http.Client
+}
+
+// NewAzureBlobClient builds a client from the AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY environment variables.
+// AZURE_STORAGE_SAS_TOKEN authenticates with a shared access signature instead of the key, as the nodes do.
+// AZURE_STORAGE_ENDPOINT overrides the blob endpoint of the account, for example for sovereign clouds.
+func NewAzureBlobClient() (*AzureBlobClient, error) {
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT cannot be empty")
+	}
+	endpoint := os.Getenv("AZURE_STORAGE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+
+	if sasToken := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sasToken != "" {
+		return newAzureBlobSASClient(account, sasToken, endpoint, http.DefaultClient)
+	}
+
+	key := os.Getenv("AZURE_STORAGE_KEY")
+	if key == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN must be set")
+	}
+	return newAzureBlobClient(account, key, endpoint, http.DefaultClient)
+}
+
+func newAzureBlobClient(account string, key string, endpoint string, httpClient *http.Client) (*AzureBlobClient, error) {
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("azure storage key is not valid base64: %v", err)
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure storage endpoint %q: %v", endpoint, err)
+	}
+
+	return &AzureBlobClient{
+		account:    account,
+		key:        decodedKey,
+		endpoint:   u,
+		httpClient: httpClient,
+	}, nil
+}
+
+func newAzureBlobSASClient(account string, sasToken string, endpoint string, httpClient *http.Client) (*AzureBlobClient, error) {
+	token, err := url.ParseQuery(strings.TrimPrefix(sasToken, "?"))
+	if err != nil {
+		return nil, fmt.Errorf("azure storage SAS token is not a valid query string: %v", err)
+	}
+	if token.Get("sig") == "" {
+		return nil, fmt.Errorf("azure storage SAS token has no signature")
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure storage endpoint %q: %v", endpoint, err)
+	}
+
+	return &AzureBlobClient{
+		account:    account,
+		sasToken:   token,
+		endpoint:   u,
+		httpClient: httpClient,
+	}, nil
+}
+
+// azureBlobError is an error response from the Blob service
+type azureBlobError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *azureBlobError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("azure blob storage returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("azure blob storage returned status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func isAzureBlobStatus(err error, statusCode int) bool {
+	azureErr, ok := err.(*azureBlobError)
+	return ok && azureErr.StatusCode == statusCode
+}
+
+// do performs a request against a container, or a blob if blob is not empty.
+// Responses with an error status are returned as an *azureBlobError, with the body consumed.
+func (c *AzureBlobClient) do(method string, container string, blob string, query url.Values, headers http.Header, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = c.endpoint.Path + "/" + container
+	if blob != "" {
+		u.Path += "/" + blob
+	}
+	if c.sasToken != nil {
+		signed := url.Values{}
+		for k, v := range query {
+			signed[k] = v
+		}
+		for k, v := range c.sasToken {
+			signed[k] = v
+		}
+		query = signed
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+	req.ContentLength = int64(len(body))
+	if len(body) != 0 {
+		req.Header.Set("Content-Length", fmt.Sprintf("%d", len(body)))
+	}
+
+	if c.sasToken == nil {
+		req.Header.Set("Authorization", "SharedKey "+c.account+":"+azureSharedKeySignature(c.account, c.key, req))
+	}
+
+	// The path is logged rather than the URL, which can include the signature of a SAS token
+	glog.V(8).Infof("azure blob storage request: %s %s", method, u.Path)
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = u.Path
+		}
+		return nil, err
+	}
+
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+		azureErr := &azureBlobError{StatusCode: response.StatusCode}
+		if data, err := ioutil.ReadAll(response.Body); err == nil && len(data) != 0 {
+			if err := xml.Unmarshal(data, azureErr); err != nil {
+				glog.V(2).Infof("unable to parse azure blob storage error %q: %v", string(data), err)
+			}
+		}
+		return nil, azureErr
+	}
+
+	return response, nil
+}
+
+// azureSharedKeySignature signs a request with the Shared Key of a storage account.
+// See https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func azureSharedKeySignature(account string, key []byte, req *http.Request) string {
+	contentLength := req.Header.Get("Content-Length")
+	if contentLength == "0" {
+		contentLength = ""
+	}
+
+	var b bytes.Buffer
+	for _, s := range []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date; we always send x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	} {
+		b.WriteString(s)
+		b.WriteString("\n")
+	}
+
+	// Canonicalized headers
+	var msHeaders []string
+	for k := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, k := range msHeaders {
+		b.WriteString(k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n")
+	}
+
+	// Canonicalized resource
+	b.WriteString("/" + account + req.URL.EscapedPath())
+	query := req.URL.Query()
+	var params []string
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := query[k]
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b.Bytes())
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,453 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/util/pkg/hashing"
+)
+
+// AzureBlobPath is a vfs path for Azure Blob Storage
+type AzureBlobPath struct {
+	client    *AzureBlobClient
+	container string
+	key       string
+	// md5Hash is the base64 Content-MD5 of the blob, if known from a listing
+	md5Hash string
+}
+
+var _ Path = &AzureBlobPath{}
+var _ HasHash = &AzureBlobPath{}
+var _ VersionedPath = &AzureBlobPath{}
+
+// AzureBlobACL is an ACL for blobs on Azure Blob Storage.
+// Blobs don't have their own ACLs: anonymous read access is a setting of the container.
+type AzureBlobACL struct {
+	// PublicRead requires the container to allow anonymous read access to its blobs
+	PublicRead bool
+}
+
+var _ ACL = &AzureBlobACL{}
+
+// azureBlobReadBackoff is the backoff strategy for Azure Blob Storage read retries
+var azureBlobReadBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   1.5,
+	Jitter:   0.1,
+	Steps:    4,
+}
+
+// azureBlobWriteBackoff is the backoff strategy for Azure Blob Storage write retries
+var azureBlobWriteBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   1.5,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+func NewAzureBlobPath(client *AzureBlobClient, container string, key string) *AzureBlobPath {
+	container = strings.TrimSuffix(container, "/")
+	key = strings.TrimPrefix(key, "/")
+
+	return &AzureBlobPath{
+		client:    client,
+		container: container,
+		key:       key,
+	}
+}
+
+func (p *AzureBlobPath) Container() string {
+	return p.container
+}
+
+func (p *AzureBlobPath) Key() string {
+	return p.key
+}
+
+func (p *AzureBlobPath) String() string {
+	return p.Path()
+}
+
+func (p *AzureBlobPath) Path() string {
+	return "azureblob://" + p.container + "/" + p.key
+}
+
+func (p *AzureBlobPath) Base() string {
+	return path.Base(p.key)
+}
+
+func (p *AzureBlobPath) Join(relativePath ...string) Path {
+	args := []string{p.key}
+	args = append(args, relativePath...)
+	joined := path.Join(args...)
+	return &AzureBlobPath{
+		client:    p.client,
+		container: p.container,
+		key:       joined,
+	}
+}
+
+// WriteTo implements io.WriterTo
+func (p *AzureBlobPath) WriteTo(out io.Writer) (int64, error) {
+	_, n, err := p.writeTo(out)
+	return n, err
+}
+
+// writeTo copies the blob to out, returning its ETag
+func (p *AzureBlobPath) writeTo(out io.Writer) (string, int64, error) {
+	glog.V(4).Infof("Reading file %q", p)
+
+	response, err := p.client.do(http.MethodGet, p.container, p.key, nil, nil, nil)
+	if err != nil {
+		if isAzureBlobStatus(err, http.StatusNotFound) {
+			return "", 0, os.ErrNotExist
+		}
+		return "", 0, fmt.Errorf("error fetching %s: %v", p, err)
+	}
+	defer response.Body.Close()
+
+	n, err := io.Copy(out, response.Body)
+	if err != nil {
+		return "", n, fmt.Errorf("error reading %s: %v", p, err)
+	}
+	return response.Header.Get("ETag"), n, nil
+}
+
+func (p *AzureBlobPath) ReadFile() ([]byte, error) {
+	data, _, err := p.ReadFileWithVersion()
+	return data, err
+}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the ETag of the blob
+func (p *AzureBlobPath) ReadFileWithVersion() ([]byte, string, error) {
+	var b bytes.Buffer
+	var etag string
+	done, err := RetryWithBackoff(azureBlobReadBackoff, func() (bool, error) {
+		b.Reset()
+		var err error
+		etag, _, err = p.writeTo(&b)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Not recoverable
+				return true, err
+			}
+			return false, err
+		}
+		// Success!
+		return true, nil
+	})
+	if err != nil {
+		return nil, "", err
+	} else if done {
+		return b.Bytes(), etag, nil
+	} else {
+		// Shouldn't happen - we always return a non-nil error with false
+		return nil, "", wait.ErrWaitTimeout
+	}
+}
+
+func (p *AzureBlobPath) WriteFile(data io.ReadSeeker, acl ACL) error {
+	_, err := p.writeFile(data, acl, nil)
+	return err
+}
+
+// CreateFile writes the file contents, but only if the file does not already exist
+func (p *AzureBlobPath) CreateFile(data io.ReadSeeker, acl ACL) error {
+	headers := http.Header{}
+	headers.Set("If-None-Match", "*")
+	_, err := p.writeFile(data, acl, headers)
+	if err != nil && isAzureBlobPreconditionFailed(err) {
+		return os.ErrExist
+	}
+	return err
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch
+func (p *AzureBlobPath) WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	headers := http.Header{}
+	if expectedVersion == "" {
+		headers.Set("If-None-Match", "*")
+	} else {
+		headers.Set("If-Match", expectedVersion)
+	}
+	version, err := p.writeFile(data, acl, headers)
+	if err != nil && (isAzureBlobPreconditionFailed(err) || (expectedVersion != "" && isAzureBlobStatus(err, http.StatusNotFound))) {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+	return version, err
+}
+
+// writeFile writes the blob, adding the given conditional headers to the request, and returns its ETag
+func (p *AzureBlobPath) writeFile(data io.ReadSeeker, acl ACL, conditions http.Header) (string, error) {
+	if acl != nil {
+		azureACL, ok := acl.(*AzureBlobACL)
+		if !ok {
+			return "", fmt.Errorf("write to %s with ACL of unexpected type %T", p, acl)
+		}
+		if azureACL.PublicRead {
+			if err := p.checkContainerPublicRead(); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	etag := ""
+	done, err := RetryWithBackoff(azureBlobWriteBackoff, func() (bool, error) {
+		glog.V(4).Infof("Writing file %q", p)
+
+		if _, err := data.Seek(0, 0); err != nil {
+			return false, fmt.Errorf("error seeking to start of data stream for write to %s: %v", p, err)
+		}
+		body, err := ioutil.ReadAll(data)
+		if err != nil {
+			return false, fmt.Errorf("error reading from data stream: %v", err)
+		}
+
+		md5Hash, err := hashing.HashAlgorithmMD5.Hash(bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+
+		headers := http.Header{}
+		for k, v := range conditions {
+			headers[k] = v
+		}
+		headers.Set("Content-Type", "application/octet-stream")
+		headers.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5Hash.HashValue))
+		headers.Set("x-ms-blob-type", "BlockBlob")
+
+		response, err := p.client.do(http.MethodPut, p.container, p.key, nil, headers, body)
+		if err != nil {
+			if azureErr, ok := err.(*azureBlobError); ok && azureErr.StatusCode < 500 {
+				// Not recoverable
+				return true, err
+			}
+			return false, fmt.Errorf("error writing %s: %v", p, err)
+		}
+		response.Body.Close()
+		etag = response.Header.Get("ETag")
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	} else if done {
+		return etag, nil
+	} else {
+		// Shouldn't happen - we always return a non-nil error with false
+		return "", wait.ErrWaitTimeout
+	}
+}
+
+// checkContainerPublicRead returns an error unless the container allows anonymous read access to blobs
+func (p *AzureBlobPath) checkContainerPublicRead() error {
+	query := url.Values{}
+	query.Set("restype", "container")
+	response, err := p.client.do(http.MethodGet, p.container, "", query, nil, nil)
+	if err != nil {
+		return fmt.Errorf("error reading properties of container %q: %v", p.container, err)
+	}
+	response.Body.Close()
+
+	switch access := response.Header.Get("x-ms-blob-public-access"); access {
+	case "blob", "container":
+		return nil
+	default:
+		return fmt.Errorf("cannot write %s with public read access: container %q does not allow anonymous read access to blobs (set its access level to blob)", p, p.container)
+	}
+}
+
+func (p *AzureBlobPath) Remove() error {
+	done, err := RetryWithBackoff(azureBlobWriteBackoff, func() (bool, error) {
+		glog.V(8).Infof("removing file %s", p)
+
+		response, err := p.client.do(http.MethodDelete, p.container, p.key, nil, nil, nil)
+		if err != nil {
+			if isAzureBlobStatus(err, http.StatusNotFound) {
+				return true, os.ErrNotExist
+			}
+			return false, fmt.Errorf("error deleting %s: %v", p, err)
+		}
+		response.Body.Close()
+		return true, nil
+	})
+	if err != nil {
+		return err
+	} else if done {
+		return nil
+	} else {
+		// Shouldn't happen - we always return a non-nil error with false
+		return wait.ErrWaitTimeout
+	}
+}
+
+// ReadDir lists the files directly under the path
+func (p *AzureBlobPath) ReadDir() ([]Path, error) {
+	return p.listBlobs("/")
+}
+
+// ReadTree lists all the files under the path
+func (p *AzureBlobPath) ReadTree() ([]Path, error) {
+	// No delimiter for recursive search
+	return p.listBlobs("")
+}
+
+// azureBlobList is the result of the List Blobs operation
+type azureBlobList struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentMD5 string `xml:"Content-MD5"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	// BlobPrefixes are the directories, when listing with a delimiter
+	BlobPrefixes []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>BlobPrefix"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (p *AzureBlobPath) listBlobs(delimiter string) ([]Path, error) {
+	prefix := p.key
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	var paths []Path
+	marker := ""
+	for {
+		query := url.Values{}
+		query.Set("restype", "container")
+		query.Set("comp", "list")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		var list azureBlobList
+		done, err := RetryWithBackoff(azureBlobReadBackoff, func() (bool, error) {
+			response, err := p.client.do(http.MethodGet, p.container, "", query, nil, nil)
+			if err != nil {
+				if isAzureBlobStatus(err, http.StatusNotFound) {
+					return true, os.ErrNotExist
+				}
+				return false, fmt.Errorf("error listing %s: %v", p, err)
+			}
+			defer response.Body.Close()
+
+			list = azureBlobList{}
+			if err := xml.NewDecoder(response.Body).Decode(&list); err != nil {
+				return false, fmt.Errorf("error parsing listing of %s: %v", p, err)
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		} else if !done {
+			// Shouldn't happen - we always return a non-nil error with false
+			return nil, wait.ErrWaitTimeout
+		}
+
+		for _, blob := range list.Blobs {
+			if blob.Name == prefix {
+				// Tolerate a blob with the name of the directory, as we do on S3
+				continue
+			}
+			paths = append(paths, &AzureBlobPath{
+				client:    p.client,
+				container: p.container,
+				key:       blob.Name,
+				md5Hash:   blob.Properties.ContentMD5,
+			})
+		}
+		for _, d := range list.BlobPrefixes {
+			paths = append(paths, &AzureBlobPath{
+				client:    p.client,
+				container: p.container,
+				key:       strings.TrimSuffix(d.Name, "/"),
+			})
+		}
+
+		if list.NextMarker == "" {
+			break
+		}
+		marker = list.NextMarker
+	}
+
+	glog.V(8).Infof("Listed files in %v: %v", p, paths)
+	return paths, nil
+}
+
+func (p *AzureBlobPath) PreferredHash() (*hashing.Hash, error) {
+	return p.Hash(hashing.HashAlgorithmMD5)
+}
+
+// Hash returns the MD5 hash of the blob, from the Content-MD5 property which is set when we write it
+func (p *AzureBlobPath) Hash(a hashing.HashAlgorithm) (*hashing.Hash, error) {
+	if a != hashing.HashAlgorithmMD5 {
+		return nil, nil
+	}
+
+	md5Hash := p.md5Hash
+	if md5Hash == "" {
+		response, err := p.client.do(http.MethodHead, p.container, p.key, nil, nil, nil)
+		if err != nil {
+			if isAzureBlobStatus(err, http.StatusNotFound) {
+				return nil, os.ErrNotExist
+			}
+			return nil, fmt.Errorf("error reading properties of %s: %v", p, err)
+		}
+		response.Body.Close()
+		md5Hash = response.Header.Get("Content-MD5")
+		if md5Hash == "" {
+			// Blobs uploaded in blocks by other tools have no Content-MD5
+			return nil, nil
+		}
+	}
+
+	md5Bytes, err := base64.StdEncoding.DecodeString(md5Hash)
+	if err != nil {
+		return nil, fmt.Errorf("Content-MD5 of %s was not a valid MD5 sum: %q", p, md5Hash)
+	}
+
+	return &hashing.Hash{Algorithm: hashing.HashAlgorithmMD5, HashValue: md5Bytes}, nil
+}
+
+func isAzureBlobPreconditionFailed(err error) bool {
+	azureErr, ok := err.(*azureBlobError)
+	if !ok {
+		return false
+	}
+	// If-None-Match: * fails with 409 BlobAlreadyExists, other conditions with 412
+	return azureErr.StatusCode == http.StatusPreconditionFailed || (azureErr.StatusCode == http.StatusConflict && azureErr.Code == "BlobAlreadyExists")
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testAzureAccount = "kopsstate"
+
+var testAzureKey = base64.StdEncoding.EncodeToString([]byte("not-a-real-storage-account-key"))
+
+const testAzureSASToken = "?sv=2018-03-28&sr=c&sp=rl&sig=not-a-real-signature"
+
+type fakeAzureBlob struct {
+	data []byte
+	etag string
+}
+
+// fakeAzureBlobServer implements the parts of the Blob service REST API we use, checking the Shared Key signatures.
+// Requests signed with testAzureSASToken may only read and list.
+type fakeAzureBlobServer struct {
+	t   *testing.T
+	url string
+
+	mutex   sync.Mutex
+	version int
+	// publicAccess is the access level of each container
+	publicAccess map[string]string
+	blobs        map[string]map[string]*fakeAzureBlob
+	// pageSize is the maximum number of blobs in a listing
+	pageSize int
+}
+
+func (s *fakeAzureBlobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if sig := r.URL.Query().Get("sig"); sig != "" {
+		if sig != "not-a-real-signature" || r.Header.Get("Authorization") != "" {
+			s.t.Errorf("unexpected SAS signature %q for %s %s", sig, r.Method, r.URL.Path)
+			writeFakeAzureError(w, http.StatusForbidden, "AuthenticationFailed")
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeFakeAzureError(w, http.StatusForbidden, "AuthorizationPermissionMismatch")
+			return
+		}
+	} else if auth, expected := r.Header.Get("Authorization"), fakeAzureSharedKeyAuthorization(r); auth != expected {
+		s.t.Errorf("unexpected Authorization %q for %s %s, expected %q", auth, r.Method, r.URL, expected)
+		writeFakeAzureError(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+	if r.Header.Get("x-ms-version") != azureStorageAPIVersion || r.Header.Get("x-ms-date") == "" {
+		s.t.Errorf("missing x-ms-version or x-ms-date headers for %s %s", r.Method, r.URL)
+	}
+
+	tokens := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	container := tokens[0]
+	blobs, found := s.blobs[container]
+	if !found {
+		writeFakeAzureError(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+
+	if len(tokens) == 1 {
+		query := r.URL.Query()
+		if r.Method != http.MethodGet || query.Get("restype") != "container" {
+			writeFakeAzureError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+			return
+		}
+		if query.Get("comp") == "list" {
+			s.list(w, blobs, query.Get("prefix"), query.Get("delimiter"), query.Get("marker"))
+			return
+		}
+		if access := s.publicAccess[container]; access != "" {
+			w.Header().Set("x-ms-blob-public-access", access)
+		}
+		return
+	}
+
+	name := tokens[1]
+	blob := blobs[name]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if blob == nil {
+			writeFakeAzureError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		w.Header().Set("ETag", blob.etag)
+		w.Header().Set("Content-MD5", fakeAzureMD5(blob.data))
+		if r.Method == http.MethodGet {
+			w.Write(blob.data)
+		}
+
+	case http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			writeFakeAzureError(w, http.StatusBadRequest, "MissingRequiredHeader")
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && blob != nil {
+			writeFakeAzureError(w, http.StatusConflict, "BlobAlreadyExists")
+			return
+		}
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (blob == nil || blob.etag != ifMatch) {
+			writeFakeAzureError(w, http.StatusPreconditionFailed, "ConditionNotMet")
+			return
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s.t.Errorf("error reading body: %v", err)
+		}
+		if r.Header.Get("Content-MD5") != fakeAzureMD5(data) {
+			writeFakeAzureError(w, http.StatusBadRequest, "Md5Mismatch")
+			return
+		}
+		s.version++
+		blobs[name] = &fakeAzureBlob{data: data, etag: fmt.Sprintf("\"0x%d\"", s.version)}
+		w.Header().Set("ETag", blobs[name].etag)
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		if blob == nil {
+			writeFakeAzureError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		writeFakeAzureError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+func (s *fakeAzureBlobServer) list(w http.ResponseWriter, blobs map[string]*fakeAzureBlob, prefix string, delimiter string, marker string) {
+	var names []string
+	seen := make(map[string]bool)
+	for name := range blobs {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i != -1 {
+				// Returned as a BlobPrefix
+				name = name[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if name <= marker && marker != "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+	nextMarker := ""
+	for i, name := range names {
+		if i == s.pageSize {
+			nextMarker = names[i-1]
+			break
+		}
+		if blobs[name] == nil {
+			b.WriteString("<BlobPrefix><Name>")
+			xml.EscapeText(&b, []byte(name))
+			b.WriteString("</Name></BlobPrefix>")
+			continue
+		}
+		b.WriteString("<Blob><Name>")
+		xml.EscapeText(&b, []byte(name))
+		b.WriteString("</Name><Properties><Content-MD5>" + fakeAzureMD5(blobs[name].data) + "</Content-MD5></Properties></Blob>")
+	}
+	b.WriteString("</Blobs><NextMarker>" + nextMarker + "</NextMarker></EnumerationResults>")
+	w.Write(b.Bytes())
+}
+
+func writeFakeAzureError(w http.ResponseWriter, statusCode int, code string) {
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code><Message>fake error</Message></Error>`, code)
+}
+
+func fakeAzureMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func fakeAzureSharedKeyAuthorization(r *http.Request) string {
+	key, _ := base64.StdEncoding.DecodeString(testAzureKey)
+	return "SharedKey " + testAzureAccount + ":" + azureSharedKeySignature(testAzureAccount, key, r)
+}
+
+func newFakeAzureBlobServer(t *testing.T) (*fakeAzureBlobServer, *AzureBlobClient, func()) {
+	s := &fakeAzureBlobServer{
+		t: t,
+		publicAccess: map[string]string{
+			"public": "blob",
+		},
+		blobs: map[string]map[string]*fakeAzureBlob{
+			"state":  {},
+			"public": {},
+		},
+		pageSize: 2,
+	}
+	server := httptest.NewServer(s)
+	s.url = server.URL
+
+	client, err := newAzureBlobClient(testAzureAccount, testAzureKey, server.URL+"/", server.Client())
+	if err != nil {
+		server.Close()
+		t.Fatalf("error building client: %v", err)
+	}
+	return s, client, server.Close
+}
+
+func TestAzureBlobPath(t *testing.T) {
+	_, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	base := NewAzureBlobPath(client, "state", "/example.com")
+	if base.Path() != "azureblob://state/example.com" {
+		t.Errorf("unexpected path %q", base.Path())
+	}
+
+	p := base.Join("config")
+	if _, err := p.ReadFile(); !os.IsNotExist(err) {
+		t.Fatalf("expected not found reading missing file, got %v", err)
+	}
+
+	if err := p.WriteFile(bytes.NewReader([]byte("config data")), nil); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	data, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	if string(data) != "config data" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+
+	if err := p.(*AzureBlobPath).CreateFile(bytes.NewReader([]byte("other")), nil); !os.IsExist(err) {
+		t.Errorf("expected exists error creating existing file, got %v", err)
+	}
+
+	hash, err := p.(HasHash).PreferredHash()
+	if err != nil {
+		t.Fatalf("error reading hash: %v", err)
+	}
+	sum := md5.Sum([]byte("config data"))
+	if hash == nil || !bytes.Equal(hash.HashValue, sum[:]) {
+		t.Errorf("unexpected hash %v", hash)
+	}
+
+	if err := p.Remove(); err != nil {
+		t.Fatalf("error removing file: %v", err)
+	}
+	if err := p.Remove(); !os.IsNotExist(err) {
+		t.Errorf("expected not found removing missing file, got %v", err)
+	}
+}
+
+func TestAzureBlobPathSASToken(t *testing.T) {
+	s, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	if err := NewAzureBlobPath(client, "state", "example.com/config").WriteFile(bytes.NewReader([]byte("config data")), nil); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	sasClient, err := newAzureBlobSASClient(testAzureAccount, testAzureSASToken, s.url, client.httpClient)
+	if err != nil {
+		t.Fatalf("error building client: %v", err)
+	}
+	p := NewAzureBlobPath(sasClient, "state", "example.com/config")
+	data, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("error reading file with SAS token: %v", err)
+	}
+	if string(data) != "config data" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+	children, err := NewAzureBlobPath(sasClient, "state", "example.com").ReadDir()
+	if err != nil {
+		t.Fatalf("error listing with SAS token: %v", err)
+	}
+	if len(children) != 1 || children[0].Path() != "azureblob://state/example.com/config" {
+		t.Errorf("unexpected listing %v", children)
+	}
+
+	if err := p.WriteFile(bytes.NewReader([]byte("other")), nil); err == nil {
+		t.Errorf("expected error writing with a read-only SAS token")
+	}
+
+	if _, err := newAzureBlobSASClient(testAzureAccount, "sv=2018-03-28&sp=rl", s.url, client.httpClient); err == nil {
+		t.Errorf("expected error for a SAS token without a signature")
+	}
+}
+
+func TestAzureBlobPathReadDir(t *testing.T) {
+	_, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	base := NewAzureBlobPath(client, "state", "example.com")
+	for _, name := range []string{"config", "cluster.spec", "instancegroup/nodes", "instancegroup/master-us-east-1a", "pki/issued/ca/1.crt"} {
+		if err := base.Join(name).WriteFile(bytes.NewReader([]byte(name)), nil); err != nil {
+			t.Fatalf("error writing %q: %v", name, err)
+		}
+	}
+	if err := NewAzureBlobPath(client, "state", "example.com2/config").WriteFile(bytes.NewReader([]byte("other")), nil); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	grid := []struct {
+		Path     Path
+		Tree     bool
+		Expected []string
+	}{
+		{
+			Path: base,
+			Expected: []string{
+				"azureblob://state/example.com/cluster.spec",
+				"azureblob://state/example.com/config",
+				"azureblob://state/example.com/instancegroup",
+				"azureblob://state/example.com/pki",
+			},
+		},
+		{
+			Path:     base.Join("instancegroup"),
+			Expected: []string{"azureblob://state/example.com/instancegroup/master-us-east-1a", "azureblob://state/example.com/instancegroup/nodes"},
+		},
+		{
+			Path: base,
+			Tree: true,
+			Expected: []string{
+				"azureblob://state/example.com/cluster.spec",
+				"azureblob://state/example.com/config",
+				"azureblob://state/example.com/instancegroup/master-us-east-1a",
+				"azureblob://state/example.com/instancegroup/nodes",
+				"azureblob://state/example.com/pki/issued/ca/1.crt",
+			},
+		},
+		{
+			Path: base.Join("missing"),
+		},
+	}
+	for _, g := range grid {
+		var paths []Path
+		var err error
+		if g.Tree {
+			paths, err = g.Path.ReadTree()
+		} else {
+			paths, err = g.Path.ReadDir()
+		}
+		if err != nil {
+			t.Errorf("error listing %s: %v", g.Path, err)
+			continue
+		}
+
+		var actual []string
+		for _, p := range paths {
+			actual = append(actual, p.Path())
+		}
+		if !reflect.DeepEqual(actual, g.Expected) {
+			t.Errorf("unexpected listing of %s (tree=%v): %v, expected %v", g.Path, g.Tree, actual, g.Expected)
+		}
+	}
+
+	// Hashes come from the listing
+	paths, err := base.Join("instancegroup").ReadDir()
+	if err != nil {
+		t.Fatalf("error listing: %v", err)
+	}
+	hash, err := paths[1].(HasHash).PreferredHash()
+	if err != nil {
+		t.Fatalf("error reading hash: %v", err)
+	}
+	sum := md5.Sum([]byte("instancegroup/nodes"))
+	if hash == nil || !bytes.Equal(hash.HashValue, sum[:]) {
+		t.Errorf("unexpected hash %v", hash)
+	}
+}
+
+func TestAzureBlobPathPublicRead(t *testing.T) {
+	_, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	acl := &AzureBlobACL{PublicRead: true}
+	if err := NewAzureBlobPath(client, "public", "assets/file").WriteFile(bytes.NewReader([]byte("data")), acl); err != nil {
+		t.Errorf("error writing public file to public container: %v", err)
+	}
+	if err := NewAzureBlobPath(client, "state", "assets/file").WriteFile(bytes.NewReader([]byte("data")), acl); err == nil {
+		t.Errorf("expected error writing public file to private container")
+	}
+	if err := NewAzureBlobPath(client, "state", "assets/file").WriteFile(bytes.NewReader([]byte("data")), &S3Acl{}); err == nil {
+		t.Errorf("expected error writing file with S3 ACL")
+	}
+}
+
+func TestWriteFileIfMatchAzureBlob(t *testing.T) {
+	_, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	testWriteFileIfMatch(t, NewAzureBlobPath(client, "state", "example.com/config"))
+}
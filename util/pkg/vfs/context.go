@@ -41,7 +41,7 @@ type VFSContext struct {
 	s3Context    *S3Context
 	k8sContext   *KubernetesContext
 	memfsContext *MemFSContext
-	// mutex guards gcsClient
+	// mutex guards gcsClient and azureBlobClient
 	mutex sync.Mutex
 	// The google cloud storage client, if initialized
 	gcsClient *storage.Service
@@ -49,6 +49,8 @@ type VFSContext struct {
 	swiftClient *gophercloud.ServiceClient
 	// ossClient is the Aliyun Open Source Storage client
 	ossClient *oss.Client
+	// azureBlobClient is the Azure Blob Storage client, if initialized
+	azureBlobClient *AzureBlobClient
 }
 
 var Context = VFSContext{
@@ -139,6 +141,10 @@ func (c *VFSContext) BuildVfsPath(p string) (Path, error) {
 		return c.buildOSSPath(p)
 	}
 
+	if strings.HasPrefix(p, "azureblob://") {
+		return c.buildAzureBlobPath(p)
+	}
+
 	return nil, fmt.Errorf("unknown / unhandled path type: %q", p)
 }
 
@@ -406,3 +412,42 @@ func (c *VFSContext) buildOSSPath(p string) (*OSSPath, error) {
 
 	return NewOSSPath(c.ossClient, bucket, u.Path)
 }
+
+func (c *VFSContext) buildAzureBlobPath(p string) (*AzureBlobPath, error) {
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure blob path: %q", p)
+	}
+
+	if u.Scheme != "azureblob" {
+		return nil, fmt.Errorf("invalid azure blob path: %q", p)
+	}
+
+	container := strings.TrimSuffix(u.Host, "/")
+	if container == "" {
+		return nil, fmt.Errorf("invalid azure blob path: %q", p)
+	}
+
+	client, err := c.getAzureBlobClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAzureBlobPath(client, container, u.Path), nil
+}
+
+func (c *VFSContext) getAzureBlobClient() (*AzureBlobClient, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.azureBlobClient != nil {
+		return c.azureBlobClient, nil
+	}
+
+	client, err := NewAzureBlobClient()
+	if err != nil {
+		return nil, fmt.Errorf("error building azure blob storage client: %v", err)
+	}
+	c.azureBlobClient = client
+	return client, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package vfs
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -48,6 +49,12 @@ func (p *FSPath) Join(relativePath ...string) Path {
 }
 
 func (p *FSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
+	return p.writeFile(data, false)
+}
+
+// writeFile writes the data to a temp file, which is then moved into place.
+// If exclusive is set the temp file is hard-linked into place instead, which fails if the file exists.
+func (p *FSPath) writeFile(data io.ReadSeeker, exclusive bool) error {
 	dir := path.Dir(p.location)
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
@@ -68,7 +75,23 @@ func (p *FSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
 		err = closeErr
 	}
 
-	if err == nil {
+	if err == nil && exclusive {
+		err = os.Link(tempfile, p.location)
+		if err == nil {
+			// The file is in place, we only need to remove the temp file
+			if removeErr := os.Remove(tempfile); removeErr != nil {
+				glog.Warningf("unable to remove temp file %q: %v", tempfile, removeErr)
+			}
+			return nil
+		}
+		if os.IsExist(err) {
+			err = os.ErrExist
+		} else {
+			err = fmt.Errorf("error during file create of %q: link failed: %v", p.location, err)
+		}
+	}
+
+	if err == nil && !exclusive {
 		err = os.Rename(tempfile, p.location)
 		if err != nil {
 			err = fmt.Errorf("error during file write of %q: rename failed: %v", p.location, err)
@@ -89,8 +112,7 @@ func (p *FSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
 
 // To prevent concurrent creates on the same file while maintaining atomicity of writes,
 // we take a process-wide lock during the operation.
-// Not a great approach, but fine for a single process (with low concurrency)
-// TODO: should we take a file lock or equivalent here?  Can we use RENAME_NOREPLACE ?
+// Other processes are excluded by hard-linking the file into place, which fails if it exists.
 var createFileLock sync.Mutex
 
 func (p *FSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
@@ -107,7 +129,54 @@ func (p *FSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
 		return err
 	}
 
-	return p.WriteFile(data, acl)
+	return p.writeFile(data, true)
+}
+
+var _ VersionedPath = &FSPath{}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the hash of the contents
+func (p *FSPath) ReadFileWithVersion() ([]byte, string, error) {
+	data, err := ioutil.ReadFile(p.location)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentVersion(data), nil
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch.
+// The check and the write are atomic within this process; the write itself is an atomic rename.
+func (p *FSPath) WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	createFileLock.Lock()
+	defer createFileLock.Unlock()
+
+	contents, err := ioutil.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("error reading data: %v", err)
+	}
+
+	if expectedVersion == "" {
+		err := p.writeFile(bytes.NewReader(contents), true)
+		if os.IsExist(err) {
+			return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+		}
+		if err != nil {
+			return "", err
+		}
+		return contentVersion(contents), nil
+	}
+
+	current, err := ioutil.ReadFile(p.location)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err != nil || contentVersion(current) != expectedVersion {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+
+	if err := p.writeFile(bytes.NewReader(contents), false); err != nil {
+		return "", err
+	}
+	return contentVersion(contents), nil
 }
 
 // ReadFile implements Path::ReadFile
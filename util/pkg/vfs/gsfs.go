@@ -21,9 +21,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -135,6 +137,15 @@ func (p *GSPath) Join(relativePath ...string) Path {
 }
 
 func (p *GSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
+	_, err := p.writeFile(data, acl, nil)
+	return err
+}
+
+// writeFile writes the object; if ifGenerationMatch is set the write only succeeds if the object has that generation,
+// where 0 means the object must not exist.  A failed precondition is not retried.
+// It returns the generation of the written object.
+func (p *GSPath) writeFile(data io.ReadSeeker, acl ACL, ifGenerationMatch *int64) (string, error) {
+	generation := ""
 	done, err := RetryWithBackoff(gcsWriteBackoff, func() (bool, error) {
 		glog.V(4).Infof("Writing file %q", p)
 
@@ -160,27 +171,34 @@ func (p *GSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
 			return false, fmt.Errorf("error seeking to start of data stream for write to %s: %v", p, err)
 		}
 
-		_, err = p.client.Objects.Insert(p.bucket, obj).Media(data).Do()
+		call := p.client.Objects.Insert(p.bucket, obj).Media(data)
+		if ifGenerationMatch != nil {
+			call = call.IfGenerationMatch(*ifGenerationMatch)
+		}
+		written, err := call.Do()
 		if err != nil {
+			if isGCSPreconditionFailed(err) {
+				return true, err
+			}
 			return false, fmt.Errorf("error writing %s: %v", p, err)
 		}
+		generation = strconv.FormatInt(written.Generation, 10)
 
 		return true, nil
 	})
 	if err != nil {
-		return err
+		return "", err
 	} else if done {
-		return nil
+		return generation, nil
 	} else {
 		// Shouldn't happen - we always return a non-nil error with false
-		return wait.ErrWaitTimeout
+		return "", wait.ErrWaitTimeout
 	}
 }
 
 // To prevent concurrent creates on the same file while maintaining atomicity of writes,
 // we take a process-wide lock during the operation.
-// Not a great approach, but fine for a single process (with low concurrency)
-// TODO: should we enable versioning?
+// Other processes are excluded by the generation precondition on the write.
 var createFileLockGCS sync.Mutex
 
 func (p *GSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
@@ -197,7 +215,13 @@ func (p *GSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
 		return err
 	}
 
-	return p.WriteFile(data, acl)
+	// A generation of 0 means the object must not exist
+	var ifGenerationMatch int64
+	_, err = p.writeFile(data, acl, &ifGenerationMatch)
+	if isGCSPreconditionFailed(err) {
+		return os.ErrExist
+	}
+	return err
 }
 
 // ReadFile implements Path::ReadFile
@@ -226,6 +250,55 @@ func (p *GSPath) ReadFile() ([]byte, error) {
 	}
 }
 
+var _ VersionedPath = &GSPath{}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the object generation
+func (p *GSPath) ReadFileWithVersion() ([]byte, string, error) {
+	glog.V(4).Infof("Reading file %q", p)
+
+	response, err := p.client.Objects.Get(p.bucket, p.key).Download()
+	if err != nil {
+		if isGCSNotFound(err) {
+			return nil, "", os.ErrNotExist
+		}
+		return nil, "", fmt.Errorf("error reading %s: %v", p, err)
+	}
+	if response == nil {
+		return nil, "", fmt.Errorf("no response returned from reading %s", p)
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", p, err)
+	}
+
+	generation := response.Header.Get("X-Goog-Generation")
+	if generation == "" {
+		return nil, "", fmt.Errorf("no generation returned from reading %s", p)
+	}
+	return data, generation, nil
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch, with a generation precondition
+func (p *GSPath) WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	// A generation of 0 means the object must not exist
+	var generation int64
+	if expectedVersion != "" {
+		var err error
+		generation, err = strconv.ParseInt(expectedVersion, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid version %q for %s: %v", expectedVersion, p, err)
+		}
+	}
+
+	version, err := p.writeFile(data, acl, &generation)
+	if isGCSPreconditionFailed(err) {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+	return version, err
+}
+
 // WriteTo implements io.WriterTo::WriteTo
 func (p *GSPath) WriteTo(out io.Writer) (int64, error) {
 	glog.V(4).Infof("Reading file %q", p)
@@ -266,6 +339,15 @@ func (p *GSPath) ReadDir() ([]Path, error) {
 				}
 				paths = append(paths, child)
 			}
+			// Prefixes represent directories
+			for _, d := range page.Prefixes {
+				child := &GSPath{
+					client: p.client,
+					bucket: p.bucket,
+					key:    strings.TrimSuffix(d, "/"),
+				}
+				paths = append(paths, child)
+			}
 			return nil
 		})
 		if err != nil {
@@ -358,6 +440,14 @@ func (p *GSPath) Hash(a hashing.HashAlgorithm) (*hashing.Hash, error) {
 	return &hashing.Hash{Algorithm: hashing.HashAlgorithmMD5, HashValue: md5Bytes}, nil
 }
 
+func isGCSPreconditionFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	ae, ok := err.(*googleapi.Error)
+	return ok && ae.Code == http.StatusPreconditionFailed
+}
+
 func isGCSNotFound(err error) bool {
 	if err == nil {
 		return false
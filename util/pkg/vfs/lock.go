@@ -0,0 +1,310 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// LockInfo is the content of a lock file
+type LockInfo struct {
+	// ID identifies this acquisition of the lock, so a holder can check the lock is still its own
+	ID string `json:"id"`
+	// Holder describes who holds the lock, e.g. user@host
+	Holder string `json:"holder"`
+	// Operation is the operation the lock was taken for, e.g. update cluster
+	Operation string `json:"operation"`
+	// Acquired is when the lock was taken
+	Acquired time.Time `json:"acquired"`
+	// Expires is when the lock can be broken by another holder
+	Expires time.Time `json:"expires"`
+	// Released is set when the holder has released the lock.
+	// A lock file can't be removed conditionally, so a released lock is left in place for the next holder to replace.
+	Released bool `json:"released,omitempty"`
+}
+
+// IsExpired returns true if the lock has expired at the time now
+func (l *LockInfo) IsExpired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("held by %s for %q since %s, expires %s", l.Holder, l.Operation, l.Acquired.Format(time.RFC3339), l.Expires.Format(time.RFC3339))
+}
+
+// LockHeldError is returned when trying to take a lock which is held by someone else
+type LockHeldError struct {
+	Path Path
+	Info *LockInfo
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("state store is locked (%s): lock %s is %s", e.Info.Operation, e.Path, e.Info)
+}
+
+// IsLockHeld returns true if the error is a LockHeldError
+func IsLockHeld(err error) bool {
+	_, ok := err.(*LockHeldError)
+	return ok
+}
+
+// LockLostError is returned when refreshing a lock which was broken, and possibly taken by someone else
+type LockLostError struct {
+	Path Path
+}
+
+func (e *LockLostError) Error() string {
+	return fmt.Sprintf("lock %s is no longer held by us", e.Path)
+}
+
+// IsLockLost returns true if the error is a LockLostError
+func IsLockLost(err error) bool {
+	_, ok := err.(*LockLostError)
+	return ok
+}
+
+// Lock is an advisory lock held in a file.
+// The lock file is only ever written with WriteFileIfMatch: it is created if it does not exist, and an expired
+// or released lock is replaced only if it is unchanged since it was read.  On backends without conditional
+// writes (OSS, SSH) the lock is best-effort: two commands which write the lock file at the same time can both
+// believe they hold it.
+type Lock struct {
+	path Path
+	info *LockInfo
+	// version is the version of the lock file we wrote, for backends which support conditional writes
+	version string
+}
+
+// Info returns the contents of the lock file
+func (l *Lock) Info() *LockInfo {
+	return l.info
+}
+
+// AcquireLock takes the lock at p, for the given holder and operation, for the duration ttl.
+// If the lock is held by someone else, and has not expired, a LockHeldError is returned.
+func AcquireLock(p Path, holder string, operation string, ttl time.Duration) (*Lock, error) {
+	id, err := randomLockID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	info := &LockInfo{
+		ID:        id,
+		Holder:    holder,
+		Operation: operation,
+		Acquired:  now,
+		Expires:   now.Add(ttl),
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error serializing lock: %v", err)
+	}
+
+	// We replace an expired lock at most once; if we lose that race, the new lock is held
+	brokeExpired := false
+	for attempt := 0; ; attempt++ {
+		if attempt >= 5 {
+			return nil, fmt.Errorf("unable to acquire lock %s", p)
+		}
+
+		err := createLockFile(p, data)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) && !IsVersionConflict(err) {
+			return nil, fmt.Errorf("error creating lock %s: %v", p, err)
+		}
+
+		existing, version, err := readLockWithVersion(p)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			// Removed while we were looking at it
+			continue
+		}
+		if !existing.Released {
+			if brokeExpired || !existing.IsExpired(time.Now()) {
+				return nil, &LockHeldError{Path: p, Info: existing}
+			}
+			glog.Warningf("breaking expired lock %s, which was %s", p, existing)
+			brokeExpired = true
+		}
+
+		// Replace the lock we read, only if it is still that lock, so that of two commands replacing it only one succeeds
+		if _, err := WriteFileIfMatch(p, bytes.NewReader(data), nil, version); err != nil {
+			if IsVersionConflict(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error replacing lock %s: %v", p, err)
+		}
+		break
+	}
+
+	// Check the lock file is ours; this only narrows the race on backends without conditional writes
+	actual, version, err := readLockWithVersion(p)
+	if err != nil {
+		return nil, err
+	}
+	if actual == nil || actual.ID != info.ID {
+		if actual == nil {
+			return nil, fmt.Errorf("lock %s was removed while it was being acquired", p)
+		}
+		return nil, &LockHeldError{Path: p, Info: actual}
+	}
+
+	glog.V(2).Infof("acquired lock %s", p)
+	return &Lock{path: p, info: info, version: version}, nil
+}
+
+// createLockFile writes the lock file only if it does not exist.
+// Paths without conditional writes fall back to CreateFile, which only checks for the file before writing it.
+func createLockFile(p Path, data []byte) error {
+	if _, ok := p.(VersionedPath); ok {
+		_, err := WriteFileIfMatch(p, bytes.NewReader(data), nil, "")
+		return err
+	}
+	glog.Warningf("%s does not support conditional writes; the lock is best-effort", p)
+	return p.CreateFile(bytes.NewReader(data), nil)
+}
+
+// Refresh extends the lock, so that it expires ttl from now.
+// The lock file is rewritten only if it is unchanged since we wrote it; if the lock was broken a LockLostError is returned.
+func (l *Lock) Refresh(ttl time.Duration) error {
+	actual, err := ReadLock(l.path)
+	if err != nil {
+		return err
+	}
+	if actual == nil || actual.ID != l.info.ID {
+		return &LockLostError{Path: l.path}
+	}
+
+	refreshed := *l.info
+	refreshed.Expires = time.Now().UTC().Add(ttl)
+	data, err := json.MarshalIndent(&refreshed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing lock: %v", err)
+	}
+	version, err := WriteFileIfMatch(l.path, bytes.NewReader(data), nil, l.version)
+	if err != nil {
+		if IsVersionConflict(err) {
+			return &LockLostError{Path: l.path}
+		}
+		return fmt.Errorf("error refreshing lock %s: %v", l.path, err)
+	}
+
+	l.info = &refreshed
+	l.version = version
+	glog.V(2).Infof("refreshed lock %s, expires %s", l.path, refreshed.Expires.Format(time.RFC3339))
+	return nil
+}
+
+// Release marks the lock file as released, if it is still held by us.
+// The lock file is rewritten only if it is unchanged since we wrote it, so a lock which was broken
+// and taken by someone else is left alone.
+func (l *Lock) Release() error {
+	actual, err := ReadLock(l.path)
+	if err != nil {
+		return err
+	}
+	if actual == nil || actual.ID != l.info.ID {
+		glog.Warningf("lock %s is no longer held by us, not releasing it", l.path)
+		return nil
+	}
+
+	released := *l.info
+	released.Released = true
+	data, err := json.MarshalIndent(&released, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing lock: %v", err)
+	}
+	if _, err := WriteFileIfMatch(l.path, bytes.NewReader(data), nil, l.version); err != nil {
+		if IsVersionConflict(err) {
+			glog.Warningf("lock %s is no longer held by us, not releasing it", l.path)
+			return nil
+		}
+		return fmt.Errorf("error releasing lock %s: %v", l.path, err)
+	}
+	glog.V(2).Infof("released lock %s", l.path)
+	return nil
+}
+
+// ReadLock reads the lock file at p; it returns nil if the lock is not held
+func ReadLock(p Path) (*LockInfo, error) {
+	info, _, err := readLockWithVersion(p)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && info.Released {
+		return nil, nil
+	}
+	return info, nil
+}
+
+// readLockWithVersion reads the lock file at p, including a released lock, and the version of the file.
+// It returns nil if there is no lock file.
+func readLockWithVersion(p Path) (*LockInfo, string, error) {
+	data, version, err := ReadFileWithVersion(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("error reading lock %s: %v", p, err)
+	}
+
+	info := &LockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, "", fmt.Errorf("error parsing lock %s: %v", p, err)
+	}
+	return info, version, nil
+}
+
+// BreakLock removes the lock at p, whoever holds it.
+// It returns the lock that was removed, or nil if the lock was not held.
+func BreakLock(p Path) (*LockInfo, error) {
+	info, err := ReadLock(p)
+	if err != nil {
+		// Still remove a lock file we can't parse, that is what this is for
+		glog.Warningf("%v", err)
+	}
+
+	if err := p.Remove(); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error removing lock %s: %v", p, err)
+	}
+	return info, nil
+}
+
+func randomLockID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating lock id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
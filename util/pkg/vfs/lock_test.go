@@ -0,0 +1,217 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testLock(t *testing.T, p Path) {
+	lock, err := AcquireLock(p, "alice@host", "update cluster", time.Hour)
+	if err != nil {
+		t.Fatalf("error acquiring lock: %v", err)
+	}
+
+	_, err = AcquireLock(p, "bob@host", "edit cluster", time.Hour)
+	if !IsLockHeld(err) {
+		t.Fatalf("expected LockHeldError acquiring held lock, got %v", err)
+	}
+	held := err.(*LockHeldError)
+	if held.Info.Holder != "alice@host" || held.Info.Operation != "update cluster" {
+		t.Fatalf("unexpected lock holder: %v", held.Info)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("error releasing lock: %v", err)
+	}
+
+	info, err := ReadLock(p)
+	if err != nil {
+		t.Fatalf("error reading released lock: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("expected lock to be released, was %v", info)
+	}
+
+	lock, err = AcquireLock(p, "bob@host", "edit cluster", time.Hour)
+	if err != nil {
+		t.Fatalf("error acquiring released lock: %v", err)
+	}
+	if lock.Info().Holder != "bob@host" {
+		t.Fatalf("unexpected lock holder: %v", lock.Info())
+	}
+
+	broken, err := BreakLock(p)
+	if err != nil {
+		t.Fatalf("error breaking lock: %v", err)
+	}
+	if broken == nil || broken.ID != lock.Info().ID {
+		t.Fatalf("unexpected broken lock: %v", broken)
+	}
+
+	// Releasing a lock which was broken does not remove someone else's lock
+	other, err := AcquireLock(p, "carol@host", "replace", time.Hour)
+	if err != nil {
+		t.Fatalf("error acquiring broken lock: %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("error releasing broken lock: %v", err)
+	}
+	info, err = ReadLock(p)
+	if err != nil {
+		t.Fatalf("error reading lock: %v", err)
+	}
+	if info == nil || info.ID != other.Info().ID {
+		t.Fatalf("expected the lock to still be held by carol, was %v", info)
+	}
+}
+
+func TestLockMemFS(t *testing.T) {
+	p := NewMemFSPath(NewMemFSContext(), "statestore/example.com/lock")
+	testLock(t, p)
+}
+
+func TestLockFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := NewFSPath(filepath.Join(dir, "example.com", "lock"))
+	testLock(t, p)
+}
+
+func TestLockAzureBlob(t *testing.T) {
+	// The fake server rejects the If-None-Match precondition when the lock blob exists
+	_, client, cleanup := newFakeAzureBlobServer(t)
+	defer cleanup()
+
+	p := NewAzureBlobPath(client, "state", "example.com/lock")
+	testLock(t, p)
+}
+
+func TestLockExpired(t *testing.T) {
+	p := NewMemFSPath(NewMemFSContext(), "statestore/example.com/lock")
+
+	expired := &LockInfo{
+		ID:        "expired",
+		Holder:    "alice@host",
+		Operation: "update cluster",
+		Acquired:  time.Now().Add(-2 * time.Hour),
+		Expires:   time.Now().Add(-time.Hour),
+	}
+	data, err := json.Marshal(expired)
+	if err != nil {
+		t.Fatalf("error serializing lock: %v", err)
+	}
+	if err := p.WriteFile(bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("error writing lock: %v", err)
+	}
+
+	lock, err := AcquireLock(p, "bob@host", "edit cluster", time.Hour)
+	if err != nil {
+		t.Fatalf("expected expired lock to be broken, got %v", err)
+	}
+	if lock.Info().Holder != "bob@host" {
+		t.Fatalf("unexpected lock holder: %v", lock.Info())
+	}
+}
+
+func TestLockExpiredRelease(t *testing.T) {
+	p := NewMemFSPath(NewMemFSContext(), "statestore/example.com/lock")
+
+	expired, err := AcquireLock(p, "alice@host", "update cluster", 0)
+	if err != nil {
+		t.Fatalf("error acquiring lock: %v", err)
+	}
+
+	lock, err := AcquireLock(p, "bob@host", "edit cluster", time.Hour)
+	if err != nil {
+		t.Fatalf("expected expired lock to be broken, got %v", err)
+	}
+
+	// The holder of the expired lock must not release the lock which replaced it
+	if err := expired.Release(); err != nil {
+		t.Fatalf("error releasing expired lock: %v", err)
+	}
+	info, err := ReadLock(p)
+	if err != nil {
+		t.Fatalf("error reading lock: %v", err)
+	}
+	if info == nil || info.ID != lock.Info().ID {
+		t.Fatalf("expected the lock to still be held by bob, was %v", info)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("error releasing lock: %v", err)
+	}
+	if _, err := AcquireLock(p, "carol@host", "replace", time.Hour); err != nil {
+		t.Fatalf("error acquiring released lock: %v", err)
+	}
+}
+
+func TestLockRefresh(t *testing.T) {
+	p := NewMemFSPath(NewMemFSContext(), "statestore/example.com/lock")
+
+	lock, err := AcquireLock(p, "alice@host", "update cluster", 0)
+	if err != nil {
+		t.Fatalf("error acquiring lock: %v", err)
+	}
+	if err := lock.Refresh(time.Hour); err != nil {
+		t.Fatalf("error refreshing lock: %v", err)
+	}
+
+	// The refreshed lock is no longer expired
+	_, err = AcquireLock(p, "bob@host", "edit cluster", time.Hour)
+	if !IsLockHeld(err) {
+		t.Fatalf("expected LockHeldError acquiring refreshed lock, got %v", err)
+	}
+
+	// The lock is released with the version written by the refresh
+	if err := lock.Release(); err != nil {
+		t.Fatalf("error releasing lock: %v", err)
+	}
+	info, err := ReadLock(p)
+	if err != nil {
+		t.Fatalf("error reading lock: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("expected lock to be released, was %v", info)
+	}
+
+	// A lock which was broken can't be refreshed
+	lock, err = AcquireLock(p, "alice@host", "update cluster", time.Hour)
+	if err != nil {
+		t.Fatalf("error acquiring lock: %v", err)
+	}
+	if _, err := BreakLock(p); err != nil {
+		t.Fatalf("error breaking lock: %v", err)
+	}
+	if _, err := AcquireLock(p, "bob@host", "edit cluster", time.Hour); err != nil {
+		t.Fatalf("error acquiring broken lock: %v", err)
+	}
+	if err := lock.Refresh(time.Hour); !IsLockLost(err) {
+		t.Fatalf("expected LockLostError refreshing broken lock, got %v", err)
+	}
+}
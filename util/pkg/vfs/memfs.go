@@ -103,7 +103,13 @@ func (p *MemFSPath) WriteFile(r io.ReadSeeker, acl ACL) error {
 	return nil
 }
 
+// createFileLockMemFS makes CreateFile and WriteFileIfMatch atomic, so they can be used for locks and compare-and-swap
+var createFileLockMemFS sync.Mutex
+
 func (p *MemFSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
+	createFileLockMemFS.Lock()
+	defer createFileLockMemFS.Unlock()
+
 	// Check if exists
 	if p.contents != nil {
 		return os.ErrExist
@@ -112,6 +118,38 @@ func (p *MemFSPath) CreateFile(data io.ReadSeeker, acl ACL) error {
 	return p.WriteFile(data, acl)
 }
 
+var _ VersionedPath = &MemFSPath{}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the hash of the contents
+func (p *MemFSPath) ReadFileWithVersion() ([]byte, string, error) {
+	createFileLockMemFS.Lock()
+	defer createFileLockMemFS.Unlock()
+
+	if p.contents == nil {
+		return nil, "", os.ErrNotExist
+	}
+	return p.contents, contentVersion(p.contents), nil
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch
+func (p *MemFSPath) WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	createFileLockMemFS.Lock()
+	defer createFileLockMemFS.Unlock()
+
+	version := ""
+	if p.contents != nil {
+		version = contentVersion(p.contents)
+	}
+	if version != expectedVersion {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+
+	if err := p.WriteFile(data, acl); err != nil {
+		return "", err
+	}
+	return contentVersion(p.contents), nil
+}
+
 // ReadFile implements Path::ReadFile
 func (p *MemFSPath) ReadFile() ([]byte, error) {
 	if p.contents == nil {
@@ -133,11 +171,19 @@ func (p *MemFSPath) WriteTo(out io.Writer) (int64, error) {
 func (p *MemFSPath) ReadDir() ([]Path, error) {
 	var paths []Path
 	for _, f := range p.children {
+		if !f.exists() {
+			continue
+		}
 		paths = append(paths, f)
 	}
 	return paths, nil
 }
 
+// exists is false for paths which have been built with Join but never written, or which have been removed
+func (p *MemFSPath) exists() bool {
+	return p.contents != nil || p.HasChildren()
+}
+
 func (p *MemFSPath) ReadTree() ([]Path, error) {
 	var paths []Path
 	p.readTree(&paths)
@@ -146,7 +192,7 @@ func (p *MemFSPath) ReadTree() ([]Path, error) {
 
 func (p *MemFSPath) readTree(dest *[]Path) {
 	for _, f := range p.children {
-		if !f.HasChildren() {
+		if !f.HasChildren() && f.exists() {
 			*dest = append(*dest, f)
 		}
 		f.readTree(dest)
@@ -175,6 +175,7 @@ func (p *OSSPath) WriteFile(data io.ReadSeeker, acl ACL) error {
 // To prevent concurrent creates on the same file while maintaining atomicity of writes,
 // we take a process-wide lock during the operation.
 // Not a great approach, but fine for a single process (with low concurrency)
+// Between processes the check is not atomic, so AcquireLock reads back the lock it wrote.
 // TODO: should we enable versioning?
 var createFileLockOSS sync.Mutex
 
@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
@@ -120,9 +121,16 @@ func (p *S3Path) Join(relativePath ...string) Path {
 }
 
 func (p *S3Path) WriteFile(data io.ReadSeeker, aclObj ACL) error {
+	_, err := p.writeFile(data, aclObj, nil)
+	return err
+}
+
+// writeFile writes the object, adding the given headers to the request; these may hold preconditions.
+// It returns the ETag of the written object.
+func (p *S3Path) writeFile(data io.ReadSeeker, aclObj ACL, headers map[string]string) (string, error) {
 	client, err := p.client()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	glog.V(4).Infof("Writing file %q", p)
@@ -156,7 +164,7 @@ func (p *S3Path) WriteFile(data io.ReadSeeker, aclObj ACL) error {
 	} else if aclObj != nil {
 		s3Acl, ok := aclObj.(*S3Acl)
 		if !ok {
-			return fmt.Errorf("write to %s with ACL of unexpected type %T", p, aclObj)
+			return "", fmt.Errorf("write to %s with ACL of unexpected type %T", p, aclObj)
 		}
 		request.ACL = s3Acl.RequestACL
 	}
@@ -165,21 +173,29 @@ func (p *S3Path) WriteFile(data io.ReadSeeker, aclObj ACL) error {
 
 	glog.V(8).Infof("Calling S3 PutObject Bucket=%q Key=%q SSE=%q ACL=%q", p.bucket, p.key, sseLog, acl)
 
-	_, err = client.PutObject(request)
+	req, response := client.PutObjectRequest(request)
+	for k, v := range headers {
+		req.HTTPRequest.Header.Set(k, v)
+	}
+	err = req.Send()
 	if err != nil {
+		if isS3PreconditionFailed(err) {
+			return "", err
+		}
 		if acl != "" {
-			return fmt.Errorf("error writing %s (with ACL=%q): %v", p, acl, err)
+			return "", fmt.Errorf("error writing %s (with ACL=%q): %v", p, acl, err)
 		} else {
-			return fmt.Errorf("error writing %s: %v", p, err)
+			return "", fmt.Errorf("error writing %s: %v", p, err)
 		}
 	}
 
-	return nil
+	return aws.StringValue(response.ETag), nil
 }
 
 // To prevent concurrent creates on the same file while maintaining atomicity of writes,
 // we take a process-wide lock during the operation.
 // Not a great approach, but fine for a single process (with low concurrency)
+// Between processes the check is not atomic; AcquireLock uses WriteFileIfMatch instead.
 // TODO: should we enable versioning?
 var createFileLockS3 sync.Mutex
 
@@ -239,6 +255,80 @@ func (p *S3Path) WriteTo(out io.Writer) (int64, error) {
 	return n, nil
 }
 
+var _ VersionedPath = &S3Path{}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the object ETag
+func (p *S3Path) ReadFileWithVersion() ([]byte, string, error) {
+	client, err := p.client()
+	if err != nil {
+		return nil, "", err
+	}
+
+	glog.V(4).Infof("Reading file %q", p)
+
+	request := &s3.GetObjectInput{}
+	request.Bucket = aws.String(p.bucket)
+	request.Key = aws.String(p.key)
+
+	response, err := client.GetObject(request)
+	if err != nil {
+		if AWSErrorCode(err) == "NoSuchKey" {
+			return nil, "", os.ErrNotExist
+		}
+		return nil, "", fmt.Errorf("error fetching %s: %v", p, err)
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", p, err)
+	}
+	return data, aws.StringValue(response.ETag), nil
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch.
+// We send If-Match / If-None-Match preconditions, which are enforced by S3 implementations that support them;
+// we also compare the ETag before writing, which is only atomic within this process.
+func (p *S3Path) WriteFileIfMatch(data io.ReadSeeker, aclObj ACL, expectedVersion string) (string, error) {
+	createFileLockS3.Lock()
+	defer createFileLockS3.Unlock()
+
+	client, err := p.client()
+	if err != nil {
+		return "", err
+	}
+
+	request := &s3.HeadObjectInput{}
+	request.Bucket = aws.String(p.bucket)
+	request.Key = aws.String(p.key)
+
+	currentVersion := ""
+	response, err := client.HeadObject(request)
+	if err != nil {
+		if AWSErrorCode(err) != "NotFound" {
+			return "", fmt.Errorf("error getting %s: %v", p, err)
+		}
+	} else {
+		currentVersion = aws.StringValue(response.ETag)
+	}
+	if currentVersion != expectedVersion {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+
+	headers := make(map[string]string)
+	if expectedVersion == "" {
+		headers["If-None-Match"] = "*"
+	} else {
+		headers["If-Match"] = expectedVersion
+	}
+
+	version, err := p.writeFile(data, aclObj, headers)
+	if isS3PreconditionFailed(err) {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+	return version, err
+}
+
 func (p *S3Path) ReadDir() ([]Path, error) {
 	client, err := p.client()
 	if err != nil {
@@ -277,6 +367,17 @@ func (p *S3Path) ReadDir() ([]Path, error) {
 			}
 			paths = append(paths, child)
 		}
+		// CommonPrefixes represent directories
+		for _, d := range page.CommonPrefixes {
+			child := &S3Path{
+				s3Context: p.s3Context,
+				bucket:    p.bucket,
+				key:       strings.TrimSuffix(aws.StringValue(d.Prefix), "/"),
+				scheme:    p.scheme,
+				sse:       p.sse,
+			}
+			paths = append(paths, child)
+		}
 		return true
 	})
 	if err != nil {
@@ -369,6 +470,12 @@ func (p *S3Path) Hash(a hashing.HashAlgorithm) (*hashing.Hash, error) {
 	return &hashing.Hash{Algorithm: hashing.HashAlgorithmMD5, HashValue: md5Bytes}, nil
 }
 
+// isS3PreconditionFailed returns true if the error is a failed If-Match / If-None-Match precondition
+func isS3PreconditionFailed(err error) bool {
+	code := AWSErrorCode(err)
+	return code == "PreconditionFailed" || code == "ConditionalRequestConflict"
+}
+
 // AWSErrorCode returns the aws error code, if it is an awserr.Error, otherwise ""
 func AWSErrorCode(err error) string {
 	if awsError, ok := err.(awserr.Error); ok {
@@ -16,7 +16,18 @@ limitations under the License.
 
 package vfs
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
 
 func Test_S3Path_Parse(t *testing.T) {
 	grid := []struct {
@@ -64,3 +75,66 @@ func Test_S3Path_Parse(t *testing.T) {
 		}
 	}
 }
+
+// testS3Listing is the response to a listing of example.com/ with delimiter /, holding two objects and two prefixes
+const testS3Listing = `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+  <Name>state</Name>
+  <Prefix>example.com/</Prefix>
+  <Delimiter>/</Delimiter>
+  <IsTruncated>false</IsTruncated>
+  <Contents><Key>example.com/</Key><ETag>"d41d8cd98f00b204e9800998ecf8427e"</ETag><Size>0</Size></Contents>
+  <Contents><Key>example.com/cluster.spec</Key><ETag>"0cc175b9c0f1b6a831c399e269772661"</ETag><Size>1</Size></Contents>
+  <Contents><Key>example.com/config</Key><ETag>"92eb5ffee6ae2fec3ad71c777531578f"</ETag><Size>1</Size></Contents>
+  <CommonPrefixes><Prefix>example.com/instancegroup/</Prefix></CommonPrefixes>
+  <CommonPrefixes><Prefix>example.com/pki/</Prefix></CommonPrefixes>
+</ListBucketResult>`
+
+func TestS3PathReadDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/state" || query.Get("prefix") != "example.com/" || query.Get("delimiter") != "/" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(testS3Listing))
+	}))
+	defer server.Close()
+
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint(server.URL).
+		WithS3ForcePathStyle(true).
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", ""))
+	sess, err := session.NewSession(config)
+	if err != nil {
+		t.Fatalf("error building session: %v", err)
+	}
+
+	s3Context := NewS3Context()
+	s3Context.clients["us-east-1"] = s3.New(sess)
+	s3Context.bucketDetails["state"] = &S3BucketDetails{context: s3Context, region: "us-east-1", name: "state"}
+
+	children, err := newS3Path(s3Context, "s3", "state", "example.com", false).ReadDir()
+	if err != nil {
+		t.Fatalf("error listing: %v", err)
+	}
+	var actual []string
+	for _, child := range children {
+		actual = append(actual, child.Path())
+	}
+	sort.Strings(actual)
+
+	// The directory itself is skipped, and the common prefixes are returned as directories without a trailing slash
+	expected := []string{
+		"s3://state/example.com/cluster.spec",
+		"s3://state/example.com/config",
+		"s3://state/example.com/instancegroup",
+		"s3://state/example.com/pki",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected listing %v, expected %v", actual, expected)
+	}
+}
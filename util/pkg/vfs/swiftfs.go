@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -241,34 +242,45 @@ func (p *SwiftPath) Join(relativePath ...string) Path {
 }
 
 func (p *SwiftPath) WriteFile(data io.ReadSeeker, acl ACL) error {
+	_, err := p.writeFile(data, swiftobject.CreateOpts{})
+	return err
+}
+
+// writeFile writes the object with the given options, which may hold preconditions.  A failed precondition is not retried.
+// It returns the ETag of the written object.
+func (p *SwiftPath) writeFile(data io.ReadSeeker, createOpts swiftobject.CreateOpts) (string, error) {
+	etag := ""
 	done, err := RetryWithBackoff(swiftWriteBackoff, func() (bool, error) {
 		glog.V(4).Infof("Writing file %q", p)
 		if _, err := data.Seek(0, 0); err != nil {
 			return false, fmt.Errorf("error seeking to start of data stream for %s: %v", p, err)
 		}
 
-		createOpts := swiftobject.CreateOpts{Content: data}
-		_, err := swiftobject.Create(p.client, p.bucket, p.key, createOpts).Extract()
+		createOpts.Content = data
+		header, err := swiftobject.Create(p.client, p.bucket, p.key, createOpts).Extract()
 		if err != nil {
+			if isSwiftPreconditionFailed(err) {
+				return true, err
+			}
 			return false, fmt.Errorf("error writing %s: %v", p, err)
 		}
+		etag = header.ETag
 
 		return true, nil
 	})
 	if err != nil {
-		return err
+		return "", err
 	} else if done {
-		return nil
+		return etag, nil
 	} else {
 		// Shouldn't happen - we always return a non-nil error with false.
-		return wait.ErrWaitTimeout
+		return "", wait.ErrWaitTimeout
 	}
 }
 
 // To prevent concurrent creates on the same file while maintaining atomicity of writes,
 // we take a process-wide lock during the operation.
-// Not a great approach, but fine for a single process (with low concurrency).
-// TODO: should we enable versioning?
+// Other processes are excluded by the If-None-Match precondition on the write.
 var createFileLockSwift sync.Mutex
 
 func (p *SwiftPath) CreateFile(data io.ReadSeeker, acl ACL) error {
@@ -299,7 +311,12 @@ func (p *SwiftPath) CreateFile(data io.ReadSeeker, acl ACL) error {
 		return err
 	}
 
-	return p.WriteFile(data, acl)
+	// If-None-Match: * makes the write fail if the object was created since we checked
+	_, err = p.writeFile(data, swiftobject.CreateOpts{IfNoneMatch: "*"})
+	if isSwiftPreconditionFailed(err) {
+		return os.ErrExist
+	}
+	return err
 }
 
 func (p *SwiftPath) createBucket() error {
@@ -351,6 +368,60 @@ func (p *SwiftPath) ReadFile() ([]byte, error) {
 	}
 }
 
+var _ VersionedPath = &SwiftPath{}
+
+// ReadFileWithVersion implements VersionedPath::ReadFileWithVersion; the version is the object ETag
+func (p *SwiftPath) ReadFileWithVersion() ([]byte, string, error) {
+	glog.V(4).Infof("Reading file %q", p)
+
+	result := swiftobject.Download(p.client, p.bucket, p.key, swiftobject.DownloadOpts{})
+	if result.Err != nil {
+		if isSwiftNotFound(result.Err) {
+			return nil, "", os.ErrNotExist
+		}
+		return nil, "", fmt.Errorf("error reading %s: %v", p, result.Err)
+	}
+
+	header, err := result.Extract()
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", p, err)
+	}
+	data, err := result.ExtractContent()
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s: %v", p, err)
+	}
+	return data, header.ETag, nil
+}
+
+// WriteFileIfMatch implements VersionedPath::WriteFileIfMatch.
+// Swift only supports If-None-Match on writes, so for an existing object we compare the ETag before writing;
+// that check is only atomic within this process.
+func (p *SwiftPath) WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	createFileLockSwift.Lock()
+	defer createFileLockSwift.Unlock()
+
+	if expectedVersion == "" {
+		version, err := p.writeFile(data, swiftobject.CreateOpts{IfNoneMatch: "*"})
+		if isSwiftPreconditionFailed(err) {
+			return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+		}
+		return version, err
+	}
+
+	header, err := swiftobject.Get(p.client, p.bucket, p.key, swiftobject.GetOpts{}).Extract()
+	if err != nil {
+		if isSwiftNotFound(err) {
+			return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+		}
+		return "", fmt.Errorf("error getting %s: %v", p, err)
+	}
+	if header.ETag != expectedVersion {
+		return "", &VersionConflictError{Path: p, ExpectedVersion: expectedVersion}
+	}
+
+	return p.writeFile(data, swiftobject.CreateOpts{})
+}
+
 // WriteTo implements io.WriterTo
 func (p *SwiftPath) WriteTo(out io.Writer) (int64, error) {
 	glog.V(4).Infof("Reading file %q", p)
@@ -379,6 +450,15 @@ func (p *SwiftPath) readPath(opt swiftobject.ListOpts) ([]Path, error) {
 				return false, err1
 			}
 			for _, o := range objects {
+				if o.Subdir != "" {
+					// A directory, when listing with a delimiter
+					paths = append(paths, &SwiftPath{
+						client: p.client,
+						bucket: p.bucket,
+						key:    strings.TrimSuffix(o.Subdir, "/"),
+					})
+					continue
+				}
 				child := &SwiftPath{
 					client: p.client,
 					bucket: p.bucket,
@@ -416,8 +496,9 @@ func (p *SwiftPath) ReadDir() ([]Path, error) {
 		prefix += "/"
 	}
 	opt := swiftobject.ListOpts{
-		Full: true,
-		Path: prefix,
+		Full:      true,
+		Prefix:    prefix,
+		Delimiter: "/",
 	}
 	return p.readPath(opt)
 }
@@ -461,6 +542,14 @@ func (p *SwiftPath) Hash(a hashing.HashAlgorithm) (*hashing.Hash, error) {
 	return &hashing.Hash{Algorithm: hashing.HashAlgorithmMD5, HashValue: md5Bytes}, nil
 }
 
+func isSwiftPreconditionFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	e, ok := err.(gophercloud.ErrUnexpectedResponseCode)
+	return ok && e.Actual == http.StatusPreconditionFailed
+}
+
 func isSwiftNotFound(err error) bool {
 	if err == nil {
 		return false
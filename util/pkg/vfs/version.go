@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+)
+
+// VersionedPath is implemented by paths which support optimistic concurrency:
+// a file is read together with its version, and can then be written only if it has not changed since.
+type VersionedPath interface {
+	Path
+
+	// ReadFileWithVersion returns the contents of the file and its current version.
+	// Versions are opaque, and only meaningful for WriteFileIfMatch on the same path.
+	ReadFileWithVersion() ([]byte, string, error)
+
+	// WriteFileIfMatch writes the file, but only if its current version is expectedVersion;
+	// an empty expectedVersion means the file must not exist.
+	// If the version does not match, a *VersionConflictError is returned.
+	// On success it returns the new version of the file.
+	WriteFileIfMatch(data io.ReadSeeker, acl ACL, expectedVersion string) (string, error)
+}
+
+// VersionConflictError is returned by WriteFileIfMatch when the file was changed since it was read
+type VersionConflictError struct {
+	Path            Path
+	ExpectedVersion string
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.ExpectedVersion == "" {
+		return fmt.Sprintf("file %s was created concurrently", e.Path)
+	}
+	return fmt.Sprintf("file %s was changed concurrently (expected version %s)", e.Path, e.ExpectedVersion)
+}
+
+// IsVersionConflict returns true if the error is a VersionConflictError
+func IsVersionConflict(err error) bool {
+	_, ok := err.(*VersionConflictError)
+	return ok
+}
+
+// ReadFileWithVersion reads the file and its version, if the path supports versions.
+// For other paths the version is empty.
+func ReadFileWithVersion(p Path) ([]byte, string, error) {
+	if versioned, ok := p.(VersionedPath); ok {
+		return versioned.ReadFileWithVersion()
+	}
+	data, err := p.ReadFile()
+	return data, "", err
+}
+
+// WriteFileIfMatch writes the file only if its version is expectedVersion, if the path supports versions.
+// Paths which do not support versions are written unconditionally, with a warning, and the returned version is empty.
+func WriteFileIfMatch(p Path, data io.ReadSeeker, acl ACL, expectedVersion string) (string, error) {
+	if versioned, ok := p.(VersionedPath); ok {
+		return versioned.WriteFileIfMatch(data, acl, expectedVersion)
+	}
+	glog.Warningf("%s does not support conditional writes; writing it unconditionally, which may overwrite a concurrent change", p)
+	return "", p.WriteFile(data, acl)
+}
+
+// contentVersion is the version of a file for backends which don't have one, the hash of its contents
+func contentVersion(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
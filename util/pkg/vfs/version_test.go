@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testWriteFileIfMatch(t *testing.T, p Path) {
+	if _, _, err := ReadFileWithVersion(p); !os.IsNotExist(err) {
+		t.Fatalf("expected not found reading missing file, got %v", err)
+	}
+
+	// An expected version of "" means the file must not exist
+	createdVersion, err := WriteFileIfMatch(p, bytes.NewReader([]byte("one")), nil, "")
+	if err != nil {
+		t.Fatalf("error creating file: %v", err)
+	}
+	if _, err := WriteFileIfMatch(p, bytes.NewReader([]byte("other")), nil, ""); !IsVersionConflict(err) {
+		t.Fatalf("expected conflict creating existing file, got %v", err)
+	}
+
+	data, version, err := ReadFileWithVersion(p)
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	if string(data) != "one" || version == "" || version != createdVersion {
+		t.Fatalf("unexpected file contents %q and version %q (created at version %q)", data, version, createdVersion)
+	}
+
+	writtenVersion, err := WriteFileIfMatch(p, bytes.NewReader([]byte("two")), nil, version)
+	if err != nil {
+		t.Fatalf("error writing file at version %q: %v", version, err)
+	}
+
+	// The file has changed since version was read
+	if _, err := WriteFileIfMatch(p, bytes.NewReader([]byte("three")), nil, version); !IsVersionConflict(err) {
+		t.Fatalf("expected conflict writing changed file, got %v", err)
+	}
+
+	data, newVersion, err := ReadFileWithVersion(p)
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	if string(data) != "two" || newVersion == version || newVersion != writtenVersion {
+		t.Fatalf("unexpected file contents %q and version %q (written at version %q)", data, newVersion, writtenVersion)
+	}
+
+	if err := p.Remove(); err != nil {
+		t.Fatalf("error removing file: %v", err)
+	}
+	if _, err := WriteFileIfMatch(p, bytes.NewReader([]byte("four")), nil, newVersion); !IsVersionConflict(err) {
+		t.Fatalf("expected conflict writing removed file, got %v", err)
+	}
+}
+
+func TestWriteFileIfMatchMemFS(t *testing.T) {
+	p := NewMemFSPath(NewMemFSContext(), "statestore/example.com/config")
+	testWriteFileIfMatch(t, p)
+}
+
+func TestWriteFileIfMatchFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "version")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := NewFSPath(filepath.Join(dir, "example.com", "config"))
+	testWriteFileIfMatch(t, p)
+}
@@ -65,7 +65,9 @@ type Path interface {
 	// Path returns a string representing the full path
 	Path() string
 
-	// ReadDir lists the files in a particular Path
+	// ReadDir lists the files and directories directly in a particular Path.
+	// In object stores, which have no directories, a directory is the common prefix of the keys below it;
+	// it is returned as a Path without a trailing slash, and reading it as a file fails.
 	ReadDir() ([]Path, error)
 
 	// ReadTree lists all files (recursively) in the subtree rooted at the current Path
@@ -102,7 +104,7 @@ func IsClusterReadable(p Path) bool {
 	}
 
 	switch p.(type) {
-	case *S3Path, *GSPath, *SwiftPath, *OSSPath:
+	case *S3Path, *GSPath, *SwiftPath, *OSSPath, *AzureBlobPath:
 		return true
 
 	case *KubernetesPath: